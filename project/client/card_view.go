@@ -0,0 +1,43 @@
+package main
+
+import "strings"
+
+// rarityIcons são os ícones de exibição de cada raridade enviada pelo servidor (campo "rarity").
+var rarityIcons = map[string]string{
+	"comum":    "⚪",
+	"incomum":  "🟢",
+	"rara":     "🔵",
+	"lendária": "🌟",
+}
+
+// cardMeta são os metadados de exibição de uma carta. Servidores antigos não os enviam:
+// nesse caso os campos ficam vazios e a carta é exibida só com nome e Força.
+type cardMeta struct {
+	Rarity   string `json:"rarity,omitempty"`
+	Faction  string `json:"faction,omitempty"`
+	ImageRef string `json:"image_ref,omitempty"`
+}
+
+// describe retorna os metadados para exibição (ex: "🌟 lendária · Neutra"), ou "" se não houver.
+func (m cardMeta) describe() string {
+	var parts []string
+	if m.Rarity != "" {
+		if icon, ok := rarityIcons[m.Rarity]; ok {
+			parts = append(parts, icon+" "+m.Rarity)
+		} else {
+			parts = append(parts, m.Rarity)
+		}
+	}
+	if m.Faction != "" {
+		parts = append(parts, m.Faction)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// withMeta acrescenta os metadados ao texto da carta, se houver (ex: "Geralt (Força: 15) — 🌟 lendária · Neutra").
+func withMeta(text string, meta cardMeta) string {
+	if desc := meta.describe(); desc != "" {
+		return text + " — " + desc
+	}
+	return text
+}
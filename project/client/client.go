@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
@@ -21,8 +22,88 @@ var stateMutex sync.Mutex
 var isSearching bool
 var isInGame bool
 
-// Tempo máximo, em segundos, que o cliente ficará na fila de matchmaking.
-const matchmakingTimeoutSeconds = 15
+// Posição do jogador na fila de matchmaking (recebida em "QUEUE_STATUS|"), também protegida por 'stateMutex'.
+var queuePosition, queueTotal int
+
+// Busca atual (incrementada a cada contador de busca iniciado), também protegida por 'stateMutex'.
+// Um contador de uma busca anterior (ex: antes de reconectar) para quando ela muda.
+var searchGeneration int
+
+// Contador de jogada atual, também protegido por 'stateMutex': um novo prazo (rodada de morte
+// súbita, extensão de tempo, GET_TIMER) substitui o contador anterior.
+var gameCountdownGeneration int
+
+// Estoque global esgotado (avisos "STOCK_EXHAUSTED"/"STOCK_REPLENISHED"), também protegido por 'stateMutex'.
+// Enquanto verdadeiro, a opção de abrir pacote fica desabilitada no menu.
+var stockExhausted bool
+
+// Estratégia de jogada automática (flag -strategy). Vazia = o jogador escolhe a carta pelo teclado.
+var playStrategy string
+
+// Dificuldade do bot do servidor (flag -bot-difficulty), usada se a busca terminar contra um bot.
+// Vazia = dificuldade padrão do servidor.
+var botDifficulty string
+
+// Token de conexão (flag -token), exigido pelos servidores com AUTH_SECRET. Vazio = só o nome.
+var authToken string
+
+// Idioma das mensagens do servidor (flag -locale: pt-BR ou en). Vazio = padrão do servidor (pt-BR).
+var serverLocale string
+
+// loginMessage é a primeira mensagem da conexão: o nome do jogador ou, com -token, "AUTH|<nome>|<token>".
+func loginMessage(playerName string) string {
+	if authToken == "" {
+		return playerName
+	}
+	return "AUTH|" + playerName + "|" + authToken
+}
+
+// openPacksCommand monta o comando de abertura de n pacotes ("OPEN_PACK" para um só),
+// com a chave opcional que evita abrir de novo se o comando for repetido.
+func openPacksCommand(n int, key string) string {
+	command := "OPEN_PACK"
+	if n != 1 {
+		command = fmt.Sprintf("OPEN_PACKS %d", n)
+	}
+	if key != "" {
+		command += " " + key
+	}
+	return command
+}
+
+// validCardIndices informa se a entrada é um número de carta ou vários separados por vírgula (ex: "1,3,5").
+func validCardIndices(indices string) bool {
+	if indices == "" {
+		return false
+	}
+	for _, part := range strings.Split(indices, ",") {
+		if _, err := strconv.Atoi(part); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// tradeCommand monta o comando de troca: "TRADE_CARD n" ou, com vários números, "TRADE_CARDS n1,n2,...",
+// com o pedido "WANT <carta>" se houver.
+func tradeCommand(indices, want string) string {
+	command := "TRADE_CARD " + indices
+	if strings.Contains(indices, ",") {
+		command = "TRADE_CARDS " + indices
+	}
+	if want != "" {
+		command += " WANT " + want
+	}
+	return command
+}
+
+// findMatchCommand monta o comando de busca da fila clássica, com a dificuldade do bot, se houver.
+func findMatchCommand() string {
+	if botDifficulty == "" {
+		return "FIND_MATCH"
+	}
+	return "FIND_MATCH " + botDifficulty
+}
 
 // Função principal que inicializa e executa o cliente.
 func main() {
@@ -30,12 +111,30 @@ func main() {
 	botMode := flag.Bool("bot", false, "Executa o cliente em modo automatizado (bot).")
 	botCount := flag.Int("count", 1, "Número de bots a serem executados em paralelo.")
 	botPrefix := flag.String("prefix", "Jogador", "Prefixo para o nome dos bots.")
+	flag.StringVar(&playStrategy, "strategy", "", "Joga automaticamente a carta escolhida pela estratégia: highest, lowest ou random.")
+	tuiMode := flag.Bool("tui", false, "Modo interativo com painéis (menu, deck, partida e log) em vez do texto corrido.")
+	flag.StringVar(&botDifficulty, "bot-difficulty", "", "Dificuldade do bot do servidor, se não houver oponente a tempo: easy, medium ou hard.")
+	flag.StringVar(&authToken, "token", "", "Token de conexão do jogador, para servidores com autenticação (AUTH_SECRET).")
+	flag.StringVar(&serverLocale, "locale", "", "Idioma das mensagens do servidor: pt-BR ou en.")
 	flag.Parse()
+	// Subcomandos não interativos: conectam, executam uma ação, imprimem o resultado e saem.
+	if args := flag.Args(); len(args) > 0 && (args[0] == "open" || args[0] == "trade") {
+		runSubcommand(args[0], args[1:])
+		return
+	}
+	if !validStrategy(playStrategy) {
+		log.Fatalf("Estratégia inválida: %q (use highest, lowest ou random)", playStrategy)
+	}
+	switch botDifficulty {
+	case "", "easy", "medium", "hard":
+	default:
+		log.Fatalf("Dificuldade do bot inválida: %q (use easy, medium ou hard)", botDifficulty)
+	}
 
 	// Pega os argumentos que não são flags, como o IP do servidor.
 	args := flag.Args()
 	if len(args) < 1 {
-		log.Fatal("Uso: ./client [-bot] [-count N] [-prefix P] <ip_do_servidor> [nome_do_jogador_manual]")
+		log.Fatal("Uso: ./client [-bot] [-count N] [-prefix P] [-strategy highest|lowest|random] [-bot-difficulty easy|medium|hard] [-tui] [-token T] <ip_do_servidor> [nome_do_jogador_manual]\n   ou: ./client open <ip_do_servidor> <nome_do_jogador> [--packs N]\n   ou: ./client trade <ip_do_servidor> <nome_do_jogador> --card N[,M...] [--want X]")
 	}
 	serverIP := args[0]
 	serverWsUrl := fmt.Sprintf("ws://%s:8080", serverIP)
@@ -62,14 +161,14 @@ func main() {
 		playerName := args[1]
 		// O envio de pacotes UDP (keep-alive) foi removido, pois o WebSocket é persistente.
 		// A funcionalidade de heartbeat deve ser tratada pelo protocolo WebSocket.
-		handleServerConnection(playerName, serverWsUrl)
+		handleServerConnection(playerName, serverWsUrl, *tuiMode)
 	}
 }
 
 // runBot define o comportamento de um cliente automatizado.
 func runBot(playerName string, serverWsUrl string) {
 	u, _ := url.Parse(serverWsUrl)
-	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	conn, _, err := wsDialer.Dial(u.String(), nil)
 	if err != nil {
 		log.Printf("[Bot %s]: Não foi possível conectar ao servidor: %v", playerName, err)
 		return
@@ -77,7 +176,7 @@ func runBot(playerName string, serverWsUrl string) {
 	defer conn.Close()
 
 	// 1. Envia o nome do jogador
-	err = conn.WriteMessage(websocket.TextMessage, []byte(playerName))
+	err = conn.WriteMessage(websocket.TextMessage, []byte(loginMessage(playerName)))
 	if err != nil {
 		log.Printf("[Bot %s]: Erro ao enviar nome: %v", playerName, err)
 		return
@@ -89,8 +188,48 @@ func runBot(playerName string, serverWsUrl string) {
 		log.Printf("[Bot %s]: Erro ao receber pacote inicial: %v", playerName, err)
 		return
 	}
+	if name, ok := strings.CutPrefix(string(p), "ASSIGNED_NAME|"); ok {
+		log.Printf("[Bot %s]: Nome já está em uso no cluster. Jogando como %s.", playerName, name)
+		playerName = name
+		if _, p, err = conn.ReadMessage(); err != nil {
+			log.Printf("[Bot %s]: Erro ao receber pacote inicial: %v", playerName, err)
+			return
+		}
+	}
+	if strings.HasPrefix(string(p), "RECONNECT_TOKEN|") {
+		// O bot não reconecta: o token de retomada não é usado
+		if _, p, err = conn.ReadMessage(); err != nil {
+			log.Printf("[Bot %s]: Erro ao receber pacote inicial: %v", playerName, err)
+			return
+		}
+	}
+	if string(p) == "NAME_TAKEN" {
+		log.Printf("[Bot %s]: Nome já está em uso no cluster. Encerrando.", playerName)
+		return
+	}
+	if string(p) == "MAINTENANCE" {
+		log.Printf("[Bot %s]: Servidor em manutenção. Encerrando.", playerName)
+		return
+	}
+	if strings.HasPrefix(string(p), "INVALID_NAME|") {
+		log.Printf("[Bot %s]: Nome inválido (%s). Encerrando.", playerName, strings.TrimPrefix(string(p), "INVALID_NAME|"))
+		return
+	}
+	if reason, ok := strings.CutPrefix(string(p), "AUTH_FAILED|"); ok {
+		log.Printf("[Bot %s]: Autenticação recusada (%s). Encerrando.", playerName, reason)
+		return
+	}
 	log.Printf("[Bot %s]: Pacote inicial recebido: %s", playerName, string(p))
 
+	if serverLocale != "" {
+		conn.WriteMessage(websocket.TextMessage, []byte("SET_LOCALE "+serverLocale))
+		if _, p, err = conn.ReadMessage(); err != nil {
+			log.Printf("[Bot %s]: Erro ao definir o idioma: %v", playerName, err)
+			return
+		}
+		log.Printf("[Bot %s]: %s", playerName, string(p))
+	}
+
 	// 3. Ação automatizada: O bot abre 2 pacotes de cartas.
 	log.Printf("[Bot %s]: Abrindo 2 pacotes extras...", playerName)
 	for i := 0; i < 2; i++ {
@@ -105,7 +244,7 @@ func runBot(playerName string, serverWsUrl string) {
 
 	// 4. Ação automatizada: O bot entra na fila para uma partida.
 	log.Printf("[Bot %s]: Procurando partida...", playerName)
-	conn.WriteMessage(websocket.TextMessage, []byte("FIND_MATCH"))
+	conn.WriteMessage(websocket.TextMessage, []byte(findMatchCommand()))
 
 	// 5. Loop principal do bot, que reage às mensagens do servidor.
 	for {
@@ -117,18 +256,52 @@ func runBot(playerName string, serverWsUrl string) {
 
 		message := strings.TrimSpace(string(p))
 
-		if strings.HasPrefix(message, "MATCH_START|") {
-			// Ao iniciar a partida, o bot joga a primeira carta ("1") automaticamente.
-			log.Printf("[Bot %s]: Partida iniciada! Jogando...", playerName)
+		if strings.HasPrefix(message, "GAME_START|") {
+			// Ao iniciar a partida, o bot joga a primeira carta ("1") automaticamente,
+			// ou a escolhida pela estratégia (-strategy).
+			start, err := parseGameStart(strings.TrimPrefix(message, "GAME_START|"))
+			if err != nil {
+				log.Printf("[Bot %s]: %v. Jogando a primeira carta.", playerName, err)
+				conn.WriteMessage(websocket.TextMessage, []byte("1"))
+				continue
+			}
+			log.Printf("[Bot %s]: Partida iniciada contra %s! Jogando...", playerName, start.Opponent)
+			choice := "1"
+			if playStrategy != "" {
+				if choice, err = chooseCard(playStrategy, start.Hand); err != nil {
+					log.Printf("[Bot %s]: %v. Jogando a primeira carta.", playerName, err)
+					choice = "1"
+				}
+				log.Printf("[Bot %s]: Estratégia %s escolheu a carta %s.", playerName, playStrategy, choice)
+			}
+			conn.WriteMessage(websocket.TextMessage, []byte(choice))
+		} else if strings.HasPrefix(message, "SUDDEN_DEATH|") {
+			// Empate: a rodada de morte súbita traz uma única carta, então o bot a joga direto
+			if round, err := parseSuddenDeath(strings.TrimPrefix(message, "SUDDEN_DEATH|")); err == nil {
+				log.Printf("[Bot %s]: Morte súbita (rodada %d de %d). Jogando...", playerName, round.Round, round.MaxRounds)
+			}
 			conn.WriteMessage(websocket.TextMessage, []byte("1"))
-		} else if strings.HasPrefix(message, "RESULT|") {
+		} else if strings.HasPrefix(message, "REVEAL|") {
+			if reveal, err := parseGameReveal(strings.TrimPrefix(message, "REVEAL|")); err == nil {
+				log.Printf("[Bot %s]: %s.", playerName, reveal)
+			}
+		} else if strings.HasPrefix(message, "GAME_END|") {
 			// Ao receber o resultado, o bot encerra sua execução.
-			log.Printf("[Bot %s]: Partida finalizada. Resultado: %s", playerName, message)
+			result := strings.TrimPrefix(message, "GAME_END|")
+			if end, err := parseGameEnd(result); err == nil {
+				result = end.String()
+			}
+			log.Printf("[Bot %s]: Partida finalizada. Resultado: %s", playerName, result)
 			break
 		} else if message == "NO_MATCH_FOUND" {
 			log.Printf("[Bot %s]: Nenhum oponente encontrado. Encerrando.", playerName)
 			break
-		} else if strings.HasPrefix(message, "TIMER|") {
+		} else if strings.HasPrefix(message, "DECK_TOO_SMALL|") {
+			log.Printf("[Bot %s]: Deck pequeno demais para jogar. Encerrando.", playerName)
+			break
+		} else if strings.HasPrefix(message, "TIMER|") || strings.HasPrefix(message, "SEARCH_TIMER|") || strings.HasPrefix(message, "QUEUE_STATUS|") || strings.HasPrefix(message, "STILL_SEARCHING|") {
+		} else if message == "STOCK_EXHAUSTED" || message == "STOCK_REPLENISHED" {
+			log.Printf("[Bot %s]: Aviso do estoque global: %s", playerName, message)
 		} else {
 			log.Printf("[Bot %s]: [Servidor]: %s", playerName, message)
 		}
@@ -137,29 +310,22 @@ func runBot(playerName string, serverWsUrl string) {
 }
 
 // handleServerConnection gerencia a lógica para um jogador humano.
-func handleServerConnection(playerName string, serverWsUrl string) {
+// Com useTUI, a exibição passa a ser a tuiDisplay (painéis) logo após a conexão.
+func handleServerConnection(playerName string, serverWsUrl string, useTUI bool) {
 	u, _ := url.Parse(serverWsUrl)
-	var conn *websocket.Conn
-	var err error
-
-	// Tenta se conectar ao servidor com um número máximo de retentativas.
-	maxRetries := 5
-	for i := 0; i < maxRetries; i++ {
-		conn, _, err = websocket.DefaultDialer.Dial(u.String(), nil)
-		if err == nil {
-			break // Conexão bem-sucedida.
-		}
-		log.Printf("%s: Falha ao conectar ao servidor (%v). Tentando novamente em 2 segundos...", playerName, err)
-		time.Sleep(2 * time.Second)
-	}
 
+	// Tenta se conectar ao servidor com um número máximo de retentativas e envia o nome do jogador.
+	conn, err := connectToServer(playerName, u.String())
 	if err != nil {
-		log.Fatalf("%s: Não foi possível conectar ao servidor após %d tentativas.", playerName, maxRetries)
+		log.Fatalf("%s: %v", playerName, err)
+	}
+	defer conn.close()
+	if useTUI {
+		tui := newTUIDisplay(playerName)
+		out = tui
+		log.SetOutput(tui) // Avisos de conexão e reconexão vão para o painel de log
+		defer tui.Close()
 	}
-	defer conn.Close()
-
-	// 1. Envia o nome do jogador
-	conn.WriteMessage(websocket.TextMessage, []byte(playerName))
 	log.Printf("%s: Conectado com sucesso!", playerName)
 
 	// Contexto para cancelar a leitura de jogada em caso de fim de partida
@@ -177,7 +343,7 @@ func handleServerConnection(playerName string, serverWsUrl string) {
 		stateMutex.Unlock()
 
 		if canShowMenu {
-			showMenu()
+			out.ShowMenu()
 			input, _ := reader.ReadString('\n')
 			choice := strings.TrimSpace(input)
 
@@ -187,119 +353,498 @@ func handleServerConnection(playerName string, serverWsUrl string) {
 				stateMutex.Lock()
 				isSearching = true // Atualiza o estado para "procurando".
 				stateMutex.Unlock()
-				conn.WriteMessage(websocket.TextMessage, []byte("FIND_MATCH"))
-				go runSearchCountdown(matchmakingTimeoutSeconds) // Inicia o contador visual.
+				conn.send(findMatchCommand())
+				// O contador visual é iniciado ao receber "SEARCH_TIMER|" com o tempo do servidor.
 			case "2":
-				conn.WriteMessage(websocket.TextMessage, []byte("OPEN_PACK"))
+				stateMutex.Lock()
+				exhausted := stockExhausted
+				stateMutex.Unlock()
+				if exhausted {
+					out.Printf("O estoque global está esgotado. Aguarde a reposição para abrir pacotes.\n")
+				} else {
+					conn.send(openPacksCommand(1, ""))
+				}
 			case "3":
-				conn.WriteMessage(websocket.TextMessage, []byte("VIEW_DECK"))
+				conn.send("VIEW_DECK")
 			case "4":
-				fmt.Print("Digite o número da carta no seu deck para trocar (começando em 1). (Use '3. Ver Meu Deck' para ver os números): ")
+				out.Promptf("Digite o número da carta no seu deck para trocar (começando em 1), ou vários separados por vírgula para trocar um pacote (ex: 1,3,5). (Use '3. Ver Meu Deck' para ver os números): ")
 				input, _ := reader.ReadString('\n')
-				cardIndexStr := strings.TrimSpace(input)
-				// Validação simples
-				if _, err := strconv.Atoi(cardIndexStr); err == nil {
-					if cardIndexStr != "" {
-						conn.WriteMessage(websocket.TextMessage, []byte("TRADE_CARD "+cardIndexStr))
-					} else {
-						fmt.Println("Entrada inválida.")
-					}
-				} else {
-					fmt.Println("Entrada inválida. Deve ser um número.")
+				cardIndexStr := strings.ReplaceAll(strings.TrimSpace(input), " ", "")
+				if !validCardIndices(cardIndexStr) {
+					out.Printf("Entrada inválida. Deve ser um número (ou números separados por vírgula).\n")
+					break
 				}
+				out.Promptf("O que você quer em troca? Nome da carta, Força mínima (ex: 5) ou Enter para aceitar qualquer carta: ")
+				wantInput, _ := reader.ReadString('\n')
+				conn.send(tradeCommand(cardIndexStr, strings.TrimSpace(wantInput)))
 			case "5":
+				conn.send("LEADERBOARD")
+			case "6":
+				conn.send("REMATCH")
+			case "7":
+				stateMutex.Lock()
+				isSearching = true // Atualiza o estado para "procurando".
+				stateMutex.Unlock()
+				conn.send("FIND_MATCH FFA")
+			case "8":
+				conn.send("STATUS")
+			case "9":
+				conn.send("REPLAY")
+			case "10":
+				conn.send("COLLECTION")
+			case "11":
+				conn.send("CREATE_PRIVATE")
+			case "12":
+				out.Promptf("Digite o código da partida privada: ")
+				input, _ := reader.ReadString('\n')
+				code := strings.ToUpper(strings.TrimSpace(input))
+				if code == "" {
+					out.Printf("Código inválido.\n")
+				} else {
+					conn.send("JOIN_PRIVATE " + code)
+				}
+			case "13":
+				out.Promptf("Digite os números das cartas do deck que entram nas partidas, separados por vírgula (ex: 1,4,7), ou deixe em branco para usar o deck inteiro: ")
+				input, _ := reader.ReadString('\n')
+				list := strings.ReplaceAll(strings.TrimSpace(input), " ", "")
+				if list == "" {
+					conn.send("SET_LOADOUT")
+				} else {
+					conn.send("SET_LOADOUT " + list)
+				}
+			case "14":
+				out.Promptf("Digite o nome do adversário: ")
+				input, _ := reader.ReadString('\n')
+				opponent := strings.TrimSpace(input)
+				if opponent == "" {
+					out.Printf("Nome inválido.\n")
+				} else {
+					conn.send("H2H " + opponent)
+				}
+			case "15":
+				conn.send("TRADE_QUEUE_PEEK")
+			case "16":
 				return // Encerra a função e o programa.
 			default:
-				fmt.Println("Opção inválida. Tente novamente.")
+				out.Printf("Opção inválida. Tente novamente.\n")
 			}
 		}
 		time.Sleep(100 * time.Millisecond) // Pausa para evitar uso excessivo de CPU.
 	}
 }
 
-// showMenu apenas exibe as opções de ação para o jogador.
-func showMenu() {
-	fmt.Println("\n--- MENU PRINCIPAL ---")
-	fmt.Println("1. Procurar Partida")
-	fmt.Println("2. Abrir Pacote de Cartas")
-	fmt.Println("3. Ver Meu Deck")
-	fmt.Println("4. Trocar Carta")
-	fmt.Println("5. Sair")
-	fmt.Print("> ")
-}
-
 // listenServerMessages roda em background para processar todas as mensagens recebidas do servidor.
-func listenServerMessages(conn *websocket.Conn, playerName string, cancelGame context.CancelFunc) {
+func listenServerMessages(conn *serverConnection, playerName string, cancelGame context.CancelFunc) {
 	for {
-		_, p, err := conn.ReadMessage()
+		p, err := conn.read()
 		if err != nil {
-			log.Printf("%s: Conexão com o servidor perdida: %v", playerName, err)
-			os.Exit(0)
+			// Queda de conexão: tenta reconectar, preservando o estado de partida/busca.
+			log.Printf("%s: Conexão com o servidor perdida: %v. Reconectando...", playerName, err)
+			if err := conn.reconnect(); err != nil {
+				log.Printf("%s: Não foi possível reconectar (%v). Encerrando.", playerName, err)
+				out.Close()
+				os.Exit(1)
+			}
+			log.Printf("%s: Reconectado ao servidor.", playerName)
+			continue
 		}
 
 		message := strings.TrimSpace(string(p))
-		fmt.Printf("\r%s\n", strings.Repeat(" ", 50)) // Limpa a linha atual antes de exibir a mensagem.
+		out.Printf("\r%s\n", strings.Repeat(" ", 50)) // Limpa a linha atual antes de exibir a mensagem.
 
 		// Trata as diferentes mensagens do servidor, atualizando o estado do cliente conforme necessário.
-		if strings.HasPrefix(message, "MATCH_START|") {
+		if name, ok := strings.CutPrefix(message, "ASSIGNED_NAME|"); ok {
+			if name != playerName {
+				out.Printf("\r[Servidor]: O nome '%s' já está em uso. Você está jogando como '%s'.\n", playerName, name)
+			}
+			playerName = name
+			conn.setPlayerName(name)
+			stateMutex.Lock()
+			assignedName = name
+			stateMutex.Unlock()
+		} else if token, ok := strings.CutPrefix(message, "RECONNECT_TOKEN|"); ok {
+			conn.setReconnectToken(token) // Usado só na próxima reconexão; não é exibido
+			continue
+		} else if strings.HasPrefix(message, "GAME_START|") {
+			start, err := parseGameStart(strings.TrimPrefix(message, "GAME_START|"))
+			if err != nil {
+				out.Printf("\r[Servidor]: %v\n", err)
+				continue
+			}
 			stateMutex.Lock()
 			isSearching = false
 			isInGame = true
 			stateMutex.Unlock()
-			handleGame(context.Background(), conn, message)
-		} else if strings.HasPrefix(message, "RESULT|") {
+			handleGame(context.Background(), conn, start)
+		} else if strings.HasPrefix(message, "SUDDEN_DEATH|") {
+			round, err := parseSuddenDeath(strings.TrimPrefix(message, "SUDDEN_DEATH|"))
+			if err != nil {
+				out.Printf("\r[Servidor]: %v\n", err)
+				continue
+			}
+			out.Printf("\r--- MORTE SÚBITA (rodada %d de %d) ---\n", round.Round, round.MaxRounds)
+			stateMutex.Lock()
+			isInGame = true
+			stateMutex.Unlock()
+			handleGame(context.Background(), conn, round.gameStart)
+		} else if strings.HasPrefix(message, "REVEAL|") {
+			cancelGame() // A jogada já foi decidida: a leitura pendente, se houver, não vale mais.
+			reveal, err := parseGameReveal(strings.TrimPrefix(message, "REVEAL|"))
+			if err != nil {
+				out.Printf("\r[Servidor]: %v\n", err)
+				continue
+			}
+			animateReveal(reveal)
+		} else if strings.HasPrefix(message, "GAME_END|") {
 			cancelGame() // Cancela a leitura de jogada, se estiver pendente.
-			parts := strings.SplitN(message, "|", 2)
-			fmt.Printf("\r--- FIM DA PARTIDA ---\n%s\n---------------------\n", parts[1])
+			result := strings.TrimPrefix(message, "GAME_END|")
+			if end, err := parseGameEnd(result); err == nil {
+				result = end.String()
+			}
+			out.Printf("\r--- FIM DA PARTIDA ---\n%s\n---------------------\n", result)
 			stateMutex.Lock()
 			isInGame = false // Retorna ao estado ocioso.
+			currentOpponent, currentHand = "", nil
 			stateMutex.Unlock()
 		} else if message == "MATCH_FOUND" {
-			fmt.Printf("\r[Servidor]: Partida encontrada! Iniciando...\n")
+			out.Printf("\r[Servidor]: Partida encontrada! Iniciando...\n")
 			stateMutex.Lock()
 			isSearching = false
 			stateMutex.Unlock()
+		} else if message == "NAME_TAKEN" {
+			out.Printf("\r[Servidor]: O nome '%s' já está em uso. Escolha outro nome.\n", playerName)
+			out.Close()
+			os.Exit(1)
+		} else if message == "MAINTENANCE" {
+			out.Printf("\r[Servidor]: O servidor está em manutenção e não aceita novas conexões. Conecte-se a outro servidor.\n")
+			out.Close()
+			os.Exit(1)
+		} else if strings.HasPrefix(message, "INVALID_NAME|") {
+			out.Printf("\r[Servidor]: Nome inválido: %s.\n", strings.TrimPrefix(message, "INVALID_NAME|"))
+			out.Close()
+			os.Exit(1)
+		} else if strings.HasPrefix(message, "AUTH_FAILED|") {
+			out.Printf("\r[Servidor]: Autenticação recusada: %s. Verifique o token (-token).\n", strings.TrimPrefix(message, "AUTH_FAILED|"))
+			out.Close()
+			os.Exit(1)
+		} else if strings.HasPrefix(message, "REMATCH_OFFER|") {
+			parts := strings.Split(message, "|")
+			out.Printf("\r[Servidor]: Revanche disponível por %s segundos! Escolha '6' no menu para aceitar.\n", parts[1])
+		} else if message == "REMATCH_EXPIRED" {
+			out.Printf("\r[Servidor]: O prazo para a revanche terminou.\n")
+		} else if strings.HasPrefix(message, "PRIVATE_CODE|") {
+			parts := strings.Split(message, "|")
+			if len(parts) == 3 {
+				out.Printf("\r[Servidor]: Partida privada criada! Código: %s (válido por %s segundos). Passe o código ao seu amigo (opção '12').\n", parts[1], parts[2])
+			}
+		} else if text, ok := strings.CutPrefix(message, "TRADE_PENDING|"); ok {
+			out.Printf("\r[Servidor]: Troca recusada: %s\n", text)
+		} else if message == "PRIVATE_EXPIRED" {
+			out.Printf("\r[Servidor]: O código da partida privada expirou sem que ninguém entrasse.\n")
+		} else if strings.HasPrefix(message, "MOVE_ACK|") {
+			out.Printf("\r[Servidor]: Jogada registrada: %s. Aguardando resultado...\n", strings.TrimPrefix(message, "MOVE_ACK|"))
+		} else if strings.HasPrefix(message, "MOVE_REJECTED|") {
+			handleMoveRejected(conn, message)
+		} else if strings.HasPrefix(message, "STATUS|") {
+			printStatus(strings.TrimPrefix(message, "STATUS|"))
+		} else if strings.HasPrefix(message, "REPLAY|") {
+			printReplay(strings.TrimPrefix(message, "REPLAY|"))
+		} else if strings.HasPrefix(message, "COLLECTION|") {
+			printCollection(strings.TrimPrefix(message, "COLLECTION|"))
+		} else if message == "NO_ACTIVE_GAME" {
+			// Resposta ao GET_TIMER após uma reconexão: a partida não existe mais no servidor.
+			stateMutex.Lock()
+			wasInGame := isInGame
+			isInGame = false
+			currentOpponent, currentHand = "", nil
+			stateMutex.Unlock()
+			if wasInGame {
+				out.Printf("\r[Servidor]: A partida em andamento foi encerrada durante a desconexão.\n")
+			}
+		} else if message == "STOCK_EXHAUSTED" {
+			out.Printf("\r[Servidor]: O estoque global de cartas acabou. A abertura de pacotes volta após a reposição.\n")
+			stateMutex.Lock()
+			stockExhausted = true
+			stateMutex.Unlock()
+		} else if message == "STOCK_REPLENISHED" {
+			out.Printf("\r[Servidor]: O estoque global de cartas foi reposto! Já é possível abrir pacotes.\n")
+			stateMutex.Lock()
+			stockExhausted = false
+			stateMutex.Unlock()
+		} else if strings.HasPrefix(message, "COMMAND_REJECTED|") {
+			// O comando não vale no estado atual (ex: em partida ou na fila); o servidor informa o motivo.
+			out.Printf("\r[Servidor]: Comando recusado: %s\n", strings.TrimPrefix(message, "COMMAND_REJECTED|"))
+		} else if message == "RATE_LIMITED" {
+			out.Printf("\r[Servidor]: Muitos comandos em sequência. Aguarde um instante e tente novamente.\n")
+			stateMutex.Lock()
+			isSearching = false // Um FIND_MATCH recusado não entra na fila.
+			stateMutex.Unlock()
+		} else if strings.HasPrefix(message, "DECK_TOO_SMALL|") {
+			parts := strings.Split(message, "|")
+			out.Printf("\r[Servidor]: Seu deck precisa de pelo menos %s cartas para jogar. Abra um pacote e tente novamente.\n", parts[1])
+			stateMutex.Lock()
+			isSearching = false // Não entrou na fila: retorna ao estado ocioso.
+			stateMutex.Unlock()
 		} else if message == "NO_MATCH_FOUND" {
-			fmt.Printf("\r[Servidor]: Nenhum oponente encontrado a tempo. Tente novamente.\n")
+			out.Printf("\r[Servidor]: Nenhum oponente encontrado a tempo. Tente novamente.\n")
 			stateMutex.Lock()
 			isSearching = false // Retorna ao estado ocioso.
 			stateMutex.Unlock()
+		} else if strings.HasPrefix(message, "SEARCH_TIMER|") {
+			parts := strings.Split(message, "|")
+			seconds, _ := strconv.Atoi(parts[1])
+			stateMutex.Lock()
+			queuePosition, queueTotal = 0, 0
+			stateMutex.Unlock()
+			startSearchCountdown(seconds) // Inicia o contador visual com o tempo informado pelo servidor.
+		} else if strings.HasPrefix(message, "SEARCH_RESUMED|") {
+			// Reconexão no meio da busca: o ticket continua na fila, com o tempo que restava.
+			seconds, _ := strconv.Atoi(strings.TrimPrefix(message, "SEARCH_RESUMED|"))
+			out.Printf("\r[Servidor]: Busca retomada após a reconexão (%d segundos restantes).\n", seconds)
+			startSearchCountdown(seconds)
+		} else if message == "SEARCH_EXPIRED" {
+			out.Printf("\r[Servidor]: A busca por partida expirou enquanto você estava desconectado. Tente novamente.\n")
+			stateMutex.Lock()
+			isSearching = false // Volta ao menu.
+			stateMutex.Unlock()
+		} else if strings.HasPrefix(message, "QUEUE_STATUS|") {
+			// Formato: QUEUE_STATUS|<posição>|<total>. O contador de busca passa a exibi-la.
+			parts := strings.Split(message, "|")
+			if len(parts) == 3 {
+				stateMutex.Lock()
+				queuePosition, _ = strconv.Atoi(parts[1])
+				queueTotal, _ = strconv.Atoi(parts[2])
+				stateMutex.Unlock()
+			}
+		} else if strings.HasPrefix(message, "STILL_SEARCHING|") {
+			// Formato: STILL_SEARCHING|<segundos restantes>. Enviado quando o jogador está sozinho na fila.
+			out.Printf("\r[Servidor]: Você é o único jogador na fila no momento. A busca continua (%s segundos restantes)...\n", strings.TrimPrefix(message, "STILL_SEARCHING|"))
 		} else if strings.HasPrefix(message, "TIMER|") {
 			parts := strings.Split(message, "|")
 			seconds, _ := strconv.Atoi(parts[1])
-			go runGameCountdown(seconds) // Inicia o contador de tempo de jogada.
+			startGameCountdown(seconds) // Resposta ao GET_TIMER (após reconectar): retoma o contador da jogada.
+		} else if payload, ok := strings.CutPrefix(message, "TURN_EXTENDED|"); ok {
+			extended, err := parseTurnExtended(payload)
+			if err != nil {
+				out.Printf("\r[Cliente]: %v\n", err)
+				continue
+			}
+			if extended.Player == playerName {
+				out.Printf("\r[Servidor]: Tempo extra concedido: %d segundos para jogar.\n", extended.TurnSeconds)
+			} else {
+				out.Printf("\r[Servidor]: %s pediu mais tempo: %d segundos para jogar.\n", extended.Player, extended.TurnSeconds)
+			}
+			startGameCountdown(extended.TurnSeconds)
 		} else {
-			// Exibe qualquer outra mensagem genérica do servidor.
-			fmt.Printf("\r[Servidor]: %s\n", message)
+			// Exibe qualquer outra mensagem genérica do servidor (a listagem do deck também vai para o painel da TUI).
+			updateDeckListing(message)
+			out.Printf("\r[Servidor]: %s\n", message)
 		}
 
 		// Se o jogador não estiver ocupado, reexibe o prompt ">" para a próxima ação.
 		stateMutex.Lock()
-		if !isSearching && !isInGame {
-			fmt.Print("> ")
-		}
+		idle := !isSearching && !isInGame
 		stateMutex.Unlock()
+		if idle {
+			out.Reprompt()
+		}
+	}
+}
+
+// revealFrames são os quadros da animação da carta do oponente sendo virada.
+var revealFrames = []string{"[ ? ]", "[ / ]", "[ | ]", "[ \\ ]"}
+
+// animateReveal vira a carta do oponente (REVEAL|) antes do resultado. Roda na goroutine que lê
+// as mensagens, então o GAME_END, que chega logo depois, só é exibido quando a animação acaba.
+func animateReveal(reveal gameReveal) {
+	for _, frame := range revealFrames {
+		out.Countdown("Carta do oponente: " + frame)
+		time.Sleep(150 * time.Millisecond)
+	}
+	out.Countdown("")
+	out.Printf("\r[Partida]: %s!\n", reveal)
+}
+
+// playerStatus é o resumo do jogador enviado pelo servidor em "STATUS|<json>".
+type playerStatus struct {
+	PlayerName  string `json:"player_name"`
+	ServerID    string `json:"server_id"`
+	State       string `json:"state"`
+	PacksOpened int    `json:"packs_opened"`
+	MaxPacks    int    `json:"max_packs"`
+	DeckSize    int    `json:"deck_size"`
+	Wins        int    `json:"wins"`
+	Losses      int    `json:"losses"`
+	Draws       int    `json:"draws"`
+	Rank        int    `json:"rank"`
+}
+
+// parseStatus lê o payload de "STATUS|<json>".
+func parseStatus(statusJSON string) (playerStatus, error) {
+	var status playerStatus
+	err := json.Unmarshal([]byte(statusJSON), &status)
+	return status, err
+}
+
+// printStatus exibe o resumo do jogador enviado pelo servidor em "STATUS|<json>".
+func printStatus(statusJSON string) {
+	status, err := parseStatus(statusJSON)
+	if err != nil {
+		out.Printf("\r[Servidor]: Status inválido recebido: %s\n", statusJSON)
+		return
+	}
+
+	out.Printf("\r--- SEU STATUS ---\n")
+	out.Printf("Jogador: %s (servidor %s)\n", status.PlayerName, status.ServerID)
+	out.Printf("Estado: %s\n", status.State)
+	out.Printf("Pacotes abertos: %d/%d | Cartas no deck: %d\n", status.PacksOpened, status.MaxPacks, status.DeckSize)
+	out.Printf("Partidas: %dV/%dD/%dE", status.Wins, status.Losses, status.Draws)
+	if status.Rank > 0 {
+		out.Printf(" | Posição no ranking: %d", status.Rank)
 	}
+	out.Printf("\n")
 }
 
-// handleGame exibe a mão do jogador e inicia a captura da sua jogada.
-func handleGame(ctx context.Context, conn *websocket.Conn, message string) {
-	parts := strings.Split(message, "|")
-	card1 := parts[1]
-	card2 := parts[2]
+// printReplay exibe os eventos da partida enviados pelo servidor em "REPLAY|<json>".
+func printReplay(replayJSON string) {
+	var replay struct {
+		GameID string `json:"game_id"`
+		Events []struct {
+			Timestamp int64  `json:"timestamp"`
+			Type      string `json:"type"`
+			Player    string `json:"player"`
+			Cards     []struct {
+				Name  string `json:"name"`
+				Forca int    `json:"forca"`
+			} `json:"cards"`
+			Outcome string `json:"outcome"`
+			Detail  string `json:"detail"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal([]byte(replayJSON), &replay); err != nil {
+		out.Printf("\r[Servidor]: Replay inválido recebido: %s\n", replayJSON)
+		return
+	}
+
+	out.Printf("\r--- REPLAY DA PARTIDA %s ---\n", replay.GameID)
+	for _, e := range replay.Events {
+		var cards []string
+		for _, c := range e.Cards {
+			cards = append(cards, fmt.Sprintf("%s (%d)", c.Name, c.Forca))
+		}
+		at := time.UnixMilli(e.Timestamp).Format("15:04:05.000")
+		switch e.Type {
+		case "hand_dealt":
+			out.Printf("[%s] Mão de %s: %s\n", at, e.Player, strings.Join(cards, ", "))
+		case "card_played":
+			out.Printf("[%s] %s jogou %s\n", at, e.Player, strings.Join(cards, ", "))
+		case "forfeit":
+			out.Printf("[%s] %s desconectou sem jogar\n", at, e.Player)
+		case "timeout":
+			out.Printf("[%s] Tempo de jogada esgotado\n", at)
+		case "result":
+			out.Printf("[%s] Resultado (%s): %s\n", at, e.Outcome, e.Detail)
+		default:
+			out.Printf("[%s] %s\n", at, e.Type)
+		}
+	}
+}
 
-	fmt.Println("\r--- PARTIDA INICIADA ---")
-	fmt.Println("Sua mão:")
-	fmt.Printf("1: %s\n", card1)
-	fmt.Printf("2: %s\n", card2)
-	fmt.Print("Escolha sua carta (1 ou 2): > ")
+// printCollection exibe a coleção enviada pelo servidor em "COLLECTION|<json>":
+// o progresso no conjunto completo, as cópias de cada carta e as cartas que faltam.
+func printCollection(collectionJSON string) {
+	var collection struct {
+		DeckSize int `json:"deck_size"`
+		Unique   int `json:"unique"`
+		SetSize  int `json:"set_size"`
+		Owned    []struct {
+			Name  string `json:"name"`
+			Forca int    `json:"forca"`
+			Count int    `json:"count"`
+			cardMeta
+		} `json:"owned"`
+		Missing []string `json:"missing"`
+	}
+	if err := json.Unmarshal([]byte(collectionJSON), &collection); err != nil {
+		out.Printf("\r[Servidor]: Coleção inválida recebida: %v\n", err)
+		return
+	}
+
+	out.Printf("\r--- SUA COLEÇÃO: %d/%d únicas (%d cartas no deck) ---\n", collection.Unique, collection.SetSize, collection.DeckSize)
+	for _, c := range collection.Owned {
+		out.Printf("%s\n", withMeta(fmt.Sprintf("%dx %s (Força: %d)", c.Count, c.Name, c.Forca), c.cardMeta))
+	}
+	if len(collection.Missing) > 0 {
+		out.Printf("Faltam: %s\n", strings.Join(collection.Missing, ", "))
+	}
+	out.Printf("------------------------------------\n")
+}
+
+// handleGame exibe o adversário e a mão do jogador (com raridade e facção, se houver), inicia o
+// contador da jogada e a captura da jogada (ou a jogada automática, com -strategy).
+func handleGame(ctx context.Context, conn *serverConnection, start gameStart) {
+	stateMutex.Lock()
+	currentOpponent, currentHand = start.Opponent, handLabels(start.Hand)
+	stateMutex.Unlock()
+
+	out.Printf("\r--- PARTIDA INICIADA ---\n")
+	out.Printf("Adversário: %s\n", start.Opponent)
+	out.Printf("Sua mão:\n")
+	for i, card := range start.Hand {
+		out.Printf("%d: %s\n", i+1, withMeta(card.label(), card.cardMeta))
+	}
+	startGameCountdown(start.TurnSeconds) // Inicia o contador de tempo de jogada.
+
+	if playStrategy != "" {
+		out.Printf("Jogada automática (estratégia %s).\n", playStrategy)
+		playAutomatically(conn, start.Hand)
+		return
+	}
+	out.Promptf("Escolha sua carta (1 a %d, '%s' para pedir mais tempo ou '%s' para desistir): > ", len(start.Hand), extensionInput, forfeitInput)
 
 	// Inicia a leitura da jogada em uma goroutine para não bloquear o programa.
 	go readPlayerInput(ctx, conn)
 }
 
+// playAutomatically escolhe e envia a jogada segundo a estratégia da flag -strategy.
+func playAutomatically(conn *serverConnection, hand []handCard) {
+	choice, err := chooseCard(playStrategy, hand)
+	if err != nil {
+		out.Printf("\r[Cliente]: %v. Jogando a primeira carta.\n", err)
+		choice = "1"
+	}
+	conn.send(choice)
+	out.Printf("Carta %s jogada automaticamente. Aguardando resultado...\n", choice)
+}
+
+// handleMoveRejected exibe o motivo da recusa da jogada e, se ainda for possível jogar
+// (carta inválida), volta a ler a jogada. Formato: MOVE_REJECTED|<motivo>|<mensagem>
+func handleMoveRejected(conn *serverConnection, message string) {
+	parts := strings.SplitN(message, "|", 3)
+	if len(parts) < 3 {
+		out.Printf("\r[Servidor]: Jogada recusada.\n")
+		return
+	}
+	reason, text := parts[1], parts[2]
+	out.Printf("\r[Servidor]: Jogada NÃO registrada: %s\n", text)
+
+	stateMutex.Lock()
+	inGame := isInGame
+	stateMutex.Unlock()
+	if reason == "INVALID_CARD" && inGame && playStrategy == "" {
+		out.Promptf("Escolha sua carta novamente: > ")
+		go readPlayerInput(context.Background(), conn)
+	}
+}
+
+// forfeitInput é o que o jogador digita, no lugar da carta, para desistir da partida (FORFEIT).
+const forfeitInput = "d"
+
+// extensionInput é o que o jogador digita, no lugar da carta, para pedir mais tempo (REQUEST_EXTENSION).
+const extensionInput = "t"
+
 // readPlayerInput gerencia a entrada do jogador durante uma partida.
-func readPlayerInput(ctx context.Context, conn *websocket.Conn) {
+func readPlayerInput(ctx context.Context, conn *serverConnection) {
 	choiceChan := make(chan string)
 	reader := bufio.NewReader(os.Stdin)
 
@@ -314,44 +859,88 @@ func readPlayerInput(ctx context.Context, conn *websocket.Conn) {
 	// O 'select' aguarda por dois eventos simultaneamente:
 	select {
 	case choice := <-choiceChan:
-		conn.WriteMessage(websocket.TextMessage, []byte(choice))
-		fmt.Println("Jogada enviada. Aguardando resultado...")
+		if strings.EqualFold(choice, forfeitInput) {
+			conn.send("FORFEIT")
+			out.Printf("Você desistiu da partida. Aguardando o resultado...\n")
+			return
+		}
+		if strings.EqualFold(choice, extensionInput) {
+			// Uma vez por partida; a resposta (TURN_EXTENDED ou a recusa) chega pelo servidor
+			conn.send("REQUEST_EXTENSION")
+			out.Promptf("Tempo extra pedido. Escolha sua carta: > ")
+			readPlayerInput(ctx, conn)
+			return
+		}
+		conn.send(choice)
+		// A jogada só conta após o "MOVE_ACK|" do servidor (ver listenServerMessages)
+		out.Printf("Jogada enviada. Aguardando confirmação do servidor...\n")
 	case <-ctx.Done():
-		fmt.Println("\nA partida terminou antes de você fazer uma jogada.")
+		out.Printf("\nA partida terminou antes de você fazer uma jogada.\n")
 		return
 	}
 }
 
+// startSearchCountdown inicia o contador visual da busca, substituindo o de uma busca anterior.
+func startSearchCountdown(seconds int) {
+	stateMutex.Lock()
+	searchGeneration++
+	generation := searchGeneration
+	stateMutex.Unlock()
+	go runSearchCountdown(seconds, generation)
+}
+
 // runSearchCountdown mostra um contador visual enquanto procura uma partida.
-func runSearchCountdown(seconds int) {
+func runSearchCountdown(seconds, generation int) {
 	for i := seconds; i > 0; i-- {
 		stateMutex.Lock()
+		if generation != searchGeneration {
+			stateMutex.Unlock()
+			return // Outro contador assumiu a linha
+		}
 		if !isSearching {
 			stateMutex.Unlock()
-			fmt.Printf("\r%s\r", strings.Repeat(" ", 50)) // Limpa a linha.
+			out.Countdown("") // Limpa a linha.
 			return
 		}
+		position, total := queuePosition, queueTotal
 		stateMutex.Unlock()
 
-		fmt.Printf("\rBuscando partida... Tempo restante: %d segundos ", i)
+		if position > 0 {
+			out.Countdown(fmt.Sprintf("Buscando partida... Tempo restante: %d segundos (posição na fila: %d de %d)", i, position, total))
+		} else {
+			out.Countdown(fmt.Sprintf("Buscando partida... Tempo restante: %d segundos", i))
+		}
 		time.Sleep(1 * time.Second)
 	}
-	fmt.Printf("\r%s\r", strings.Repeat(" ", 50))
+	out.Countdown("")
+}
+
+// startGameCountdown inicia o contador visual da jogada, substituindo o anterior.
+func startGameCountdown(seconds int) {
+	stateMutex.Lock()
+	gameCountdownGeneration++
+	generation := gameCountdownGeneration
+	stateMutex.Unlock()
+	go runGameCountdown(seconds, generation)
 }
 
 // runGameCountdown mostra um contador visual para o tempo de jogada.
-func runGameCountdown(seconds int) {
+func runGameCountdown(seconds, generation int) {
 	for i := seconds; i > 0; i-- {
 		stateMutex.Lock()
+		if generation != gameCountdownGeneration {
+			stateMutex.Unlock()
+			return // Outro contador assumiu a linha
+		}
 		if !isInGame {
 			stateMutex.Unlock()
-			fmt.Printf("\r%s\r", strings.Repeat(" ", 50)) // Limpa a linha.
+			out.Countdown("") // Limpa a linha.
 			return
 		}
 		stateMutex.Unlock()
 
-		fmt.Printf("\rTempo de jogada restante: %d segundos... ", i)
+		out.Countdown(fmt.Sprintf("Tempo de jogada restante: %d segundos...", i))
 		time.Sleep(1 * time.Second)
 	}
-	fmt.Printf("\r%s\r", strings.Repeat(" ", 50))
+	out.Countdown("")
 }
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Ciclo de vida de uma partida, como enviado pelo servidor:
+//
+//	MATCH_FOUND         a busca acabou: o oponente está definido e a partida vai começar
+//	GAME_START|<json>   adversário, mão e tempo da jogada, numa única mensagem (gameStart)
+//	REVEAL|<json>       carta jogada pelo oponente, logo antes do resultado (gameReveal; só no modo clássico)
+//	SUDDEN_DEATH|<json> empate: nova rodada, com uma carta nova e novo prazo (suddenDeath; só com SUDDEN_DEATH_ROUNDS)
+//	TURN_EXTENDED|<json> um dos jogadores pediu mais tempo (REQUEST_EXTENSION): novo prazo (turnExtended)
+//	GAME_END|<json>     resultado da partida (gameEnd)
+//
+// O "TIMER|n" continua existindo apenas como resposta ao GET_TIMER (após uma reconexão).
+
+// abilityLabels traduz as habilidades das cartas (campo "ability") para exibição.
+var abilityLabels = map[string]string{
+	"Boost":   "Reforço",
+	"Weather": "Clima",
+	"Spy":     "Espião",
+}
+
+// gameStart é o payload de "GAME_START|<json>".
+type gameStart struct {
+	GameID      string     `json:"game_id"`
+	Mode        string     `json:"mode"`
+	Opponent    string     `json:"opponent"` // No modo FFA, todos os oponentes separados por ", "
+	Hand        []handCard `json:"hand"`
+	TurnSeconds int        `json:"turn_seconds"`
+}
+
+// suddenDeath é o payload de "SUDDEN_DEATH|<json>": a nova mão e o prazo da rodada, como num GAME_START.
+type suddenDeath struct {
+	gameStart
+	Round     int `json:"round"`
+	MaxRounds int `json:"max_rounds"`
+}
+
+// turnExtended é o payload de "TURN_EXTENDED|<json>".
+type turnExtended struct {
+	GameID      string `json:"game_id"`
+	Player      string `json:"player"` // Quem pediu a extensão
+	TurnSeconds int    `json:"turn_seconds"`
+}
+
+// gameReveal é o payload de "REVEAL|<json>". Card é nil se o oponente não jogou.
+type gameReveal struct {
+	GameID   string    `json:"game_id"`
+	Opponent string    `json:"opponent"`
+	Card     *handCard `json:"card"`
+}
+
+// gameEnd é o payload de "GAME_END|<json>".
+type gameEnd struct {
+	GameID  string `json:"game_id"`
+	Result  string `json:"result"` // VITÓRIA, DERROTA ou EMPATE
+	Reason  string `json:"reason"` // Ex: opponent_disconnected, opponent_timeout, opponent_forfeit ("" = decidida pelas cartas)
+	Message string `json:"message"`
+}
+
+// parseGameStart lê o payload de "GAME_START|<json>".
+func parseGameStart(payload string) (gameStart, error) {
+	var start gameStart
+	if err := json.Unmarshal([]byte(payload), &start); err != nil {
+		return start, fmt.Errorf("início de partida inválido: %w", err)
+	}
+	if len(start.Hand) == 0 {
+		return start, fmt.Errorf("início de partida sem mão")
+	}
+	return start, nil
+}
+
+// parseSuddenDeath lê o payload de "SUDDEN_DEATH|<json>".
+func parseSuddenDeath(payload string) (suddenDeath, error) {
+	var round suddenDeath
+	if err := json.Unmarshal([]byte(payload), &round); err != nil {
+		return round, fmt.Errorf("rodada de morte súbita inválida: %w", err)
+	}
+	if len(round.Hand) == 0 {
+		return round, fmt.Errorf("rodada de morte súbita sem mão")
+	}
+	return round, nil
+}
+
+// parseTurnExtended lê o payload de "TURN_EXTENDED|<json>".
+func parseTurnExtended(payload string) (turnExtended, error) {
+	var extended turnExtended
+	if err := json.Unmarshal([]byte(payload), &extended); err != nil {
+		return extended, fmt.Errorf("extensão de tempo inválida: %w", err)
+	}
+	return extended, nil
+}
+
+// parseGameEnd lê o payload de "GAME_END|<json>".
+func parseGameEnd(payload string) (gameEnd, error) {
+	var end gameEnd
+	if err := json.Unmarshal([]byte(payload), &end); err != nil {
+		return end, fmt.Errorf("resultado de partida inválido: %w", err)
+	}
+	return end, nil
+}
+
+// parseGameReveal lê o payload de "REVEAL|<json>".
+func parseGameReveal(payload string) (gameReveal, error) {
+	var reveal gameReveal
+	if err := json.Unmarshal([]byte(payload), &reveal); err != nil {
+		return reveal, fmt.Errorf("revelação de carta inválida: %w", err)
+	}
+	return reveal, nil
+}
+
+// String formata a carta revelada (ex: "Bob jogou Grifo (3) [Clima]").
+func (r gameReveal) String() string {
+	if r.Card == nil {
+		return fmt.Sprintf("%s não jogou nenhuma carta", r.Opponent)
+	}
+	return fmt.Sprintf("%s jogou %s", r.Opponent, r.Card.label())
+}
+
+// label formata a carta para a mão (ex: "Grifo (3) [Clima]").
+func (c handCard) label() string {
+	text := fmt.Sprintf("%s (%d)", c.Name, c.Forca)
+	if name, ok := abilityLabels[c.Ability]; ok {
+		text += fmt.Sprintf(" [%s]", name)
+	}
+	return text
+}
+
+// handLabels retorna as cartas da mão formatadas, na ordem da jogada (1, 2, ...).
+func handLabels(hand []handCard) []string {
+	labels := make([]string, 0, len(hand))
+	for _, c := range hand {
+		labels = append(labels, c.label())
+	}
+	return labels
+}
+
+// String formata o resultado para exibição (ex: "VITÓRIA: Sua carta ...").
+func (e gameEnd) String() string {
+	return strings.TrimSpace(e.Result + ": " + e.Message)
+}
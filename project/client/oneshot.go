@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// oneShotResult é o resultado impresso (em JSON, na saída padrão) pelos subcomandos
+// "open" e "trade", para uso em scripts e CI.
+type oneShotResult struct {
+	Action    string        `json:"action"`
+	Player    string        `json:"player"`
+	Command   string        `json:"command,omitempty"`
+	OK        bool          `json:"ok"`
+	Responses []string      `json:"responses"` // Mensagens do servidor em resposta ao comando
+	Deck      []string      `json:"deck"`      // Deck após o comando
+	Status    *playerStatus `json:"status,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// runSubcommand executa um subcomando não interativo ("open" ou "trade") e encerra o
+// programa: código 0 se o servidor aceitou o comando, 1 caso contrário.
+// Uso: client open <ip> <nome> [--packs N] [--key K] | client trade <ip> <nome> --card N[,M...] [--want X]
+func runSubcommand(action string, args []string) {
+	fs := flag.NewFlagSet(action, flag.ExitOnError)
+	timeout := fs.Duration("timeout", 10*time.Second, "Tempo máximo de espera pelas respostas do servidor.")
+	packs := 1
+	var cards, want, key string
+	if action == "open" {
+		fs.IntVar(&packs, "packs", 1, "Número de pacotes a abrir.")
+		fs.StringVar(&key, "key", "", "Chave do pedido: repetir o comando com a mesma chave não abre outros pacotes.")
+	} else {
+		fs.StringVar(&cards, "card", "", "Número da carta no deck (começando em 1), ou vários separados por vírgula (ex: 1,3,5).")
+		fs.StringVar(&want, "want", "", "Carta pedida em troca: nome ou Força mínima.")
+	}
+	if len(args) < 2 {
+		fmt.Fprintf(os.Stderr, "Uso: ./client %s <ip_do_servidor> <nome_do_jogador> [opções]\n", action)
+		fs.PrintDefaults()
+		os.Exit(2)
+	}
+	fs.Parse(args[2:])
+	serverWsUrl := fmt.Sprintf("ws://%s:8080", args[0])
+
+	result := oneShotResult{Action: action, Player: args[1], Responses: []string{}}
+	switch action {
+	case "open":
+		if packs < 1 {
+			result.Error = "--packs deve ser pelo menos 1"
+		} else {
+			result.Command = openPacksCommand(packs, key)
+		}
+	case "trade":
+		cards = strings.ReplaceAll(cards, " ", "")
+		if !validCardIndices(cards) {
+			result.Error = "--card deve ser um número (ou números separados por vírgula)"
+		} else {
+			result.Command = tradeCommand(cards, strings.TrimSpace(want))
+		}
+	}
+	if result.Error == "" {
+		if err := runOneShot(&result, serverWsUrl, *timeout); err != nil {
+			result.OK = false
+			result.Error = err.Error()
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(result)
+	if !result.OK {
+		os.Exit(1)
+	}
+}
+
+// runOneShot conecta, envia o comando e coleta as respostas. Um "STATUS" é enviado antes do
+// comando como barreira (tudo o que o servidor manda na conexão, como o pacote inicial, chega
+// antes da resposta); depois do comando vêm "VIEW_DECK" e outro "STATUS", e as mensagens entre
+// as duas respostas de status são as respostas ao comando. O servidor processa os comandos de
+// uma conexão em ordem, então o segundo status já reflete o comando.
+func runOneShot(result *oneShotResult, serverWsUrl string, timeout time.Duration) error {
+	conn, err := connectToServer(result.Player, serverWsUrl)
+	if err != nil {
+		return err
+	}
+	defer conn.close()
+	// Encerra a conexão se o servidor não responder a tempo; a leitura pendente falha.
+	var timedOut atomic.Bool
+	timer := time.AfterFunc(timeout, func() {
+		timedOut.Store(true)
+		conn.close()
+	})
+	defer timer.Stop()
+
+	for _, message := range []string{"STATUS", result.Command, "VIEW_DECK", "STATUS"} {
+		if err := conn.send(message); err != nil {
+			return err
+		}
+	}
+
+	var before *playerStatus
+	for {
+		p, err := conn.read()
+		if err != nil {
+			if timedOut.Load() {
+				return fmt.Errorf("sem resposta do servidor em %v", timeout)
+			}
+			return err
+		}
+		message := string(p)
+
+		switch {
+		case strings.HasPrefix(message, "ASSIGNED_NAME|"):
+			result.Player = strings.TrimPrefix(message, "ASSIGNED_NAME|")
+			conn.setPlayerName(result.Player)
+			continue
+		case message == "NAME_TAKEN":
+			return errors.New("nome já em uso")
+		case message == "MAINTENANCE":
+			return errors.New("servidor em manutenção; conecte-se a outro servidor")
+		case strings.HasPrefix(message, "INVALID_NAME|"):
+			return fmt.Errorf("nome inválido: %s", strings.TrimPrefix(message, "INVALID_NAME|"))
+		case strings.HasPrefix(message, "AUTH_FAILED|"):
+			return fmt.Errorf("autenticação recusada: %s", strings.TrimPrefix(message, "AUTH_FAILED|"))
+		case strings.HasPrefix(message, "STATUS|"):
+			status, err := parseStatus(strings.TrimPrefix(message, "STATUS|"))
+			if err != nil {
+				return fmt.Errorf("status inválido recebido: %w", err)
+			}
+			if before == nil {
+				before = &status
+				continue
+			}
+			result.Status = &status
+			result.OK = commandAccepted(result, before.PacksOpened)
+			return nil
+		case before == nil:
+			// Mensagens da conexão (boas-vindas, pacote inicial), anteriores ao comando.
+			continue
+		}
+
+		if cards, ok := parseDeckListing(message); ok {
+			result.Deck = append([]string{}, cards...)
+			continue
+		}
+		result.Responses = append(result.Responses, message)
+	}
+}
+
+// commandAccepted informa se o servidor aceitou o comando: nenhuma resposta foi uma recusa e,
+// ao abrir pacotes, o contador de pacotes abertos aumentou; numa troca, ela foi realizada ou as
+// cartas entraram na fila de trocas (a troca em fila só se completa enquanto o jogador estiver conectado).
+func commandAccepted(result *oneShotResult, packsBefore int) bool {
+	for _, response := range result.Responses {
+		if strings.HasPrefix(response, "COMMAND_REJECTED|") || response == "RATE_LIMITED" {
+			return false
+		}
+	}
+	if result.Action == "open" {
+		return result.Status.PacksOpened > packsBefore
+	}
+	for _, response := range result.Responses {
+		if strings.HasPrefix(response, "Troca realizada!") || strings.Contains(response, "à fila de trocas") {
+			return true
+		}
+	}
+	return false
+}
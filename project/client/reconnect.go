@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	maxConnectRetries = 5               // Tentativas de conexão (e de reconexão) ao servidor
+	connectRetryDelay = 2 * time.Second // Espera entre as tentativas
+	rejoinReplyWait   = 5 * time.Second // Prazo para a resposta ao "REJOIN|"
+)
+
+// wsDialer é o websocket.DefaultDialer com compressão por mensagem (permessage-deflate):
+// respostas grandes do servidor (replays, coleção, deck) viajam comprimidas.
+var wsDialer = &websocket.Dialer{
+	Proxy:             http.ProxyFromEnvironment,
+	HandshakeTimeout:  45 * time.Second,
+	EnableCompression: true,
+}
+
+// serverConnection guarda a conexão atual com o servidor. Ela é trocada quando o cliente
+// reconecta, então todo envio passa por aqui em vez de usar um *websocket.Conn fixo.
+type serverConnection struct {
+	mu             sync.Mutex
+	conn           *websocket.Conn
+	playerName     string
+	url            string
+	reconnectToken string // Último "RECONNECT_TOKEN|" do servidor: permite voltar à partida com REJOIN
+}
+
+// connectToServer abre a conexão com retentativas e se identifica com o nome do jogador.
+func connectToServer(playerName, serverURL string) (*serverConnection, error) {
+	sc := &serverConnection{playerName: playerName, url: serverURL}
+	if err := sc.dial(); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// dialWithRetries abre uma conexão WebSocket, tentando até maxConnectRetries vezes.
+func (sc *serverConnection) dialWithRetries() (*websocket.Conn, error) {
+	var conn *websocket.Conn
+	var err error
+	for i := 0; i < maxConnectRetries; i++ {
+		conn, _, err = wsDialer.Dial(sc.url, nil)
+		if err == nil {
+			return conn, nil // Conexão bem-sucedida.
+		}
+		log.Printf("%s: Falha ao conectar ao servidor (%v). Tentando novamente em %v...", sc.playerName, err, connectRetryDelay)
+		time.Sleep(connectRetryDelay)
+	}
+	return nil, fmt.Errorf("não foi possível conectar ao servidor após %d tentativas: %w", maxConnectRetries, err)
+}
+
+// dial tenta se conectar ao servidor até maxConnectRetries vezes e envia o nome do jogador.
+func (sc *serverConnection) dial() error {
+	conn, err := sc.dialWithRetries()
+	if err != nil {
+		return err
+	}
+
+	// Envia o nome do jogador (com o token, se houver)
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(loginMessage(sc.playerName))); err != nil {
+		conn.Close()
+		return err
+	}
+	return sc.install(conn)
+}
+
+// install pede o idioma na nova conexão e a torna a conexão atual.
+func (sc *serverConnection) install(conn *websocket.Conn) error {
+	// O idioma vale por conexão: é pedido de novo a cada reconexão
+	if serverLocale != "" {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("SET_LOCALE "+serverLocale)); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	sc.mu.Lock()
+	if sc.conn != nil {
+		sc.conn.Close()
+	}
+	sc.conn = conn
+	sc.mu.Unlock()
+	return nil
+}
+
+// rejoin tenta voltar à sessão anterior com "REJOIN|<nome>|<token>": o servidor devolve o
+// jogador com as partidas em andamento e as mensagens perdidas na queda. Retorna false (sem
+// erro) se não há token ou se o servidor recusou ("REJOIN_FAILED"); aí o cliente entra pelo
+// login normal.
+func (sc *serverConnection) rejoin() (bool, error) {
+	sc.mu.Lock()
+	name, token := sc.playerName, sc.reconnectToken
+	sc.mu.Unlock()
+	if token == "" {
+		return false, nil
+	}
+
+	conn, err := sc.dialWithRetries()
+	if err != nil {
+		return false, err
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("REJOIN|"+name+"|"+token)); err != nil {
+		conn.Close()
+		return false, nil
+	}
+	conn.SetReadDeadline(time.Now().Add(rejoinReplyWait))
+	_, p, err := conn.ReadMessage()
+	conn.SetReadDeadline(time.Time{})
+	if err != nil || string(p) != "REJOINED" {
+		conn.Close()
+		return false, nil
+	}
+	return true, sc.install(conn)
+}
+
+// setReconnectToken guarda o token de retomada recebido do servidor.
+func (sc *serverConnection) setReconnectToken(token string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.reconnectToken = token
+}
+
+// reconnect reabre a conexão após uma queda e ressincroniza o estado com o servidor.
+// Primeiro tenta a retomada (REJOIN), que devolve a partida em andamento; se o servidor a
+// recusar, entra pelo login normal. Em seguida, se estava em partida, pergunta o tempo
+// restante (GET_TIMER); se estava procurando, retoma a busca com o ticket que ficou na fila
+// (RESUME_SEARCH), e o servidor responde com o tempo restante ("SEARCH_RESUMED|") ou avisa
+// que ela expirou ("SEARCH_EXPIRED"). Os estados isInGame/isSearching são preservados.
+func (sc *serverConnection) reconnect() error {
+	rejoined, err := sc.rejoin()
+	if err != nil {
+		return err
+	}
+	if rejoined {
+		log.Printf("%s: Sessão retomada no servidor.", sc.playerName)
+	} else if err := sc.dial(); err != nil {
+		return err
+	}
+
+	stateMutex.Lock()
+	inGame, searching := isInGame, isSearching
+	stateMutex.Unlock()
+
+	if inGame {
+		return sc.send("GET_TIMER")
+	}
+	if searching {
+		return sc.send("RESUME_SEARCH")
+	}
+	return nil
+}
+
+// setPlayerName troca o nome enviado nas próximas conexões pelo nome efetivo atribuído pelo
+// servidor ("ASSIGNED_NAME|"), para que uma reconexão volte como o mesmo jogador.
+func (sc *serverConnection) setPlayerName(name string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.playerName = name
+}
+
+// read lê a próxima mensagem da conexão atual.
+func (sc *serverConnection) read() ([]byte, error) {
+	sc.mu.Lock()
+	conn := sc.conn
+	sc.mu.Unlock()
+	_, p, err := conn.ReadMessage()
+	return p, err
+}
+
+// send envia uma mensagem de texto pela conexão atual.
+func (sc *serverConnection) send(message string) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.conn.WriteMessage(websocket.TextMessage, []byte(message))
+}
+
+// close encerra a conexão atual.
+func (sc *serverConnection) close() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.conn.Close()
+}
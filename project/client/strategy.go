@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+)
+
+// Estratégias de jogada automática (flag -strategy). Sem estratégia, a jogada é lida do teclado.
+const (
+	strategyHighest = "highest" // Maior Força (desempate pela Agilidade)
+	strategyLowest  = "lowest"  // Menor Força (desempate pela Agilidade)
+	strategyRandom  = "random"  // Carta aleatória
+)
+
+// handCard é uma carta da mão recebida em "GAME_START|<json>" (ver lifecycle.go).
+type handCard struct {
+	Name    string `json:"name"`
+	Forca   int    `json:"forca"`
+	Ability string `json:"ability,omitempty"`
+	Speed   int    `json:"speed,omitempty"`
+	cardMeta
+}
+
+// validStrategy informa se o nome é uma estratégia conhecida (ou vazio, para o modo interativo).
+func validStrategy(strategy string) bool {
+	switch strategy {
+	case "", strategyHighest, strategyLowest, strategyRandom:
+		return true
+	}
+	return false
+}
+
+// chooseCard escolhe a carta segundo a estratégia e retorna o comando da jogada ("1", "2", ...).
+func chooseCard(strategy string, hand []handCard) (string, error) {
+	if len(hand) == 0 {
+		return "", fmt.Errorf("mão vazia")
+	}
+
+	best := 0
+	switch strategy {
+	case strategyRandom:
+		best = rand.Intn(len(hand))
+	case strategyHighest:
+		for i, c := range hand {
+			if c.Forca > hand[best].Forca || (c.Forca == hand[best].Forca && c.Speed > hand[best].Speed) {
+				best = i
+			}
+		}
+	case strategyLowest:
+		for i, c := range hand {
+			if c.Forca < hand[best].Forca || (c.Forca == hand[best].Forca && c.Speed < hand[best].Speed) {
+				best = i
+			}
+		}
+	}
+	return strconv.Itoa(best + 1), nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	tuiDefaultWidth  = 100 // Tamanho usado quando não é possível consultar o terminal
+	tuiDefaultHeight = 30
+	tuiMinWidth      = 60
+	tuiMinHeight     = 24
+	tuiLogSize       = 200 // Linhas guardadas no painel de log
+)
+
+// tuiDisplay é a exibição da flag -tui: painéis fixos desenhados com sequências ANSI, sem
+// dependências externas. O terminal é dividido em:
+//
+//	linha 1            cabeçalho (jogador, estado, estoque)
+//	linhas 2..H-3      menu e deck (esquerda) | partida/busca e log (direita)
+//	linha H-2          separador
+//	linhas H-1..H      entrada do teclado (região de rolagem própria)
+//
+// A entrada continua sendo lida linha a linha do stdin: a região de rolagem faz o Enter rolar
+// apenas as duas últimas linhas, e os painéis são redesenhados salvando e restaurando o cursor,
+// então as mensagens que chegam enquanto o jogador digita não apagam o que ele está digitando.
+type tuiDisplay struct {
+	mu            sync.Mutex
+	player        string
+	width, height int
+	logLines      []string
+	countdown     string
+}
+
+// newTUIDisplay prepara o terminal (tela limpa e região de rolagem da entrada).
+func newTUIDisplay(player string) *tuiDisplay {
+	t := &tuiDisplay{player: player}
+	t.width, t.height = terminalSize()
+	fmt.Printf("\033[2J\033[%d;%dr\033[%d;1H", t.height-1, t.height, t.height)
+	t.draw()
+	return t
+}
+
+// terminalSize consulta o tamanho do terminal (stty), com COLUMNS/LINES e o tamanho padrão como alternativa.
+func terminalSize() (width, height int) {
+	width, height = tuiDefaultWidth, tuiDefaultHeight
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = os.Stdin
+	if raw, err := cmd.Output(); err == nil {
+		if fields := strings.Fields(string(raw)); len(fields) == 2 {
+			height, _ = strconv.Atoi(fields[0])
+			width, _ = strconv.Atoi(fields[1])
+		}
+	} else {
+		if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil {
+			width = n
+		}
+		if n, err := strconv.Atoi(os.Getenv("LINES")); err == nil {
+			height = n
+		}
+	}
+	return max(width, tuiMinWidth), max(height, tuiMinHeight)
+}
+
+// Printf acrescenta as linhas da mensagem ao painel de log. Os "\r" e as linhas em branco
+// (usados pelo modo console para limpar a linha atual) são descartados.
+func (t *tuiDisplay) Printf(format string, args ...any) {
+	text := strings.ReplaceAll(fmt.Sprintf(format, args...), "\r", "")
+	t.mu.Lock()
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			t.logLines = append(t.logLines, line)
+		}
+	}
+	if len(t.logLines) > tuiLogSize {
+		t.logLines = t.logLines[len(t.logLines)-tuiLogSize:]
+	}
+	t.mu.Unlock()
+	t.draw()
+}
+
+// Promptf escreve o pedido de entrada na última linha.
+func (t *tuiDisplay) Promptf(format string, args ...any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Printf("\033[%d;1H\033[2K%s", t.height, fmt.Sprintf(format, args...))
+}
+
+// Countdown mostra o contador de busca ou de jogada no painel da partida.
+func (t *tuiDisplay) Countdown(text string) {
+	t.mu.Lock()
+	t.countdown = text
+	t.mu.Unlock()
+	t.draw()
+}
+
+// ShowMenu redesenha os painéis (o menu fica sempre visível) e pede a opção.
+func (t *tuiDisplay) ShowMenu() {
+	t.draw()
+	t.Promptf("> ")
+}
+
+// Reprompt não faz nada: as mensagens vão para os painéis e não apagam a linha de entrada,
+// então repetir o prompt apagaria o que o jogador já digitou.
+func (t *tuiDisplay) Reprompt() {}
+
+// Close restaura a região de rolagem e deixa o cursor abaixo dos painéis.
+func (t *tuiDisplay) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Printf("\033[r\033[%d;1H\n", t.height)
+}
+
+// Write permite usar a TUI como saída do pacote log (mensagens de conexão e reconexão).
+func (t *tuiDisplay) Write(p []byte) (int, error) {
+	t.Printf("%s", p)
+	return len(p), nil
+}
+
+// draw redesenha o cabeçalho e os painéis, sem mexer na linha de entrada.
+func (t *tuiDisplay) draw() {
+	stateMutex.Lock()
+	state := "Menu"
+	if isInGame {
+		state = "Em partida"
+	} else if isSearching {
+		state = "Procurando partida"
+	}
+	stock := "disponível"
+	if stockExhausted {
+		stock = "esgotado"
+	}
+	opponent := currentOpponent
+	hand := append([]string(nil), currentHand...)
+	deck := append([]string(nil), deckListing...)
+	inGame, searching := isInGame, isSearching
+	player := t.player
+	if assignedName != "" {
+		player = assignedName
+	}
+	stateMutex.Unlock()
+	menu := menuLines()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	leftWidth := t.width * 2 / 5
+	rightWidth := t.width - leftWidth - 1
+	bodyHeight := t.height - 4
+
+	left := []string{panelTitle("Menu", leftWidth)}
+	left = append(left, menu...)
+	left = append(left, panelTitle("Deck", leftWidth))
+	if len(deck) == 0 {
+		left = append(left, "(escolha '3' para listar o deck)")
+	}
+	for i, card := range deck {
+		left = append(left, fmt.Sprintf("%d: %s", i+1, card))
+	}
+
+	right := []string{panelTitle("Partida", rightWidth)}
+	switch {
+	case inGame:
+		right = append(right, "Adversário: "+opponent)
+		for i, card := range hand {
+			right = append(right, fmt.Sprintf("%d: %s", i+1, card))
+		}
+	case searching:
+		right = append(right, "Procurando oponente...")
+	default:
+		right = append(right, "Nenhuma partida em andamento.")
+	}
+	if t.countdown != "" {
+		right = append(right, t.countdown)
+	}
+	right = append(right, panelTitle("Log", rightWidth))
+	if free := bodyHeight - len(right); free > 0 {
+		logLines := t.logLines
+		if len(logLines) > free {
+			logLines = logLines[len(logLines)-free:]
+		}
+		right = append(right, logLines...)
+	}
+
+	var b strings.Builder
+	b.WriteString("\0337") // Salva o cursor (linha de entrada)
+	header := fmt.Sprintf(" %s | Estado: %s | Estoque: %s", player, state, stock)
+	fmt.Fprintf(&b, "\033[1;1H\033[2K\033[7m%s\033[0m", fitWidth(header, t.width))
+	for row := 0; row < bodyHeight; row++ {
+		var l, r string
+		if row < len(left) {
+			l = left[row]
+		}
+		if row < len(right) {
+			r = right[row]
+		}
+		fmt.Fprintf(&b, "\033[%d;1H\033[2K%s│%s", row+2, fitWidth(l, leftWidth), fitWidth(r, rightWidth))
+	}
+	fmt.Fprintf(&b, "\033[%d;1H\033[2K%s", t.height-2, fitWidth(strings.Repeat("─", t.width), t.width))
+	b.WriteString("\0338") // Restaura o cursor
+	fmt.Print(b.String())
+}
+
+// panelTitle monta a linha de título de um painel (ex: "── Menu ─────").
+func panelTitle(title string, width int) string {
+	line := "── " + title + " "
+	return line + strings.Repeat("─", max(width-utf8.RuneCountInString(line), 0))
+}
+
+// fitWidth corta ou completa o texto com espaços até a largura da coluna.
+func fitWidth(text string, width int) string {
+	n := utf8.RuneCountInString(text)
+	if n > width {
+		runes := []rune(text)
+		return string(runes[:width-1]) + "…"
+	}
+	return text + strings.Repeat(" ", width-n)
+}
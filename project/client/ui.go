@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// display é a camada de exibição do cliente interativo. O tratamento das mensagens do servidor
+// (listenServerMessages) e da entrada do teclado (handleServerConnection) é o mesmo nos dois modos;
+// só a exibição muda: consoleDisplay (padrão) escreve direto no stdout, tuiDisplay (-tui) desenha painéis.
+type display interface {
+	Printf(format string, args ...any)  // Mensagens e blocos de texto (na TUI, vão para o painel de log)
+	Promptf(format string, args ...any) // Pedido de entrada do teclado (na TUI, fica na linha de entrada)
+	Countdown(text string)              // Contador de busca ou de jogada; "" apaga (na TUI, painel da partida)
+	ShowMenu()                          // Exibe o menu principal (menuLines) e pede a opção
+	Reprompt()                          // Repete o "> " do menu depois de uma mensagem do servidor
+	Close()                             // Restaura o terminal antes de sair
+}
+
+// out é a exibição em uso. Trocada por uma tuiDisplay com a flag -tui.
+var out display = consoleDisplay{}
+
+// Estado mostrado nos painéis da TUI, atualizado pelo tratamento das mensagens.
+// Protegido por 'stateMutex', como os demais estados do cliente.
+var (
+	currentOpponent string   // Adversário da partida em andamento ("" fora de partida)
+	currentHand     []string // Cartas da mão recebidas em "GAME_START|", formatadas (handCard.label)
+	deckListing     []string // Cartas da última resposta ao VIEW_DECK ("Seu deck: a | b | ...")
+	assignedName    string   // Nome efetivo recebido em "ASSIGNED_NAME|" ("" = o nome escolhido)
+)
+
+// menuLines retorna as opções do menu principal, conforme o estado atual.
+func menuLines() []string {
+	stateMutex.Lock()
+	exhausted := stockExhausted
+	stateMutex.Unlock()
+
+	openPack := "2. Abrir Pacote de Cartas"
+	if exhausted {
+		openPack += " (indisponível: estoque esgotado)"
+	}
+	return []string{
+		"1. Procurar Partida",
+		openPack,
+		"3. Ver Meu Deck",
+		"4. Trocar Carta",
+		"5. Ver Ranking",
+		"6. Aceitar Revanche",
+		"7. Procurar Partida (Todos contra Todos)",
+		"8. Ver Meu Status",
+		"9. Ver Replay da Última Partida",
+		"10. Ver Minha Coleção",
+		"11. Criar Partida Privada",
+		"12. Entrar em Partida Privada",
+		"13. Definir Loadout (cartas que entram nas partidas)",
+		"14. Ver Confronto Direto contra um Jogador",
+		"15. Ver Fila de Trocas",
+		"16. Sair",
+	}
+}
+
+// parseDeckListing lê as cartas de uma resposta ao VIEW_DECK ("Seu deck: a | b | ..." ou
+// "Seu deck está vazio."). ok é false se a mensagem não for uma listagem do deck.
+func parseDeckListing(message string) (cards []string, ok bool) {
+	if deck, found := strings.CutPrefix(message, "Seu deck: "); found {
+		return strings.Split(deck, " | "), true
+	}
+	return nil, message == "Seu deck está vazio."
+}
+
+// updateDeckListing guarda as cartas de uma resposta ao VIEW_DECK, para o painel do deck.
+func updateDeckListing(message string) {
+	cards, ok := parseDeckListing(message)
+	if !ok {
+		return
+	}
+	stateMutex.Lock()
+	deckListing = cards
+	stateMutex.Unlock()
+}
+
+// consoleDisplay é a exibição padrão: tudo no stdout, na ordem em que acontece.
+// Os "\r" nas mensagens sobrescrevem a linha do contador ou do prompt.
+type consoleDisplay struct{}
+
+func (consoleDisplay) Printf(format string, args ...any) {
+	fmt.Printf(format, args...)
+}
+
+func (consoleDisplay) Promptf(format string, args ...any) {
+	fmt.Printf(format, args...)
+}
+
+func (consoleDisplay) Countdown(text string) {
+	if text == "" {
+		fmt.Printf("\r%s\r", strings.Repeat(" ", 90)) // Limpa a linha.
+		return
+	}
+	fmt.Printf("\r%s ", text)
+}
+
+func (consoleDisplay) ShowMenu() {
+	fmt.Println("\n--- MENU PRINCIPAL ---")
+	for _, line := range menuLines() {
+		fmt.Println(line)
+	}
+	fmt.Print("> ")
+}
+
+func (consoleDisplay) Reprompt() {
+	fmt.Print("> ")
+}
+
+func (consoleDisplay) Close() {}
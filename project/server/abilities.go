@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Habilidades especiais que uma carta pode ter (campo Card.Ability).
+const (
+	AbilityBoost   = "Boost"   // Soma +2 à Força da própria carta
+	AbilityWeather = "Weather" // Reduz pela metade a Força da carta adversária
+	AbilitySpy     = "Spy"     // Vence em caso de empate
+)
+
+const boostBonus = 2
+
+// abilityNames traduz as habilidades para exibição ao jogador.
+var abilityNames = map[string]string{
+	AbilityBoost:   "Reforço",
+	AbilityWeather: "Clima",
+	AbilitySpy:     "Espião",
+}
+
+// abilityTag retorna o sufixo de exibição da habilidade (ex: " [Clima]"), ou "" se a carta não tiver.
+func (c Card) abilityTag() string {
+	if name, ok := abilityNames[c.Ability]; ok {
+		return fmt.Sprintf(" [%s]", name)
+	}
+	return ""
+}
+
+// CardDuel é o resultado da comparação de duas cartas após aplicar as habilidades.
+type CardDuel struct {
+	P1Forca int             // Força efetiva da carta do P1
+	P2Forca int             // Força efetiva da carta do P2
+	Winner  int             // 1 = P1 vence, 2 = P2 vence, 0 = empate
+	Effects []LocalizedText // Efeitos das habilidades, na ordem em que foram aplicados (ver i18n.go)
+}
+
+// resolveCardDuel aplica as habilidades das duas cartas e decide o vencedor.
+// A ordem dos efeitos é sempre a mesma, independente de quem é P1 ou P2:
+//  1. Reforço (Boost): +2 na própria carta
+//  2. Clima (Weather): metade da Força adversária (arredondada para baixo), já com o Reforço
+//  3. Comparação das Forças efetivas
+//  4. Espião (Spy): desempata a favor de quem o possui
+//  5. Agilidade (Speed): se o empate persistir, vence a carta mais ágil (só a mesma Agilidade empata)
+func resolveCardDuel(p1Card, p2Card Card) CardDuel {
+	duel := CardDuel{P1Forca: p1Card.Forca, P2Forca: p2Card.Forca}
+
+	// 1. Reforço
+	if p1Card.Ability == AbilityBoost {
+		duel.P1Forca += boostBonus
+		duel.Effects = append(duel.Effects, localized(msgEffectBoost, p1Card.Name, boostBonus))
+	}
+	if p2Card.Ability == AbilityBoost {
+		duel.P2Forca += boostBonus
+		duel.Effects = append(duel.Effects, localized(msgEffectBoost, p2Card.Name, boostBonus))
+	}
+
+	// 2. Clima (calculado sobre as Forças já reforçadas, aplicado simultaneamente)
+	p1Before, p2Before := duel.P1Forca, duel.P2Forca
+	if p1Card.Ability == AbilityWeather {
+		duel.P2Forca = p2Before / 2
+		duel.Effects = append(duel.Effects, localized(msgEffectWeather, p1Card.Name, p2Card.Name, duel.P2Forca))
+	}
+	if p2Card.Ability == AbilityWeather {
+		duel.P1Forca = p1Before / 2
+		duel.Effects = append(duel.Effects, localized(msgEffectWeather, p2Card.Name, p1Card.Name, duel.P1Forca))
+	}
+
+	// 3. Comparação
+	switch {
+	case duel.P1Forca > duel.P2Forca:
+		duel.Winner = 1
+	case duel.P2Forca > duel.P1Forca:
+		duel.Winner = 2
+	default:
+		// 4. Espião desempata
+		p1Spy := p1Card.Ability == AbilitySpy
+		p2Spy := p2Card.Ability == AbilitySpy
+		if p1Spy && !p2Spy {
+			duel.Winner = 1
+			duel.Effects = append(duel.Effects, localized(msgEffectSpy, p1Card.Name))
+		} else if p2Spy && !p1Spy {
+			duel.Winner = 2
+			duel.Effects = append(duel.Effects, localized(msgEffectSpy, p2Card.Name))
+		} else if p1Card.Speed > p2Card.Speed {
+			// 5. Agilidade desempata
+			duel.Winner = 1
+			duel.Effects = append(duel.Effects, localized(msgEffectSpeed, p1Card.Name, p1Card.Speed, p2Card.Name, p2Card.Speed))
+		} else if p2Card.Speed > p1Card.Speed {
+			duel.Winner = 2
+			duel.Effects = append(duel.Effects, localized(msgEffectSpeed, p2Card.Name, p2Card.Speed, p1Card.Name, p1Card.Speed))
+		}
+	}
+
+	return duel
+}
+
+// effectsSuffix monta os trechos dos efeitos de habilidade para anexar à mensagem "RESULT|"
+// (ex: " Habilidades: Reforço de Grifo: +2 de Força; ...").
+func (d CardDuel) effectsSuffix() []LocalizedText {
+	if len(d.Effects) == 0 {
+		return nil
+	}
+	texts := []LocalizedText{localized(msgAbilitiesHeader)}
+	for i, effect := range d.Effects {
+		if i > 0 {
+			texts = append(texts, localized(msgListSeparator))
+		}
+		texts = append(texts, effect)
+	}
+	return append(texts, localized(msgSentenceEnd))
+}
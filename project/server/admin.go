@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redis/v8"
+)
+
+// authorizeAdmin verifica o cabeçalho "Authorization: Bearer <ADMIN_TOKEN>" dos endpoints de administração.
+// Responde ao cliente e retorna false se a requisição não for autorizada.
+func (s *Server) authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if s.Config.AdminToken == "" {
+		writeAPIError(w, http.StatusForbidden, errCodeAdminDisabled, "Endpoints de administração desabilitados (ADMIN_TOKEN não definido)")
+		return false
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.Config.AdminToken)) != 1 {
+		slog.Warn("Requisição de administração não autorizada", "event", "admin_unauthorized",
+			"path", r.URL.Path, "remote_addr", r.RemoteAddr)
+		writeAPIError(w, http.StatusUnauthorized, errCodeUnauthorized, "Não autorizado")
+		return false
+	}
+	return true
+}
+
+// handleForceResolve implementa o endpoint de administração POST /api/v1/game/{gameID}/resolve.
+// Resolve uma partida travada como no timeout (quem jogou vence quem não jogou), publica os
+// resultados no canal de cada jogador e apaga o estado da partida. Funciona em qualquer servidor:
+// a reserva game:resolved:<GameID> impede que o cérebro decida a mesma partida de novo, e o
+// evento "RESOLVED" no canal da partida encerra o listener do cérebro, se ele ainda estiver vivo.
+func (s *Server) handleForceResolve(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	gameID := chi.URLParam(r, "gameID")
+	logger := slog.With("game_id", gameID, "remote_addr", r.RemoteAddr, "user_agent", r.UserAgent())
+
+	meta, err := s.loadGameMeta(gameID)
+	if errors.Is(err, redis.Nil) {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "Partida não encontrada (ou já encerrada)")
+		return
+	}
+	if err != nil {
+		logger.Error("Erro ao ler metadados da partida", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Erro interno ao consultar a partida")
+		return
+	}
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	moves, err := s.RedisClient.HGetAll(ctx, gameStatePrefix+gameID).Result()
+	if err != nil {
+		logger.Error("Erro ao ler jogadas da partida", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Erro interno ao consultar a partida")
+		return
+	}
+	session, err := ghostSession(meta)
+	if err != nil {
+		logger.Error("Metadados de partida inválidos", "players", meta.Players, "error", err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Metadados da partida inválidos")
+		return
+	}
+
+	var outcome string
+	var results map[string]string
+	var ok bool
+	if meta.Mode == gameModeFFA {
+		outcome, results, ok = s.determineFFAWinner(session, moves)
+	} else {
+		outcome, results, ok = s.forceResolveClassic(session, moves)
+	}
+	if !ok {
+		writeAPIError(w, http.StatusConflict, errCodeAlreadyResolved, "A partida já foi decidida")
+		return
+	}
+
+	s.clearGameState(gameID)
+	if err := s.RedisClient.Publish(ctx, fmt.Sprintf("game:channel:%s", gameID), gameResolvedEvent).Err(); err != nil {
+		logger.Error("Erro ao avisar o cérebro sobre a resolução forçada", "error", err)
+	}
+	logger.Warn("Partida resolvida pela administração.", "event", "game_force_resolved", "mode", meta.Mode,
+		"brain_server_id", meta.BrainServerID, "outcome", outcome, "players", meta.Players)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ForceResolveResponse{GameID: gameID, Mode: meta.Mode, Outcome: outcome, Results: results})
+}
+
+// forceResolveClassic decide uma partida clássica fora do cérebro. Diferente de determineWinner,
+// os dois resultados seguem via Pub/Sub: o servidor de cada jogador limpa o estado, registra o
+// ranking e oferece a revanche pelo fluxo normal de "RESULT|".
+func (s *Server) forceResolveClassic(session *GameSession, moves map[string]string) (outcomeLabel string, results map[string]string, ok bool) {
+	s.fillSessionFromMoves(session, moves)
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if !s.claimGameResolution(session.GameID) {
+		return "", nil, false
+	}
+	resultP1, resultP2, logMessage, outcomeLabel := classicResults(session)
+	gamesFinishedTotal.WithLabelValues(outcomeLabel).Inc()
+	s.appendReplayEvent(session.GameID, ReplayEvent{Type: replayEventResult, Outcome: outcomeLabel, Detail: logMessage})
+	s.saveGameRecord(s.classicGameRecord(session, outcomeLabel, resultP1, resultP2))
+	slog.Info("Partida finalizada. "+logMessage, "event", "game_finished", "game_id", session.GameID, "outcome", outcomeLabel,
+		"player1", session.Player1.Name, "player2", session.Player2.Name)
+
+	s.markRecentOpponents(session.Player1.Name, session.Player2.Name)
+	if outcome, ok := outcomeFromResult(resultP1); ok {
+		s.recordHeadToHead(session.Player1.Name, session.Player2.Name, outcome)
+	}
+
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	results = map[string]string{session.Player1.Name: resultP1, session.Player2.Name: resultP2}
+	for name, result := range results {
+		if err := s.publishToPlayer(ctx, name, gameResultMessage(session.GameID, result)); err != nil {
+			slog.Error("Erro ao publicar resultado via Redis", "game_id", session.GameID, "player", name, "error", err)
+		}
+	}
+	return outcomeLabel, results, true
+}
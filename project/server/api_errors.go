@@ -0,0 +1,27 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Códigos de erro da API REST (/api/v1), enviados no campo "code" do APIError.
+// O status HTTP dá a classe do erro; o código diz qual foi, para quem consome a API.
+const (
+	errCodeInvalidRequest  = "invalid_request"  // Corpo ou parâmetros inválidos
+	errCodeInternal        = "internal_error"   // Falha no Redis ou em outra dependência
+	errCodeNotFound        = "not_found"        // Partida ou replay inexistente (ou já encerrado)
+	errCodeNoLocalPlayer   = "no_local_player"  // Notificação de partida sem jogador neste servidor
+	errCodeStockEmpty      = "stock_empty"      // Não há um pacote completo no estoque global
+	errCodeAlreadyResolved = "already_resolved" // A partida já foi decidida
+	errCodeAdminDisabled   = "admin_disabled"   // ADMIN_TOKEN não definido
+	errCodeUnauthorized    = "unauthorized"     // Token de administração ausente ou incorreto
+	errCodeAuthDisabled    = "auth_disabled"    // AUTH_SECRET não definido
+)
+
+// writeAPIError responde um erro da API REST no formato {"error": "...", "code": "..."}.
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(APIError{Error: message, Code: code})
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// auditKeyPrefix guarda, por jogador, a lista (Redis LIST) das suas ações mais recentes.
+	auditKeyPrefix = "audit:"
+	// auditMaxEntries limita o tamanho da lista (as entradas mais antigas são descartadas).
+	auditMaxEntries = 500
+	// auditTTL apaga o histórico de quem não joga há este tempo.
+	auditTTL = 7 * 24 * time.Hour
+	// auditDefaultLimit é o número de entradas devolvidas pelo endpoint sem "?limit=".
+	auditDefaultLimit = 50
+)
+
+// Ações registradas no histórico do jogador.
+const (
+	auditConnect    = "connect"
+	auditDisconnect = "disconnect"
+	auditOpenPack   = "open_pack"
+	auditFindMatch  = "find_match"
+	auditTrade      = "trade"
+	auditMove       = "move"
+	auditResult     = "result"
+)
+
+// AuditEntry é uma ação de um jogador, como gravada em audit:<nome>.
+type AuditEntry struct {
+	Timestamp int64  `json:"timestamp"` // Unix em milissegundos
+	ServerID  string `json:"server_id"` // Servidor que registrou a ação
+	Action    string `json:"action"`
+	GameID    string `json:"game_id,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// AuditResponse é a resposta do endpoint de administração GET /api/v1/players/{name}/audit.
+type AuditResponse struct {
+	PlayerName string       `json:"player_name"`
+	Entries    []AuditEntry `json:"entries"` // Da mais recente para a mais antiga
+}
+
+// audit registra uma ação no histórico do jogador sem bloquear quem chamou: a escrita roda em
+// outra goroutine e falhas são apenas registradas no log. Cada ação é gravada uma única vez, pelo
+// servidor que a observou (o do jogador; os resultados seguem recordGameResult), então o histórico
+// de um jogador que trocou de servidor continua completo e com o ServerID de cada ação.
+func (s *Server) audit(playerName, action, gameID, detail string) {
+	entry := AuditEntry{
+		Timestamp: time.Now().UnixMilli(),
+		ServerID:  s.ServerID,
+		Action:    action,
+		GameID:    gameID,
+		Detail:    detail,
+	}
+	go func() {
+		entryJSON, _ := json.Marshal(entry)
+		ctx, cancel := s.redisCtx()
+		defer cancel()
+		key := auditKeyPrefix + playerName
+		pipe := s.RedisClient.TxPipeline()
+		pipe.LPush(ctx, key, entryJSON)
+		pipe.LTrim(ctx, key, 0, auditMaxEntries-1)
+		pipe.Expire(ctx, key, auditTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			slog.Error("Erro ao gravar histórico do jogador", "player", playerName, "action", action, "error", err)
+		}
+	}()
+}
+
+// resultAuditDetail descreve o resultado no histórico: o desfecho e, se a partida não foi decidida
+// pelas cartas, o motivo (ex: "loss:disconnected"), para identificar quem abandona partidas.
+func resultAuditDetail(outcome, resultMsg string) string {
+	if _, reason, _ := parseResultMessage(resultMsg); reason != "" {
+		return outcome + ":" + reason
+	}
+	return outcome
+}
+
+// getAudit lê as entradas mais recentes do histórico do jogador, da mais recente para a mais antiga.
+// As escritas são assíncronas, então a ordem é refeita pelo Timestamp.
+func (s *Server) getAudit(playerName string, limit int) ([]AuditEntry, error) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	raw, err := s.RedisClient.LRange(ctx, auditKeyPrefix+playerName, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]AuditEntry, 0, len(raw))
+	for _, r := range raw {
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(r), &entry); err != nil {
+			slog.Error("Entrada de histórico corrompida", "player", playerName, "error", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Timestamp > entries[j].Timestamp })
+	return entries, nil
+}
+
+// handleGetAudit implementa o endpoint de administração GET /api/v1/players/{name}/audit?limit=N.
+func (s *Server) handleGetAudit(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	playerName := chi.URLParam(r, "name")
+
+	limit := auditDefaultLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > auditMaxEntries {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "limit deve ser um inteiro entre 1 e "+strconv.Itoa(auditMaxEntries))
+			return
+		}
+		limit = n
+	}
+
+	entries, err := s.getAudit(playerName, limit)
+	if err != nil {
+		slog.Error("Erro ao ler histórico do jogador", "player", playerName, "error", err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Erro interno ao consultar o histórico")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AuditResponse{PlayerName: playerName, Entries: entries})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Com AUTH_SECRET definido, a primeira mensagem da conexão WebSocket deixa de ser só o nome e
+// passa a ser "AUTH|<nome>|<token>", com o token assinado para esse nome:
+//
+//	<token> = <expira em (Unix)>.<HMAC-SHA256(AUTH_SECRET, "<nome>|<expira em>") em hex>
+//
+// Sem o token, com a assinatura de outro nome ou com o token vencido, o servidor responde
+// "AUTH_FAILED|<motivo>" e fecha a conexão. O token é emitido pelo endpoint de administração
+// POST /api/v1/auth/token (ou por qualquer ferramenta que conheça o segredo). Sem AUTH_SECRET
+// (o padrão, para desenvolvimento local), o nome continua bastando.
+
+const (
+	authPrefix       = "AUTH|"
+	authFailedPrefix = "AUTH_FAILED|"
+)
+
+// AuthTokenRequest é o corpo de POST /api/v1/auth/token.
+type AuthTokenRequest struct {
+	PlayerName string `json:"player_name"`
+}
+
+// AuthTokenResponse é a resposta de POST /api/v1/auth/token.
+type AuthTokenResponse struct {
+	PlayerName string `json:"player_name"`
+	Token      string `json:"token"`
+	ExpiresAt  int64  `json:"expires_at"` // Unix
+}
+
+// authSignature é a assinatura do nome com a validade do token.
+func authSignature(secret, playerName string, expiresAt int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s|%d", playerName, expiresAt)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signAuthToken emite o token que autentica o jogador até expiresAt.
+func signAuthToken(secret, playerName string, expiresAt int64) string {
+	return fmt.Sprintf("%d.%s", expiresAt, authSignature(secret, playerName, expiresAt))
+}
+
+// verifyAuthToken confere se o token foi assinado para o nome e ainda vale.
+// Retorna o motivo da recusa, ou "" se o token for válido.
+func verifyAuthToken(secret, playerName, token string, now time.Time) string {
+	rawExpiry, signature, found := strings.Cut(token, ".")
+	expiresAt, err := strconv.ParseInt(rawExpiry, 10, 64)
+	if !found || err != nil {
+		return "token malformado"
+	}
+	if !hmac.Equal([]byte(signature), []byte(authSignature(secret, playerName, expiresAt))) {
+		return "token inválido para este nome"
+	}
+	if now.Unix() >= expiresAt {
+		return "token expirado"
+	}
+	return ""
+}
+
+// authenticateConnection lê a primeira mensagem da conexão e retorna o nome do jogador.
+// Com a autenticação habilitada, reason explica a recusa ("" se a conexão foi aceita).
+func (s *Server) authenticateConnection(first string) (playerName, reason string) {
+	if s.Config.AuthSecret == "" {
+		return first, ""
+	}
+	rest, found := strings.CutPrefix(first, authPrefix)
+	if !found {
+		return "", "autenticação obrigatória: envie AUTH|<nome>|<token>"
+	}
+	playerName, token, found := strings.Cut(rest, "|")
+	if !found || token == "" {
+		return playerName, "token ausente"
+	}
+	return playerName, verifyAuthToken(s.Config.AuthSecret, playerName, token, time.Now())
+}
+
+// handleIssueAuthToken implementa o endpoint de administração POST /api/v1/auth/token:
+// emite um token para o jogador, válido por AUTH_TOKEN_TTL.
+func (s *Server) handleIssueAuthToken(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	if s.Config.AuthSecret == "" {
+		writeAPIError(w, http.StatusConflict, errCodeAuthDisabled, "Autenticação desabilitada (AUTH_SECRET não definido)")
+		return
+	}
+	var req AuthTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "Requisição inválida: "+err.Error())
+		return
+	}
+	if reason := validatePlayerName(req.PlayerName); reason != "" {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "Nome inválido: "+reason)
+		return
+	}
+
+	expiresAt := time.Now().Add(s.Config.AuthTokenTTL).Unix()
+	slog.Info("Token de autenticação emitido.", "event", "auth_token_issued", "player", req.PlayerName,
+		"expires_at", expiresAt, "remote_addr", r.RemoteAddr)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AuthTokenResponse{
+		PlayerName: req.PlayerName,
+		Token:      signAuthToken(s.Config.AuthSecret, req.PlayerName, expiresAt),
+		ExpiresAt:  expiresAt,
+	})
+}
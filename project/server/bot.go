@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	botNamePrefix = "Bot-"
+	// Tempo mínimo que o bot "pensa" antes de jogar, para que a partida pareça normal.
+	botMinThinkTime = 1 * time.Second
+)
+
+// startBotGame inicia uma partida clássica entre o jogador local (P1) e um bot do servidor (P2).
+// Usado quando a busca por oponente expira e BOT_FALLBACK está habilitado.
+// A partida segue o fluxo normal: o P1-Server (este) é o "cérebro" e decide o vencedor.
+// Deve ser chamada com player.cmdMu travado (ver matchmakingTimeout).
+func (s *Server) startBotGame(player *PlayerState) {
+	gameID := newRandomID()
+
+	pool := s.matchPool(player)
+	hand := selectRandomCards(pool, s.Config.HandSize, s.Config.DistinctHand)
+	if hand == nil {
+		slog.Warn("Jogador não tem cartas suficientes para jogar.", "game_id", gameID, "player", player.Name)
+		s.sendWebSocketMessage(player, "NO_MATCH_FOUND")
+		return
+	}
+
+	player.mu.Lock()
+	difficulty := player.botDifficulty
+	player.mu.Unlock()
+	bot := &PlayerState{Name: botNamePrefix + newRandomID()[:6], ServerID: s.ServerID, isBot: true,
+		bot: newBotPlayer(difficulty, botSeed(gameID), pool)}
+	session := &GameSession{
+		GameID:       gameID,
+		Player1:      player,
+		Player2:      bot,
+		Player1Hand:  hand,
+		Player2Hand:  selectRandomCards(baseCards, s.Config.HandSize, s.Config.DistinctHand),
+		Server1ID:    s.ServerID,
+		Server2ID:    s.ServerID,
+		TurnDeadline: s.claimTurnDeadline(gameID),
+		StartedAt:    time.Now(),
+		mu:           sync.Mutex{},
+	}
+
+	s.GamesMutex.Lock()
+	s.ActiveGames[gameID] = session
+	s.GamesMutex.Unlock()
+
+	player.mu.Lock()
+	player.addGame(gameID, session)
+	player.mu.Unlock()
+
+	s.appendReplayEvent(gameID, ReplayEvent{Type: replayEventHandDealt, Player: player.Name, Cards: hand})
+	s.appendReplayEvent(gameID, ReplayEvent{Type: replayEventHandDealt, Player: bot.Name, Cards: session.Player2Hand})
+
+	slog.Info("Iniciando partida contra bot (P1)", "event", "game_started", "game_id", gameID, "player", player.Name,
+		"opponent", bot.Name, "bot_difficulty", bot.bot.difficulty)
+	s.sendWebSocketMessage(player, "Nenhum oponente encontrado a tempo. Você vai enfrentar um bot!")
+	s.sendWebSocketMessage(player, "MATCH_FOUND")
+	s.sendGameStart(player, session, bot.Name, hand)
+
+	gamesStartedTotal.Inc()
+	go s.listenForGameEvents(session, gameID)
+}
+
+// playBotMove escolhe a carta do bot (segundo a dificuldade, ver bot_difficulty.go) e a registra como a jogada do P2,
+// depois de um tempo aleatório dentro do turno. Chamada pelo listenForGameEvents.
+func (s *Server) playBotMove(session *GameSession, gameID string) {
+	session.mu.Lock()
+	hand := session.Player2Hand
+	field := cardField("p2_card", session.SuddenDeathRound)
+	bot := session.Player2.bot
+	logger := slog.With("game_id", session.GameID, "player", session.Player2.Name)
+	session.mu.Unlock()
+	if len(hand) == 0 {
+		return
+	}
+
+	// Joga entre botMinThinkTime e metade do tempo de turno
+	think := botMinThinkTime
+	if window := s.Config.GameTurnTimeout/2 - botMinThinkTime; window > 0 {
+		think += time.Duration(rand.Int63n(int64(window)))
+	}
+	time.Sleep(think)
+
+	best := hand[bot.chooseCard(hand)]
+
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	cardJSON, _ := json.Marshal(best)
+	gameKey := fmt.Sprintf("game:state:%s", gameID)
+	if err := s.RedisClient.HSetNX(ctx, gameKey, field, cardJSON).Err(); err != nil {
+		logger.Error("Erro ao registrar jogada do bot", "error", err)
+		return
+	}
+	s.RedisClient.Expire(ctx, gameKey, s.gameStateTTL())
+	s.RedisClient.Publish(ctx, fmt.Sprintf("game:channel:%s", gameID), "MOVE_MADE")
+	logger.Info("Jogada do bot registrada no Redis", "event", "move_made", "card", best.Name)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+)
+
+// Dificuldades do bot do BOT_FALLBACK, escolhidas no "FIND_MATCH <dificuldade>".
+const (
+	BotEasy   = "easy"   // Joga uma carta aleatória
+	BotMedium = "medium" // Sorteia duas cartas e joga a mais forte
+	BotHard   = "hard"   // Joga a carta com mais chance de vencer uma carta do deck do adversário
+
+	defaultBotDifficulty = BotMedium
+)
+
+// botPlayer guarda a dificuldade e o gerador de números do bot de uma partida.
+// O gerador é semeado pelo GameID (botSeed), então a escolha do bot é reproduzível.
+type botPlayer struct {
+	difficulty   string
+	rng          *rand.Rand
+	opponentPool []Card // Cartas do deck do adversário (a mão é sorteada delas), usadas no modo difícil
+}
+
+// newBotPlayer cria o bot de uma partida com a dificuldade e a semente informadas.
+func newBotPlayer(difficulty string, seed int64, opponentPool []Card) *botPlayer {
+	if difficulty == "" {
+		difficulty = defaultBotDifficulty
+	}
+	return &botPlayer{difficulty: difficulty, rng: rand.New(rand.NewSource(seed)), opponentPool: opponentPool}
+}
+
+// botSeed deriva a semente do bot a partir do GameID da partida.
+func botSeed(gameID string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(gameID))
+	return int64(h.Sum64())
+}
+
+// parseFindMatch lê o argumento opcional do "FIND_MATCH <dificuldade>" (easy, medium ou hard).
+// Sem argumento, retorna "" (o bot usa defaultBotDifficulty).
+func parseFindMatch(command string) (difficulty string, err error) {
+	arg := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(command, "FIND_MATCH")))
+	switch arg {
+	case "", BotEasy, BotMedium, BotHard:
+		return arg, nil
+	}
+	return "", fmt.Errorf("dificuldade do bot inválida %q. Use: FIND_MATCH [easy|medium|hard]", arg)
+}
+
+// handleFindMatch processa o "FIND_MATCH [dificuldade]" da fila clássica. A dificuldade fica
+// guardada no jogador e só é usada se a busca terminar em uma partida contra o bot (BOT_FALLBACK).
+func (s *Server) handleFindMatch(player *PlayerState, command string) {
+	difficulty, err := parseFindMatch(command)
+	if err != nil {
+		s.sendWebSocketMessage(player, err.Error())
+		return
+	}
+	player.mu.Lock()
+	player.botDifficulty = difficulty
+	player.mu.Unlock()
+	s.addToMatchmakingQueue(player, matchmakingQueueKey)
+}
+
+// chooseCard escolhe a carta que o bot joga (índice na mão), segundo a dificuldade.
+func (b *botPlayer) chooseCard(hand []Card) int {
+	switch b.difficulty {
+	case BotEasy:
+		return b.rng.Intn(len(hand))
+	case BotHard:
+		if best := bestCardAgainst(hand, b.opponentPool); best >= 0 {
+			return best
+		}
+		return strongestCard(hand)
+	default:
+		first, second := b.rng.Intn(len(hand)), b.rng.Intn(len(hand))
+		if strongestCard([]Card{hand[first], hand[second]}) == 1 {
+			return second
+		}
+		return first
+	}
+}
+
+// strongestCard retorna o índice da carta de maior Força (desempate pela Agilidade).
+func strongestCard(hand []Card) int {
+	best := 0
+	for i, c := range hand {
+		if c.Forca > hand[best].Forca || (c.Forca == hand[best].Forca && c.Speed > hand[best].Speed) {
+			best = i
+		}
+	}
+	return best
+}
+
+// bestCardAgainst retorna o índice da carta que vence mais duelos contra as cartas do adversário
+// (cada cópia no deck pesa igual, como no sorteio da mão). Entre cartas com a mesma chance, fica
+// a de menor Força. Retorna -1 se não houver cartas do adversário para comparar.
+func bestCardAgainst(hand []Card, opponentPool []Card) int {
+	if len(opponentPool) == 0 {
+		return -1
+	}
+	best, bestWins := -1, -1
+	for i, c := range hand {
+		wins := 0
+		for _, opp := range opponentPool {
+			if resolveCardDuel(c, opp).Winner == 1 {
+				wins++
+			}
+		}
+		if wins > bestWins || (wins == bestWins && c.Forca < hand[best].Forca) {
+			best, bestWins = i, wins
+		}
+	}
+	return best
+}
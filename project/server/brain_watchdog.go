@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// gameBrainPrefix indica, por GameID, qual servidor é o "cérebro" da partida.
+	// A chave é mantida viva por heartbeat enquanto o listener da partida estiver rodando.
+	gameBrainPrefix = "game:brain:"
+	// A chave fica em uma chave própria (e não no hash game:state:<id>) porque o hash
+	// guarda apenas as jogadas e é contado com HLEN nas partidas FFA.
+	brainHeartbeatInterval = 1 * time.Second
+	brainHeartbeatTTL      = 3 * time.Second
+	// brainWatchdogGrace é a folga, após o prazo da jogada, antes de o watchdog começar a verificar o cérebro.
+	brainWatchdogGrace = 5 * time.Second
+)
+
+// startBrainHeartbeat publica que ESTE servidor é o cérebro da partida e renova a chave
+// periodicamente. A função retornada encerra o heartbeat; a chave expira sozinha depois de
+// brainHeartbeatTTL, dando tempo para o "RESULT|" chegar aos outros servidores.
+func (s *Server) startBrainHeartbeat(gameID string) (stop func()) {
+	key := gameBrainPrefix + gameID
+	done := make(chan struct{})
+
+	ctx, cancel := s.redisCtx()
+	s.RedisClient.Set(ctx, key, s.ServerID, brainHeartbeatTTL)
+	cancel()
+	go func() {
+		ticker := time.NewTicker(brainHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				ctx, cancel := s.redisCtx()
+				if err := s.RedisClient.Set(ctx, key, s.ServerID, brainHeartbeatTTL).Err(); err != nil {
+					slog.Error("Erro ao renovar heartbeat do cérebro da partida", "game_id", gameID, "error", err)
+				}
+				cancel()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// watchGameBrain roda no servidor de um jogador cuja partida tem o cérebro em OUTRO servidor.
+// Se o prazo da jogada passar, a partida não tiver resultado e o heartbeat do cérebro tiver
+// sumido (o servidor caiu), a partida é resolvida aqui para o jogador local.
+func (s *Server) watchGameBrain(player *PlayerState, session *GameSession) {
+	session.mu.Lock()
+	deadline := session.TurnDeadline
+	gameID := session.GameID
+	session.mu.Unlock()
+
+	select {
+	case <-player.done:
+		return
+	case <-time.After(time.Until(deadline) + brainWatchdogGrace):
+	}
+
+	ticker := time.NewTicker(brainHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		player.mu.Lock()
+		stillInGame := player.Games[gameID] == session
+		player.mu.Unlock()
+		if !stillInGame {
+			return // O resultado chegou normalmente
+		}
+
+		ctx, cancel := s.redisCtx()
+		alive, err := s.RedisClient.Exists(ctx, gameBrainPrefix+gameID).Result()
+		cancel()
+		if err != nil {
+			slog.Error("Erro ao verificar heartbeat do cérebro da partida", "game_id", gameID, "error", err)
+		} else if alive == 0 {
+			s.resolveOrphanedGame(player, session)
+			return
+		}
+
+		select {
+		case <-player.done:
+			return
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// resolveOrphanedGame encerra, para o jogador local, uma partida cujo cérebro caiu.
+// No modo clássico, o jogador presente vence se tiver jogado (senão, empate).
+// No modo FFA, a partida é anulada com empate para os jogadores locais.
+// O resultado é publicado no canal do próprio jogador, seguindo o fluxo normal de "RESULT|"
+// (limpeza do estado e registro no ranking).
+func (s *Server) resolveOrphanedGame(player *PlayerState, session *GameSession) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+
+	session.mu.Lock()
+	gameID := session.GameID
+	mode := session.Mode
+	gameKey := fmt.Sprintf("game:state:%s", session.GameID)
+	round := session.SuddenDeathRound
+	session.mu.Unlock()
+
+	field := player.Name
+	if mode != gameModeFFA {
+		// A rodada registrada pelo cérebro vale mesmo se a última SUDDEN_DEATH não chegou a este servidor
+		if stored, err := s.RedisClient.HGet(ctx, gameKey, suddenDeathRoundField).Int(); err == nil && stored > round {
+			round = stored
+		}
+		field = cardField("p2_card", round)
+	}
+
+	var result string
+	if mode == gameModeFFA {
+		result = resultMessage("EMPATE", resultReasonServerLost, localized(msgResultServerLostVoid))
+	} else if played, _ := s.RedisClient.HExists(ctx, gameKey, field).Result(); played {
+		result = resultMessage("VITÓRIA", resultReasonServerLost, localized(msgResultServerLostWin))
+	} else {
+		result = resultMessage("EMPATE", resultReasonServerLost, localized(msgResultServerLostDraw))
+	}
+	if mode != gameModeFFA {
+		// No modo clássico este é o único jogador a resolver: apaga as jogadas e os metadados, para que
+		// o cérebro, se reiniciar, não resolva a partida de novo. (No FFA, outros jogadores ainda podem consultá-la.)
+		s.clearGameState(gameID)
+	}
+
+	gamesFinishedTotal.WithLabelValues("brain_lost").Inc()
+	slog.Warn("Cérebro da partida caiu. Partida resolvida pelo watchdog.", "event", "game_orphaned",
+		"game_id", gameID, "mode", mode, "player", player.Name)
+
+	if err := s.publishToPlayer(ctx, player.Name, gameResultMessage(gameID, result)); err != nil {
+		slog.Error("Erro ao publicar resultado da partida órfã", "game_id", gameID, "player", player.Name, "error", err)
+	}
+}
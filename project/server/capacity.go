@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// serverFullPrefix marca, por ServerID, que o servidor atingiu MAX_CONCURRENT_GAMES. A marca é
+	// renovada enquanto ele estiver lotado e expira sozinha se ele cair.
+	serverFullPrefix   = "server:full:"
+	serverFullTTL      = 3 * time.Second
+	capacityCheckEvery = 1 * time.Second
+)
+
+// activeGameCount retorna quantas sessões de jogo este servidor mantém em ActiveGames.
+func (s *Server) activeGameCount() int {
+	s.GamesMutex.Lock()
+	defer s.GamesMutex.Unlock()
+	return len(s.ActiveGames)
+}
+
+// atGameCapacity indica se este servidor atingiu MAX_CONCURRENT_GAMES (0 = sem limite).
+func (s *Server) atGameCapacity() bool {
+	return s.Config.MaxConcurrentGames > 0 && s.activeGameCount() >= s.Config.MaxConcurrentGames
+}
+
+// capacityLoop publica no Redis (server:full:<ServerID>) se este servidor está lotado, para que
+// o matchmaker de qualquer servidor deixe os seus jogadores na fila até ele ter vaga.
+func (s *Server) capacityLoop() {
+	if s.Config.MaxConcurrentGames == 0 {
+		return
+	}
+	ticker := time.NewTicker(capacityCheckEvery)
+	defer ticker.Stop()
+
+	full := false
+	for range ticker.C {
+		if s.shuttingDown() {
+			return
+		}
+		nowFull := s.atGameCapacity()
+		ctx, cancel := s.redisCtx()
+		var err error
+		if nowFull {
+			err = s.RedisClient.Set(ctx, serverFullPrefix+s.ServerID, s.activeGameCount(), serverFullTTL).Err()
+		} else if full {
+			err = s.RedisClient.Del(ctx, serverFullPrefix+s.ServerID).Err()
+		}
+		cancel()
+		if err != nil {
+			slog.Error("Erro ao publicar a lotação do servidor", "error", err)
+			continue
+		}
+
+		if nowFull != full {
+			if nowFull {
+				slog.Warn("Servidor lotado. Novos pareamentos com seus jogadores ficam suspensos.", "event", "server_at_capacity",
+					"active_games", s.activeGameCount(), "max_concurrent_games", s.Config.MaxConcurrentGames)
+			} else {
+				slog.Info("Servidor voltou a ter vaga para novas partidas.", "event", "server_below_capacity",
+					"active_games", s.activeGameCount(), "max_concurrent_games", s.Config.MaxConcurrentGames)
+			}
+		}
+		full = nowFull
+	}
+}
+
+// fullServers retorna os servidores lotados (ou em manutenção, ver maintenance.go) entre os dos
+// tickets. Este servidor é consultado direto na memória; os demais, pelas marcas
+// server:full:<ServerID> e server:maintenance:<ServerID>. Se o Redis falhar, nenhum
+// servidor é considerado lotado (melhor uma partida a mais que uma fila parada).
+func (s *Server) fullServers(ctx context.Context, tickets []MatchmakingTicket) map[string]bool {
+	full := make(map[string]bool)
+	pipe := s.RedisClient.Pipeline()
+	cmds := make(map[string]*redis.IntCmd)
+	for _, t := range tickets {
+		if t.ServerID == s.ServerID {
+			full[t.ServerID] = s.atGameCapacity() || s.inMaintenance()
+			continue
+		}
+		if _, ok := cmds[t.ServerID]; !ok {
+			cmds[t.ServerID] = pipe.Exists(ctx, serverFullPrefix+t.ServerID, serverMaintenancePrefix+t.ServerID)
+		}
+	}
+	if len(cmds) == 0 {
+		return full
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.Error("Erro ao consultar a lotação dos servidores", "error", err)
+		return full
+	}
+	for serverID, cmd := range cmds {
+		full[serverID] = cmd.Val() > 0
+	}
+	return full
+}
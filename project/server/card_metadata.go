@@ -0,0 +1,74 @@
+package main
+
+import "strings"
+
+// Raridades das cartas (campo Card.Rarity), seguindo as faixas de Força do estoque padrão
+// (ver defaultStockSpec). Servem apenas para exibição: a lógica do jogo usa só a Força.
+const (
+	RarityCommon    = "comum"    // Força 1-3
+	RarityUncommon  = "incomum"  // Força 4-6
+	RarityRare      = "rara"     // Força 7-10
+	RarityLegendary = "lendária" // Força acima de 10
+)
+
+// Facções das cartas (campo Card.Faction).
+const (
+	FactionNorth     = "Reinos do Norte"
+	FactionNilfgaard = "Nilfgaard"
+	FactionScoiatael = "Scoia'tael"
+	FactionMonsters  = "Monstros"
+	FactionNeutral   = "Neutra"
+)
+
+// cardImageDir é o prefixo das imagens das cartas no cliente (campo Card.ImageRef).
+const cardImageDir = "cards/"
+
+// cardRarity retorna a raridade de uma carta a partir da sua Força.
+func cardRarity(forca int) string {
+	switch {
+	case forca <= 3:
+		return RarityCommon
+	case forca <= 6:
+		return RarityUncommon
+	case forca <= 10:
+		return RarityRare
+	default:
+		return RarityLegendary
+	}
+}
+
+// imageSlugReplacer remove acentos e pontuação do nome da carta para formar o nome do arquivo.
+var imageSlugReplacer = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "é", "e", "ê", "e", "í", "i",
+	"ó", "o", "ô", "o", "õ", "o", "ú", "u", "ç", "c", "'", "", " ", "-",
+)
+
+// cardImageRef retorna a referência da imagem da carta (ex: "cards/geralt-de-rivia.png").
+func cardImageRef(name string) string {
+	return cardImageDir + imageSlugReplacer.Replace(strings.ToLower(name)) + ".png"
+}
+
+// withMetadata retorna a carta com a raridade e a imagem preenchidas, se ainda não as tiver.
+func (c Card) withMetadata() Card {
+	if c.Rarity == "" {
+		c.Rarity = cardRarity(c.Forca)
+	}
+	if c.ImageRef == "" {
+		c.ImageRef = cardImageRef(c.Name)
+	}
+	return c
+}
+
+// withCardMetadata preenche a raridade e a imagem das cartas que ainda não as têm.
+func withCardMetadata(cards []Card) []Card {
+	for i := range cards {
+		cards[i] = cards[i].withMetadata()
+	}
+	return cards
+}
+
+// collectionEntry monta a entrada da coleção de uma carta com as suas n cópias.
+func collectionEntry(card Card, n int) CollectionEntry {
+	return CollectionEntry{Name: card.Name, Forca: card.Forca, Ability: card.Ability,
+		Rarity: card.Rarity, Faction: card.Faction, ImageRef: card.ImageRef, Count: n}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Visão do cluster: GET /api/v1/cluster lista os servidores registrados (servers:<ServerID>,
+// ver registry.go) com a carga de cada um, consultada no GET /api/v1/server/load do próprio
+// servidor. Serve para o cliente escolher a que servidor conectar e para painéis de operação.
+// Um servidor registrado que não responde (caiu há menos de serverRegistryTTL, ou a rede falhou)
+// aparece como "stale", sem derrubar a resposta inteira.
+
+// ServerLoad é a carga atual de um servidor.
+type ServerLoad struct {
+	ServerID           string `json:"server_id"`
+	Players            int    `json:"players"`
+	ActiveGames        int    `json:"active_games"`
+	MaxConcurrentGames int    `json:"max_concurrent_games"` // 0 = sem limite
+	Full               bool   `json:"full"`
+	Maintenance        bool   `json:"maintenance"`
+}
+
+// ClusterServer é um servidor registrado na visão do cluster. Load fica vazio se ele não respondeu.
+type ClusterServer struct {
+	ServerID string      `json:"server_id"`
+	RestAddr string      `json:"rest_addr"`
+	Stale    bool        `json:"stale"`
+	Error    string      `json:"error,omitempty"`
+	Load     *ServerLoad `json:"load,omitempty"`
+}
+
+// ClusterResponse é a resposta de GET /api/v1/cluster.
+type ClusterResponse struct {
+	Servers []ClusterServer `json:"servers"`
+}
+
+// localLoad retorna a carga deste servidor.
+func (s *Server) localLoad() ServerLoad {
+	s.PlayerMutex.Lock()
+	players := len(s.Players)
+	s.PlayerMutex.Unlock()
+	return ServerLoad{
+		ServerID:           s.ServerID,
+		Players:            players,
+		ActiveGames:        s.activeGameCount(),
+		MaxConcurrentGames: s.Config.MaxConcurrentGames,
+		Full:               s.atGameCapacity(),
+		Maintenance:        s.inMaintenance(),
+	}
+}
+
+// handleGetServerLoad implementa o endpoint REST GET /api/v1/server/load.
+func (s *Server) handleGetServerLoad(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(s.localLoad())
+}
+
+// fetchServerLoad consulta a carga de um servidor remoto.
+func (s *Server) fetchServerLoad(r *http.Request, addr string) (*ServerLoad, error) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, fmt.Sprintf("http://%s/api/v1/server/load", addr), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("servidor remoto retornou status %d", resp.StatusCode)
+	}
+	var load ServerLoad
+	if err := json.NewDecoder(resp.Body).Decode(&load); err != nil {
+		return nil, err
+	}
+	return &load, nil
+}
+
+// handleGetCluster implementa o endpoint REST GET /api/v1/cluster.
+func (s *Server) handleGetCluster(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.redisCtx()
+	keys, err := s.scanKeys(ctx, serverRegistryPrefix+"*")
+	servers := []ClusterServer{}
+	if err == nil && len(keys) > 0 {
+		// Um GET por chave (e não MGET): no modo cluster as chaves ficam em slots diferentes
+		pipe := s.RedisClient.Pipeline()
+		addrs := make([]*redis.StringCmd, len(keys))
+		for i, key := range keys {
+			addrs[i] = pipe.Get(ctx, key)
+		}
+		if _, err = pipe.Exec(ctx); err == redis.Nil {
+			err = nil // Um registro expirou entre o SCAN e o GET: o servidor é omitido
+		}
+		for i, key := range keys {
+			if addrs[i].Err() != nil {
+				continue
+			}
+			servers = append(servers, ClusterServer{ServerID: strings.TrimPrefix(key, serverRegistryPrefix), RestAddr: addrs[i].Val()})
+		}
+	}
+	cancel()
+	if err != nil {
+		slog.Error("Erro ao ler o registro de servidores", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Erro interno ao consultar o registro de servidores")
+		return
+	}
+
+	// Os servidores remotos são consultados em paralelo: um que não responde só atrasa a
+	// resposta até o timeout do cliente REST (NOTIFY_TIMEOUT).
+	var wg sync.WaitGroup
+	for i := range servers {
+		server := &servers[i]
+		if server.ServerID == s.ServerID {
+			load := s.localLoad()
+			server.Load = &load
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			load, err := s.fetchServerLoad(r, server.RestAddr)
+			if err != nil {
+				slog.Warn("Servidor registrado não respondeu à consulta de carga", "event", "cluster_peer_stale",
+					"remote_server_id", server.ServerID, "rest_addr", server.RestAddr, "error", err)
+				server.Stale, server.Error = true, err.Error()
+				return
+			}
+			server.Load = load
+		}()
+	}
+	wg.Wait()
+	sort.Slice(servers, func(i, j int) bool { return servers[i].ServerID < servers[j].ServerID })
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ClusterResponse{Servers: servers})
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+)
+
+// buildCollection agrega o deck em quantidades por carta e compara com o conjunto completo
+// de cartas do estoque (StockSpec). A ordem segue a do conjunto; cartas fora dele (ex: de uma
+// distribuição anterior) aparecem no fim. Deve ser chamada com player.mu travado.
+func (s *Server) buildCollection(deck []Card) CollectionResponse {
+	counts := make(map[string]int)
+	for _, card := range deck {
+		counts[card.Name]++
+	}
+
+	resp := CollectionResponse{DeckSize: len(deck), SetSize: len(s.StockSpec.Cards)}
+	inSet := make(map[string]bool, len(s.StockSpec.Cards))
+	for _, c := range s.StockSpec.Cards {
+		inSet[c.Name] = true
+		if n := counts[c.Name]; n > 0 {
+			resp.Owned = append(resp.Owned, collectionEntry(c.card(), n))
+			resp.Unique++
+		} else {
+			resp.Missing = append(resp.Missing, c.Name)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, card := range deck {
+		if inSet[card.Name] || seen[card.Name] {
+			continue
+		}
+		seen[card.Name] = true
+		resp.Owned = append(resp.Owned, collectionEntry(card, counts[card.Name]))
+	}
+	return resp
+}
+
+// handleCollection responde ao comando "COLLECTION" com a coleção do jogador ("COLLECTION|<json>"):
+// quantas cópias tem de cada carta e quais cartas do conjunto completo ainda faltam.
+func (s *Server) handleCollection(player *PlayerState) {
+	player.mu.Lock()
+	collection := s.buildCollection(player.Deck)
+	player.mu.Unlock()
+
+	collectionJSON, err := json.Marshal(collection)
+	if err != nil {
+		slog.Error("Erro ao serializar coleção", "player", player.Name, "error", err)
+		s.sendWebSocketMessage(player, "Erro interno ao montar a coleção. Tente novamente.")
+		return
+	}
+	s.sendWebSocketMessage(player, "COLLECTION|"+string(collectionJSON))
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+)
+
+// Cada comando é processado com player.cmdMu travado, do momento em que o estado do jogador
+// é lido até o fim da ação. As mudanças de estado que vêm de outras goroutines (início de
+// partida, resultado, timeout da fila) também travam cmdMu, então um comando nunca age sobre
+// um estado que mudou no meio do caminho (ex: FIND_MATCH junto com o início de uma partida).
+//
+// Ordem dos locks, para evitar deadlock: player.cmdMu -> session.mu -> player.mu.
+// Um comando não pode esperar, com cmdMu travado, por algo que trave o cmdMu do próprio
+// jogador (ex: startLocalGame); nesses casos a ação é disparada em outra goroutine.
+
+// searchingCommands são os comandos aceitos enquanto o jogador está na fila de matchmaking.
+// Os demais mudariam o deck ou o estado do jogador no meio da busca.
+var searchingCommands = []string{"VIEW_DECK", "COLLECTION", "LEADERBOARD", "H2H", "STATUS", "TRADE_QUEUE_PEEK", "GET_TIMER", "REPLAY", "OPEN_PACK", "SET_LOADOUT", "SET_LOCALE"}
+
+// commandRejection informa por que o comando não é válido no estado atual do jogador,
+// ou "" se ele pode ser processado. Deve ser chamada com player.cmdMu travado.
+func commandRejection(state string, inGame bool, command string) string {
+	switch {
+	case inGame:
+		return "" // Jogadas, GET_TIMER e STATUS; o resto é recusado pelo handleGameMove
+	case state == "Searching":
+		if strings.HasPrefix(command, "FIND_MATCH") {
+			return "Você já está na fila de matchmaking."
+		}
+		for _, allowed := range searchingCommands {
+			if strings.HasPrefix(command, allowed) {
+				return ""
+			}
+		}
+		return "Comando indisponível enquanto você procura uma partida."
+	}
+	return ""
+}
+
+// rejectCommand avisa o jogador que o comando foi recusado pelo estado atual: "COMMAND_REJECTED|<motivo>".
+func (s *Server) rejectCommand(player *PlayerState, reason string) {
+	s.sendWebSocketMessage(player, "COMMAND_REJECTED|"+reason)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandRejection(t *testing.T) {
+	tests := []struct {
+		state    string
+		inGame   bool
+		command  string
+		rejected bool
+	}{
+		{"Menu", false, "FIND_MATCH", false},
+		{"Menu", false, "TRADE_CARD 1", false},
+		{"Searching", false, "FIND_MATCH", true},
+		{"Searching", false, "FIND_MATCH FFA", true},
+		{"Searching", false, "TRADE_CARD 1", true},
+		{"Searching", false, "CREATE_PRIVATE", true},
+		{"Searching", false, "VIEW_DECK", false},
+		{"Searching", false, "OPEN_PACK", false},
+		{"Searching", false, "GET_TIMER", false},
+		{"InGame", true, "TRADE_CARD 1", false}, // Recusado depois, pelo handleGameMove
+	}
+	for _, tt := range tests {
+		reason := commandRejection(tt.state, tt.inGame, tt.command)
+		if (reason != "") != tt.rejected {
+			t.Errorf("commandRejection(%q, %v, %q) = %q, recusado esperado: %v", tt.state, tt.inGame, tt.command, reason, tt.rejected)
+		}
+	}
+}
+
+// newSearchingPlayer cria um jogador local, na fila, com cartas suficientes para uma partida.
+func newSearchingPlayer(t *testing.T, s *Server, name string) *PlayerState {
+	t.Helper()
+	player := newTestPlayer(name)
+	player.State = "Searching"
+	for i := 0; i < s.Config.MinDeckSize+s.Config.HandSize; i++ {
+		player.Deck = append(player.Deck, Card{Name: "Ghoul", Forca: 1})
+	}
+	s.PlayerMutex.Lock()
+	s.Players[name] = player
+	s.PlayerMutex.Unlock()
+	t.Cleanup(func() { close(player.done) }) // Encerra o vigia do cérebro da partida
+	return player
+}
+
+func TestSearchingPlayerCannotTrade(t *testing.T) {
+	s, _ := newTestServer(t)
+	player := newSearchingPlayer(t, s, "Alice")
+	deckSize := len(player.Deck)
+
+	player.cmdMu.Lock()
+	s.handleCommand(player, "TRADE_CARD 1")
+	player.cmdMu.Unlock()
+
+	messages := sentMessages(player)
+	if len(messages) != 1 || !strings.HasPrefix(messages[0], "COMMAND_REJECTED|") {
+		t.Errorf("TRADE_CARD na fila deveria ser recusado, mensagens: %q", messages)
+	}
+	if got := len(player.deckSnapshot()); got != deckSize {
+		t.Errorf("o deck não deveria mudar: %d cartas, esperado %d", got, deckSize)
+	}
+}
+
+func TestMatchStartWaitsForCommandInProgress(t *testing.T) {
+	s, _ := newTestServer(t)
+	player := newSearchingPlayer(t, s, "Alice")
+
+	// Um comando está em andamento: o início da partida não pode mudar o estado no meio dele
+	player.cmdMu.Lock()
+	started := make(chan struct{})
+	go func() {
+		s.startLocalGame("g1", "Bob", "Alice", "Server-Other", s.ServerID)
+		close(started)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	player.mu.Lock()
+	state, games := player.State, len(player.Games)
+	player.mu.Unlock()
+	if state != "Searching" || games != 0 {
+		t.Fatalf("o estado mudou durante o comando: estado %q, %d partida(s)", state, games)
+	}
+	player.cmdMu.Unlock()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("startLocalGame não terminou depois do comando")
+	}
+	player.mu.Lock()
+	state, inGame := player.State, player.Games["g1"] != nil
+	player.mu.Unlock()
+	if state != "InGame" || !inGame {
+		t.Errorf("depois do comando, o jogador deveria estar na partida: estado %q, na partida %v", state, inGame)
+	}
+}
+
+func TestMatchStartAbortsWhenCommandChangedState(t *testing.T) {
+	s, _ := newTestServer(t)
+	player := newSearchingPlayer(t, s, "Alice")
+
+	player.cmdMu.Lock()
+	started := make(chan struct{})
+	go func() {
+		s.startLocalGame("g2", "Bob", "Alice", "Server-Other", s.ServerID)
+		close(started)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	// O comando em andamento colocou o jogador em outra partida (ex: JOIN_PRIVATE)
+	other := &GameSession{GameID: "g1", Mode: gameModeClassic}
+	player.mu.Lock()
+	player.addGame("g1", other)
+	player.mu.Unlock()
+	player.cmdMu.Unlock()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("startLocalGame não terminou depois do comando")
+	}
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	if len(player.Games) != 1 || player.Games["g1"] != other {
+		t.Errorf("o jogador deveria continuar só na partida do comando, partidas: %v", player.gameIDs())
+	}
+}
+
+func TestMatchmakingTimeoutAfterMatchStart(t *testing.T) {
+	s, mr := newTestServer(t)
+	player := newSearchingPlayer(t, s, "Alice")
+	const ticket = `{"player":"Alice"}`
+	mr.ZAdd(matchmakingQueueKey, 1, ticket)
+
+	// O timeout da fila espera o início da partida e, com o jogador já em jogo, não faz nada
+	player.cmdMu.Lock()
+	done := make(chan struct{})
+	go func() {
+		s.matchmakingTimeout(player, 0, matchmakingQueueKey, ticket)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	player.mu.Lock()
+	player.addGame("g1", &GameSession{GameID: "g1", Mode: gameModeClassic})
+	player.mu.Unlock()
+	player.cmdMu.Unlock()
+	<-done
+
+	player.mu.Lock()
+	state := player.State
+	player.mu.Unlock()
+	if state != "InGame" {
+		t.Errorf("o timeout da fila não deveria tirar o jogador da partida: estado %q", state)
+	}
+	if members, _ := mr.ZMembers(matchmakingQueueKey); len(members) != 1 {
+		t.Errorf("o timeout não deveria mexer na fila depois do pareamento, fila: %v", members)
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Valores padrão da configuração (usados quando a variável de ambiente não é definida).
+const (
+	defaultMatchmakingTimeout = 15 * time.Second
+	defaultGameTurnTimeout    = 10 * time.Second
+	defaultTurnExtension      = 5 * time.Second
+	defaultPackSize           = 3
+	defaultMaxPacksPerPlayer  = 3
+	defaultPackIdemWindow     = 30 * time.Second
+	defaultMatchmakerLockTTL  = 1 * time.Second
+	defaultTradeLockTTL       = 3 * time.Second
+	defaultTradeWantTTL       = 5 * time.Minute
+	defaultTradePendingTTL    = 30 * time.Minute
+	defaultPlayerInboxTTL     = 24 * time.Hour
+	defaultReconnectGrace     = 15 * time.Second
+	defaultRematchWindow      = 15 * time.Second
+	defaultRecentOpponentTTL  = 5 * time.Minute
+	defaultPrivateMatchTTL    = 2 * time.Minute
+	defaultMatchAffinityWait  = 3 * time.Second
+	defaultRequeueBonus       = 5  // segundos
+	defaultAbandonPenalty     = 10 // segundos
+	defaultAbandonWindow      = 10 * time.Minute
+	defaultRedisTimeout       = 3 * time.Second
+	defaultAuthTokenTTL       = 24 * time.Hour
+	defaultFFAPlayers         = 3
+	defaultMaxGamesPerPlayer  = 1
+	defaultHandSize           = 2
+	defaultNotifyMaxAttempts  = 3
+	defaultNotifyTimeout      = 2 * time.Second
+	defaultNotifyBackoff      = 200 * time.Millisecond
+	defaultCommandRate        = 5
+	defaultCommandBurst       = 10
+	defaultHeavyCommandCost   = 3
+	defaultStockLowWatermark  = 1000
+	defaultRestockBatchSize   = 10000
+	defaultStockInitBatchSize = 5000
+	defaultCardForcaMin       = 1
+	defaultCardForcaMax       = 20
+)
+
+// Config reúne os parâmetros ajustáveis por implantação, lidos das variáveis de ambiente.
+type Config struct {
+	MatchmakingTimeout time.Duration // MATCHMAKING_TIMEOUT: tempo máximo na fila de matchmaking
+	GameTurnTimeout    time.Duration // GAME_TURN_TIMEOUT: tempo para cada jogador fazer sua jogada
+	TurnExtension      time.Duration // TURN_EXTENSION: tempo extra de um REQUEST_EXTENSION, uma vez por jogador e partida (ver turn_extension.go)
+	PackSize           int           // PACK_SIZE: número de cartas por pacote extra (OPEN_PACK, OPEN_PACKS)
+	StarterPackSize    int           // STARTER_PACK_SIZE: número de cartas do pacote inicial obrigatório (padrão: PACK_SIZE)
+	MaxPacksPerPlayer  int           // MAX_PACKS_PER_PLAYER: limite de pacotes extras por jogador (o pacote inicial não conta)
+	PackIdemWindow     time.Duration // PACK_IDEMPOTENCY_WINDOW: por quanto tempo a chave de um OPEN_PACK(S) repete o resultado (ver pack_idempotency.go)
+	MatchmakerLockTTL  time.Duration // MATCHMAKER_LOCK_TTL: TTL do lock da fila de matchmaking
+	TradeLockTTL       time.Duration // TRADE_LOCK_TTL: TTL do lock da fila de trocas
+	TradeWantTTL       time.Duration // TRADE_WANT_TTL: tempo na fila de uma troca com pedido (WANT) antes de devolver as cartas
+	TradePendingTTL    time.Duration // TRADE_PENDING_TTL: por quanto tempo uma troca em aberto impede o jogador de oferecer outra (ver trade_pending.go)
+	PlayerInboxTTL     time.Duration // PLAYER_INBOX_TTL: por quanto tempo as mensagens de um jogador desconectado esperam a reconexão (ver player_inbox.go)
+	ReconnectGrace     time.Duration // RECONNECT_GRACE: por quanto tempo quem caiu no meio da partida pode voltar com REJOIN (ver rejoin.go)
+	RematchWindow      time.Duration // REMATCH_WINDOW: janela para aceitar a revanche após uma partida
+	RecentOpponentTTL  time.Duration // RECENT_OPPONENT_WINDOW: por quanto tempo o matchmaker evita repetir o mesmo oponente
+	PrivateMatchTTL    time.Duration // PRIVATE_MATCH_TTL: validade do código de uma partida privada
+	MatchAffinityWait  time.Duration // MATCH_AFFINITY_WAIT: por quanto tempo o matchmaker prefere parear jogadores do mesmo servidor
+	RequeueBonus       int           // QUEUE_REQUEUE_BONUS: segundos à frente na fila para o ticket devolvido após uma partida abortada (0 = sem bônus, ver queue_priority.go)
+	AbandonPenalty     int           // QUEUE_ABANDON_PENALTY: segundos atrás na fila para quem abandonou uma partida recentemente (0 = sem penalidade)
+	AbandonWindow      time.Duration // QUEUE_ABANDON_WINDOW: por quanto tempo o abandono de uma partida vale a penalidade
+	MaxConcurrentGames int           // MAX_CONCURRENT_GAMES: máximo de partidas simultâneas neste servidor (0 = sem limite)
+	MaxGamesPerPlayer  int           // MAX_GAMES_PER_PLAYER: partidas simultâneas de um mesmo jogador (ver multi_game.go)
+	FFAPlayers         int           // FFA_PLAYERS: número de jogadores por partida "todos contra todos"
+	HandSize           int           // HAND_SIZE: número de cartas na mão em cada partida
+	DistinctHand       bool          // HAND_DISTINCT: evita cartas de mesmo nome na mão, quando o deck permite
+	MinDeckSize        int           // MIN_DECK_SIZE: mínimo de cartas no deck para jogar (padrão: HAND_SIZE)
+	SuddenDeathRounds  int           // SUDDEN_DEATH_ROUNDS: rodadas de morte súbita após um empate (0 = o empate vale, ver sudden_death.go)
+	NotifyMaxAttempts  int           // NOTIFY_MAX_ATTEMPTS: tentativas de notificar um servidor remoto sobre uma partida
+	NotifyTimeout      time.Duration // NOTIFY_TIMEOUT: timeout de cada tentativa de notificação REST
+	NotifyBackoff      time.Duration // NOTIFY_BACKOFF: espera antes da 2ª tentativa (dobra a cada nova tentativa)
+	BotFallback        bool          // BOT_FALLBACK: joga contra um bot quando a busca por oponente expira
+	BotFallbackAlone   time.Duration // BOT_FALLBACK_ALONE_AFTER: com BOT_FALLBACK, antecipa o bot para quem está sozinho na fila há este tempo (padrão: MATCHMAKING_TIMEOUT)
+	CommandRate        int           // COMMAND_RATE: fichas de comando repostas por segundo, por jogador
+	CommandBurst       int           // COMMAND_BURST: máximo de fichas acumuladas (rajada)
+	HeavyCommandCost   int           // HEAVY_COMMAND_COST: fichas de OPEN_PACK(S), TRADE_CARD, FIND_MATCH e JOIN_PRIVATE
+	StockSpecFile      string        // STOCK_SPEC_FILE: arquivo JSON com a distribuição do estoque (opcional)
+	StockLowWatermark  int           // STOCK_LOW_WATERMARK: abaixo deste número de cartas, o estoque é reposto automaticamente
+	RestockBatchSize   int           // RESTOCK_BATCH_SIZE: cartas adicionadas em cada reposição automática
+	StockInitBatchSize int           // STOCK_INIT_BATCH_SIZE: cartas por RPUSH na criação do estoque (ver pushStockInBatches)
+	CardForcaMin       int           // CARD_FORCA_MIN: menor Força aceita em uma carta do estoque
+	CardForcaMax       int           // CARD_FORCA_MAX: maior Força aceita em uma carta do estoque
+	AutoRestock        bool          // AUTO_RESTOCK: habilita a reposição automática pelo watermark
+	AllowPartialPack   bool          // ALLOW_PARTIAL_PACK: entrega as últimas cartas do estoque (menos que um pacote) como um pacote incompleto
+	NameConflict       string        // NAME_CONFLICT: nome já em uso no cluster: "reject" (NAME_TAKEN) ou "suffix" (Bob#2, via ASSIGNED_NAME)
+	AdminToken         string        // ADMIN_TOKEN: token dos endpoints de administração (vazio = desabilitados)
+	AuthSecret         string        // AUTH_SECRET: segredo dos tokens de conexão (vazio = sem autenticação, ver auth.go)
+	AuthTokenTTL       time.Duration // AUTH_TOKEN_TTL: validade dos tokens emitidos por POST /api/v1/auth/token
+	AllowedOrigins     []string      // ALLOWED_ORIGINS: origens aceitas nas conexões WebSocket ("*" = qualquer uma, ver origin.go)
+	RedisTimeout       time.Duration // REDIS_TIMEOUT: tempo máximo de cada operação no Redis
+}
+
+// loadConfig lê a configuração das variáveis de ambiente, aplicando os padrões e validando os valores.
+func loadConfig() (Config, error) {
+	var cfg Config
+	var err error
+
+	if cfg.MatchmakingTimeout, err = envDuration("MATCHMAKING_TIMEOUT", defaultMatchmakingTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.GameTurnTimeout, err = envDuration("GAME_TURN_TIMEOUT", defaultGameTurnTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.TurnExtension, err = envDuration("TURN_EXTENSION", defaultTurnExtension); err != nil {
+		return cfg, err
+	}
+	if cfg.PackSize, err = envInt("PACK_SIZE", defaultPackSize); err != nil {
+		return cfg, err
+	}
+	if cfg.StarterPackSize, err = envInt("STARTER_PACK_SIZE", cfg.PackSize); err != nil {
+		return cfg, err
+	}
+	if cfg.PackSize < 1 || cfg.StarterPackSize < 1 {
+		return cfg, fmt.Errorf("PACK_SIZE e STARTER_PACK_SIZE devem ser de pelo menos 1")
+	}
+	if cfg.MaxPacksPerPlayer, err = envInt("MAX_PACKS_PER_PLAYER", defaultMaxPacksPerPlayer); err != nil {
+		return cfg, err
+	}
+	if cfg.PackIdemWindow, err = envDuration("PACK_IDEMPOTENCY_WINDOW", defaultPackIdemWindow); err != nil {
+		return cfg, err
+	}
+	if cfg.MatchmakerLockTTL, err = envDuration("MATCHMAKER_LOCK_TTL", defaultMatchmakerLockTTL); err != nil {
+		return cfg, err
+	}
+	if cfg.TradeLockTTL, err = envDuration("TRADE_LOCK_TTL", defaultTradeLockTTL); err != nil {
+		return cfg, err
+	}
+	if cfg.TradeWantTTL, err = envDuration("TRADE_WANT_TTL", defaultTradeWantTTL); err != nil {
+		return cfg, err
+	}
+	if cfg.TradePendingTTL, err = envDuration("TRADE_PENDING_TTL", defaultTradePendingTTL); err != nil {
+		return cfg, err
+	}
+	if cfg.PlayerInboxTTL, err = envDuration("PLAYER_INBOX_TTL", defaultPlayerInboxTTL); err != nil {
+		return cfg, err
+	}
+	if cfg.ReconnectGrace, err = envDuration("RECONNECT_GRACE", defaultReconnectGrace); err != nil {
+		return cfg, err
+	}
+	if cfg.RematchWindow, err = envDuration("REMATCH_WINDOW", defaultRematchWindow); err != nil {
+		return cfg, err
+	}
+	if cfg.RecentOpponentTTL, err = envDuration("RECENT_OPPONENT_WINDOW", defaultRecentOpponentTTL); err != nil {
+		return cfg, err
+	}
+	if cfg.PrivateMatchTTL, err = envDuration("PRIVATE_MATCH_TTL", defaultPrivateMatchTTL); err != nil {
+		return cfg, err
+	}
+	if cfg.MatchAffinityWait, err = envDuration("MATCH_AFFINITY_WAIT", defaultMatchAffinityWait); err != nil {
+		return cfg, err
+	}
+	if cfg.RequeueBonus, err = envNonNegativeInt("QUEUE_REQUEUE_BONUS", defaultRequeueBonus); err != nil {
+		return cfg, err
+	}
+	if cfg.AbandonPenalty, err = envNonNegativeInt("QUEUE_ABANDON_PENALTY", defaultAbandonPenalty); err != nil {
+		return cfg, err
+	}
+	if cfg.AbandonWindow, err = envDuration("QUEUE_ABANDON_WINDOW", defaultAbandonWindow); err != nil {
+		return cfg, err
+	}
+	if cfg.FFAPlayers, err = envInt("FFA_PLAYERS", defaultFFAPlayers); err != nil {
+		return cfg, err
+	}
+	if cfg.FFAPlayers < 3 {
+		return cfg, fmt.Errorf("FFA_PLAYERS deve ser de pelo menos 3")
+	}
+	if cfg.HandSize, err = envInt("HAND_SIZE", defaultHandSize); err != nil {
+		return cfg, err
+	}
+	if cfg.DistinctHand, err = envBool("HAND_DISTINCT", false); err != nil {
+		return cfg, err
+	}
+	if cfg.MinDeckSize, err = envInt("MIN_DECK_SIZE", cfg.HandSize); err != nil {
+		return cfg, err
+	}
+	if cfg.SuddenDeathRounds, err = envNonNegativeInt("SUDDEN_DEATH_ROUNDS", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.NotifyMaxAttempts, err = envInt("NOTIFY_MAX_ATTEMPTS", defaultNotifyMaxAttempts); err != nil {
+		return cfg, err
+	}
+	if cfg.NotifyTimeout, err = envDuration("NOTIFY_TIMEOUT", defaultNotifyTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.NotifyBackoff, err = envDuration("NOTIFY_BACKOFF", defaultNotifyBackoff); err != nil {
+		return cfg, err
+	}
+	if cfg.BotFallback, err = envBool("BOT_FALLBACK", false); err != nil {
+		return cfg, err
+	}
+	if cfg.BotFallbackAlone, err = envDuration("BOT_FALLBACK_ALONE_AFTER", cfg.MatchmakingTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxConcurrentGames, err = envNonNegativeInt("MAX_CONCURRENT_GAMES", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxGamesPerPlayer, err = envInt("MAX_GAMES_PER_PLAYER", defaultMaxGamesPerPlayer); err != nil {
+		return cfg, err
+	}
+	if cfg.CommandRate, err = envInt("COMMAND_RATE", defaultCommandRate); err != nil {
+		return cfg, err
+	}
+	if cfg.CommandBurst, err = envInt("COMMAND_BURST", defaultCommandBurst); err != nil {
+		return cfg, err
+	}
+	if cfg.HeavyCommandCost, err = envInt("HEAVY_COMMAND_COST", defaultHeavyCommandCost); err != nil {
+		return cfg, err
+	}
+	if cfg.HeavyCommandCost > cfg.CommandBurst {
+		return cfg, fmt.Errorf("HEAVY_COMMAND_COST (%d) não pode ser maior que COMMAND_BURST (%d)", cfg.HeavyCommandCost, cfg.CommandBurst)
+	}
+	cfg.StockSpecFile = os.Getenv("STOCK_SPEC_FILE")
+	if cfg.AutoRestock, err = envBool("AUTO_RESTOCK", true); err != nil {
+		return cfg, err
+	}
+	if cfg.AllowPartialPack, err = envBool("ALLOW_PARTIAL_PACK", false); err != nil {
+		return cfg, err
+	}
+	if cfg.StockLowWatermark, err = envInt("STOCK_LOW_WATERMARK", defaultStockLowWatermark); err != nil {
+		return cfg, err
+	}
+	if cfg.RestockBatchSize, err = envInt("RESTOCK_BATCH_SIZE", defaultRestockBatchSize); err != nil {
+		return cfg, err
+	}
+	if cfg.StockInitBatchSize, err = envInt("STOCK_INIT_BATCH_SIZE", defaultStockInitBatchSize); err != nil {
+		return cfg, err
+	}
+	if cfg.CardForcaMin, err = envInt("CARD_FORCA_MIN", defaultCardForcaMin); err != nil {
+		return cfg, err
+	}
+	if cfg.CardForcaMax, err = envInt("CARD_FORCA_MAX", defaultCardForcaMax); err != nil {
+		return cfg, err
+	}
+	if cfg.CardForcaMin < 1 || cfg.CardForcaMax < cfg.CardForcaMin {
+		return cfg, fmt.Errorf("faixa de Força inválida: CARD_FORCA_MIN (%d) deve ser pelo menos 1 e no máximo CARD_FORCA_MAX (%d)", cfg.CardForcaMin, cfg.CardForcaMax)
+	}
+	cfg.NameConflict = os.Getenv("NAME_CONFLICT")
+	switch cfg.NameConflict {
+	case "":
+		cfg.NameConflict = nameConflictReject
+	case nameConflictReject, nameConflictSuffix:
+	default:
+		return cfg, fmt.Errorf("NAME_CONFLICT inválido (%q): use %q ou %q", cfg.NameConflict, nameConflictReject, nameConflictSuffix)
+	}
+	cfg.AdminToken = os.Getenv("ADMIN_TOKEN")
+	cfg.AuthSecret = os.Getenv("AUTH_SECRET")
+	if cfg.AuthTokenTTL, err = envDuration("AUTH_TOKEN_TTL", defaultAuthTokenTTL); err != nil {
+		return cfg, err
+	}
+	allowedOrigins, ok := os.LookupEnv("ALLOWED_ORIGINS")
+	if !ok {
+		allowedOrigins = allowAllOrigins
+	}
+	if cfg.AllowedOrigins, err = parseAllowedOrigins(allowedOrigins); err != nil {
+		return cfg, err
+	}
+	if cfg.RedisTimeout, err = envDuration("REDIS_TIMEOUT", defaultRedisTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.MinDeckSize < cfg.HandSize {
+		return cfg, fmt.Errorf("MIN_DECK_SIZE (%d) não pode ser menor que HAND_SIZE (%d)", cfg.MinDeckSize, cfg.HandSize)
+	}
+
+	// O cliente recebe os tempos em segundos inteiros (ex: "TIMER|10").
+	if cfg.MatchmakingTimeout < time.Second || cfg.GameTurnTimeout < time.Second || cfg.RematchWindow < time.Second || cfg.PrivateMatchTTL < time.Second {
+		return cfg, fmt.Errorf("MATCHMAKING_TIMEOUT, GAME_TURN_TIMEOUT, REMATCH_WINDOW e PRIVATE_MATCH_TTL devem ser de pelo menos 1s")
+	}
+	// Ajustes limitados garantem que ninguém espere indefinidamente na fila (ver queue_priority.go)
+	if limit := int(cfg.MatchmakingTimeout / time.Second); cfg.RequeueBonus >= limit || cfg.AbandonPenalty >= limit {
+		return cfg, fmt.Errorf("QUEUE_REQUEUE_BONUS e QUEUE_ABANDON_PENALTY devem ser menores que MATCHMAKING_TIMEOUT (%ds)", limit)
+	}
+	// A reserva do nome do jogador desligado é renovada uma vez, ao cair, e tem que durar a espera
+	if cfg.ReconnectGrace >= presenceTTL {
+		return cfg, fmt.Errorf("RECONNECT_GRACE (%s) deve ser menor que %s", cfg.ReconnectGrace, presenceTTL)
+	}
+	// A extensão é uma tolerância para conexões lentas, não um segundo turno
+	if cfg.TurnExtension > cfg.GameTurnTimeout {
+		return cfg, fmt.Errorf("TURN_EXTENSION (%s) não pode ser maior que GAME_TURN_TIMEOUT (%s)", cfg.TurnExtension, cfg.GameTurnTimeout)
+	}
+	return cfg, nil
+}
+
+// logConfig registra a configuração efetiva no início do servidor.
+func (cfg Config) logConfig() {
+	slog.Info("Configuração efetiva",
+		"matchmaking_timeout", cfg.MatchmakingTimeout,
+		"game_turn_timeout", cfg.GameTurnTimeout,
+		"turn_extension", cfg.TurnExtension,
+		"pack_size", cfg.PackSize,
+		"starter_pack_size", cfg.StarterPackSize,
+		"max_packs_per_player", cfg.MaxPacksPerPlayer,
+		"pack_idempotency_window", cfg.PackIdemWindow,
+		"matchmaker_lock_ttl", cfg.MatchmakerLockTTL,
+		"trade_lock_ttl", cfg.TradeLockTTL,
+		"trade_want_ttl", cfg.TradeWantTTL,
+		"trade_pending_ttl", cfg.TradePendingTTL,
+		"player_inbox_ttl", cfg.PlayerInboxTTL,
+		"reconnect_grace", cfg.ReconnectGrace,
+		"rematch_window", cfg.RematchWindow,
+		"recent_opponent_window", cfg.RecentOpponentTTL,
+		"private_match_ttl", cfg.PrivateMatchTTL,
+		"match_affinity_wait", cfg.MatchAffinityWait,
+		"queue_requeue_bonus", cfg.RequeueBonus,
+		"queue_abandon_penalty", cfg.AbandonPenalty,
+		"queue_abandon_window", cfg.AbandonWindow,
+		"max_concurrent_games", cfg.MaxConcurrentGames,
+		"max_games_per_player", cfg.MaxGamesPerPlayer,
+		"ffa_players", cfg.FFAPlayers,
+		"hand_size", cfg.HandSize,
+		"hand_distinct", cfg.DistinctHand,
+		"min_deck_size", cfg.MinDeckSize,
+		"sudden_death_rounds", cfg.SuddenDeathRounds,
+		"notify_max_attempts", cfg.NotifyMaxAttempts,
+		"notify_timeout", cfg.NotifyTimeout,
+		"notify_backoff", cfg.NotifyBackoff,
+		"bot_fallback", cfg.BotFallback,
+		"bot_fallback_alone_after", cfg.BotFallbackAlone,
+		"command_rate", cfg.CommandRate,
+		"command_burst", cfg.CommandBurst,
+		"heavy_command_cost", cfg.HeavyCommandCost,
+		"stock_spec_file", cfg.StockSpecFile,
+		"auto_restock", cfg.AutoRestock,
+		"allow_partial_pack", cfg.AllowPartialPack,
+		"stock_low_watermark", cfg.StockLowWatermark,
+		"restock_batch_size", cfg.RestockBatchSize,
+		"stock_init_batch_size", cfg.StockInitBatchSize,
+		"card_forca_min", cfg.CardForcaMin,
+		"card_forca_max", cfg.CardForcaMax,
+		"redis_timeout", cfg.RedisTimeout,
+		"name_conflict", cfg.NameConflict,
+		"auth_enabled", cfg.AuthSecret != "",
+		"auth_token_ttl", cfg.AuthTokenTTL,
+		"allowed_origins", cfg.AllowedOrigins,
+		"admin_api_enabled", cfg.AdminToken != "") // Os segredos em si nunca vão para o log
+}
+
+// envDuration lê uma duração positiva. Aceita o formato do Go ("15s", "500ms") ou segundos inteiros ("15").
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		secs, errInt := strconv.Atoi(raw)
+		if errInt != nil {
+			return 0, fmt.Errorf("%s inválido (%q): use uma duração como \"15s\" ou segundos inteiros", name, raw)
+		}
+		d = time.Duration(secs) * time.Second
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s deve ser positivo, recebido %q", name, raw)
+	}
+	return d, nil
+}
+
+// envInt lê um inteiro positivo.
+func envInt(name string, def int) (int, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("%s deve ser um inteiro positivo, recebido %q", name, raw)
+	}
+	return v, nil
+}
+
+// envNonNegativeInt lê um inteiro maior ou igual a zero, para opções em que 0 desliga o recurso.
+func envNonNegativeInt(name string, def int) (int, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("%s deve ser um inteiro maior ou igual a zero, recebido %q", name, raw)
+	}
+	return v, nil
+}
+
+// envBool lê um booleano ("true"/"false", "1"/"0").
+func envBool(name string, def bool) (bool, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("%s deve ser um booleano (true/false), recebido %q", name, raw)
+	}
+	return v, nil
+}
+
+// packSize retorna o número de cartas de um pacote: o inicial obrigatório (STARTER_PACK_SIZE)
+// ou o extra (PACK_SIZE). É a única fonte desses tamanhos para a retirada do estoque.
+func (cfg Config) packSize(starter bool) int {
+	if starter {
+		return cfg.StarterPackSize
+	}
+	return cfg.PackSize
+}
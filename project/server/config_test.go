@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+// Opções em que 0 desliga o recurso: o 0 explícito é aceito, e um valor negativo é recusado.
+func TestNonNegativeIntConfig(t *testing.T) {
+	options := []struct {
+		env   string
+		def   int
+		field func(Config) int
+	}{
+		{"MAX_CONCURRENT_GAMES", 0, func(c Config) int { return c.MaxConcurrentGames }},
+		{"SUDDEN_DEATH_ROUNDS", 0, func(c Config) int { return c.SuddenDeathRounds }},
+		{"QUEUE_REQUEUE_BONUS", defaultRequeueBonus, func(c Config) int { return c.RequeueBonus }},
+		{"QUEUE_ABANDON_PENALTY", defaultAbandonPenalty, func(c Config) int { return c.AbandonPenalty }},
+	}
+	values := []struct {
+		value   string
+		want    int
+		invalid bool
+	}{
+		{"", -1, false}, // Sem a variável: o padrão da opção
+		{"0", 0, false},
+		{"3", 3, false},
+		{"-1", 0, true},
+		{"muitas", 0, true},
+	}
+	for _, opt := range options {
+		for _, tt := range values {
+			t.Run(opt.env+"="+tt.value, func(t *testing.T) {
+				t.Setenv(opt.env, tt.value)
+				cfg, err := loadConfig()
+				if tt.invalid {
+					if err == nil {
+						t.Errorf("%s=%q deveria ser recusado", opt.env, tt.value)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("%s=%q recusado: %v", opt.env, tt.value, err)
+				}
+				want := tt.want
+				if tt.value == "" {
+					want = opt.def
+				}
+				if got := opt.field(cfg); got != want {
+					t.Errorf("%s=%q lido como %d, esperado %d", opt.env, tt.value, got, want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+)
+
+const (
+	// deadLetterKey guarda (para inspeção) os tickets corrompidos retirados das filas de
+	// matchmaking e de trocas, como "<fila>|<payload>". Só os mais recentes são mantidos.
+	deadLetterKey     = "queue:dead_letter"
+	deadLetterMaxSize = 1000
+	// deadLetterLogSize limita o tamanho do payload registrado no log.
+	deadLetterLogSize = 256
+)
+
+// deadLetterTicket registra um ticket que não pôde ser desserializado e já saiu da fila
+// (ZREM/LREM/LPOP feito pelo chamador). Sem isso, o ticket ficaria no início da fila e
+// travaria todos os jogadores atrás dele a cada rodada.
+func (s *Server) deadLetterTicket(ctx context.Context, queueKey, payload string, cause error) {
+	logged := payload
+	if len(logged) > deadLetterLogSize {
+		logged = logged[:deadLetterLogSize] + "..."
+	}
+	slog.Error("Ticket corrompido retirado da fila", "event", "ticket_dead_lettered", "queue", queueKey,
+		"payload", logged, "error", cause)
+	deadLetterTicketsTotal.WithLabelValues(queueKey).Inc()
+
+	pipe := s.RedisClient.TxPipeline()
+	pipe.LPush(ctx, deadLetterKey, queueKey+"|"+payload)
+	pipe.LTrim(ctx, deadLetterKey, 0, deadLetterMaxSize-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.Error("Erro ao guardar ticket corrompido", "queue", queueKey, "error", err)
+	}
+}
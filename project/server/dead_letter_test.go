@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMalformedRequestBodyRejected(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", testAdminToken) // A reposição exige o token antes de ler o corpo
+	s, _ := newTestServer(t)
+	handlers := []struct {
+		path    string
+		handler http.HandlerFunc
+	}{
+		{"/api/v1/stock/take", s.handleTakeCardPack},
+		{"/api/v1/stock/restock", s.handleRestock},
+		{"/api/v1/match/notify", s.handleMatchNotification},
+		{"/api/v1/match/ffa/notify", s.handleFFAMatchNotification},
+	}
+	for _, h := range handlers {
+		for _, body := range []string{`{"player_name": "Alice"`, `não é json`, `["lista"]`} {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, h.path, strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+testAdminToken)
+			h.handler(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s com corpo %q: status = %d, esperado 400", h.path, body, rec.Code)
+				continue
+			}
+			var apiErr APIError
+			if err := json.NewDecoder(rec.Body).Decode(&apiErr); err != nil {
+				t.Errorf("%s com corpo %q: resposta não é um APIError: %v", h.path, body, err)
+				continue
+			}
+			if apiErr.Code != errCodeInvalidRequest || apiErr.Error == "" {
+				t.Errorf("%s com corpo %q: resposta = %+v, esperado code %q e uma mensagem", h.path, body, apiErr, errCodeInvalidRequest)
+			}
+		}
+	}
+}
+
+func TestCorruptedMatchmakingTicketDeadLettered(t *testing.T) {
+	s, mr := newTestServer(t)
+	const corrupted = `{"player_name": "Alice", "server_id":`
+	valid, _ := json.Marshal(MatchmakingTicket{PlayerName: "Bob", ServerID: "Server-Other"})
+	mr.ZAdd(matchmakingQueueKey, 1, corrupted)
+	mr.ZAdd(matchmakingQueueKey, 2, string(valid))
+
+	s.tryPairPlayers(context.Background())
+
+	members, _ := mr.ZMembers(matchmakingQueueKey)
+	if len(members) != 1 || members[0] != string(valid) {
+		t.Errorf("só o ticket corrompido deveria sair da fila, fila: %q", members)
+	}
+	if dead, _ := mr.List(deadLetterKey); len(dead) != 1 || dead[0] != matchmakingQueueKey+"|"+corrupted {
+		t.Errorf("dead letter = %q, esperado o ticket corrompido da fila %s", dead, matchmakingQueueKey)
+	}
+}
+
+func TestCorruptedTradeTicketDeadLettered(t *testing.T) {
+	s, mr := newTestServer(t)
+	const corrupted = `{"player_name": "Alice", "cards": [`
+	mr.RPush(tradeQueueKey, corrupted)
+	player := newTestPlayer("Bob")
+	card := Card{Name: "Ghoul", Forca: 1}
+
+	s.performDistributedTrade(player, []Card{card}, nil)
+
+	// O ticket corrompido não volta à fila: o de Bob entra no lugar dele, à espera de outro jogador
+	queue, _ := mr.List(tradeQueueKey)
+	if len(queue) != 1 {
+		t.Fatalf("fila de trocas = %q, esperado só o ticket de Bob", queue)
+	}
+	var ticket TradeTicket
+	if err := json.Unmarshal([]byte(queue[0]), &ticket); err != nil || ticket.PlayerName != "Bob" {
+		t.Errorf("ticket na fila = %q, esperado o de Bob", queue[0])
+	}
+	if dead, _ := mr.List(deadLetterKey); len(dead) != 1 || dead[0] != tradeQueueKey+"|"+corrupted {
+		t.Errorf("dead letter = %q, esperado o ticket corrompido da fila %s", dead, tradeQueueKey)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// O deck do jogador muda por comandos (OPEN_PACK, TRADE_CARD) e também fora deles, pelo
+// Pub/Sub (TRADE_COMPLETE), que roda em outra goroutine. Por isso toda leitura ou escrita de
+// player.Deck é feita com player.mu travado, de preferência por estes métodos.
+//
+// O deck é salvo em player:deck:<nome> a cada mudança (giveCards, saveDeck) e restaurado na
+// conexão seguinte, em qualquer servidor: quem volta não precisa do pacote inicial de novo.
+
+// deckKeyPrefix guarda, por jogador, o deck como uma lista JSON de cartas (player:deck:<nome>).
+const deckKeyPrefix = "player:deck:"
+
+// addCards adiciona cartas ao deck do jogador.
+func (p *PlayerState) addCards(cards ...Card) {
+	p.mu.Lock()
+	p.Deck = append(p.Deck, cards...)
+	p.mu.Unlock()
+}
+
+// deckSnapshot retorna uma cópia do deck do jogador, que pode ser lida sem o lock.
+func (p *PlayerState) deckSnapshot() []Card {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Card(nil), p.Deck...)
+}
+
+// giveCards adiciona cartas ao deck do jogador e salva o deck.
+func (s *Server) giveCards(player *PlayerState, cards ...Card) {
+	player.addCards(cards...)
+	s.saveDeck(player)
+}
+
+// saveDeck grava o deck atual do jogador no Redis. Chamada depois de cada mudança do deck.
+// deckSaveMu mantém as gravações na ordem: a última sempre tem o deck mais recente.
+func (s *Server) saveDeck(player *PlayerState) {
+	if player.isBot {
+		return
+	}
+	player.deckSaveMu.Lock()
+	defer player.deckSaveMu.Unlock()
+	raw, _ := json.Marshal(player.deckSnapshot())
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	if err := s.RedisClient.Set(ctx, deckKeyPrefix+player.Name, raw, 0).Err(); err != nil {
+		slog.Error("Erro ao salvar o deck do jogador", "player", player.Name, "error", err)
+	}
+}
+
+// loadDeck lê o deck salvo do jogador (vazio se não houver).
+func (s *Server) loadDeck(playerName string) []Card {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	raw, err := s.RedisClient.Get(ctx, deckKeyPrefix+playerName).Result()
+	if err != nil {
+		if err != redis.Nil {
+			slog.Error("Erro ao ler o deck do jogador", "player", playerName, "error", err)
+		}
+		return []Card{}
+	}
+	var deck []Card
+	if err := json.Unmarshal([]byte(raw), &deck); err != nil {
+		slog.Error("Deck corrompido no Redis", "player", playerName, "error", err)
+		return []Card{}
+	}
+	return deck
+}
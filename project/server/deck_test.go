@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// Rode com "go test -race": abrir pacotes, receber trocas e ver o deck ao mesmo tempo não pode
+// disputar player.Deck nem perder cartas.
+func TestDeckConcurrentPacksAndTrades(t *testing.T) {
+	const packs, trades = 10, 10
+	t.Setenv("PACK_SIZE", "3")
+	t.Setenv("MAX_PACKS_PER_PLAYER", fmt.Sprint(packs))
+	s, mr := newTestServer(t)
+	seedStock(t, s, Card{Name: "Ghoul", Forca: 1}, packs*3)
+	player := newTestPlayer("Alice")
+	for i := 0; i < trades; i++ {
+		// A troca já foi fechada na fila: falta creditar o lado de quem recebe a notificação
+		mr.HSet(tradePendingPrefix+fmt.Sprintf("trade-%d", i), "ticket_a", "{}")
+	}
+
+	received, _ := json.Marshal([]Card{{Name: "Grifo", Forca: 3}})
+	var wg sync.WaitGroup
+	for i := 0; i < trades; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			s.openCardPack(player, false)
+		}()
+		go func(i int) {
+			defer wg.Done()
+			s.routePlayerMessage(player, fmt.Sprintf("TRADE_COMPLETE|trade-%d|%s", i, received))
+		}(i)
+		go func() {
+			defer wg.Done()
+			s.viewDeck(player)
+		}()
+	}
+	wg.Wait()
+
+	deck := player.deckSnapshot()
+	if want := packs*3 + trades; len(deck) != want {
+		t.Fatalf("deck com %d cartas, esperado %d (cartas perdidas em escritas concorrentes)", len(deck), want)
+	}
+	grifos := 0
+	for _, card := range deck {
+		if card.Name == "Grifo" {
+			grifos++
+		}
+	}
+	if grifos != trades {
+		t.Errorf("cartas recebidas em trocas = %d, esperado %d", grifos, trades)
+	}
+}
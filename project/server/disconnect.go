@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	// disconnectEventPrefix é publicado em game:channel:<id> quando um jogador cai no meio da partida.
+	disconnectEventPrefix = "DISCONNECT|"
+	// forfeitEventPrefix é publicado em game:channel:<id> quando um jogador desiste (comando FORFEIT).
+	forfeitEventPrefix = "FORFEIT|"
+	// pendingResultGrace é a folga, além do tempo de jogada, que o listener Pub/Sub de um jogador
+	// desconectado aguarda pelo "RESULT|" da partida em andamento (para registrar o ranking).
+	pendingResultGrace = 5 * time.Second
+)
+
+// isDisconnected indica se a conexão WebSocket do jogador já foi encerrada.
+// Jogadores "fantasmas" (remotos) nunca são considerados desconectados.
+func (p *PlayerState) isDisconnected() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// handleInGameDisconnect avisa o "cérebro" de cada partida em andamento que o jogador caiu,
+// para que ela seja resolvida imediatamente (vitória do oponente) em vez de aguardar o timeout.
+// Se o jogador já tiver jogado, o listener mantém a jogada e segue esperando o oponente.
+func (s *Server) handleInGameDisconnect(player *PlayerState) {
+	player.mu.Lock()
+	games := make(map[string]*GameSession, len(player.Games))
+	for gameID, game := range player.Games {
+		games[gameID] = game
+	}
+	player.mu.Unlock()
+
+	for gameID, game := range games {
+		s.publishInGameDisconnect(player, gameID, game)
+	}
+}
+
+// publishInGameDisconnect publica a desconexão do jogador no canal da partida.
+func (s *Server) publishInGameDisconnect(player *PlayerState, gameID string, game *GameSession) {
+	slog.Info("Jogador desconectou no meio da partida.", "event", "player_disconnected_ingame", "game_id", gameID, "player", player.Name)
+
+	gameChannel := fmt.Sprintf("game:channel:%s", gameID)
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	if err := s.RedisClient.Publish(ctx, gameChannel, disconnectEventPrefix+player.Name).Err(); err != nil {
+		slog.Error("Erro ao publicar desconexão na partida", "game_id", gameID, "player", player.Name, "error", err)
+	}
+
+	// Remove a sessão local. O listener do master mantém sua própria referência à sessão.
+	s.GamesMutex.Lock()
+	if s.ActiveGames[gameID] == game {
+		delete(s.ActiveGames, gameID)
+	}
+	s.GamesMutex.Unlock()
+}
+
+// handleForfeit implementa "FORFEIT [gameID]": o jogador desiste e a partida é decidida na hora a
+// favor do oponente, mesmo que ele já tenha jogado (diferente da desconexão, que mantém a jogada).
+// Sem o GameID, vale para a única partida em andamento. Só existe no modo clássico.
+func (s *Server) handleForfeit(player *PlayerState, command string) {
+	gameID := strings.TrimSpace(strings.TrimPrefix(command, "FORFEIT"))
+	player.mu.Lock()
+	if gameID == "" && len(player.Games) == 1 {
+		gameID = player.defaultGameID()
+	}
+	game := player.Games[gameID]
+	games := len(player.Games)
+	player.mu.Unlock()
+
+	if game == nil {
+		if gameID == "" {
+			s.rejectCommand(player, fmt.Sprintf("Você está em %d partidas: use FORFEIT <gameID>.", games))
+		} else {
+			s.rejectCommand(player, fmt.Sprintf("Você não está na partida %q.", gameID))
+		}
+		return
+	}
+	game.mu.Lock()
+	mode := game.Mode
+	game.mu.Unlock()
+	if mode == gameModeFFA {
+		s.rejectCommand(player, "Não é possível desistir de uma partida FFA.")
+		return
+	}
+
+	slog.Info("Jogador desistiu da partida.", "event", "player_forfeit", "game_id", gameID, "player", player.Name)
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	if err := s.RedisClient.Publish(ctx, fmt.Sprintf("game:channel:%s", gameID), forfeitEventPrefix+player.Name).Err(); err != nil {
+		slog.Error("Erro ao publicar desistência na partida", "game_id", gameID, "player", player.Name, "error", err)
+		s.sendWebSocketMessage(player, "Erro interno ao desistir da partida. Tente novamente.")
+	}
+}
+
+// forfeitingPlayerName extrai o nome do jogador de um evento "FORFEIT|<nome>".
+func forfeitingPlayerName(payload string) (string, bool) {
+	return strings.CutPrefix(payload, forfeitEventPrefix)
+}
+
+// disconnectedPlayerName extrai o nome do jogador de um evento "DISCONNECT|<nome>".
+func disconnectedPlayerName(payload string) (string, bool) {
+	if !strings.HasPrefix(payload, disconnectEventPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(payload, disconnectEventPrefix), true
+}
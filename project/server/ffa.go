@@ -0,0 +1,486 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Modo "todos contra todos" (FFA): N jogadores, vence a carta de maior Força.
+// Roda em paralelo ao modo clássico 1v1, com fila, lock e endpoint próprios.
+const (
+	gameModeClassic = ""    // Modo padrão (1v1), usa Player1/Player2 da GameSession
+	gameModeFFA     = "ffa" // Modo FFA, usa Players/Hands/Cards da GameSession
+
+	ffaQueueKey = "matchmaking_queue:ffa"
+	ffaLockKey  = "lock:matchmaker:ffa"
+)
+
+// SCRIPT LUA
+// Retira atomicamente os N primeiros tickets da fila FFA, somente se houver N disponíveis.
+//
+// KEYS[1] = a fila FFA (ffaQueueKey)
+// ARGV[1] = o número de jogadores por partida
+var atomicPopFFATicketsScript = redis.NewScript(`
+    local n = tonumber(ARGV[1])
+    if redis.call('ZCARD', KEYS[1]) < n then
+        return {}
+    end
+    local tickets = redis.call('ZRANGE', KEYS[1], 0, n - 1)
+    redis.call('ZREM', KEYS[1], unpack(tickets))
+    return tickets
+`)
+
+// distributedFFAMatchmaker é a goroutine que tenta formar partidas FFA com N jogadores.
+func (s *Server) distributedFFAMatchmaker() {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if s.shuttingDown() {
+			return
+		}
+		if s.inMaintenance() {
+			continue
+		}
+		// Tenta adquirir o lock distribuído do matchmaker FFA
+		lockValue := newRandomID()
+		ctx, cancel := s.redisCtx()
+		ok, err := s.RedisClient.SetNX(ctx, ffaLockKey, lockValue, s.Config.MatchmakerLockTTL).Result()
+		if err != nil {
+			cancel()
+			slog.Error("Erro ao tentar adquirir lock do matchmaker FFA", "error", err)
+			continue
+		}
+		if !ok {
+			cancel()
+			continue
+		}
+
+		stopRenewal := s.keepLockAlive(ffaLockKey, lockValue, s.Config.MatchmakerLockTTL)
+		s.tryFormFFAMatch(ctx)
+		stopRenewal()
+		cancel()
+
+		// Libera o lock (somente se ainda for nosso). Contexto próprio: o lock é liberado
+		// mesmo que a rodada tenha esgotado o seu.
+		ctx, cancel = s.redisCtx()
+		s.releaseLock(ctx, ffaLockKey, lockValue)
+		cancel()
+	}
+}
+
+// tryFormFFAMatch retira N tickets da fila e, se conseguir, notifica os servidores envolvidos.
+// Se algum dos N primeiros for de um servidor lotado (MAX_CONCURRENT_GAMES), a rodada é pulada
+// e os tickets continuam na fila.
+func (s *Server) tryFormFFAMatch(ctx context.Context) {
+	if s.ffaHeadHasFullServer(ctx) {
+		return
+	}
+	result, err := atomicPopFFATicketsScript.Run(ctx, s.RedisClient, []string{ffaQueueKey}, s.Config.FFAPlayers).StringSlice()
+	if err != nil {
+		slog.Error("Erro ao retirar tickets da fila FFA", "error", err)
+		return
+	}
+	if len(result) < s.Config.FFAPlayers {
+		return
+	}
+
+	var tickets []MatchmakingTicket
+	for _, ticketJSON := range result {
+		var ticket MatchmakingTicket
+		if err := json.Unmarshal([]byte(ticketJSON), &ticket); err != nil {
+			s.deadLetterTicket(ctx, ffaQueueKey, ticketJSON, err) // Já retirado pelo script
+			continue
+		}
+		tickets = append(tickets, ticket)
+	}
+	if len(tickets) < 2 {
+		// Tickets corrompidos demais para formar uma partida; devolve os válidos à fila.
+		for _, t := range tickets {
+			ticketJSON, _ := json.Marshal(t)
+			s.RedisClient.ZAdd(ctx, ffaQueueKey, &redis.Z{Score: t.queueScore(), Member: string(ticketJSON)})
+		}
+		return
+	}
+
+	gameID := newRandomID()
+	req := FFAMatchNotificationRequest{GameID: gameID, Players: tickets}
+	observeMatchWait(gameModeFFA, tickets...)
+	slog.Info("Partida FFA formada", "event", "match_paired", "game_id", gameID, "mode", gameModeFFA, "players", len(tickets))
+
+	// Roda fora do lock: as retentativas com backoff podem levar alguns segundos.
+	go s.notifyFFAMatchStart(req)
+}
+
+// ffaHeadHasFullServer verifica se algum dos N primeiros tickets da fila FFA é de um servidor lotado.
+func (s *Server) ffaHeadHasFullServer(ctx context.Context) bool {
+	members, err := s.RedisClient.ZRange(ctx, ffaQueueKey, 0, int64(s.Config.FFAPlayers-1)).Result()
+	if err != nil || len(members) < s.Config.FFAPlayers {
+		return false // O script de retirada decide com a fila real
+	}
+	tickets := make([]MatchmakingTicket, 0, len(members))
+	for _, member := range members {
+		var ticket MatchmakingTicket
+		if json.Unmarshal([]byte(member), &ticket) == nil {
+			tickets = append(tickets, ticket)
+		}
+	}
+	for serverID, full := range s.fullServers(ctx, tickets) {
+		if full {
+			slog.Debug("Partida FFA adiada: servidor lotado", "event", "server_full_skipped", "mode", gameModeFFA, "server_id", serverID)
+			return true
+		}
+	}
+	return false
+}
+
+// notifyFFAMatchStart notifica cada servidor remoto envolvido (uma vez) e depois inicia os jogadores locais.
+func (s *Server) notifyFFAMatchStart(req FFAMatchNotificationRequest) {
+	notified := map[string]bool{s.ServerID: true}
+	for _, t := range req.Players {
+		if notified[t.ServerID] {
+			continue
+		}
+		if err := s.callRemoteFFAMatchNotification(t.ServerID, req); err != nil {
+			slog.Error("FALHA AO NOTIFICAR servidor da partida FFA. Partida abortada.", "event", "match_aborted",
+				"game_id", req.GameID, "remote_server_id", t.ServerID, "error", err)
+			// Compensação: jogadores cujos servidores ainda não foram notificados (inclusive os locais)
+			// voltam para a fila. Os já notificados terminam a partida por timeout.
+			var requeue []MatchmakingTicket
+			for _, p := range req.Players {
+				if p.ServerID == s.ServerID || (!notified[p.ServerID] && p.ServerID != t.ServerID) {
+					requeue = append(requeue, p)
+				}
+			}
+			s.requeueTickets(ffaQueueKey, requeue...)
+			return
+		}
+		notified[t.ServerID] = true
+	}
+
+	s.startLocalFFAGame(req)
+}
+
+// callRemoteFFAMatchNotification envia a notificação de partida FFA para um servidor remoto via REST.
+func (s *Server) callRemoteFFAMatchNotification(remoteServerID string, req FFAMatchNotificationRequest) error {
+	return s.postMatchNotification(remoteServerID, "/api/v1/match/ffa/notify", req.GameID, req)
+}
+
+// handleFFAMatchNotification implementa o endpoint REST que recebe a notificação de partida FFA.
+func (s *Server) handleFFAMatchNotification(w http.ResponseWriter, r *http.Request) {
+	var req FFAMatchNotificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.GameID == "" {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "Requisição inválida: game_id ausente ou JSON malformado")
+		return
+	}
+
+	// Notificação repetida (retentativa): responde 200 sem criar uma segunda sessão.
+	first, err := s.claimMatchNotification(req.GameID)
+	if err != nil {
+		slog.Error("Erro ao registrar notificação de partida", "game_id", req.GameID, "mode", gameModeFFA, "error", err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Erro interno ao registrar a notificação")
+		return
+	}
+	if !first {
+		slog.Info("Notificação de partida duplicada ignorada", "event", "match_notify_duplicate", "game_id", req.GameID, "mode", gameModeFFA)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]bool{"success": true, "duplicate": true})
+		return
+	}
+
+	s.startLocalFFAGame(req)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// startLocalFFAGame inicia a partida FFA para todos os participantes conectados a ESTE servidor.
+// O servidor do primeiro participante (Players[0]) é o "master": escuta as jogadas e decide o vencedor.
+func (s *Server) startLocalFFAGame(req FFAMatchNotificationRequest) {
+	session := &GameSession{
+		GameID:       req.GameID,
+		Mode:         gameModeFFA,
+		Hands:        make(map[string][]Card),
+		Cards:        make(map[string]*Card),
+		TurnDeadline: s.claimTurnDeadline(req.GameID),
+		StartedAt:    time.Now(),
+		mu:           sync.Mutex{},
+	}
+
+	var localPlayers []*PlayerState
+	s.PlayerMutex.Lock()
+	for _, t := range req.Players {
+		p, ok := s.Players[t.PlayerName]
+		if t.ServerID != s.ServerID || !ok {
+			// Jogador remoto: "fantasma"
+			session.Players = append(session.Players, &PlayerState{Name: t.PlayerName, ServerID: t.ServerID})
+			continue
+		}
+		session.Players = append(session.Players, p)
+		localPlayers = append(localPlayers, p)
+	}
+	s.PlayerMutex.Unlock()
+
+	s.GamesMutex.Lock()
+	s.ActiveGames[session.GameID] = session
+	s.GamesMutex.Unlock()
+
+	for _, p := range localPlayers {
+		// Trava os comandos do jogador durante a transição para "InGame" (ver command_guard.go)
+		p.cmdMu.Lock()
+		hand := selectRandomCards(s.matchPool(p), s.Config.HandSize, s.Config.DistinctHand)
+		if hand == nil {
+			p.cmdMu.Unlock()
+			slog.Warn("Jogador não tem cartas suficientes para jogar.", "game_id", req.GameID, "player", p.Name)
+			s.sendWebSocketMessage(p, fmt.Sprintf("Erro: Você não tem cartas suficientes (mínimo %d).", s.Config.MinDeckSize))
+			continue
+		}
+
+		session.mu.Lock()
+		session.Hands[p.Name] = hand
+		session.mu.Unlock()
+
+		p.mu.Lock()
+		p.addGame(req.GameID, session)
+		p.mu.Unlock()
+		p.cmdMu.Unlock()
+		s.appendReplayEvent(req.GameID, ReplayEvent{Type: replayEventHandDealt, Player: p.Name, Cards: hand})
+
+		slog.Info("Iniciando partida (FFA)", "event", "game_started", "game_id", req.GameID, "mode", gameModeFFA, "player", p.Name)
+		s.sendWebSocketMessage(p, "MATCH_FOUND")
+		s.sendGameStart(p, session, ffaOpponents(req.Players, p.Name), hand)
+	}
+
+	if len(req.Players) > 0 && req.Players[0].ServerID == s.ServerID {
+		gamesStartedTotal.Inc()
+		go s.listenForFFAGameEvents(session)
+	} else {
+		// O cérebro está em outro servidor: vigia para o caso de ele cair
+		for _, p := range localPlayers {
+			p.mu.Lock()
+			inThisGame := p.Games[req.GameID] == session
+			p.mu.Unlock()
+			if inThisGame {
+				go s.watchGameBrain(p, session)
+			}
+		}
+	}
+}
+
+// ffaOpponents lista os nomes dos demais participantes da partida FFA, separados por ", ".
+func ffaOpponents(players []MatchmakingTicket, playerName string) string {
+	var names []string
+	for _, t := range players {
+		if t.PlayerName != playerName {
+			names = append(names, t.PlayerName)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// handleFFAGameMove escreve a jogada FFA no Redis (campo = nome do jogador) e publica um evento.
+// A carta já foi validada contra a mão do jogador em handleGameMove.
+func (s *Server) handleFFAGameMove(player *PlayerState, session *GameSession, chosenCard Card) {
+	session.mu.Lock()
+	gameID := session.GameID
+	session.mu.Unlock()
+
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	gameKey := fmt.Sprintf("game:state:%s", gameID)
+	cardJSON, _ := json.Marshal(chosenCard)
+
+	// HSETNX garante uma única jogada por jogador
+	set, err := s.RedisClient.HSetNX(ctx, gameKey, player.Name, cardJSON).Result()
+	if err != nil {
+		slog.Error("Erro ao registrar jogada FFA no Redis", "game_id", gameID, "player", player.Name, "error", err)
+		s.rejectMove(player, moveRejectedError, "Erro interno ao registrar sua jogada. Tente novamente.")
+		return
+	}
+	if !set {
+		s.rejectMove(player, moveRejectedAlreadyPlayed, "Você já fez sua jogada; a primeira jogada é a que vale.")
+		return
+	}
+	s.ackMove(player, gameID, chosenCard)
+	s.RedisClient.Expire(ctx, gameKey, s.gameStateTTL())
+
+	s.RedisClient.Publish(ctx, fmt.Sprintf("game:channel:%s", gameID), "MOVE_MADE")
+	slog.Info("Jogada registrada no Redis", "event", "move_made", "game_id", gameID, "mode", gameModeFFA, "player", player.Name, "card", chosenCard.Name)
+}
+
+// listenForFFAGameEvents é o "cérebro" da partida FFA. Roda apenas no servidor master.
+func (s *Server) listenForFFAGameEvents(session *GameSession) {
+	gameChannel := fmt.Sprintf("game:channel:%s", session.GameID)
+	gameKey := fmt.Sprintf("game:state:%s", session.GameID)
+	logger := slog.With("game_id", session.GameID, "mode", gameModeFFA)
+
+	// A assinatura dura a partida inteira: só o encerramento do servidor a interrompe
+	pubsub := s.RedisClient.Subscribe(s.ctx, gameChannel)
+	defer pubsub.Close()
+	ch := pubsub.Channel()
+
+	session.mu.Lock()
+	timeout := time.NewTimer(time.Until(session.TurnDeadline))
+	session.mu.Unlock()
+	defer timeout.Stop()
+
+	// Sinaliza aos outros servidores que o cérebro da partida está vivo (ver brain_watchdog.go)
+	stopHeartbeat := s.startBrainHeartbeat(session.GameID)
+	defer stopHeartbeat()
+
+	// Registra este servidor como cérebro, para a reconciliação caso ele reinicie (ver game_reconcile.go)
+	meta := GameMeta{GameID: session.GameID, Mode: gameModeFFA, BrainServerID: s.ServerID}
+	for _, p := range session.Players {
+		meta.Players = append(meta.Players, p.Name)
+	}
+	s.saveGameMeta(meta)
+
+	// Jogadores que desconectaram sem jogar: não há mais jogada a esperar deles.
+	forfeited := make(map[string]bool)
+	// Jogadas já gravadas no replay (o campo do hash é o nome do jogador)
+	recordedMoves := make(map[string]bool)
+
+	for {
+		select {
+		case msg := <-ch:
+			if msg.Payload == gameResolvedEvent {
+				// A partida foi resolvida fora do cérebro (admin): os resultados já foram publicados.
+				logger.Warn("Partida resolvida externamente. Encerrando o listener.", "event", "game_resolved_externally")
+				return
+			}
+			ctx, cancel := s.redisCtx()
+			moves, err := s.RedisClient.HGetAll(ctx, gameKey).Result()
+			cancel()
+			if err != nil {
+				logger.Error("Erro ao ler hash do Redis", "error", err)
+				continue
+			}
+			s.recordNewMoves(session.GameID, moves, nil, recordedMoves)
+			if name, ok := disconnectedPlayerName(msg.Payload); ok {
+				if _, played := moves[name]; played {
+					logger.Info("Jogador desconectou após jogar; jogada mantida.", "player", name)
+				} else {
+					logger.Info("Jogador desconectou sem jogar.", "event", "forfeit", "player", name)
+					forfeited[name] = true
+					s.appendReplayEvent(session.GameID, ReplayEvent{Type: replayEventForfeit, Player: name})
+				}
+			}
+			if len(moves)+len(forfeited) < len(session.Players) {
+				continue
+			}
+			logger.Info("Todas as jogadas recebidas. Determinando vencedor.")
+		case <-timeout.C:
+			logger.Info("Timeout! Verificando jogadas e determinando vencedor.", "event", "turn_timeout")
+			s.appendReplayEvent(session.GameID, ReplayEvent{Type: replayEventTimeout})
+		case <-s.ctx.Done():
+			// Servidor encerrando: a partida é resolvida pela reconciliação no próximo startup
+			logger.Warn("Servidor encerrando. Listener da partida interrompido.")
+			return
+		}
+
+		ctx, cancel := s.redisCtx()
+		moves, _ := s.RedisClient.HGetAll(ctx, gameKey).Result()
+		cancel()
+		s.recordNewMoves(session.GameID, moves, nil, recordedMoves)
+		if _, _, ok := s.determineFFAWinner(session, moves); ok {
+			s.clearGameState(session.GameID)
+		}
+		return
+	}
+}
+
+// determineFFAWinner escolhe a carta de maior Força entre todas as jogadas.
+// Empate na maior Força é desempatado pela Agilidade; só quem empata nas duas recebe EMPATE.
+// Quem não jogou perde.
+// Os resultados são enviados a TODOS os jogadores via Pub/Sub (inclusive os locais),
+// para que cada servidor limpe o estado e registre o ranking do seu próprio jogador.
+// Retorna ok=false se a partida já havia sido decidida por outro caminho.
+func (s *Server) determineFFAWinner(session *GameSession, moves map[string]string) (outcomeLabel string, results map[string]string, ok bool) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if !s.claimGameResolution(session.GameID) {
+		slog.Warn("determineFFAWinner chamado, mas a partida já foi decidida por outro caminho.", "game_id", session.GameID)
+		return "", nil, false
+	}
+
+	bestForca := -1
+	for _, p := range session.Players {
+		var card Card
+		if raw, ok := moves[p.Name]; ok && json.Unmarshal([]byte(raw), &card) == nil {
+			c := card
+			session.Cards[p.Name] = &c
+			if card.Forca > bestForca {
+				bestForca = card.Forca
+			}
+		}
+	}
+
+	// Desempate pela Agilidade entre as cartas de maior Força
+	bestSpeed, tiedOnForca := -1, 0
+	for _, c := range session.Cards {
+		if c.Forca == bestForca {
+			tiedOnForca++
+			if c.Speed > bestSpeed {
+				bestSpeed = c.Speed
+			}
+		}
+	}
+
+	var winners []string
+	for _, p := range session.Players {
+		if c := session.Cards[p.Name]; c != nil && c.Forca == bestForca && c.Speed == bestSpeed {
+			winners = append(winners, p.Name)
+		}
+	}
+
+	outcomeLabel = "decided"
+	if len(winners) == 0 {
+		outcomeLabel = "double_timeout"
+	} else if len(winners) > 1 {
+		outcomeLabel = "draw"
+	}
+	gamesFinishedTotal.WithLabelValues(outcomeLabel).Inc()
+	s.appendReplayEvent(session.GameID, ReplayEvent{Type: replayEventResult, Outcome: outcomeLabel,
+		Detail: fmt.Sprintf("Maior Força: %d. Vencedor(es): %s.", bestForca, strings.Join(winners, ", "))})
+	slog.Info("Partida FFA finalizada.", "event", "game_finished", "game_id", session.GameID, "mode", gameModeFFA,
+		"outcome", outcomeLabel, "winners", winners, "best_forca", bestForca)
+
+	results = make(map[string]string, len(session.Players))
+	for _, p := range session.Players {
+		card := session.Cards[p.Name]
+		var result string
+		switch {
+		case card == nil:
+			result = resultMessage("DERROTA", resultReasonTimeout, localized(msgResultTimeout))
+		case card.Forca == bestForca && card.Speed == bestSpeed && len(winners) == 1 && tiedOnForca > 1:
+			result = resultMessage("VITÓRIA", "", localized(msgResultFFASpeedWin, card.Name, card.Forca, card.Speed, tiedOnForca))
+		case card.Forca == bestForca && card.Speed == bestSpeed && len(winners) == 1:
+			result = resultMessage("VITÓRIA", "", localized(msgResultFFAStrongest, card.Name, card.Forca, len(session.Players)))
+		case card.Forca == bestForca && card.Speed == bestSpeed:
+			result = resultMessage("EMPATE", "", localized(msgResultFFADraw, card.Name, card.Forca, len(winners)-1))
+		case card.Forca == bestForca:
+			result = resultMessage("DERROTA", "", localized(msgResultFFASpeedLoss, card.Name, card.Forca, card.Speed, bestSpeed, strings.Join(winners, ", ")))
+		default:
+			result = resultMessage("DERROTA", "", localized(msgResultFFALoss, card.Name, card.Forca, bestForca, strings.Join(winners, ", ")))
+		}
+
+		results[p.Name] = result
+		ctx, cancel := s.redisCtx()
+		err := s.publishToPlayer(ctx, p.Name, gameResultMessage(session.GameID, result))
+		cancel()
+		if err != nil {
+			slog.Error("Erro ao publicar resultado via Redis", "game_id", session.GameID, "player", p.Name, "error", err)
+		}
+	}
+	s.saveGameRecord(s.ffaGameRecord(session, outcomeLabel, results))
+	return outcomeLabel, results, true
+}
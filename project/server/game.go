@@ -1,130 +1,313 @@
 package main
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
 	"strconv"
+	"strings"
 	"time"
+)
+
+// Motivos de recusa de uma jogada, enviados em "MOVE_REJECTED|<motivo>|<mensagem>".
+// Só INVALID_CARD permite ao jogador tentar de novo; nos demais a jogada não vai mais contar.
+const (
+	moveRejectedNoHand        = "NO_HAND"        // O jogador não tem mão nesta partida
+	moveRejectedInvalidCard   = "INVALID_CARD"   // Comando não é o número de uma carta da mão
+	moveRejectedAlreadyPlayed = "ALREADY_PLAYED" // O jogador já jogou; a primeira jogada é a que vale
+	moveRejectedTurnOver      = "TURN_OVER"      // O prazo da jogada já terminou
+	moveRejectedError         = "ERROR"          // Falha interna ao registrar a jogada
+)
 
-	"github.com/gorilla/websocket"
+// Motivos de um resultado que não foi decidido pelas cartas, enviados em "reason" no GAME_END
+// (vazio quando as duas cartas foram comparadas). Explicam o desfecho ao jogador e permitem
+// distinguir, no histórico (audit), quem abandona partidas de quem só perdeu o prazo.
+const (
+	resultReasonOpponentDisconnected = "opponent_disconnected" // O oponente desconectou sem jogar
+	resultReasonOpponentTimeout      = "opponent_timeout"      // O oponente não jogou a tempo
+	resultReasonOpponentForfeit      = "opponent_forfeit"      // O oponente desistiu (FORFEIT)
+	resultReasonDisconnected         = "disconnected"          // O próprio jogador desconectou sem jogar
+	resultReasonTimeout              = "timeout"               // O próprio jogador não jogou a tempo
+	resultReasonForfeit              = "forfeit"               // O próprio jogador desistiu
+	resultReasonServerLost           = "server_lost"           // O servidor que coordenava a partida caiu
 )
 
+// ackMove confirma ao jogador que a jogada foi registrada: "MOVE_ACK|<carta>".
+func (s *Server) ackMove(player *PlayerState, gameID string, card Card) {
+	s.sendWebSocketMessage(player, "MOVE_ACK|"+card.Name)
+	s.audit(player.Name, auditMove, gameID, card.Name)
+}
+
+// rejectMove avisa o jogador que a jogada NÃO foi registrada, com o motivo.
+func (s *Server) rejectMove(player *PlayerState, reason, message string) {
+	s.sendWebSocketMessage(player, fmt.Sprintf("MOVE_REJECTED|%s|%s", reason, message))
+}
+
 // handleGameMove escreve a jogada no Redis e publica um evento.
+// Toda jogada recebe uma resposta: "MOVE_ACK|<carta>" ou "MOVE_REJECTED|<motivo>|<mensagem>".
 func (s *Server) handleGameMove(player *PlayerState, session *GameSession, command string) {
-	// 1. Valida o comando e seleciona a carta
-	choice, err := strconv.Atoi(command)
-	if err != nil || (choice != 1 && choice != 2) {
-		s.sendWebSocketMessage(player, "Comando inválido. Jogue '1' ou '2'.")
+	// 1. Identifica a mão do jogador nesta partida
+	session.mu.Lock()
+	hand := session.handFor(player.Name)
+	deadline := session.TurnDeadline
+	session.mu.Unlock()
+	if len(hand) == 0 {
+		s.rejectMove(player, moveRejectedNoHand, "Você não tem uma mão nesta partida.")
 		return
 	}
 
-	// 2. Identifica o jogador e o ID do jogo
-	session.mu.Lock()
-	gameID := session.Player1.Name
-	isP1 := (player.Name == session.Player1.Name)
-	session.mu.Unlock()
+	// 2. Valida o comando contra o tamanho REAL da mão e seleciona a carta
+	choice, errMsg := parseMoveChoice(command, len(hand))
+	if errMsg != "" {
+		s.rejectMove(player, moveRejectedInvalidCard, errMsg)
+		return
+	}
+	// Depois do prazo o cérebro já decide sem esta jogada: confirmá-la seria enganoso
+	if !deadline.IsZero() && time.Now().After(deadline) {
+		s.rejectMove(player, moveRejectedTurnOver, "O tempo de jogada terminou; sua jogada não foi registrada.")
+		return
+	}
+	chosenCard := hand[choice-1]
 
-	gameKey := fmt.Sprintf("game:state:%s", gameID)
-	var field string
-	var chosenCard Card
+	// Partidas FFA têm fluxo próprio (ver ffa.go)
+	if session.Mode == gameModeFFA {
+		s.handleFFAGameMove(player, session, chosenCard)
+		return
+	}
 
-	// 3. Define a carta jogada e o campo do Redis
-	if isP1 {
-		field = "p1_card"
-		chosenCard = session.Player1Hand[choice-1]
-	} else {
-		field = "p2_card"
-		chosenCard = session.Player2Hand[choice-1]
+	// 3. Identifica o jogador, o ID do jogo e o campo do Redis
+	session.mu.Lock()
+	gameID := session.GameID
+	field := cardField("p2_card", session.SuddenDeathRound)
+	if player.Name == session.Player1.Name {
+		field = cardField("p1_card", session.SuddenDeathRound)
 	}
+	logger := slog.With("game_id", gameID, "player", player.Name)
+	session.mu.Unlock()
 
-	ctx := context.Background()
+	gameKey := fmt.Sprintf("game:state:%s", gameID)
+	ctx, cancel := s.redisCtx()
+	defer cancel()
 
-	// 4. Verifica se a jogada já foi feita (no Redis)
-	exists, err := s.RedisClient.HExists(ctx, gameKey, field).Result()
+	// 4. Salva a jogada no Redis. HSETNX garante uma única jogada por jogador:
+	// uma segunda jogada (mesmo válida) é recusada em vez de sobrescrever a primeira.
+	cardJSON, err := json.Marshal(chosenCard)
 	if err != nil {
-		log.Printf("Erro ao verificar HExists no Redis: %v", err)
+		logger.Error("Erro ao serializar carta", "error", err)
+		s.rejectMove(player, moveRejectedError, "Erro interno ao registrar sua jogada. Tente novamente.")
 		return
 	}
-	if exists {
-		s.sendWebSocketMessage(player, "Você já fez sua jogada.")
+	set, err := s.RedisClient.HSetNX(ctx, gameKey, field, cardJSON).Result()
+	if err != nil {
+		logger.Error("Erro ao registrar jogada no Redis", "error", err)
+		s.rejectMove(player, moveRejectedError, "Erro interno ao registrar sua jogada. Tente novamente.")
 		return
 	}
-
-	// 5. Salva a jogada no Redis
-	cardJSON, err := json.Marshal(chosenCard)
-	if err != nil {
-		log.Printf("Erro ao serializar carta: %v", err)
+	if !set {
+		s.rejectMove(player, moveRejectedAlreadyPlayed, "Você já fez sua jogada; a primeira jogada é a que vale.")
 		return
 	}
-	s.RedisClient.HSet(ctx, gameKey, field, cardJSON)
+	s.ackMove(player, gameID, chosenCard)
+	// TTL de segurança: se o cérebro cair, o estado da partida não fica para sempre no Redis
+	s.RedisClient.Expire(ctx, gameKey, s.gameStateTTL())
 
-	// 6. Notifica o "cérebro" (o listener do P1-Server) que uma jogada foi feita
+	// 5. Notifica o "cérebro" (o listener do P1-Server) que uma jogada foi feita
 	gameChannel := fmt.Sprintf("game:channel:%s", gameID)
 	s.RedisClient.Publish(ctx, gameChannel, "MOVE_MADE")
 
-	log.Printf("Jogador %s jogou %s. (Escrito no Redis)", player.Name, chosenCard.Name)
+	logger.Info("Jogada registrada no Redis", "event", "move_made", "card", chosenCard.Name)
+}
+
+// parseMoveChoice valida a jogada enviada pelo cliente contra o tamanho da mão.
+// Retorna o número da carta (de 1 a handSize) ou uma mensagem de erro específica.
+func parseMoveChoice(command string, handSize int) (int, string) {
+	choice, err := strconv.Atoi(strings.TrimSpace(command))
+	if err != nil {
+		return 0, fmt.Sprintf("Jogada inválida: envie o número da carta (de 1 a %d).", handSize)
+	}
+	if choice < 1 || choice > handSize {
+		return 0, fmt.Sprintf("A carta %d não existe na sua mão. Escolha de 1 a %d.", choice, handSize)
+	}
+	return choice, ""
 }
 
 // listenForGameEvents é o "cérebro" da partida. Roda apenas no P1-Server.
-// Escuta eventos de jogada (via Pub/Sub) e o timeout.
+// Escuta eventos de jogada (via Pub/Sub) e o timeout. Com SUDDEN_DEATH_ROUNDS, um empate inicia
+// uma rodada de morte súbita (ver sudden_death.go) e o cérebro continua escutando, com novo prazo.
 func (s *Server) listenForGameEvents(session *GameSession, gameID string) {
-	ctx := context.Background()
 	gameChannel := fmt.Sprintf("game:channel:%s", gameID)
 	gameKey := fmt.Sprintf("game:state:%s", gameID)
 
-	// 1. Subscribe to move notifications
-	pubsub := s.RedisClient.Subscribe(ctx, gameChannel)
+	// 1. Subscribe to move notifications (a assinatura dura a partida; só o encerramento do servidor a interrompe)
+	pubsub := s.RedisClient.Subscribe(s.ctx, gameChannel)
 	defer pubsub.Close()
 
 	ch := pubsub.Channel()
 
-	// 2. Create the game turn timeout
-	timeout := time.NewTimer(gameTurnTimeout)
+	// 2. Create the game turn timeout (até o prazo compartilhado da partida)
+	session.mu.Lock()
+	logger := slog.With("game_id", gameID)
+	timeout := time.NewTimer(time.Until(session.TurnDeadline))
+	// Campos do hash -> jogadores, para gravar no replay as jogadas local e remota
+	replayPlayers := map[string]string{"p1_card": session.Player1.Name, "p2_card": session.Player2.Name}
+	// Registra este servidor como cérebro, para a reconciliação caso ele reinicie (ver game_reconcile.go)
+	s.saveGameMeta(GameMeta{GameID: gameID, Mode: gameModeClassic, BrainServerID: s.ServerID,
+		Players: []string{session.Player1.Name, session.Player2.Name}})
+	session.mu.Unlock()
+	recordedMoves := make(map[string]bool)
 	defer timeout.Stop()
+	// Campos das jogadas da rodada atual (mudam a cada rodada de morte súbita, ver cardField)
+	p1Field, p2Field := "p1_card", "p2_card"
+
+	// resetTimeout reprograma o timeout para o novo prazo da rodada
+	resetTimeout := func(deadline time.Time) {
+		if !timeout.Stop() {
+			select {
+			case <-timeout.C:
+			default:
+			}
+		}
+		timeout.Reset(time.Until(deadline))
+	}
+
+	// decide encerra a partida com as jogadas da rodada ou, num empate com rodadas de morte súbita
+	// ainda disponíveis, inicia a próxima rodada. Retorna true se a partida terminou.
+	decide := func(p1CardJSON, p2CardJSON string) bool {
+		s.fillSessionFromRedis(session, p1CardJSON, p2CardJSON)
+		session.mu.Lock()
+		suddenDeath := session.tiedByCards() && session.SuddenDeathRound < s.Config.SuddenDeathRounds
+		session.mu.Unlock()
+		if !suddenDeath {
+			if s.determineWinner(session) {
+				s.clearGameState(gameID) // Limpa o estado do jogo
+			}
+			return true
+		}
+		round, deadline := s.startSuddenDeath(session)
+		p1Field, p2Field = cardField("p1_card", round), cardField("p2_card", round)
+		replayPlayers[p1Field], replayPlayers[p2Field] = replayPlayers["p1_card"], replayPlayers["p2_card"]
+		resetTimeout(deadline)
+		return false
+	}
+
+	logger.Info("Listener (P1-Server) aguardando jogadas ou timeout.")
+
+	// Sinaliza aos outros servidores que o cérebro da partida está vivo (ver brain_watchdog.go)
+	session.mu.Lock()
+	stopHeartbeat := s.startBrainHeartbeat(gameID)
+	session.mu.Unlock()
+	defer stopHeartbeat()
 
-	log.Printf("[Game %s]: Listener (P1-Server) aguardando jogadas ou timeout.", gameID)
+	// Se o P2 for um bot do servidor, ele joga dentro do mesmo tempo de turno.
+	if session.Player2 != nil && session.Player2.isBot {
+		go s.playBotMove(session, gameID)
+	}
 
 	for {
 		select {
 		case msg := <-ch:
-			// 3. Uma jogada foi feita (via handleGameMove)
-			log.Printf("[Game %s]: Notificação recebida: %s", gameID, msg.Payload)
+			// 3. Uma jogada foi feita (via handleGameMove) ou um jogador desconectou
+			logger.Debug("Notificação recebida", "payload", msg.Payload)
+
+			if msg.Payload == gameResolvedEvent {
+				// A partida foi resolvida fora do cérebro (admin): os resultados já foram publicados.
+				logger.Warn("Partida resolvida externamente. Encerrando o listener.", "event", "game_resolved_externally")
+				return
+			}
+
+			if name, ok := turnExtensionRequester(msg.Payload); ok {
+				// Pedido de mais tempo (ver turn_extension.go): o prazo vale para os dois jogadores
+				if deadline, ok := s.extendTurn(session, name); ok {
+					resetTimeout(deadline)
+				}
+				continue
+			}
 
 			// Verifica no Redis se AMBAS as jogadas estão lá
+			ctx, cancel := s.redisCtx()
 			moves, err := s.RedisClient.HGetAll(ctx, gameKey).Result()
+			cancel()
 			if err != nil {
-				log.Printf("[Game %s]: Erro ao ler hash do Redis %s: %v", gameKey, err)
+				logger.Error("Erro ao ler hash do Redis", "error", err)
 				continue
 			}
+			s.recordNewMoves(gameID, moves, replayPlayers, recordedMoves)
+
+			if name, ok := forfeitingPlayerName(msg.Payload); ok {
+				// Desistência explícita: vale mesmo que o jogador já tenha jogado
+				logger.Info("Jogador desistiu. Oponente vence por W.O.", "event", "surrender", "player", name)
+				s.appendReplayEvent(gameID, ReplayEvent{Type: replayEventForfeit, Player: name})
+				session.mu.Lock()
+				session.ForfeitedBy = name
+				session.ForfeitReason = resultReasonForfeit
+				session.mu.Unlock()
+				s.fillSessionFromRedis(session, moves[p1Field], moves[p2Field])
+				if s.determineWinner(session) {
+					s.clearGameState(gameID)
+				}
+				return
+			}
+
+			if name, ok := disconnectedPlayerName(msg.Payload); ok {
+				session.mu.Lock()
+				field := p2Field
+				if name == session.Player1.Name {
+					field = p1Field
+				}
+				session.mu.Unlock()
 
-			if p1CardJSON, ok1 := moves["p1_card"]; ok1 {
-				if p2CardJSON, ok2 := moves["p2_card"]; ok2 {
+				if _, played := moves[field]; played {
+					// A jogada feita antes de cair continua valendo: segue aguardando o oponente.
+					logger.Info("Jogador desconectou após jogar; jogada mantida.", "player", name)
+					continue
+				}
+
+				logger.Info("Jogador desconectou sem jogar. Oponente vence por W.O.", "event", "forfeit", "player", name)
+				s.appendReplayEvent(gameID, ReplayEvent{Type: replayEventForfeit, Player: name})
+				session.mu.Lock()
+				session.ForfeitedBy = name
+				session.ForfeitReason = resultReasonDisconnected
+				session.mu.Unlock()
+				s.fillSessionFromRedis(session, moves[p1Field], moves[p2Field])
+				if s.determineWinner(session) {
+					s.clearGameState(gameID) // Limpa o estado do jogo
+				}
+				return // Encerra a goroutine
+			}
+
+			if p1CardJSON, ok1 := moves[p1Field]; ok1 {
+				if p2CardJSON, ok2 := moves[p2Field]; ok2 {
 					// AMBOS JOGARAM
-					log.Printf("[Game %s]: Ambas as jogadas recebidas. Determinando vencedor.", gameID)
-					s.fillSessionFromRedis(session, p1CardJSON, p2CardJSON)
-					s.determineWinner(session)
-					s.RedisClient.Del(ctx, gameKey) // Limpa o estado do jogo
-					return                          // Encerra a goroutine
+					logger.Info("Ambas as jogadas recebidas. Determinando vencedor.")
+					if decide(p1CardJSON, p2CardJSON) {
+						return // Encerra a goroutine
+					}
 				}
 			}
 			// Se só um jogou, continua esperando
 
 		case <-timeout.C:
 			// 4. TEMPO ESGOTADO
-			log.Printf("[Game %s]: Timeout! Verificando jogadas e determinando vencedor.", gameID)
+			logger.Info("Timeout! Verificando jogadas e determinando vencedor.", "event", "turn_timeout")
 
 			// Pega o que tiver no Redis
+			ctx, cancel := s.redisCtx()
 			moves, _ := s.RedisClient.HGetAll(ctx, gameKey).Result()
-			p1CardJSON, _ := moves["p1_card"]
-			p2CardJSON, _ := moves["p2_card"]
+			cancel()
+			p1CardJSON, _ := moves[p1Field]
+			p2CardJSON, _ := moves[p2Field]
+			s.recordNewMoves(gameID, moves, replayPlayers, recordedMoves)
+			s.appendReplayEvent(gameID, ReplayEvent{Type: replayEventTimeout})
+
+			if decide(p1CardJSON, p2CardJSON) {
+				return // Encerra a goroutine
+			}
 
-			s.fillSessionFromRedis(session, p1CardJSON, p2CardJSON)
-			s.determineWinner(session)
-			s.RedisClient.Del(ctx, gameKey) // Limpa o estado do jogo
-			return                          // Encerra a goroutine
+		case <-s.ctx.Done():
+			// Servidor encerrando: a partida é resolvida pela reconciliação no próximo startup
+			logger.Warn("Servidor encerrando. Listener da partida interrompido.")
+			return
 		}
 	}
 }
@@ -149,89 +332,280 @@ func (s *Server) fillSessionFromRedis(session *GameSession, p1CardJSON, p2CardJS
 	}
 }
 
-// determineWinner agora é chamado APENAS pelo P1-Server.
-// Ela envia o resultado do P1 localmente e do P2 via Redis Pub/Sub.
-func (s *Server) determineWinner(session *GameSession) {
-	session.mu.Lock()
-	defer session.mu.Unlock()
-
-	// Prevenção contra chamada dupla
-	if session.Player1.State != "InGame" {
-		log.Printf("[Game %s]: determineWinner chamado, mas P1 não está InGame (provavelmente já terminou).", session.Player1.Name)
-		return
-	}
-
+// classicResults calcula as mensagens "RESULT|" de cada jogador de uma partida clássica.
+// outcomeLabel é o rótulo da métrica cardgame_games_finished_total. Deve ser chamada com session.mu travado.
+func classicResults(session *GameSession) (resultP1, resultP2, logMessage, outcomeLabel string) {
 	p1Card := session.Player1Card
 	p2Card := session.Player2Card
-	var resultP1, resultP2, logMessage string
 
-	// Lógica de comparação de cartas
-	if p1Card != nil && p2Card != nil {
-		if p1Card.Forca > p2Card.Forca {
-			resultP1 = fmt.Sprintf("RESULT|VITÓRIA|Sua carta %s (%d) venceu %s (%d) de %s.\n", p1Card.Name, p1Card.Forca, p2Card.Name, p2Card.Forca, session.Player2.Name)
-			resultP2 = fmt.Sprintf("RESULT|DERROTA|Sua carta %s (%d) perdeu para %s (%d) de %s.\n", p2Card.Name, p2Card.Forca, p1Card.Name, p1Card.Forca, session.Player1.Name)
+	// Lógica de comparação de cartas (com as habilidades especiais aplicadas antes)
+	if session.ForfeitedBy != "" {
+		// Um jogador desconectou antes de jogar, ou desistiu (FORFEIT): o oponente vence por W.O.
+		loserText, winnerText, how := msgResultDisconnected, msgResultOpponentDisconnect, "desconexão"
+		loserReason, winnerReason := resultReasonDisconnected, resultReasonOpponentDisconnected
+		outcomeLabel = "forfeit"
+		if session.ForfeitReason == resultReasonForfeit {
+			loserText, winnerText, how = msgResultForfeit, msgResultOpponentForfeit, "desistência"
+			loserReason, winnerReason = resultReasonForfeit, resultReasonOpponentForfeit
+			outcomeLabel = "surrender"
+		}
+		if session.ForfeitedBy == session.Player1.Name {
+			resultP1 = resultMessage("DERROTA", loserReason, localized(loserText))
+			resultP2 = resultMessage("VITÓRIA", winnerReason, localized(winnerText, session.Player1.Name))
+			logMessage = fmt.Sprintf("Resultado: %s venceu %s por %s.", session.Player2.Name, session.Player1.Name, how)
+		} else {
+			resultP2 = resultMessage("DERROTA", loserReason, localized(loserText))
+			resultP1 = resultMessage("VITÓRIA", winnerReason, localized(winnerText, session.Player2.Name))
+			logMessage = fmt.Sprintf("Resultado: %s venceu %s por %s.", session.Player1.Name, session.Player2.Name, how)
+		}
+	} else if p1Card != nil && p2Card != nil {
+		duel := resolveCardDuel(*p1Card, *p2Card)
+		effects := duel.effectsSuffix()
+		round := session.suddenDeathText() // Vazio fora da morte súbita
+		// duelText monta o texto completo: a rodada de morte súbita, o duelo e os efeitos das habilidades
+		duelText := func(text LocalizedText) []LocalizedText {
+			return append(append(append([]LocalizedText{}, round...), text), effects...)
+		}
+		if duel.Winner == 1 {
+			resultP1 = resultMessage("VITÓRIA", "", duelText(localized(msgResultCardWon, p1Card.Name, duel.P1Forca, p2Card.Name, duel.P2Forca, session.Player2.Name))...)
+			resultP2 = resultMessage("DERROTA", "", duelText(localized(msgResultCardLost, p2Card.Name, duel.P2Forca, p1Card.Name, duel.P1Forca, session.Player1.Name))...)
 			logMessage = fmt.Sprintf("Resultado: %s venceu %s.", session.Player1.Name, session.Player2.Name)
-		} else if p2Card.Forca > p1Card.Forca {
-			resultP2 = fmt.Sprintf("RESULT|VITÓRIA|Sua carta %s (%d) venceu %s (%d) de %s.\n", p2Card.Name, p2Card.Forca, p1Card.Name, p1Card.Forca, session.Player1.Name)
-			resultP1 = fmt.Sprintf("RESULT|DERROTA|Sua carta %s (%d) perdeu para %s (%d) de %s.\n", p1Card.Name, p1Card.Forca, p2Card.Name, p2Card.Forca, session.Player2.Name)
+			outcomeLabel = "decided"
+		} else if duel.Winner == 2 {
+			resultP2 = resultMessage("VITÓRIA", "", duelText(localized(msgResultCardWon, p2Card.Name, duel.P2Forca, p1Card.Name, duel.P1Forca, session.Player1.Name))...)
+			resultP1 = resultMessage("DERROTA", "", duelText(localized(msgResultCardLost, p1Card.Name, duel.P1Forca, p2Card.Name, duel.P2Forca, session.Player2.Name))...)
 			logMessage = fmt.Sprintf("Resultado: %s venceu %s.", session.Player2.Name, session.Player1.Name)
+			outcomeLabel = "decided"
 		} else {
-			result := fmt.Sprintf("RESULT|EMPATE|Empate! Ambas as cartas têm força %d.\n", p1Card.Forca)
+			result := resultMessage("EMPATE", "", duelText(localized(msgResultCardDraw, duel.P1Forca, p1Card.Speed))...)
 			resultP1, resultP2 = result, result
 			logMessage = fmt.Sprintf("Resultado: Empate entre %s e %s.", session.Player1.Name, session.Player2.Name)
+			outcomeLabel = "draw"
 		}
 	} else if p1Card == nil && p2Card != nil {
-		resultP1 = "RESULT|DERROTA|Você não jogou a tempo e perdeu.\n"
-		resultP2 = fmt.Sprintf("RESULT|VITÓRIA|%s não jogou a tempo. Você venceu!\n", session.Player1.Name)
+		resultP1 = resultMessage("DERROTA", resultReasonTimeout, localized(msgResultTimeout))
+		resultP2 = resultMessage("VITÓRIA", resultReasonOpponentTimeout, localized(msgResultOpponentTimeout, session.Player1.Name))
 		logMessage = fmt.Sprintf("Resultado: %s venceu %s por timeout.", session.Player2.Name, session.Player1.Name)
+		outcomeLabel = "timeout"
 	} else if p2Card == nil && p1Card != nil {
-		resultP2 = "RESULT|DERROTA|Você não jogou a tempo e perdeu.\n"
-		resultP1 = fmt.Sprintf("RESULT|VITÓRIA|%s não jogou a tempo. Você venceu!\n", session.Player2.Name)
+		resultP2 = resultMessage("DERROTA", resultReasonTimeout, localized(msgResultTimeout))
+		resultP1 = resultMessage("VITÓRIA", resultReasonOpponentTimeout, localized(msgResultOpponentTimeout, session.Player2.Name))
 		logMessage = fmt.Sprintf("Resultado: %s venceu %s por timeout.", session.Player1.Name, session.Player2.Name)
+		outcomeLabel = "timeout"
 	} else {
-		result := "RESULT|EMPATE|Nenhum jogador jogou a tempo. Empate.\n"
+		result := resultMessage("EMPATE", resultReasonTimeout, localized(msgResultDoubleTimeout))
 		resultP1, resultP2 = result, result
 		logMessage = fmt.Sprintf("Resultado: Empate por timeout duplo entre %s e %s.", session.Player1.Name, session.Player2.Name)
+		outcomeLabel = "double_timeout"
 	}
+	return resultP1, resultP2, logMessage, outcomeLabel
+}
 
-	log.Printf("Partida entre %s e %s finalizada. %s", session.Player1.Name, session.Player2.Name, logMessage)
+// determineWinner agora é chamado APENAS pelo P1-Server.
+// Ela envia o resultado do P1 localmente e do P2 via Redis Pub/Sub.
+// Retorna false se a partida já havia sido decidida (por exemplo, pela resolução forçada do admin).
+func (s *Server) determineWinner(session *GameSession) bool {
+	// Uma jogada atrasada do P1 não pode ser processada junto com o fim da partida (ver command_guard.go)
+	player1 := session.lockPlayer1Commands()
+	defer player1.cmdMu.Unlock()
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	// Prevenção contra chamada dupla
+	session.Player1.mu.Lock()
+	p1InGame := session.Player1.Games[session.GameID] == session
+	session.Player1.mu.Unlock()
+	if !p1InGame {
+		slog.Warn("determineWinner chamado, mas P1 não está nesta partida (provavelmente já terminou).",
+			"game_id", session.GameID, "player1", session.Player1.Name)
+		return false
+	}
+	if !s.claimGameResolution(session.GameID) {
+		slog.Warn("determineWinner chamado, mas a partida já foi decidida por outro caminho.",
+			"game_id", session.GameID, "player1", session.Player1.Name)
+		return false
+	}
+
+	resultP1, resultP2, logMessage, outcomeLabel := classicResults(session)
+	gamesFinishedTotal.WithLabelValues(outcomeLabel).Inc()
+	s.appendReplayEvent(session.GameID, ReplayEvent{Type: replayEventResult, Outcome: outcomeLabel, Detail: logMessage})
+	s.saveGameRecord(s.classicGameRecord(session, outcomeLabel, resultP1, resultP2))
 
-	// Envia para P1 (jogador local) via WebSocket
-	if session.Player1 != nil && session.Player1.WsConn != nil {
-		if resultP1 != "" {
-			if err := session.Player1.WsConn.WriteMessage(websocket.TextMessage, []byte(resultP1)); err != nil {
-				log.Printf("Erro ao enviar resultado para %s: %v", session.Player1.Name, err)
+	logger := slog.With("game_id", session.GameID)
+	logger.Info("Partida finalizada. "+logMessage, "event", "game_finished", "outcome", outcomeLabel,
+		"player1", session.Player1.Name, "player2", session.Player2.Name)
+
+	// Envia para P1 (jogador local) via WebSocket: a carta do oponente e, em seguida, o resultado.
+	// A fila de saída do jogador (outbox) preserva a ordem.
+	if session.Player1 != nil && resultP1 != "" {
+		s.sendWebSocketMessage(session.Player1, revealMessage(session.GameID, session.Player2.Name, session.Player2Card))
+		s.sendWebSocketMessage(session.Player1, gameEndMessage(session.GameID, resultP1, session.Player1.currentLocale()))
+	}
+
+	// Envia para P2 (jogador remoto) via Redis Pub/Sub, na mesma ordem. Cada PUBLISH só começa
+	// depois do anterior ter sido entregue, então o P2-Server recebe o REVEAL antes do RESULT
+	// e o encaminha ao jogador como qualquer outra mensagem. Se o P2 já tiver saído, as duas
+	// mensagens esperam a próxima conexão dele (ver player_inbox.go).
+	if session.Player2 != nil && !session.Player2.isBot && resultP2 != "" {
+		for _, message := range []string{revealMessage(session.GameID, session.Player1.Name, session.Player1Card), gameResultMessage(session.GameID, resultP2)} {
+			ctx, cancel := s.redisCtx()
+			err := s.publishToPlayer(ctx, session.Player2.Name, message)
+			cancel()
+			if err != nil {
+				logger.Error("Erro ao publicar resultado via Redis", "player", session.Player2.Name, "error", err)
 			}
 		}
 	}
 
-	// Envia para P2 (jogador remoto) via Redis Pub/Sub
-	if session.Player2 != nil && resultP2 != "" {
-		p2Channel := fmt.Sprintf("player:%s", session.Player2.Name)
-		if err := s.RedisClient.Publish(context.Background(), p2Channel, resultP2).Err(); err != nil {
-			log.Printf("Erro ao publicar resultado para %s via Redis: %v", session.Player2.Name, err)
+	// Evita que o matchmaker pareie os dois de novo logo em seguida (não vale para o bot)
+	if !session.Player2.isBot {
+		s.markRecentOpponents(session.Player1.Name, session.Player2.Name)
+		if outcome, ok := outcomeFromResult(resultP1); ok {
+			s.recordHeadToHead(session.Player1.Name, session.Player2.Name, outcome)
 		}
 	}
 
-	// Reseta o estado do P1 (local)
+	// Registra no ranking apenas o resultado do P1 (local).
+	// O resultado do P2 é registrado pelo P2-Server ao receber o "RESULT|" via Pub/Sub.
+	if outcome, ok := outcomeFromResult(resultP1); ok {
+		s.recordGameResult(session.Player1.Name, outcome)
+		s.audit(session.Player1.Name, auditResult, session.GameID, resultAuditDetail(outcome, resultP1))
+		s.recordAbandonment(session.Player1.Name, resultP1)
+	}
+
+	// Reseta o estado do P1 (local): sem outras partidas, ele volta ao "Menu" (ver multi_game.go)
 	if session.Player1 != nil {
 		session.Player1.mu.Lock()
-		session.Player1.State = "Menu"
-		session.Player1.CurrentGame = nil
+		session.Player1.removeGame(session.GameID)
 		session.Player1.mu.Unlock()
 	}
 	// (O estado do P2 será limpo pelo listenRedisPubSub no P2-Server)
 
+	// Oferece revanche ao P1 (a oferta ao P2 é feita pelo P2-Server). Não há revanche contra o bot.
+	if session.Player1 != nil && session.Player1.WsConn != nil && !session.Player1.isDisconnected() && !session.Player2.isBot {
+		s.offerRematch(session.Player1, session)
+	}
+
 	// Remove a sessão do mapa de jogos ativos (APENAS no P1-Server)
 	s.GamesMutex.Lock()
-	if session.Player1 != nil {
-		delete(s.ActiveGames, session.Player1.Name)
-	}
+	delete(s.ActiveGames, session.GameID)
 	s.GamesMutex.Unlock()
+	return true
+}
+
+// handFor retorna a mão do jogador local na partida (nil se ele não tiver uma).
+// Deve ser chamada com g.mu travado.
+func (g *GameSession) handFor(playerName string) []Card {
+	if g.Mode == gameModeFFA {
+		return g.Hands[playerName]
+	}
+	if g.Player1 != nil && g.Player1.Name == playerName {
+		return g.Player1Hand
+	}
+	if g.Player2 != nil && g.Player2.Name == playerName {
+		return g.Player2Hand
+	}
+	return nil
+}
+
+// Ciclo de vida de uma partida, do ponto de vista do cliente:
+//
+//	MATCH_FOUND         oponente(s) definido(s): a busca acabou e a partida vai começar
+//	GAME_START|<json>   adversário, mão e prazo da jogada, numa única mensagem (GameStart)
+//	REVEAL|<json>       carta jogada pelo oponente, logo antes do resultado (GameReveal; só no modo clássico)
+//	GAME_END|<json>     resultado da partida (GameEnd)
+//
+// Entre os servidores, o resultado continua trafegando como "RESULT|<resultado>|<motivo>|<texto>"
+// (Pub/Sub, ranking, replay); ele só é convertido em GAME_END na entrega ao jogador (gameEndMessage).
+
+// GameStart é o payload de "GAME_START|<json>". No modo FFA, Opponent traz os nomes de todos
+// os oponentes separados por ", ".
+type GameStart struct {
+	GameID      string `json:"game_id"`
+	Mode        string `json:"mode"`
+	Opponent    string `json:"opponent"`
+	Hand        []Card `json:"hand"`
+	TurnSeconds int    `json:"turn_seconds"` // Tempo restante para jogar (o mesmo do "TIMER|")
 }
 
-// selectRandomCards (Função inalterada)
-func selectRandomCards(deck []Card, count int) []Card {
+// GameReveal é o payload de "REVEAL|<json>": a carta que o oponente jogou, enviada antes do
+// GAME_END para que o cliente possa animar a virada. Card é null se o oponente não jogou
+// (timeout ou desconexão).
+type GameReveal struct {
+	GameID   string `json:"game_id"`
+	Opponent string `json:"opponent"`
+	Card     *Card  `json:"card"`
+}
+
+// GameEnd é o payload de "GAME_END|<json>".
+type GameEnd struct {
+	GameID  string `json:"game_id,omitempty"`
+	Result  string `json:"result"`           // VITÓRIA, DERROTA ou EMPATE
+	Reason  string `json:"reason,omitempty"` // Por que a partida não foi decidida pelas cartas (resultReason*)
+	Message string `json:"message"`          // O texto, no idioma do jogador (SET_LOCALE)
+
+	MessageParts []LocalizedText `json:"message_parts,omitempty"` // Os trechos do texto, para o cliente traduzir (ver i18n.go)
+}
+
+// sendGameStart envia ao jogador o início da partida em uma única mensagem, depois do MATCH_FOUND.
+func (s *Server) sendGameStart(player *PlayerState, session *GameSession, opponent string, hand []Card) {
+	session.mu.Lock()
+	start := GameStart{
+		GameID:      session.GameID,
+		Mode:        session.Mode,
+		Opponent:    opponent,
+		Hand:        hand,
+		TurnSeconds: remainingSeconds(session.TurnDeadline),
+	}
+	session.mu.Unlock()
+	startJSON, _ := json.Marshal(start)
+	s.sendWebSocketMessage(player, "GAME_START|"+string(startJSON))
+}
+
+// revealMessage monta o "REVEAL|<json>" com a carta jogada pelo oponente (nil = não jogou).
+func revealMessage(gameID, opponent string, card *Card) string {
+	revealJSON, _ := json.Marshal(GameReveal{GameID: gameID, Opponent: opponent, Card: card})
+	return "REVEAL|" + string(revealJSON)
+}
+
+// resultMessage monta o resultado interno de um jogador: "RESULT|<resultado>|<motivo>|<texto>".
+// O motivo (resultReason*) fica vazio quando a partida foi decidida pelas cartas. O texto são os
+// trechos do catálogo em JSON, renderizados no idioma do jogador pelo servidor dele (ver i18n.go).
+func resultMessage(result, reason string, text ...LocalizedText) string {
+	textJSON, _ := json.Marshal(text)
+	return fmt.Sprintf("RESULT|%s|%s|%s\n", result, reason, textJSON)
+}
+
+// parseResultMessage separa o resultado interno em resultado, motivo e texto. Aceita também o
+// formato sem motivo ("RESULT|<resultado>|<texto>"), de servidores anteriores ao campo.
+func parseResultMessage(resultMsg string) (result, reason, text string) {
+	parts := strings.SplitN(strings.TrimSpace(resultMsg), "|", 4)
+	switch len(parts) {
+	case 4:
+		return parts[1], parts[2], parts[3]
+	case 3:
+		return parts[1], "", parts[2]
+	case 2:
+		return parts[1], "", ""
+	}
+	return "", "", ""
+}
+
+// gameEndMessage converte o "RESULT|..." interno no "GAME_END|<json>" enviado ao jogador,
+// com o texto no idioma dele.
+func gameEndMessage(gameID, resultMsg, locale string) string {
+	end := GameEnd{GameID: gameID}
+	var text string
+	end.Result, end.Reason, text = parseResultMessage(resultMsg)
+	end.Message, end.MessageParts = localizedResultText(text, locale)
+	endJSON, _ := json.Marshal(end)
+	return "GAME_END|" + string(endJSON)
+}
+
+// selectRandomCards sorteia count cartas do deck. Com distinct (HAND_DISTINCT), evita cartas
+// de mesmo nome na mão, que apareceriam como opções idênticas no menu: as repetidas só entram
+// quando o deck não tem nomes distintos suficientes.
+func selectRandomCards(deck []Card, count int, distinct bool) []Card {
 	if len(deck) < count {
 		return nil
 	}
@@ -244,5 +618,22 @@ func selectRandomCards(deck []Card, count int) []Card {
 		deckCopy[i], deckCopy[j] = deckCopy[j], deckCopy[i]
 	})
 
-	return deckCopy[:count]
+	if !distinct {
+		return deckCopy[:count]
+	}
+	hand := make([]Card, 0, count)
+	var repeated []Card
+	seen := make(map[string]bool)
+	for _, card := range deckCopy {
+		if seen[card.Name] {
+			repeated = append(repeated, card)
+			continue
+		}
+		seen[card.Name] = true
+		hand = append(hand, card)
+		if len(hand) == count {
+			return hand
+		}
+	}
+	return append(hand, repeated[:count-len(hand)]...)
 }
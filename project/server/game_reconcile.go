@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// gameStatePrefix é o hash de jogadas de cada partida (game:state:<GameID>). O canal de eventos
+	// da partida segue a mesma chave (game:channel:<GameID>).
+	gameStatePrefix = "game:state:"
+	// gameMetaPrefix guarda, por GameID, quem é o cérebro da partida e seus jogadores.
+	// Fica fora do hash porque o hash guarda apenas as jogadas (contadas no FFA).
+	gameMetaPrefix = "game:meta:"
+	// gameResolvedPrefix marca, por GameID, que a partida já foi decidida. Garante um único resultado
+	// quando o cérebro e a resolução forçada (admin) concorrem.
+	gameResolvedPrefix = "game:resolved:"
+	// gameResolvedEvent é publicado no canal da partida quando ela é resolvida fora do cérebro,
+	// para que o listener do cérebro encerre sem decidir de novo.
+	gameResolvedEvent = "RESOLVED"
+	// gameStateGrace é a folga, além do tempo de jogada, antes de o estado de uma partida
+	// abandonada expirar sozinho no Redis.
+	gameStateGrace = 1 * time.Minute
+	// reconcileTimeout limita a varredura das partidas abandonadas no startup.
+	reconcileTimeout = 1 * time.Minute
+)
+
+// GameMeta identifica o cérebro e os participantes de uma partida em andamento.
+// Usado na reconciliação do startup, quando o servidor não tem mais a sessão em memória.
+type GameMeta struct {
+	GameID        string   `json:"game_id"`
+	Mode          string   `json:"mode"`
+	BrainServerID string   `json:"brain_server_id"`
+	Players       []string `json:"players"` // No modo clássico: [P1, P2]
+}
+
+// gameStateTTL é o TTL do hash de jogadas e do registro de metadados de uma partida.
+func (s *Server) gameStateTTL() time.Duration {
+	return s.Config.GameTurnTimeout + gameStateGrace
+}
+
+// saveGameMeta registra ESTE servidor como cérebro da partida. Chamada pelo listener da partida.
+// Os metadados também permitem encontrar a partida pelo GameID na API de administração.
+func (s *Server) saveGameMeta(meta GameMeta) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	metaJSON, _ := json.Marshal(meta)
+	if err := s.RedisClient.Set(ctx, gameMetaPrefix+meta.GameID, metaJSON, s.gameStateTTL()).Err(); err != nil {
+		slog.Error("Erro ao registrar metadados da partida", "game_id", meta.GameID, "error", err)
+	}
+}
+
+// clearGameState apaga o hash de jogadas e os metadados da partida quando ela termina.
+func (s *Server) clearGameState(gameID string) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	s.RedisClient.Del(ctx, gameStatePrefix+gameID, gameMetaPrefix+gameID, gameExtensionPrefix+gameID)
+}
+
+// claimGameResolution reserva a decisão da partida (SETNX em game:resolved:<GameID>).
+// Retorna false se ela já foi decidida por outro caminho (cérebro, startup ou admin).
+// Se o Redis falhar, a decisão segue: melhor um resultado duplicado que uma partida travada.
+func (s *Server) claimGameResolution(gameID string) bool {
+	if gameID == "" {
+		return true
+	}
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	claimed, err := s.RedisClient.SetNX(ctx, gameResolvedPrefix+gameID, s.ServerID, s.gameStateTTL()).Result()
+	if err != nil {
+		slog.Error("Erro ao reservar a decisão da partida", "game_id", gameID, "error", err)
+		return true
+	}
+	return claimed
+}
+
+// reconcileStaleGames resolve, no startup, as partidas que ficaram no Redis quando ESTE servidor
+// (o cérebro delas) caiu. Como as sessões se perderam com o processo, a partida é reconstruída
+// a partir dos metadados e resolvida como no timeout: quem jogou vence quem não jogou.
+// Os resultados seguem pelo fluxo normal de "RESULT|" (Pub/Sub), então os jogadores ainda
+// conectados em outros servidores são avisados e têm o estado limpo.
+// Partidas sem metadados (ou de servidores sem registro) recebem um TTL para expirarem sozinhas.
+func (s *Server) reconcileStaleGames() {
+	ctx, cancel := s.redisCtxTimeout(reconcileTimeout)
+	defer cancel()
+	keys, err := s.scanKeys(ctx, gameStatePrefix+"*")
+	if err != nil {
+		slog.Error("Erro ao procurar partidas abandonadas", "error", err)
+	}
+	for _, key := range keys {
+		gameID := strings.TrimPrefix(key, gameStatePrefix)
+
+		metaJSON, err := s.RedisClient.Get(ctx, gameMetaPrefix+gameID).Result()
+		var meta GameMeta
+		if err != nil || json.Unmarshal([]byte(metaJSON), &meta) != nil {
+			// Sem cérebro conhecido: garante que o estado expire em vez de se acumular.
+			if ttl, err := s.RedisClient.TTL(ctx, key).Result(); err == nil && ttl < 0 {
+				s.RedisClient.Expire(ctx, key, s.gameStateTTL())
+				slog.Warn("Estado de partida sem cérebro conhecido; expiração agendada.", "event", "stale_game_expiring", "game_id", gameID)
+			}
+			continue
+		}
+		if meta.BrainServerID != s.ServerID {
+			continue // O cérebro (ou o watchdog dos outros servidores) cuida desta partida
+		}
+
+		moves, err := s.RedisClient.HGetAll(ctx, key).Result()
+		if err != nil {
+			slog.Error("Erro ao ler partida abandonada", "game_id", meta.GameID, "error", err)
+			continue
+		}
+
+		slog.Warn("Partida abandonada encontrada no startup. Resolvendo.", "event", "stale_game_resolved",
+			"game_id", meta.GameID, "mode", meta.Mode, "players", meta.Players)
+		s.resolveStaleGame(meta, moves)
+		s.clearGameState(gameID)
+	}
+}
+
+// loadGameMeta lê os metadados de uma partida em andamento a partir do GameID.
+// Retorna redis.Nil se a partida não existir (ou já tiver terminado).
+func (s *Server) loadGameMeta(gameID string) (meta GameMeta, err error) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	metaJSON, err := s.RedisClient.Get(ctx, gameMetaPrefix+gameID).Result()
+	if err != nil {
+		return meta, err
+	}
+	if err := json.Unmarshal([]byte(metaJSON), &meta); err != nil {
+		return meta, err
+	}
+	return meta, nil
+}
+
+// ghostSession reconstrói a sessão de uma partida a partir dos metadados, com jogadores "fantasmas".
+// Usada quando o servidor que decide a partida não tem a sessão em memória.
+func ghostSession(meta GameMeta) (*GameSession, error) {
+	session := &GameSession{GameID: meta.GameID, Mode: meta.Mode, mu: sync.Mutex{}}
+	if meta.Mode == gameModeFFA {
+		session.Cards = make(map[string]*Card)
+		for _, name := range meta.Players {
+			session.Players = append(session.Players, &PlayerState{Name: name})
+		}
+		return session, nil
+	}
+	if len(meta.Players) != 2 {
+		return nil, fmt.Errorf("partida clássica com %d jogadores", len(meta.Players))
+	}
+	// O P1 fantasma "está" na partida: determineWinner só decide partidas em andamento para o P1
+	session.Player1 = &PlayerState{Name: meta.Players[0], State: "InGame", Games: map[string]*GameSession{meta.GameID: session}}
+	session.Player2 = &PlayerState{Name: meta.Players[1], Games: make(map[string]*GameSession)}
+	return session, nil
+}
+
+// resolveStaleGame reconstrói a sessão com jogadores "fantasmas" e aplica a decisão normal.
+func (s *Server) resolveStaleGame(meta GameMeta, moves map[string]string) {
+	session, err := ghostSession(meta)
+	if err != nil {
+		slog.Error("Metadados de partida inválidos", "game_id", meta.GameID, "players", meta.Players, "error", err)
+		return
+	}
+
+	if meta.Mode == gameModeFFA {
+		s.determineFFAWinner(session, moves)
+		return
+	}
+
+	// O P1 era local a este servidor e perdeu a conexão com a queda: seu resultado só é registrado no ranking.
+	session.Server1ID = s.ServerID
+	s.fillSessionFromMoves(session, moves)
+	s.determineWinner(session)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestReconcileStaleGamePublishesResult(t *testing.T) {
+	s, mr := newTestServer(t)
+	s.saveGameMeta(GameMeta{GameID: "g1", Mode: gameModeClassic, BrainServerID: s.ServerID, Players: []string{"Alice", "Bob"}})
+	card, _ := json.Marshal(Card{Name: "Dragão", Forca: 10})
+	mr.HSet("game:state:g1", "p2_card", string(card)) // Só o P2 jogou antes da queda do cérebro
+
+	s.reconcileStaleGames()
+
+	pending, _ := mr.List(playerInboxPrefix + "Bob")
+	var result string
+	for _, payload := range pending {
+		if _, r, ok := parseGameResult(payload); ok {
+			result = r
+		}
+	}
+	if !strings.HasPrefix(result, "RESULT|VITÓRIA") {
+		t.Errorf("resultado do P2 = %q, esperado a vitória de quem jogou (pendentes: %q)", result, pending)
+	}
+	if mr.Exists("game:state:g1") || mr.Exists(gameMetaPrefix+"g1") {
+		t.Errorf("o estado da partida reconciliada deveria ser apagado")
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-redis/redis/v8"
+)
+
+// Cada partida terminada deixa um registro (GameRecord) para resolver disputas depois: quem
+// jogou, de qual servidor, com qual carta, o resultado e o motivo de cada um e quando a partida
+// começou e terminou. O registro é gravado por quem decidiu a partida, logo depois de
+// claimGameResolution (o cérebro, a reconciliação no startup ou a resolução forçada do admin),
+// então existe exatamente um por partida, inclusive nas decididas por timeout, desistência ou
+// desconexão. Só as partidas resolvidas pelo watchdog (o cérebro caiu, ver brain_watchdog.go)
+// ficam sem registro: cada servidor resolve ali apenas o próprio jogador.
+//
+//	game:record:<GameID>   hash com os campos do registro (os jogadores em JSON)
+//	game:records           ZSET dos GameIDs, pelo horário do fim da partida (Unix em ms)
+//
+// Consulta (token de administração): GET /api/v1/games/records e GET /api/v1/games/records/{gameID}.
+
+const (
+	gameRecordPrefix   = "game:record:"
+	gameRecordIndexKey = "game:records"
+	// gameRecordTTL é por quanto tempo o registro de uma partida fica disponível.
+	gameRecordTTL = 30 * 24 * time.Hour
+	// gameRecordsDefaultLimit e gameRecordsMaxLimit limitam a listagem dos registros recentes.
+	gameRecordsDefaultLimit = 20
+	gameRecordsMaxLimit     = 200
+)
+
+// GameRecordPlayer é um participante no registro da partida.
+type GameRecordPlayer struct {
+	Name     string `json:"name"`
+	ServerID string `json:"server_id,omitempty"`
+	Card     *Card  `json:"card"`   // nil se não jogou
+	Result   string `json:"result"` // VITÓRIA, DERROTA ou EMPATE
+	Reason   string `json:"reason,omitempty"`
+}
+
+// GameRecord é o registro durável de uma partida terminada.
+type GameRecord struct {
+	GameID           string             `json:"game_id"`
+	Mode             string             `json:"mode"`
+	Outcome          string             `json:"outcome"` // O mesmo rótulo da métrica cardgame_games_finished_total
+	Players          []GameRecordPlayer `json:"players"`
+	SuddenDeathRound int                `json:"sudden_death_round,omitempty"` // Rodada de morte súbita que decidiu a partida
+	ResolvedBy       string             `json:"resolved_by"`                  // Servidor que decidiu a partida
+	StartedAt        int64              `json:"started_at,omitempty"`         // Unix em ms (0 = desconhecido, ex: após reiniciar)
+	FinishedAt       int64              `json:"finished_at"`                  // Unix em ms
+}
+
+// GameRecordsResponse é a resposta de GET /api/v1/games/records.
+type GameRecordsResponse struct {
+	Records []GameRecord `json:"records"`
+}
+
+// recordPlayer monta o participante do registro a partir da mensagem de resultado dele.
+func recordPlayer(name, serverID string, card *Card, resultMsg string) GameRecordPlayer {
+	result, reason, _ := parseResultMessage(resultMsg)
+	return GameRecordPlayer{Name: name, ServerID: serverID, Card: card, Result: result, Reason: reason}
+}
+
+// classicGameRecord monta o registro de uma partida clássica. Deve ser chamada com session.mu travado.
+func (s *Server) classicGameRecord(session *GameSession, outcome, resultP1, resultP2 string) GameRecord {
+	return GameRecord{
+		GameID:  session.GameID,
+		Mode:    gameModeClassic,
+		Outcome: outcome,
+		Players: []GameRecordPlayer{
+			recordPlayer(session.Player1.Name, session.Server1ID, session.Player1Card, resultP1),
+			recordPlayer(session.Player2.Name, session.Server2ID, session.Player2Card, resultP2),
+		},
+		SuddenDeathRound: session.SuddenDeathRound,
+		ResolvedBy:       s.ServerID,
+		StartedAt:        unixMilliOrZero(session.StartedAt),
+		FinishedAt:       time.Now().UnixMilli(),
+	}
+}
+
+// ffaGameRecord monta o registro de uma partida FFA. Deve ser chamada com session.mu travado.
+func (s *Server) ffaGameRecord(session *GameSession, outcome string, results map[string]string) GameRecord {
+	record := GameRecord{
+		GameID:     session.GameID,
+		Mode:       gameModeFFA,
+		Outcome:    outcome,
+		ResolvedBy: s.ServerID,
+		StartedAt:  unixMilliOrZero(session.StartedAt),
+		FinishedAt: time.Now().UnixMilli(),
+	}
+	for _, p := range session.Players {
+		record.Players = append(record.Players, recordPlayer(p.Name, p.ServerID, session.Cards[p.Name], results[p.Name]))
+	}
+	return record
+}
+
+// unixMilliOrZero converte o horário para Unix em ms, com 0 para o horário não definido.
+func unixMilliOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// saveGameRecord grava o registro da partida e o indexa pelo horário do fim. Falhas são apenas
+// registradas: o registro nunca interrompe o fim da partida.
+func (s *Server) saveGameRecord(record GameRecord) {
+	playersJSON, err := json.Marshal(record.Players)
+	if err != nil {
+		slog.Error("Erro ao serializar registro da partida", "game_id", record.GameID, "error", err)
+		return
+	}
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	key := gameRecordPrefix + record.GameID
+	pipe := s.RedisClient.TxPipeline()
+	pipe.HSet(ctx, key,
+		"game_id", record.GameID,
+		"mode", record.Mode,
+		"outcome", record.Outcome,
+		"players", string(playersJSON),
+		"sudden_death_round", record.SuddenDeathRound,
+		"resolved_by", record.ResolvedBy,
+		"started_at", record.StartedAt,
+		"finished_at", record.FinishedAt)
+	pipe.Expire(ctx, key, gameRecordTTL)
+	pipe.ZAdd(ctx, gameRecordIndexKey, &redis.Z{Score: float64(record.FinishedAt), Member: record.GameID})
+	// O índice acompanha o TTL dos registros
+	cutoff := time.Now().Add(-gameRecordTTL).UnixMilli()
+	pipe.ZRemRangeByScore(ctx, gameRecordIndexKey, "-inf", "("+strconv.FormatInt(cutoff, 10))
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.Error("Erro ao gravar registro da partida", "game_id", record.GameID, "error", err)
+		return
+	}
+	slog.Debug("Registro da partida gravado", "game_id", record.GameID, "outcome", record.Outcome)
+}
+
+// parseGameRecord monta o registro a partir dos campos do hash game:record:<GameID>.
+func parseGameRecord(fields map[string]string) (GameRecord, error) {
+	record := GameRecord{
+		GameID:     fields["game_id"],
+		Mode:       fields["mode"],
+		Outcome:    fields["outcome"],
+		ResolvedBy: fields["resolved_by"],
+	}
+	record.SuddenDeathRound, _ = strconv.Atoi(fields["sudden_death_round"])
+	record.StartedAt, _ = strconv.ParseInt(fields["started_at"], 10, 64)
+	record.FinishedAt, _ = strconv.ParseInt(fields["finished_at"], 10, 64)
+	err := json.Unmarshal([]byte(fields["players"]), &record.Players)
+	return record, err
+}
+
+// handleGetGameRecords implementa GET /api/v1/games/records?limit=N: os registros mais recentes primeiro.
+func (s *Server) handleGetGameRecords(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	limit := gameRecordsDefaultLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > gameRecordsMaxLimit {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "limit deve ser um inteiro entre 1 e "+strconv.Itoa(gameRecordsMaxLimit))
+			return
+		}
+		limit = n
+	}
+
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	gameIDs, err := s.RedisClient.ZRevRange(ctx, gameRecordIndexKey, 0, int64(limit-1)).Result()
+	if err != nil {
+		slog.Error("Erro ao listar registros de partidas", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Erro interno ao consultar os registros")
+		return
+	}
+	cmds := make([]*redis.StringStringMapCmd, len(gameIDs))
+	_, err = s.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, gameID := range gameIDs {
+			cmds[i] = pipe.HGetAll(ctx, gameRecordPrefix+gameID)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Erro ao ler registros de partidas", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Erro interno ao consultar os registros")
+		return
+	}
+
+	response := GameRecordsResponse{Records: []GameRecord{}}
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			continue // Expirou antes de sair do índice
+		}
+		record, err := parseGameRecord(fields)
+		if err != nil {
+			slog.Warn("Registro de partida corrompido ignorado", "game_id", gameIDs[i], "error", err)
+			continue
+		}
+		response.Records = append(response.Records, record)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetGameRecord implementa GET /api/v1/games/records/{gameID}.
+func (s *Server) handleGetGameRecord(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	gameID := chi.URLParam(r, "gameID")
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	fields, err := s.RedisClient.HGetAll(ctx, gameRecordPrefix+gameID).Result()
+	if err != nil {
+		slog.Error("Erro ao ler registro da partida", "game_id", gameID, "error", err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Erro interno ao consultar o registro")
+		return
+	}
+	if len(fields) == 0 {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "Registro da partida não encontrado")
+		return
+	}
+	record, err := parseGameRecord(fields)
+	if err != nil {
+		slog.Error("Registro de partida corrompido", "game_id", gameID, "error", err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Registro da partida corrompido")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(record)
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMoveChoice(t *testing.T) {
+	tests := []struct {
+		command  string
+		handSize int
+		want     int
+		wantErr  bool
+	}{
+		{"1", 2, 1, false},
+		{" 2 ", 2, 2, false},
+		{"3", 3, 3, false},
+		{"0", 2, 0, true},
+		{"3", 2, 0, true},
+		{"-1", 2, 0, true},
+		{"abc", 2, 0, true},
+		{"1a", 2, 0, true},
+		{"", 2, 0, true},
+	}
+	for _, tt := range tests {
+		got, errMsg := parseMoveChoice(tt.command, tt.handSize)
+		if got != tt.want || (errMsg != "") != tt.wantErr {
+			t.Errorf("parseMoveChoice(%q, %d) = %d, %q; quer %d, erro=%v", tt.command, tt.handSize, got, errMsg, tt.want, tt.wantErr)
+		}
+	}
+	// Mensagens distintas para "não é número" e "fora da mão"
+	_, notNumber := parseMoveChoice("abc", 2)
+	_, outOfRange := parseMoveChoice("5", 2)
+	if notNumber == outOfRange {
+		t.Errorf("mensagens iguais para entrada não numérica e fora da mão: %q", notNumber)
+	}
+}
+
+// newTestClassicGame cria uma partida clássica local entre p1 e p2, com as mãos dadas.
+func newTestClassicGame(s *Server, gameID string, p1, p2 *PlayerState, hand1, hand2 []Card) *GameSession {
+	session := &GameSession{
+		GameID:      gameID,
+		Mode:        gameModeClassic,
+		Player1:     p1,
+		Player2:     p2,
+		Player1Hand: hand1,
+		Player2Hand: hand2,
+		Server1ID:   s.ServerID,
+		Server2ID:   s.ServerID,
+	}
+	p1.Games[gameID] = session
+	p2.Games[gameID] = session
+	s.ActiveGames[gameID] = session
+	return session
+}
+
+func TestHandleGameMoveValidation(t *testing.T) {
+	s, mr := newTestServer(t)
+	p1, p2 := newTestPlayer("Alice"), newTestPlayer("Bob")
+	hand := []Card{{Name: "Dragão", Forca: 10}, {Name: "Goblin", Forca: 2}}
+	session := newTestClassicGame(s, "g1", p1, p2, hand, hand)
+
+	moves := []struct {
+		command string
+		want    string
+	}{
+		{"abc", "MOVE_REJECTED|" + moveRejectedInvalidCard + "|"},
+		{"3", "MOVE_REJECTED|" + moveRejectedInvalidCard + "|"},
+		{"0", "MOVE_REJECTED|" + moveRejectedInvalidCard + "|"},
+		{"2", "MOVE_ACK|Goblin"},
+		// Segunda jogada válida: recusada, sem sobrescrever a primeira
+		{"1", "MOVE_REJECTED|" + moveRejectedAlreadyPlayed + "|"},
+		{"2", "MOVE_REJECTED|" + moveRejectedAlreadyPlayed + "|"},
+	}
+	for _, m := range moves {
+		s.handleGameMove(p1, session, m.command)
+		sent := sentMessages(p1)
+		if len(sent) != 1 || !strings.HasPrefix(sent[0], m.want) {
+			t.Fatalf("jogada %q: enviado %q, quer prefixo %q", m.command, sent, m.want)
+		}
+	}
+
+	raw := mr.HGet("game:state:g1", "p1_card")
+	var stored Card
+	if err := json.Unmarshal([]byte(raw), &stored); err != nil || stored.Name != "Goblin" {
+		t.Fatalf("jogada registrada = %q (%v), quer a primeira (Goblin)", raw, err)
+	}
+}
+
+func TestClassicResults(t *testing.T) {
+	dragon, goblin := &Card{Name: "Dragão", Forca: 10}, &Card{Name: "Goblin", Forca: 2}
+	tests := []struct {
+		name                string
+		p1Card, p2Card      *Card
+		forfeitedBy, reason string
+		wantP1, wantP2      string // Prefixos esperados: "RESULT|<resultado>|<motivo>|"
+		wantOutcome         string
+	}{
+		{"vitória do P1", dragon, goblin, "", "", "RESULT|VITÓRIA||", "RESULT|DERROTA||", "decided"},
+		{"vitória do P2", goblin, dragon, "", "", "RESULT|DERROTA||", "RESULT|VITÓRIA||", "decided"},
+		{"empate", dragon, dragon, "", "", "RESULT|EMPATE||", "RESULT|EMPATE||", "draw"},
+		{"P1 desconectou", nil, goblin, "Alice", resultReasonDisconnected,
+			"RESULT|DERROTA|" + resultReasonDisconnected + "|", "RESULT|VITÓRIA|" + resultReasonOpponentDisconnected + "|", "forfeit"},
+		{"P2 desistiu depois de jogar", dragon, goblin, "Bob", resultReasonForfeit,
+			"RESULT|VITÓRIA|" + resultReasonOpponentForfeit + "|", "RESULT|DERROTA|" + resultReasonForfeit + "|", "surrender"},
+		{"P1 não jogou", nil, goblin, "", "",
+			"RESULT|DERROTA|" + resultReasonTimeout + "|", "RESULT|VITÓRIA|" + resultReasonOpponentTimeout + "|", "timeout"},
+		{"P2 não jogou", goblin, nil, "", "",
+			"RESULT|VITÓRIA|" + resultReasonOpponentTimeout + "|", "RESULT|DERROTA|" + resultReasonTimeout + "|", "timeout"},
+		{"timeout duplo", nil, nil, "", "",
+			"RESULT|EMPATE|" + resultReasonTimeout + "|", "RESULT|EMPATE|" + resultReasonTimeout + "|", "double_timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &GameSession{
+				GameID:        "g1",
+				Mode:          gameModeClassic,
+				Player1:       newTestPlayer("Alice"),
+				Player2:       newTestPlayer("Bob"),
+				Player1Card:   tt.p1Card,
+				Player2Card:   tt.p2Card,
+				ForfeitedBy:   tt.forfeitedBy,
+				ForfeitReason: tt.reason,
+			}
+			resultP1, resultP2, _, outcome := classicResults(session)
+			if !strings.HasPrefix(resultP1, tt.wantP1) {
+				t.Errorf("resultado do P1 = %q, esperado o prefixo %q", resultP1, tt.wantP1)
+			}
+			if !strings.HasPrefix(resultP2, tt.wantP2) {
+				t.Errorf("resultado do P2 = %q, esperado o prefixo %q", resultP2, tt.wantP2)
+			}
+			if outcome != tt.wantOutcome {
+				t.Errorf("outcome = %q, esperado %q", outcome, tt.wantOutcome)
+			}
+		})
+	}
+}
+
+// O cérebro da partida (P1 local, P2 remoto) decide cada desfecho: o P1 recebe o GAME_END pela
+// fila de saída, o P2 recebe REVEAL e RESULT pelo canal player:<nome>, e o estado da partida é apagado.
+func TestDetermineWinnerPublishesResults(t *testing.T) {
+	dragon, goblin := Card{Name: "Dragão", Forca: 10}, Card{Name: "Goblin", Forca: 2}
+	tests := []struct {
+		name           string
+		p1Card, p2Card *Card
+		event          string // Publicado no canal da partida ("" = espera o timeout)
+		wantP1, wantP2 string // Resultado (GAME_END) do P1 e prefixo do RESULT publicado para o P2
+	}{
+		{"vitória do P1", &dragon, &goblin, "MOVE", "VITÓRIA", "RESULT|DERROTA||"},
+		{"vitória do P2", &goblin, &dragon, "MOVE", "DERROTA", "RESULT|VITÓRIA||"},
+		{"empate", &dragon, &dragon, "MOVE", "EMPATE", "RESULT|EMPATE||"},
+		{"P1 desconectou", nil, &goblin, disconnectEventPrefix + "Alice", "DERROTA", "RESULT|VITÓRIA|" + resultReasonOpponentDisconnected + "|"},
+		{"P2 desistiu", nil, nil, forfeitEventPrefix + "Bob", "VITÓRIA", "RESULT|DERROTA|" + resultReasonForfeit + "|"},
+		{"P2 não jogou", &dragon, nil, "", "VITÓRIA", "RESULT|DERROTA|" + resultReasonTimeout + "|"},
+		{"timeout duplo", nil, nil, "", "EMPATE", "RESULT|EMPATE|" + resultReasonTimeout + "|"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mr := newTestServer(t)
+			p1, p2 := newTestPlayer("Alice"), newTestPlayer("Bob")
+			session := newTestClassicGame(s, "g1", p1, p2, []Card{dragon}, []Card{goblin})
+			session.Server2ID = "Server-Other"
+			session.TurnDeadline = time.Now().Add(5 * time.Second)
+			if tt.event == "" {
+				session.TurnDeadline = time.Now().Add(100 * time.Millisecond)
+			}
+			p1.State = "InGame"
+			for field, card := range map[string]*Card{"p1_card": tt.p1Card, "p2_card": tt.p2Card} {
+				if card != nil {
+					cardJSON, _ := json.Marshal(card)
+					mr.HSet("game:state:g1", field, string(cardJSON))
+				}
+			}
+
+			// O P2 é de outro servidor: só o canal dele recebe o resultado
+			toP2 := s.RedisClient.Subscribe(context.Background(), "player:Bob")
+			defer toP2.Close()
+			if _, err := toP2.Receive(context.Background()); err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+
+			finished := make(chan struct{})
+			go func() {
+				s.listenForGameEvents(session, "g1")
+				close(finished)
+			}()
+			if tt.event != "" {
+				waitFor(t, "cérebro da partida inscrito", func() bool { return mr.PubSubNumSub("game:channel:g1")["game:channel:g1"] > 0 })
+				mr.Publish("game:channel:g1", tt.event)
+			}
+			select {
+			case <-finished:
+			case <-time.After(5 * time.Second):
+				t.Fatal("a partida não terminou")
+			}
+
+			var end GameEnd
+			for _, message := range sentMessages(p1) {
+				if rest, ok := strings.CutPrefix(message, "GAME_END|"); ok {
+					json.Unmarshal([]byte(rest), &end)
+				}
+			}
+			if end.Result != tt.wantP1 || end.GameID != "g1" {
+				t.Errorf("GAME_END do P1 = %+v, esperado o resultado %q", end, tt.wantP1)
+			}
+
+			var published []string
+			for len(published) < 2 {
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				msg, err := toP2.ReceiveMessage(ctx)
+				cancel()
+				if err != nil {
+					t.Fatalf("o P2 deveria receber REVEAL e RESULT, recebido %q: %v", published, err)
+				}
+				published = append(published, msg.Payload)
+			}
+			if !strings.HasPrefix(published[0], "REVEAL|") {
+				t.Errorf("primeira mensagem do P2 = %q, esperado o REVEAL", published[0])
+			}
+			if want := gameResultPrefix + "g1|" + tt.wantP2; !strings.HasPrefix(published[1], want) {
+				t.Errorf("resultado publicado para o P2 = %q, esperado o prefixo %q", published[1], want)
+			}
+
+			if mr.Exists("game:state:g1") {
+				t.Errorf("o estado da partida (game:state:g1) deveria ser apagado")
+			}
+			p1.mu.Lock()
+			inGame := p1.Games["g1"] != nil
+			p1.mu.Unlock()
+			if inGame {
+				t.Errorf("o P1 deveria sair da partida")
+			}
+			s.GamesMutex.Lock()
+			_, active := s.ActiveGames["g1"]
+			s.GamesMutex.Unlock()
+			if active {
+				t.Errorf("a partida deveria sair de ActiveGames")
+			}
+		})
+	}
+}
+
+// handNames conta as cartas da mão por nome.
+func handNames(hand []Card) map[string]int {
+	names := make(map[string]int)
+	for _, card := range hand {
+		names[card.Name]++
+	}
+	return names
+}
+
+func TestSelectRandomCardsDistinct(t *testing.T) {
+	repeated := func(name string, copies int) []Card {
+		cards := make([]Card, copies)
+		for i := range cards {
+			cards[i] = Card{Name: name, Forca: 1}
+		}
+		return cards
+	}
+	// Muitas cópias de uma carta fraca e poucas de outras, como no estoque padrão
+	deck := append(append(repeated("Ghoul", 40), repeated("Grifo", 3)...), repeated("Dragão", 1)...)
+
+	for i := 0; i < 50; i++ {
+		hand := selectRandomCards(deck, 3, true)
+		if names := handNames(hand); len(hand) != 3 || len(names) != 3 {
+			t.Fatalf("com 3 nomes no deck, a mão deveria ter 3 cartas distintas: %v", hand)
+		}
+	}
+
+	// Menos nomes distintos que o tamanho da mão: completa com repetidas
+	short := append(repeated("Ghoul", 5), repeated("Grifo", 1)...)
+	for i := 0; i < 50; i++ {
+		hand := selectRandomCards(short, 4, true)
+		names := handNames(hand)
+		if len(hand) != 4 || names["Grifo"] != 1 || names["Ghoul"] != 3 {
+			t.Fatalf("com 2 nomes no deck, a mão deveria ter os dois e completar com repetidas: %v", hand)
+		}
+	}
+
+	// Um único nome: a mão inteira é repetida
+	if hand := selectRandomCards(repeated("Ghoul", 3), 3, true); len(hand) != 3 {
+		t.Errorf("um deck de um só nome deveria dar uma mão completa, recebido %v", hand)
+	}
+	if hand := selectRandomCards(short, 7, true); hand != nil {
+		t.Errorf("com o deck menor que a mão, o sorteio deveria retornar nil, recebido %v", hand)
+	}
+}
+
+func TestSelectRandomCardsUniformByDefault(t *testing.T) {
+	// Sem HAND_DISTINCT, o sorteio é uniforme: num deck quase só de Ghoul, mãos repetidas aparecem
+	deck := []Card{{Name: "Dragão", Forca: 10}}
+	for i := 0; i < 20; i++ {
+		deck = append(deck, Card{Name: "Ghoul", Forca: 1})
+	}
+	repeats := false
+	for i := 0; i < 50 && !repeats; i++ {
+		hand := selectRandomCards(deck, 2, false)
+		if len(hand) != 2 {
+			t.Fatalf("mão com %d cartas, esperado 2", len(hand))
+		}
+		repeats = handNames(hand)["Ghoul"] == 2
+	}
+	if !repeats {
+		t.Errorf("sem HAND_DISTINCT, a mão deveria poder repetir cartas")
+	}
+	if cfg, err := loadConfig(); err != nil || cfg.DistinctHand {
+		t.Errorf("HAND_DISTINCT deveria vir desligado por padrão (DistinctHand = %v, erro %v)", cfg.DistinctHand, err)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// O endpoint de administração GET /api/v1/games mostra as partidas de que ESTE servidor é o
+// cérebro (o servidor do P1 no modo clássico, o master no FFA), para depurar partidas entre
+// servidores durante um incidente. Cada servidor responde só pelas suas: para ver o cluster,
+// consulte todos. As jogadas vêm do hash game:state:<GameID>, e só se sabe QUEM jogou, não a carta.
+
+// ActiveGamePlayer é um participante de uma partida em GET /api/v1/games.
+type ActiveGamePlayer struct {
+	Name     string `json:"name"`
+	ServerID string `json:"server_id"`
+	Played   bool   `json:"played"` // Já jogou na rodada atual
+}
+
+// ActiveGameView é uma partida em andamento em GET /api/v1/games.
+type ActiveGameView struct {
+	GameID           string             `json:"game_id"`
+	Mode             string             `json:"mode"`
+	Players          []ActiveGamePlayer `json:"players"`
+	SuddenDeathRound int                `json:"sudden_death_round,omitempty"`
+	RemainingSeconds int                `json:"remaining_seconds"` // Até o prazo da jogada (0 = já venceu)
+}
+
+// ActiveGamesResponse é a resposta de GET /api/v1/games.
+type ActiveGamesResponse struct {
+	ServerID string           `json:"server_id"`
+	Games    []ActiveGameView `json:"games"`
+}
+
+// brainGames retorna as sessões de ActiveGames de que este servidor é o cérebro.
+func (s *Server) brainGames() []*GameSession {
+	s.GamesMutex.Lock()
+	sessions := make([]*GameSession, 0, len(s.ActiveGames))
+	for _, session := range s.ActiveGames {
+		sessions = append(sessions, session)
+	}
+	s.GamesMutex.Unlock()
+
+	// Os campos da sessão são lidos sob session.mu, fora do GamesMutex
+	owned := sessions[:0]
+	for _, session := range sessions {
+		session.mu.Lock()
+		brain := session.Server1ID == s.ServerID
+		if session.Mode == gameModeFFA {
+			brain = len(session.Players) > 0 && session.Players[0].ServerID == s.ServerID
+		}
+		session.mu.Unlock()
+		if brain {
+			owned = append(owned, session)
+		}
+	}
+	return owned
+}
+
+// activeGameView monta a visão da partida a partir da sessão e dos campos do hash de jogadas.
+func activeGameView(session *GameSession, played map[string]bool) ActiveGameView {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	view := ActiveGameView{
+		GameID:           session.GameID,
+		Mode:             session.Mode,
+		SuddenDeathRound: session.SuddenDeathRound,
+		RemainingSeconds: remainingSeconds(session.TurnDeadline),
+	}
+	if session.Mode == gameModeFFA {
+		// No FFA, o campo do hash é o nome do jogador
+		for _, p := range session.Players {
+			view.Players = append(view.Players, ActiveGamePlayer{Name: p.Name, ServerID: p.ServerID, Played: played[p.Name]})
+		}
+		return view
+	}
+	view.Players = []ActiveGamePlayer{
+		{Name: session.Player1.Name, ServerID: session.Server1ID, Played: played[cardField("p1_card", session.SuddenDeathRound)]},
+		{Name: session.Player2.Name, ServerID: session.Server2ID, Played: played[cardField("p2_card", session.SuddenDeathRound)]},
+	}
+	return view
+}
+
+// handleListActiveGames implementa o endpoint de administração GET /api/v1/games.
+func (s *Server) handleListActiveGames(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	sessions := s.brainGames()
+
+	// As jogadas de todas as partidas numa única ida ao Redis
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	fields := make([]*redis.StringSliceCmd, len(sessions))
+	_, err := s.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, session := range sessions {
+			session.mu.Lock()
+			gameID := session.GameID
+			session.mu.Unlock()
+			fields[i] = pipe.HKeys(ctx, gameStatePrefix+gameID)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Erro ao ler as jogadas das partidas em andamento", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Erro interno ao consultar as partidas")
+		return
+	}
+
+	response := ActiveGamesResponse{ServerID: s.ServerID, Games: []ActiveGameView{}}
+	for i, session := range sessions {
+		played := make(map[string]bool)
+		for _, field := range fields[i].Val() {
+			played[field] = true
+		}
+		response.Games = append(response.Games, activeGameView(session, played))
+	}
+	sort.Slice(response.Games, func(i, j int) bool { return response.Games[i].GameID < response.Games[j].GameID })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
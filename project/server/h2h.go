@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+const (
+	// h2hPrefix guarda o confronto direto de cada par de jogadores (h2h:<a>:<b>, com a < b),
+	// em um hash com as vitórias de cada um ("wins:<nome>") e os empates ("draws").
+	h2hPrefix = "h2h:"
+	h2hDraws  = "draws"
+)
+
+// h2hKey retorna a chave canônica do confronto: a mesma para (a, b) e (b, a).
+func h2hKey(a, b string) string {
+	if b < a {
+		a, b = b, a
+	}
+	return h2hPrefix + a + ":" + b
+}
+
+// recordHeadToHead registra o resultado de uma partida clássica no confronto direto dos dois jogadores.
+// Diferente do ranking (cada servidor registra o seu jogador), o confronto é um registro único do par:
+// por isso é gravado apenas por quem decidiu a partida, depois de claimGameResolution
+// (determineWinner no cérebro ou forceResolveClassic na API de administração).
+// outcomeP1 é o resultado do ponto de vista do P1 (outcomeFromResult).
+func (s *Server) recordHeadToHead(player1, player2, outcomeP1 string) {
+	var field string
+	switch outcomeP1 {
+	case outcomeWin:
+		field = "wins:" + player1
+	case outcomeLoss:
+		field = "wins:" + player2
+	case outcomeDraw:
+		field = h2hDraws
+	default:
+		return
+	}
+
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	if err := s.RedisClient.HIncrBy(ctx, h2hKey(player1, player2), field, 1).Err(); err != nil {
+		slog.Error("Erro ao registrar confronto direto", "player1", player1, "player2", player2, "outcome", outcomeP1, "error", err)
+	}
+}
+
+// getHeadToHead lê o confronto direto do ponto de vista de playerName.
+func (s *Server) getHeadToHead(playerName, opponent string) (LeaderboardEntry, error) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	entry := LeaderboardEntry{PlayerName: playerName}
+
+	stats, err := s.RedisClient.HGetAll(ctx, h2hKey(playerName, opponent)).Result()
+	if err != nil {
+		return entry, err
+	}
+	entry.Wins, _ = strconv.Atoi(stats["wins:"+playerName])
+	entry.Losses, _ = strconv.Atoi(stats["wins:"+opponent])
+	entry.Draws, _ = strconv.Atoi(stats[h2hDraws])
+	return entry, nil
+}
+
+// handleH2HCommand responde ao comando "H2H <adversário>" com o confronto direto do jogador contra ele.
+func (s *Server) handleH2HCommand(player *PlayerState, command string) {
+	opponent := strings.TrimSpace(strings.TrimPrefix(command, "H2H"))
+	if opponent == "" {
+		s.sendWebSocketMessage(player, "Uso: H2H <adversário>")
+		return
+	}
+	if opponent == player.Name {
+		s.sendWebSocketMessage(player, "Você não pode consultar o confronto contra si mesmo.")
+		return
+	}
+
+	h2h, err := s.getHeadToHead(player.Name, opponent)
+	if err != nil {
+		slog.Error("Erro ao ler confronto direto", "player", player.Name, "opponent", opponent, "error", err)
+		s.sendWebSocketMessage(player, "Erro ao consultar o confronto direto. Tente novamente.")
+		return
+	}
+	total := h2h.Wins + h2h.Losses + h2h.Draws
+	if total == 0 {
+		s.sendWebSocketMessage(player, fmt.Sprintf("Você ainda não enfrentou %s.", opponent))
+		return
+	}
+	s.sendWebSocketMessage(player, fmt.Sprintf("Confronto direto contra %s: %dV/%dD/%dE em %d partida(s).",
+		opponent, h2h.Wins, h2h.Losses, h2h.Draws, total))
+}
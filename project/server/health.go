@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const readinessPingTimeout = 500 * time.Millisecond
+
+// handleHealthz implementa a sonda de liveness: responde 200 enquanto o processo estiver no ar.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleReadyz implementa a sonda de readiness: verifica a conexão com o Redis (Ping curto)
+// e se o estoque de cartas já foi inicializado. Retorna 503 se alguma verificação falhar ou se o
+// servidor estiver em manutenção (ver maintenance.go).
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessPingTimeout)
+	defer cancel()
+
+	status := map[string]string{"redis": "ok", "stock": "ok", "games": strconv.Itoa(s.activeGameCount())}
+	if s.Config.MaxConcurrentGames > 0 {
+		// Lotado não tira o servidor do balanceamento: só suspende novos pareamentos (ver capacity.go)
+		status["games"] += "/" + strconv.Itoa(s.Config.MaxConcurrentGames)
+	}
+	ready := true
+
+	if err := s.RedisClient.Ping(ctx).Err(); err != nil {
+		status["redis"] = err.Error()
+		ready = false
+	}
+	if !s.stockReady.Load() {
+		status["stock"] = "inicializando"
+		ready = false
+	}
+	if s.inMaintenance() {
+		status["maintenance"] = "ativa"
+		ready = false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if ready {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// newTestServer cria um servidor com a configuração padrão (ou a das variáveis definidas com
+// t.Setenv antes da chamada) ligado a um Redis em memória (miniredis).
+func newTestServer(t *testing.T) (*Server, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	return newTestServerOn(t, mr, "Server-Test"), mr
+}
+
+// newTestServerOn cria mais um servidor ligado ao mesmo Redis em memória, como um segundo
+// servidor do cluster.
+func newTestServerOn(t *testing.T, mr *miniredis.Miniredis, serverID string) *Server {
+	t.Helper()
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	s := &Server{
+		RedisClient: rdb,
+		Players:     make(map[string]*PlayerState),
+		PlayerMutex: &sync.Mutex{},
+		ServerID:    serverID,
+		RestAddr:    "localhost:0",
+		Config:      cfg,
+		HTTPClient:  newServerHTTPClient(cfg.NotifyTimeout),
+		ActiveGames: make(map[string]*GameSession),
+		detached:    make(map[string]*PlayerState),
+	}
+	s.ctx, s.stop = context.WithCancel(context.Background())
+	t.Cleanup(s.stop)
+	return s
+}
+
+// newTestPlayer cria um jogador local sem conexão: as mensagens ficam em player.outbox
+// (ver sentMessages) e nenhum writeLoop as consome.
+func newTestPlayer(name string) *PlayerState {
+	return &PlayerState{
+		Name:    name,
+		State:   "Menu",
+		Games:   make(map[string]*GameSession),
+		done:    make(chan struct{}),
+		limiter: newTokenBucket(1000, 1000),
+		outbox:  make(chan outboxItem, outboxSize),
+	}
+}
+
+// sentMessages retira da fila de saída do jogador as mensagens enfileiradas até agora, como se
+// o writeLoop as tivesse escrito (os avisos de afterDelivery são disparados na ordem).
+func sentMessages(player *PlayerState) []string {
+	var messages []string
+	for {
+		select {
+		case item := <-player.outbox:
+			if item.delivered != nil {
+				item.delivered()
+				continue
+			}
+			messages = append(messages, item.message)
+		default:
+			return messages
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// As mensagens ao jogador que passam por aqui são identificadas por um msgID e renderizadas no
+// idioma do jogador, escolhido com "SET_LOCALE <idioma>" (padrão: pt-BR). Os parâmetros (nomes de
+// cartas e de jogadores, números) vão já formatados como texto e não são traduzidos.
+//
+// O resultado da partida é decidido no cérebro, que não conhece o idioma dos jogadores remotos:
+// por isso o "RESULT|<resultado>|<motivo>|<texto>" leva no texto a lista de trechos (LocalizedText)
+// em JSON, e o servidor de cada jogador a renderiza no idioma dele ao montar o GAME_END. O
+// GAME_END também traz os trechos em "message_parts", para clientes que queiram traduzir sozinhos.
+// Um texto que não é JSON (servidores anteriores) é entregue como está.
+//
+// Mensagens fora do catálogo continuam em português.
+
+const defaultLocale = "pt-BR"
+
+// msgID identifica uma mensagem do catálogo.
+type msgID string
+
+const (
+	msgResultCardWon            msgID = "result_card_won"
+	msgResultCardLost           msgID = "result_card_lost"
+	msgResultCardDraw           msgID = "result_card_draw"
+	msgResultDisconnected       msgID = "result_disconnected"
+	msgResultOpponentDisconnect msgID = "result_opponent_disconnected"
+	msgResultForfeit            msgID = "result_forfeit"
+	msgResultOpponentForfeit    msgID = "result_opponent_forfeit"
+	msgResultTimeout            msgID = "result_timeout"
+	msgResultOpponentTimeout    msgID = "result_opponent_timeout"
+	msgResultDoubleTimeout      msgID = "result_double_timeout"
+	msgResultSuddenDeath        msgID = "result_sudden_death"
+	msgResultServerLostVoid     msgID = "result_server_lost_void"
+	msgResultServerLostWin      msgID = "result_server_lost_win"
+	msgResultServerLostDraw     msgID = "result_server_lost_draw"
+	msgResultFFASpeedWin        msgID = "result_ffa_speed_win"
+	msgResultFFAStrongest       msgID = "result_ffa_strongest"
+	msgResultFFADraw            msgID = "result_ffa_draw"
+	msgResultFFASpeedLoss       msgID = "result_ffa_speed_loss"
+	msgResultFFALoss            msgID = "result_ffa_loss"
+	msgAbilitiesHeader          msgID = "abilities_header"
+	msgListSeparator            msgID = "list_separator"
+	msgSentenceEnd              msgID = "sentence_end"
+	msgEffectBoost              msgID = "effect_boost"
+	msgEffectWeather            msgID = "effect_weather"
+	msgEffectSpy                msgID = "effect_spy"
+	msgEffectSpeed              msgID = "effect_speed"
+	msgQueueJoined              msgID = "queue_joined"
+	msgQueueAlreadySearching    msgID = "queue_already_searching"
+	msgQueueError               msgID = "queue_error"
+	msgMaintenance              msgID = "maintenance"
+	msgInvalidCommand           msgID = "invalid_command"
+	msgTradeCompleted           msgID = "trade_completed"
+	msgTradeReceivedCard        msgID = "trade_received_card"
+	msgTradeReceivedBundle      msgID = "trade_received_bundle"
+	msgTradeReceivedError       msgID = "trade_received_error"
+	msgTradeError               msgID = "trade_error"
+	msgTradeBusy                msgID = "trade_busy"
+	msgTradePending             msgID = "trade_pending"
+	msgTradeNotAllowed          msgID = "trade_not_allowed"
+	msgTradeMinDeck             msgID = "trade_min_deck"
+	msgTradeCardUsage           msgID = "trade_card_usage"
+	msgTradeBundleUsage         msgID = "trade_bundle_usage"
+	msgTradeBundleTooLarge      msgID = "trade_bundle_too_large"
+	msgTradeInvalidIndex        msgID = "trade_invalid_index"
+	msgTradeIndexOutOfRange     msgID = "trade_index_out_of_range"
+	msgTradeDuplicateIndex      msgID = "trade_duplicate_index"
+	msgTradeQueuedCard          msgID = "trade_queued_card"
+	msgTradeQueuedBundle        msgID = "trade_queued_bundle"
+	msgTradeWantNotice          msgID = "trade_want_notice"
+	msgTradeWantAny             msgID = "trade_want_any"
+	msgTradeWantCard            msgID = "trade_want_card"
+	msgTradeWantMinForca        msgID = "trade_want_min_forca"
+	msgTradeWantMissing         msgID = "trade_want_missing"
+	msgTradeWantBadForca        msgID = "trade_want_bad_forca"
+	msgTradeWantUnknownCard     msgID = "trade_want_unknown_card"
+	msgTradeQueueError          msgID = "trade_queue_error"
+	msgTradeCorruptedTicket     msgID = "trade_corrupted_ticket"
+	msgTradeSelfMatch           msgID = "trade_self_match"
+	msgTradeExpired             msgID = "trade_expired"
+	msgLocaleSet                msgID = "locale_set"
+	msgLocaleUnsupported        msgID = "locale_unsupported"
+)
+
+// catalog guarda o modelo (fmt, com %s) de cada mensagem, por idioma.
+var catalog = map[string]map[msgID]string{
+	"pt-BR": {
+		msgResultCardWon:            "Sua carta %s (%s) venceu %s (%s) de %s.",
+		msgResultCardLost:           "Sua carta %s (%s) perdeu para %s (%s) de %s.",
+		msgResultCardDraw:           "Empate! Ambas as cartas têm força %s e agilidade %s.",
+		msgResultDisconnected:       "Você desconectou e perdeu a partida.",
+		msgResultOpponentDisconnect: "%s desconectou. Você venceu!",
+		msgResultForfeit:            "Você desistiu e perdeu a partida.",
+		msgResultOpponentForfeit:    "%s desistiu. Você venceu!",
+		msgResultTimeout:            "Você não jogou a tempo e perdeu.",
+		msgResultOpponentTimeout:    "%s não jogou a tempo. Você venceu!",
+		msgResultDoubleTimeout:      "Nenhum jogador jogou a tempo. Empate.",
+		msgResultSuddenDeath:        "Morte súbita (rodada %s): ",
+		msgResultServerLostVoid:     "O servidor que coordenava a partida caiu. Partida anulada.",
+		msgResultServerLostWin:      "O servidor do oponente caiu. Você venceu!",
+		msgResultServerLostDraw:     "O servidor do oponente caiu antes da sua jogada. Empate.",
+		msgResultFFASpeedWin:        "Sua carta %s (%s) venceu o desempate por Agilidade (%s) entre %s cartas de mesma Força.",
+		msgResultFFAStrongest:       "Sua carta %s (%s) foi a mais forte entre %s jogadores.",
+		msgResultFFADraw:            "Sua carta %s (%s) empatou na Força e na Agilidade com %s jogador(es).",
+		msgResultFFASpeedLoss:       "Sua carta %s (%s) empatou na Força, mas perdeu no desempate por Agilidade (%s contra %s de %s).",
+		msgResultFFALoss:            "Sua carta %s (%s) perdeu. Maior Força da partida: %s (%s).",
+		msgAbilitiesHeader:          " Habilidades: ",
+		msgListSeparator:            "; ",
+		msgSentenceEnd:              ".",
+		msgEffectBoost:              "Reforço de %s: +%s de Força",
+		msgEffectWeather:            "Clima de %s: Força de %s reduzida para %s",
+		msgEffectSpy:                "Espião de %s venceu o empate",
+		msgEffectSpeed:              "Desempate por Agilidade: %s (%s) foi mais rápida que %s (%s)",
+		msgQueueJoined:              "Entrou na fila de matchmaking. Aguardando oponente...",
+		msgQueueAlreadySearching:    "Você já está na fila de matchmaking.",
+		msgQueueError:               "Erro interno ao entrar na fila. Tente novamente.",
+		msgMaintenance:              "Servidor em manutenção: novas partidas estão suspensas. Termine as partidas em andamento e conecte-se a outro servidor.",
+		msgInvalidCommand:           "Comando inválido.",
+		msgTradeCompleted:           "Troca realizada! Você enviou %s e recebeu %s.",
+		msgTradeReceivedCard:        "Troca concluída! Sua carta anterior foi trocada por %s.",
+		msgTradeReceivedBundle:      "Troca concluída! Seu pacote de %s cartas foi trocado por %s.",
+		msgTradeReceivedError:       "Erro ao processar uma troca recebida.",
+		msgTradeError:               "Erro interno no sistema de trocas. Tente novamente.",
+		msgTradeBusy:                "O sistema de trocas está ocupado. Tente novamente em alguns segundos.",
+		msgTradePending:             "Você já tem uma troca aguardando na fila. Espere ela ser concluída antes de oferecer outras cartas.",
+		msgTradeNotAllowed:          "Você não pode trocar cartas enquanto estiver em jogo ou procurando partida.",
+		msgTradeMinDeck:             "Troca recusada: seu deck ficaria com menos de %s cartas, o mínimo para jogar. Abra um pacote antes de trocar.",
+		msgTradeCardUsage:           "Comando inválido. Use 'TRADE_CARD [numero] [WANT carta]'.",
+		msgTradeBundleUsage:         "Comando inválido. Use 'TRADE_CARDS [n1,n2,...] [WANT carta]'.",
+		msgTradeBundleTooLarge:      "Você pode trocar no máximo %s cartas de uma vez.",
+		msgTradeInvalidIndex:        "Número da carta inválido.",
+		msgTradeIndexOutOfRange:     "Número da carta fora do alcance do seu deck.",
+		msgTradeDuplicateIndex:      "A carta %s foi informada mais de uma vez.",
+		msgTradeQueuedCard:          "Sua carta '%s' foi adicionada à fila de trocas. Aguardando outro jogador...",
+		msgTradeQueuedBundle:        "Suas %s cartas (%s) foram adicionadas à fila de trocas em pacote. Aguardando outro jogador com um pacote do mesmo tamanho...",
+		msgTradeWantNotice:          "A troca só acontece com quem oferecer %s. Se ninguém oferecer em %s, as cartas voltam para o seu deck.",
+		msgTradeWantAny:             "qualquer carta",
+		msgTradeWantCard:            "'%s'",
+		msgTradeWantMinForca:        "uma carta com Força %s ou mais",
+		msgTradeWantMissing:         "Informe a carta desejada. Use '... WANT <nome>' ou '... WANT <Força mínima>'.",
+		msgTradeWantBadForca:        "Força mínima inválida. Use um valor entre 1 e %s.",
+		msgTradeWantUnknownCard:     "A carta '%s' não existe.",
+		msgTradeQueueError:          "Erro interno ao acessar a fila de trocas. Tente novamente.",
+		msgTradeCorruptedTicket:     "Erro! O ticket na fila estava corrompido. Suas cartas foram devolvidas.",
+		msgTradeSelfMatch:           "Troca recusada: o ticket disponível na fila é seu. Suas cartas foram devolvidas.",
+		msgTradeExpired:             "Ninguém ofereceu %s a tempo. %s voltou para o seu deck.",
+		msgLocaleSet:                "Idioma definido: %s.",
+		msgLocaleUnsupported:        "Idioma não suportado: %s. Use %s.",
+	},
+	"en": {
+		msgResultCardWon:            "Your card %s (%s) beat %s (%s) from %s.",
+		msgResultCardLost:           "Your card %s (%s) lost to %s (%s) from %s.",
+		msgResultCardDraw:           "Draw! Both cards have strength %s and speed %s.",
+		msgResultDisconnected:       "You disconnected and lost the game.",
+		msgResultOpponentDisconnect: "%s disconnected. You won!",
+		msgResultForfeit:            "You forfeited and lost the game.",
+		msgResultOpponentForfeit:    "%s forfeited. You won!",
+		msgResultTimeout:            "You did not play in time and lost.",
+		msgResultOpponentTimeout:    "%s did not play in time. You won!",
+		msgResultDoubleTimeout:      "Neither player played in time. Draw.",
+		msgResultSuddenDeath:        "Sudden death (round %s): ",
+		msgResultServerLostVoid:     "The server running the game went down. Game voided.",
+		msgResultServerLostWin:      "Your opponent's server went down. You won!",
+		msgResultServerLostDraw:     "Your opponent's server went down before your move. Draw.",
+		msgResultFFASpeedWin:        "Your card %s (%s) won the Speed tiebreak (%s) among %s cards of equal Strength.",
+		msgResultFFAStrongest:       "Your card %s (%s) was the strongest among %s players.",
+		msgResultFFADraw:            "Your card %s (%s) tied on Strength and Speed with %s player(s).",
+		msgResultFFASpeedLoss:       "Your card %s (%s) tied on Strength but lost the Speed tiebreak (%s against %s from %s).",
+		msgResultFFALoss:            "Your card %s (%s) lost. Highest Strength in the game: %s (%s).",
+		msgAbilitiesHeader:          " Abilities: ",
+		msgListSeparator:            "; ",
+		msgSentenceEnd:              ".",
+		msgEffectBoost:              "%s's Boost: +%s Strength",
+		msgEffectWeather:            "%s's Weather: %s's Strength reduced to %s",
+		msgEffectSpy:                "%s's Spy won the tie",
+		msgEffectSpeed:              "Speed tiebreak: %s (%s) was faster than %s (%s)",
+		msgQueueJoined:              "Joined the matchmaking queue. Waiting for an opponent...",
+		msgQueueAlreadySearching:    "You are already in the matchmaking queue.",
+		msgQueueError:               "Internal error while joining the queue. Please try again.",
+		msgMaintenance:              "Server under maintenance: new games are suspended. Finish your current games and connect to another server.",
+		msgInvalidCommand:           "Invalid command.",
+		msgTradeCompleted:           "Trade completed! You sent %s and received %s.",
+		msgTradeReceivedCard:        "Trade completed! Your previous card was traded for %s.",
+		msgTradeReceivedBundle:      "Trade completed! Your bundle of %s cards was traded for %s.",
+		msgTradeReceivedError:       "Error while processing a received trade.",
+		msgTradeError:               "Internal error in the trading system. Please try again.",
+		msgTradeBusy:                "The trading system is busy. Please try again in a few seconds.",
+		msgTradePending:             "You already have a trade waiting in the queue. Wait for it to complete before offering more cards.",
+		msgTradeNotAllowed:          "You cannot trade cards while in a game or searching for a match.",
+		msgTradeMinDeck:             "Trade refused: your deck would have fewer than %s cards, the minimum to play. Open a pack before trading.",
+		msgTradeCardUsage:           "Invalid command. Use 'TRADE_CARD [number] [WANT card]'.",
+		msgTradeBundleUsage:         "Invalid command. Use 'TRADE_CARDS [n1,n2,...] [WANT card]'.",
+		msgTradeBundleTooLarge:      "You can trade at most %s cards at once.",
+		msgTradeInvalidIndex:        "Invalid card number.",
+		msgTradeIndexOutOfRange:     "Card number is outside your deck.",
+		msgTradeDuplicateIndex:      "Card %s was given more than once.",
+		msgTradeQueuedCard:          "Your card '%s' was added to the trade queue. Waiting for another player...",
+		msgTradeQueuedBundle:        "Your %s cards (%s) were added to the trade queue as a bundle. Waiting for another player with a bundle of the same size...",
+		msgTradeWantNotice:          "The trade only happens with someone who offers %s. If nobody does within %s, the cards return to your deck.",
+		msgTradeWantAny:             "any card",
+		msgTradeWantCard:            "'%s'",
+		msgTradeWantMinForca:        "a card with Strength %s or more",
+		msgTradeWantMissing:         "Name the card you want. Use '... WANT <name>' or '... WANT <minimum Strength>'.",
+		msgTradeWantBadForca:        "Invalid minimum Strength. Use a value between 1 and %s.",
+		msgTradeWantUnknownCard:     "The card '%s' does not exist.",
+		msgTradeQueueError:          "Internal error while accessing the trade queue. Please try again.",
+		msgTradeCorruptedTicket:     "Error! The ticket in the queue was corrupted. Your cards were returned.",
+		msgTradeSelfMatch:           "Trade refused: the available ticket in the queue is yours. Your cards were returned.",
+		msgTradeExpired:             "Nobody offered %s in time. %s returned to your deck.",
+		msgLocaleSet:                "Language set: %s.",
+		msgLocaleUnsupported:        "Unsupported language: %s. Use %s.",
+	},
+}
+
+// supportedLocales lista os idiomas do catálogo, na ordem em que são sugeridos ao jogador.
+var supportedLocales = []string{"pt-BR", "en"}
+
+// LocalizedText é um trecho de mensagem do catálogo: o ID e os parâmetros, já como texto.
+type LocalizedText struct {
+	ID     msgID    `json:"id"`
+	Params []string `json:"params,omitempty"`
+}
+
+// localized monta um trecho do catálogo, formatando os parâmetros como texto.
+func localized(id msgID, params ...any) LocalizedText {
+	text := LocalizedText{ID: id}
+	for _, p := range params {
+		text.Params = append(text.Params, fmt.Sprint(p))
+	}
+	return text
+}
+
+// render formata o trecho no idioma pedido (ou no padrão, se a mensagem não existir nele).
+func (t LocalizedText) render(locale string) string {
+	template, ok := catalog[locale][t.ID]
+	if !ok {
+		template, ok = catalog[defaultLocale][t.ID]
+	}
+	if !ok {
+		return string(t.ID)
+	}
+	args := make([]any, len(t.Params))
+	for i, p := range t.Params {
+		args[i] = p
+	}
+	return fmt.Sprintf(template, args...)
+}
+
+// renderTexts formata e concatena os trechos no idioma pedido.
+func renderTexts(locale string, texts []LocalizedText) string {
+	var b strings.Builder
+	for _, t := range texts {
+		b.WriteString(t.render(locale))
+	}
+	return b.String()
+}
+
+// localizedResultText interpreta o texto do "RESULT|": os trechos em JSON, renderizados no
+// idioma pedido, ou o texto como está (servidores anteriores ao catálogo).
+func localizedResultText(text, locale string) (string, []LocalizedText) {
+	var texts []LocalizedText
+	if !strings.HasPrefix(text, "[") || json.Unmarshal([]byte(text), &texts) != nil {
+		return text, nil
+	}
+	return renderTexts(locale, texts), texts
+}
+
+// normalizeLocale converte o idioma pedido pelo jogador (ex: "en-US", "pt_br") para um do
+// catálogo. Retorna "" se o idioma não for suportado.
+func normalizeLocale(raw string) string {
+	lang, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(strings.TrimSpace(raw), "_", "-")), "-")
+	switch lang {
+	case "pt":
+		return "pt-BR"
+	case "en":
+		return "en"
+	}
+	return ""
+}
+
+// currentLocale é o idioma escolhido pelo jogador (padrão: pt-BR).
+func (p *PlayerState) currentLocale() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.locale == "" {
+		return defaultLocale
+	}
+	return p.locale
+}
+
+// sendLocalized envia ao jogador uma mensagem do catálogo, no idioma dele.
+func (s *Server) sendLocalized(player *PlayerState, id msgID, params ...any) {
+	s.sendWebSocketMessage(player, localized(id, params...).render(player.currentLocale()))
+}
+
+// handleSetLocale processa o comando "SET_LOCALE <idioma>" (pt-BR ou en).
+func (s *Server) handleSetLocale(player *PlayerState, command string) {
+	raw := strings.TrimSpace(strings.TrimPrefix(command, "SET_LOCALE"))
+	locale := normalizeLocale(raw)
+	if locale == "" {
+		s.sendLocalized(player, msgLocaleUnsupported, raw, strings.Join(supportedLocales, ", "))
+		return
+	}
+	player.mu.Lock()
+	player.locale = locale
+	player.mu.Unlock()
+	s.sendLocalized(player, msgLocaleSet, locale)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCatalogComplete(t *testing.T) {
+	for _, locale := range supportedLocales {
+		for id, template := range catalog[defaultLocale] {
+			translated, ok := catalog[locale][id]
+			if !ok {
+				t.Errorf("%s: mensagem %q sem tradução", locale, id)
+				continue
+			}
+			if got, want := strings.Count(translated, "%s"), strings.Count(template, "%s"); got != want {
+				t.Errorf("%s: mensagem %q com %d parâmetro(s), esperado %d", locale, id, got, want)
+			}
+		}
+	}
+}
+
+func TestTradeRepliesLocalized(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string // Prefixos das respostas, em inglês
+	}{
+		{"TRADE_CARD 99", []string{"Card number is outside your deck."}},
+		{"TRADE_CARDS 1,1", []string{"Card 1 was given more than once."}},
+		{"TRADE_CARD 1 WANT Inexistente", []string{"The card 'Inexistente' does not exist."}},
+		{"TRADE_CARD 1 WANT 5", []string{"Your card 'Alice-0' was added to the trade queue.", "The trade only happens with someone who offers a card with Strength 5 or more."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			s.StockSpec = defaultStockSpec()
+			player := newTradingPlayer(s, "Alice")
+			player.locale = "en"
+
+			s.handleTradeCard(player, tt.command)
+			messages := sentMessages(player)
+			if len(messages) != len(tt.want) {
+				t.Fatalf("respostas = %q, esperado %d", messages, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(messages[i], want) {
+					t.Errorf("resposta %d = %q, esperado o prefixo %q", i, messages[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestTradeExpiredLocalized(t *testing.T) {
+	t.Setenv("TRADE_WANT_TTL", "1s")
+	s, mr := newTestServer(t)
+	ticket, _ := json.Marshal(TradeTicket{
+		PlayerName: "Alice",
+		ServerID:   s.ServerID,
+		Cards:      []Card{{Name: "Ghoul", Forca: 1}},
+		Want:       &TradeWant{Name: "Grifo"},
+		QueuedAt:   time.Now().Add(-time.Minute).Unix(),
+	})
+	mr.RPush(tradeQueueKey, string(ticket))
+	s.expireTradeWants(tradeQueueKey)
+
+	pending, _ := mr.List(playerInboxPrefix + "Alice")
+	if len(pending) != 1 {
+		t.Fatalf("a devolução deveria ficar guardada para Alice, pendentes: %q", pending)
+	}
+	for locale, want := range map[string]string{
+		"en":    "Nobody offered 'Grifo' in time.",
+		"pt-BR": "Ninguém ofereceu 'Grifo' a tempo.",
+	} {
+		player := newTestPlayer("Alice")
+		player.locale = locale
+		s.routePlayerMessage(player, pending[0])
+		if messages := sentMessages(player); len(messages) != 1 || !strings.HasPrefix(messages[0], want) {
+			t.Errorf("%s: mensagens = %q, esperado o prefixo %q", locale, messages, want)
+		}
+		if got := len(player.deckSnapshot()); got != 1 {
+			t.Errorf("%s: a carta deveria voltar ao deck, deck com %d cartas", locale, got)
+		}
+	}
+}
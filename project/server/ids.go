@@ -0,0 +1,39 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+)
+
+// randomIDBytes é o tamanho, em bytes aleatórios, dos identificadores gerados (128 bits).
+const randomIDBytes = 16
+
+// newRandomID gera um identificador aleatório, imprevisível, codificado em base64url (sem padding).
+// Deve ser usado para qualquer identificador relevante para segurança (tokens, IDs de partida
+// e de ofertas, valores de lock), no lugar de valores derivados de ServerID + UnixNano.
+func newRandomID() string {
+	b := make([]byte, randomIDBytes)
+	if _, err := rand.Read(b); err != nil {
+		// Só acontece se a fonte de entropia do sistema operacional estiver indisponível.
+		panic("falha ao gerar identificador aleatório: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// inviteCodeAlphabet omite caracteres fáceis de confundir ao digitar (0/O, 1/I/L).
+const inviteCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// inviteCodeLength é o tamanho dos códigos de partida privada (31^6 ≈ 887 milhões de combinações).
+const inviteCodeLength = 6
+
+// newInviteCode gera um código curto e aleatório para ser digitado por outro jogador (ver private.go).
+func newInviteCode() string {
+	b := make([]byte, inviteCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		panic("falha ao gerar código de convite: " + err.Error())
+	}
+	for i := range b {
+		b[i] = inviteCodeAlphabet[int(b[i])%len(inviteCodeAlphabet)]
+	}
+	return string(b)
+}
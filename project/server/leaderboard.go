@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	leaderboardKey          = "leaderboard"
+	leaderboardStatsPrefix  = "leaderboard:stats:"
+	leaderboardDefaultLimit = 10
+	leaderboardMaxLimit     = 100
+)
+
+// Resultados possíveis de uma partida, do ponto de vista de um jogador.
+const (
+	outcomeWin  = "wins"
+	outcomeLoss = "losses"
+	outcomeDraw = "draws"
+)
+
+// SCRIPT LUA
+// Registra o resultado de UMA partida para UM jogador de forma atômica:
+// incrementa o campo correspondente no hash de estatísticas e atualiza o
+// score do jogador no ZSET do ranking (score = número de vitórias).
+//
+// KEYS[1] = o ZSET do ranking (leaderboardKey)
+// KEYS[2] = o hash de estatísticas do jogador (leaderboard:stats:<nome>)
+// ARGV[1] = o nome do jogador
+// ARGV[2] = o campo a incrementar ("wins", "losses" ou "draws")
+var atomicRecordResultScript = redis.NewScript(`
+    local leaderboard_key = KEYS[1]
+    local stats_key = KEYS[2]
+    local player_name = ARGV[1]
+    local outcome = ARGV[2]
+
+    -- 1. Incrementa o contador do resultado
+    redis.call('HINCRBY', stats_key, outcome, 1)
+
+    -- 2. O score do ranking é sempre o total de vitórias
+    local wins = tonumber(redis.call('HGET', stats_key, 'wins') or '0')
+    redis.call('ZADD', leaderboard_key, wins, player_name)
+
+    return wins
+`)
+
+// recordGameResult grava no ranking o resultado de uma partida para um único jogador.
+// Cada servidor registra apenas o SEU jogador local, para que partidas distribuídas
+// não sejam contadas duas vezes.
+func (s *Server) recordGameResult(playerName, outcome string) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	statsKey := leaderboardStatsPrefix + playerName
+
+	err := atomicRecordResultScript.Run(ctx, s.RedisClient, []string{leaderboardKey, statsKey}, playerName, outcome).Err()
+	if err != nil {
+		slog.Error("Erro ao registrar resultado no ranking", "player", playerName, "outcome", outcome, "error", err)
+	}
+}
+
+// outcomeFromResult extrai o resultado ("wins", "losses" ou "draws") de uma mensagem "RESULT|...".
+func outcomeFromResult(resultMsg string) (string, bool) {
+	parts := strings.SplitN(resultMsg, "|", 3)
+	if len(parts) < 2 || parts[0] != "RESULT" {
+		return "", false
+	}
+	switch parts[1] {
+	case "VITÓRIA":
+		return outcomeWin, true
+	case "DERROTA":
+		return outcomeLoss, true
+	case "EMPATE":
+		return outcomeDraw, true
+	}
+	return "", false
+}
+
+// getLeaderboard lê os N primeiros jogadores do ranking, com suas estatísticas detalhadas.
+func (s *Server) getLeaderboard(limit int) ([]LeaderboardEntry, error) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+
+	top, err := s.RedisClient.ZRevRangeWithScores(ctx, leaderboardKey, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]LeaderboardEntry, 0, len(top))
+	for i, z := range top {
+		name, _ := z.Member.(string)
+		entry, err := s.getPlayerStats(name)
+		if err != nil {
+			return nil, err
+		}
+		entry.Rank = i + 1
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// getPlayerStats lê o hash de estatísticas de um jogador.
+func (s *Server) getPlayerStats(playerName string) (LeaderboardEntry, error) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	entry := LeaderboardEntry{PlayerName: playerName}
+
+	stats, err := s.RedisClient.HGetAll(ctx, leaderboardStatsPrefix+playerName).Result()
+	if err != nil {
+		return entry, err
+	}
+	entry.Wins, _ = strconv.Atoi(stats[outcomeWin])
+	entry.Losses, _ = strconv.Atoi(stats[outcomeLoss])
+	entry.Draws, _ = strconv.Atoi(stats[outcomeDraw])
+	return entry, nil
+}
+
+// parseLeaderboardLimit converte o limite informado pelo usuário, aplicando o padrão e o máximo.
+func parseLeaderboardLimit(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return leaderboardDefaultLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("limite inválido: %q", raw)
+	}
+	if limit > leaderboardMaxLimit {
+		limit = leaderboardMaxLimit
+	}
+	return limit, nil
+}
+
+// handleLeaderboardCommand responde ao comando "LEADERBOARD [N]" do cliente com o top N
+// e a posição do próprio jogador.
+func (s *Server) handleLeaderboardCommand(player *PlayerState, command string) {
+	limit, err := parseLeaderboardLimit(strings.TrimPrefix(command, "LEADERBOARD"))
+	if err != nil {
+		s.sendWebSocketMessage(player, "Comando inválido. Use 'LEADERBOARD [N]'.")
+		return
+	}
+
+	entries, err := s.getLeaderboard(limit)
+	if err != nil {
+		slog.Error("Erro ao ler ranking", "player", player.Name, "error", err)
+		s.sendWebSocketMessage(player, "Erro interno ao consultar o ranking. Tente novamente.")
+		return
+	}
+
+	response := fmt.Sprintf("Ranking (top %d):", limit)
+	if len(entries) == 0 {
+		response += " nenhuma partida registrada ainda."
+	}
+	for _, e := range entries {
+		response += fmt.Sprintf("\n%d. %s - %dV/%dD/%dE", e.Rank, e.PlayerName, e.Wins, e.Losses, e.Draws)
+	}
+
+	// Posição do próprio jogador
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	rank, err := s.RedisClient.ZRevRank(ctx, leaderboardKey, player.Name).Result()
+	if err == redis.Nil {
+		response += "\nVocê ainda não está no ranking."
+	} else if err != nil {
+		slog.Error("Erro ao ler posição no ranking", "player", player.Name, "error", err)
+	} else {
+		own, _ := s.getPlayerStats(player.Name)
+		response += fmt.Sprintf("\nSua posição: %d (%dV/%dD/%dE)", rank+1, own.Wins, own.Losses, own.Draws)
+	}
+
+	s.sendWebSocketMessage(player, response)
+}
+
+// handleGetLeaderboard implementa o endpoint REST GET /api/v1/leaderboard?limit=N.
+func (s *Server) handleGetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLeaderboardLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "Parâmetro 'limit' inválido")
+		return
+	}
+
+	entries, err := s.getLeaderboard(limit)
+	if err != nil {
+		slog.Error("Erro ao ler ranking via REST", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Erro interno ao consultar o ranking")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(LeaderboardResponse{Entries: entries})
+}
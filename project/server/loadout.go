@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// loadoutKeyPrefix guarda, por jogador, o loadout escolhido com "SET_LOADOUT" (player:loadout:<nome>),
+// como uma lista JSON de nomes de cartas. Guardar nomes (e não posições) mantém o loadout válido
+// quando o deck muda de ordem, e permite recuperá-lo ao reconectar ou entrar por outro servidor.
+const loadoutKeyPrefix = "player:loadout:"
+
+// handleSetLoadout processa o comando "SET_LOADOUT <n1,n2,...>": as cartas escolhidas (números
+// de "VIEW_DECK") passam a ser as únicas de onde a mão das partidas é sorteada.
+// "SET_LOADOUT" sem números volta a usar o deck inteiro.
+func (s *Server) handleSetLoadout(player *PlayerState, command string) {
+	list := strings.TrimSpace(strings.TrimPrefix(command, "SET_LOADOUT"))
+
+	player.mu.Lock()
+	var selected []Card
+	if list != "" {
+		var errMsg string
+		selected, errMsg = parseLoadout(list, player.Deck, s.Config.HandSize)
+		if errMsg != "" {
+			player.mu.Unlock()
+			s.sendWebSocketMessage(player, errMsg)
+			return
+		}
+	}
+	player.loadout = cardNames(selected)
+	player.mu.Unlock()
+
+	if err := s.saveLoadout(player.Name, cardNames(selected)); err != nil {
+		slog.Error("Erro ao salvar loadout", "player", player.Name, "error", err)
+		s.sendWebSocketMessage(player, "Loadout definido, mas não foi possível salvá-lo para as próximas conexões.")
+		return
+	}
+
+	if len(selected) == 0 {
+		slog.Info("Loadout removido", "event", "loadout_cleared", "player", player.Name)
+		s.sendWebSocketMessage(player, "Loadout removido. As mãos voltam a ser sorteadas do deck inteiro.")
+		return
+	}
+	slog.Info("Loadout definido", "event", "loadout_set", "player", player.Name, "cards", len(selected))
+	s.sendWebSocketMessage(player, fmt.Sprintf("Loadout definido com %d cartas: %s.", len(selected), strings.Join(cardNames(selected), ", ")))
+}
+
+// parseLoadout valida os números das cartas contra o deck e retorna as cartas escolhidas,
+// ou uma mensagem de erro. O loadout precisa de pelo menos handSize cartas para montar uma mão.
+func parseLoadout(list string, deck []Card, handSize int) ([]Card, string) {
+	seen := make(map[int]bool)
+	var selected []Card
+	for _, raw := range strings.Split(list, ",") {
+		index, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, "Comando inválido. Use 'SET_LOADOUT [n1,n2,...]'."
+		}
+		if index < 1 || index > len(deck) {
+			return nil, "Número da carta fora do alcance do seu deck."
+		}
+		if seen[index] {
+			return nil, fmt.Sprintf("A carta %d foi informada mais de uma vez.", index)
+		}
+		seen[index] = true
+		selected = append(selected, deck[index-1])
+	}
+	if len(selected) < handSize {
+		return nil, fmt.Sprintf("O loadout precisa de pelo menos %d cartas.", handSize)
+	}
+	return selected, ""
+}
+
+// saveLoadout grava o loadout no Redis; um loadout vazio apaga a chave.
+func (s *Server) saveLoadout(playerName string, names []string) error {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	if len(names) == 0 {
+		return s.RedisClient.Del(ctx, loadoutKeyPrefix+playerName).Err()
+	}
+	raw, _ := json.Marshal(names)
+	return s.RedisClient.Set(ctx, loadoutKeyPrefix+playerName, raw, 0).Err()
+}
+
+// loadLoadout lê o loadout salvo do jogador (nil se não houver).
+func (s *Server) loadLoadout(playerName string) []string {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	raw, err := s.RedisClient.Get(ctx, loadoutKeyPrefix+playerName).Result()
+	if err != nil {
+		if err != redis.Nil {
+			slog.Error("Erro ao ler loadout", "player", playerName, "error", err)
+		}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal([]byte(raw), &names); err != nil {
+		slog.Error("Loadout corrompido no Redis", "player", playerName, "error", err)
+		return nil
+	}
+	return names
+}
+
+// matchPool retorna as cartas de onde a mão da partida é sorteada: as do loadout que ainda
+// estão no deck (o deck pode ter mudado por trocas desde o SET_LOADOUT) ou, sem loadout,
+// o deck inteiro. Se sobrarem menos cartas do loadout do que o tamanho da mão, o deck
+// inteiro é usado e o jogador é avisado.
+func (s *Server) matchPool(player *PlayerState) []Card {
+	player.mu.Lock()
+	deck := append([]Card(nil), player.Deck...) // Cópia: o deck pode mudar enquanto a mão é sorteada
+	loadout := player.loadout
+	player.mu.Unlock()
+	if len(loadout) == 0 {
+		return deck
+	}
+
+	wanted := make(map[string]int, len(loadout))
+	for _, name := range loadout {
+		wanted[name]++
+	}
+	var pool []Card
+	for _, card := range deck {
+		if wanted[card.Name] > 0 {
+			wanted[card.Name]--
+			pool = append(pool, card)
+		}
+	}
+
+	if len(pool) < s.Config.HandSize {
+		slog.Warn("Loadout sem cartas suficientes no deck; usando o deck inteiro", "event", "loadout_fallback",
+			"player", player.Name, "loadout", len(loadout), "available", len(pool))
+		s.sendWebSocketMessage(player, "As cartas do seu loadout não estão mais no deck (trocas?). Usando o deck inteiro nesta partida.")
+		return deck
+	}
+	if missing := len(loadout) - len(pool); missing > 0 {
+		s.sendWebSocketMessage(player, fmt.Sprintf("%d carta(s) do seu loadout não estão mais no deck e ficaram de fora desta partida.", missing))
+	}
+	return pool
+}
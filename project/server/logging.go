@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// setupLogger configura o logger estruturado (log/slog) padrão do servidor.
+// Todas as entradas carregam o atributo "server_id", o que permite correlacionar
+// eventos de uma mesma partida entre o P1-Server e o P2-Server.
+//
+// LOG_LEVEL  = debug | info | warn | error (padrão: info)
+// LOG_FORMAT = text | json (padrão: text)
+func setupLogger(serverID string) error {
+	var level slog.Level
+	switch strings.ToLower(os.Getenv("LOG_LEVEL")) {
+	case "", "info":
+		level = slog.LevelInfo
+	case "debug":
+		level = slog.LevelDebug
+	case "warn":
+		level = slog.LevelWarn
+	case "error":
+		level = slog.LevelError
+	default:
+		return fmt.Errorf("LOG_LEVEL inválido: %q", os.Getenv("LOG_LEVEL"))
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(os.Getenv("LOG_FORMAT")) {
+	case "", "text":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("LOG_FORMAT inválido: %q", os.Getenv("LOG_FORMAT"))
+	}
+
+	slog.SetDefault(slog.New(handler).With("server_id", serverID))
+	return nil
+}
+
+// fatal registra um erro e encerra o processo (equivalente estruturado ao log.Fatalf).
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
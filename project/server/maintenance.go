@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// Em manutenção (POST /api/v1/maintenance, para deploys sem abandono de partidas), o servidor
+// esvazia aos poucos: recusa novas conexões ("MAINTENANCE"), novas entradas na fila de
+// matchmaking e novas partidas, enquanto as partidas em andamento terminam normalmente.
+//
+//   - o matchmaker deste servidor (clássico e FFA) deixa de disputar o lock da fila;
+//   - os demais servidores tratam este como lotado (server:maintenance:<ServerID>, ver
+//     fullServers): os jogadores dele que já estavam na fila não são pareados e a busca
+//     termina com NO_MATCH_FOUND;
+//   - o /readyz responde 503, para que o balanceador pare de mandar conexões para cá.
+//
+// A marca no Redis é renovada com o registro do servidor (serverRegistryLoop) e expira sozinha
+// se ele cair. O modo não sobrevive a um reinício: o servidor sobe sempre fora de manutenção.
+
+// serverMaintenancePrefix marca, por ServerID, que o servidor está em manutenção.
+const serverMaintenancePrefix = "server:maintenance:"
+
+// maintenanceMessage é a resposta a uma nova conexão durante a manutenção.
+const maintenanceMessage = "MAINTENANCE"
+
+// MaintenanceRequest é o corpo de POST /api/v1/maintenance.
+type MaintenanceRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// MaintenanceResponse é a resposta de POST /api/v1/maintenance.
+type MaintenanceResponse struct {
+	ServerID    string `json:"server_id"`
+	Maintenance bool   `json:"maintenance"`
+	ActiveGames int    `json:"active_games"` // Partidas deste servidor ainda em andamento
+}
+
+// inMaintenance informa se o servidor está em manutenção.
+func (s *Server) inMaintenance() bool {
+	return s.maintenance.Load()
+}
+
+// publishMaintenance grava (ou apaga) a marca de manutenção deste servidor no Redis.
+func (s *Server) publishMaintenance(enabled bool) error {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	key := serverMaintenancePrefix + s.ServerID
+	if enabled {
+		return s.RedisClient.Set(ctx, key, "1", serverRegistryTTL).Err()
+	}
+	return s.RedisClient.Del(ctx, key).Err()
+}
+
+// handleMaintenance implementa o endpoint de administração POST /api/v1/maintenance
+// ({"enabled": true} ou {"enabled": false}).
+func (s *Server) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	var req MaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, `Requisição inválida: use {"enabled": true} ou {"enabled": false}`)
+		return
+	}
+	enabled := *req.Enabled
+
+	if err := s.publishMaintenance(enabled); err != nil {
+		slog.Error("Erro ao publicar o modo de manutenção", "enabled", enabled, "error", err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Erro interno ao alterar o modo de manutenção")
+		return
+	}
+	if s.maintenance.Swap(enabled) != enabled {
+		if enabled {
+			slog.Warn("Servidor em manutenção: novas conexões e partidas suspensas.", "event", "maintenance_enabled",
+				"active_games", s.activeGameCount())
+		} else {
+			slog.Info("Servidor saiu da manutenção.", "event", "maintenance_disabled")
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MaintenanceResponse{ServerID: s.ServerID, Maintenance: enabled, ActiveGames: s.activeGameCount()})
+}
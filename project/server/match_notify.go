@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// matchNotifiedPrefix registra, por servidor, as notificações de partida já processadas.
+	matchNotifiedPrefix = "match:notified:"
+	// matchNotifiedTTL cobre com folga qualquer retentativa da mesma notificação.
+	matchNotifiedTTL = 10 * time.Minute
+	// serverHTTPMaxIdlePerHost é o número de conexões mantidas abertas com cada servidor remoto.
+	// O padrão do net/http (2) fecha conexões a cada rajada de pareamentos.
+	serverHTTPMaxIdlePerHost = 16
+	// serverHTTPIdleTimeout fecha as conexões ociosas com servidores remotos.
+	serverHTTPIdleTimeout = 90 * time.Second
+)
+
+// newServerHTTPClient cria o cliente REST servidor-servidor, compartilhado por todas as chamadas
+// (Server.HTTPClient). O timeout vale para a requisição inteira, inclusive a leitura da resposta:
+// um servidor remoto que aceita a conexão mas nunca responde não prende a goroutine de quem chamou
+// (ex: o matchmaker). A conexão e o cabeçalho da resposta têm limites próprios, menores ou iguais.
+func newServerHTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = serverHTTPMaxIdlePerHost
+	transport.IdleConnTimeout = serverHTTPIdleTimeout
+	transport.ResponseHeaderTimeout = timeout
+	transport.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// claimMatchNotification marca a partida como processada por ESTE servidor (SETNX com TTL).
+// Retorna false se a mesma notificação já foi processada antes (ex: retentativa após um timeout
+// em que a primeira requisição na verdade teve sucesso), e o jogo NÃO deve ser criado de novo.
+func (s *Server) claimMatchNotification(gameID string) (bool, error) {
+	key := fmt.Sprintf("%s%s:%s", matchNotifiedPrefix, gameID, s.ServerID)
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	return s.RedisClient.SetNX(ctx, key, time.Now().Unix(), matchNotifiedTTL).Result()
+}
+
+// postMatchNotification envia uma notificação de partida (POST JSON) para um servidor remoto,
+// com até NotifyMaxAttempts tentativas, timeout por tentativa e backoff exponencial entre elas.
+// Como o endpoint remoto é idempotente pelo game_id, repetir uma tentativa que na verdade
+// teve sucesso não cria uma segunda partida.
+// Não deve ser chamada com nenhum lock do Redis em mãos: o backoff dorme entre as tentativas.
+func (s *Server) postMatchNotification(remoteServerID, path, gameID string, payload interface{}) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	backoff := s.Config.NotifyBackoff
+	var lastErr error
+	for attempt := 1; attempt <= s.Config.NotifyMaxAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		lastErr = s.tryPostMatchNotification(remoteServerID, path, jsonData)
+		if lastErr == nil {
+			return nil
+		}
+		slog.Warn("Falha ao notificar servidor remoto", "event", "match_notify_retry", "game_id", gameID,
+			"remote_server_id", remoteServerID, "attempt", attempt, "max_attempts", s.Config.NotifyMaxAttempts, "error", lastErr)
+	}
+	return fmt.Errorf("notificação falhou após %d tentativas: %w", s.Config.NotifyMaxAttempts, lastErr)
+}
+
+// tryPostMatchNotification faz uma única tentativa de notificação.
+func (s *Server) tryPostMatchNotification(remoteServerID, path string, jsonData []byte) error {
+	// O endereço é resolvido a cada tentativa: o servidor remoto pode ter reiniciado em outro endereço.
+	addr, err := s.lookupServerAddr(remoteServerID)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("http://%s%s", addr, path)
+
+	// Com o contexto do servidor, o encerramento interrompe uma tentativa em andamento
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr APIError
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Code != "" {
+			return fmt.Errorf("servidor remoto retornou status %d (%s: %s)", resp.StatusCode, apiErr.Code, apiErr.Error)
+		}
+		return fmt.Errorf("servidor remoto retornou status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// requeueTickets devolve tickets à fila de matchmaking, mantendo o timestamp original
+// (e, portanto, a posição na fila), com o bônus QUEUE_REQUEUE_BONUS (ver queue_priority.go).
+// Usado como compensação quando uma partida é abortada antes de começar para esses jogadores.
+func (s *Server) requeueTickets(queueKey string, tickets ...MatchmakingTicket) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	for _, t := range tickets {
+		ticketJSON, _ := json.Marshal(t)
+		err := s.RedisClient.ZAdd(ctx, queueKey, &redis.Z{Score: s.requeueScore(t), Member: string(ticketJSON)}).Err()
+		if err != nil {
+			slog.Error("Erro ao devolver ticket à fila", "player", t.PlayerName, "queue", queueKey, "error", err)
+			continue
+		}
+		slog.Info("Ticket devolvido à fila após partida abortada", "event", "ticket_requeued", "player", t.PlayerName, "queue", queueKey)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startSlowServer registra serverID no Redis apontando para um servidor que aceita a conexão
+// mas só responde quando o teste termina. Retorna o contador de requisições recebidas.
+func startSlowServer(t *testing.T, s *Server, serverID string) *int32 {
+	t.Helper()
+	var calls int32
+	release := make(chan struct{})
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+	}))
+	t.Cleanup(hs.Close)
+	t.Cleanup(func() { close(release) }) // Roda antes do hs.Close, que espera os handlers
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	if err := s.RedisClient.Set(ctx, serverRegistryPrefix+serverID, strings.TrimPrefix(hs.URL, "http://"), 0).Err(); err != nil {
+		t.Fatalf("registro do servidor: %v", err)
+	}
+	return &calls
+}
+
+func TestMatchNotificationTimesOutOnSlowServer(t *testing.T) {
+	t.Setenv("NOTIFY_TIMEOUT", "100ms")
+	t.Setenv("NOTIFY_MAX_ATTEMPTS", "1")
+	s, _ := newTestServer(t)
+	calls := startSlowServer(t, s, "Server-Slow")
+
+	start := time.Now()
+	err := s.callRemoteMatchNotification("Server-Slow", MatchNotificationRequest{GameID: "g1", Player1Name: "Alice", Player2Name: "Bob"})
+	elapsed := time.Since(start)
+	if err == nil {
+		t.Fatal("a notificação a um servidor que não responde deveria falhar")
+	}
+	if elapsed > time.Second {
+		t.Errorf("a chamada levou %s, esperado voltar perto do NOTIFY_TIMEOUT (%s)", elapsed, s.Config.NotifyTimeout)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("o servidor remoto recebeu %d requisição(ões), esperado 1", got)
+	}
+}
+
+func TestMatchNotificationRetriesSlowServerWithinBudget(t *testing.T) {
+	t.Setenv("NOTIFY_TIMEOUT", "100ms")
+	t.Setenv("NOTIFY_MAX_ATTEMPTS", "3")
+	t.Setenv("NOTIFY_BACKOFF", "10ms")
+	s, _ := newTestServer(t)
+	calls := startSlowServer(t, s, "Server-Slow")
+
+	// Cada tentativa tem o próprio timeout: as três terminam, com o backoff, bem antes de 1s
+	start := time.Now()
+	err := s.callRemoteMatchNotification("Server-Slow", MatchNotificationRequest{GameID: "g1", Player1Name: "Alice", Player2Name: "Bob"})
+	if err == nil {
+		t.Fatal("a notificação a um servidor que não responde deveria falhar")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("as tentativas levaram %s, esperado perto de 3 x NOTIFY_TIMEOUT", elapsed)
+	}
+	if got := atomic.LoadInt32(calls); got != 3 {
+		t.Errorf("o servidor remoto recebeu %d requisição(ões), esperado 3", got)
+	}
+}
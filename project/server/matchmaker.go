@@ -1,13 +1,10 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-	"net/http"
-	"strings"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -17,51 +14,134 @@ import (
 const (
 	matchmakingQueueKey = "matchmaking_queue"
 	matchmakingLockKey  = "lock:matchmaker"
+	// recentOpponentsPrefix é o SET, por jogador, dos oponentes das partidas recentes (TTL RECENT_OPPONENT_WINDOW).
+	recentOpponentsPrefix = "recent:"
+	// matchmakingScanSize é quantos tickets do início da fila o matchmaker considera a cada rodada.
+	matchmakingScanSize = 20
+	// matchAffinityLookahead é quantos tickets do início da fila são examinados em busca de um par
+	// do mesmo servidor (partida local, sem REST nem Pub/Sub entre servidores).
+	matchAffinityLookahead = 5
 )
 
+// SCRIPT LUA
+// Enfileira o ticket do jogador removendo, na mesma operação, qualquer ticket anterior
+// com o mesmo nome (ex: um FIND_MATCH repetido após reconectar). Assim um jogador nunca
+// tem dois tickets vivos na fila e não pode ser pareado contra si mesmo.
+//
+// KEYS[1] = a fila de matchmaking (matchmakingQueueKey ou ffaQueueKey)
+// ARGV[1] = o nome do jogador
+// ARGV[2] = o score do novo ticket (timestamp ajustado pela prioridade, ver queueScore)
+// ARGV[3] = o novo ticket (JSON)
+var atomicEnqueueTicketScript = redis.NewScript(`
+    local removed = 0
+    for _, member in ipairs(redis.call('ZRANGE', KEYS[1], 0, -1)) do
+        local ok, ticket = pcall(cjson.decode, member)
+        if ok and type(ticket) == 'table' and ticket.player_name == ARGV[1] then
+            redis.call('ZREM', KEYS[1], member)
+            removed = removed + 1
+        end
+    end
+    redis.call('ZADD', KEYS[1], ARGV[2], ARGV[3])
+    return removed
+`)
+
+// SCRIPT LUA
+// Retira da fila os dois tickets do par escolhido, somente se AMBOS ainda estiverem nela.
+// Com ZREM simples, um ticket já retirado por outro caminho (timeout, outro servidor) fazia
+// o outro ticket sair da fila sem partida. Retorna 2 se retirou o par, 0 caso contrário.
+//
+// KEYS[1] = a fila clássica (matchmakingQueueKey)
+// ARGV[1] = o ticket do P1 (JSON)
+// ARGV[2] = o ticket do P2 (JSON)
+var atomicRemovePairScript = redis.NewScript(`
+    if not redis.call('ZSCORE', KEYS[1], ARGV[1]) or not redis.call('ZSCORE', KEYS[1], ARGV[2]) then
+        return 0
+    end
+    return redis.call('ZREM', KEYS[1], ARGV[1], ARGV[2])
+`)
+
 // addToMatchmakingQueue adiciona o jogador à fila de matchmaking distribuída (Redis ZSET).
-func (s *Server) addToMatchmakingQueue(player *PlayerState) {
-	ctx := context.Background()
+// queueKey é a fila clássica 1v1 (matchmakingQueueKey) ou a fila FFA (ffaQueueKey).
+func (s *Server) addToMatchmakingQueue(player *PlayerState, queueKey string) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+
+	if s.inMaintenance() {
+		s.sendLocalized(player, msgMaintenance)
+		return
+	}
 
-	// ATUALIZA ESTADO DO JOGADOR
+	// Deck precisa ter o mínimo de cartas para montar a mão da partida
 	player.mu.Lock()
+	deckSize := len(player.Deck)
+	player.mu.Unlock()
+	if deckSize < s.Config.MinDeckSize {
+		slog.Info("Jogador recusado na fila: deck pequeno demais", "event", "deck_too_small", "player", player.Name,
+			"deck_size", deckSize, "min_deck_size", s.Config.MinDeckSize)
+		s.sendWebSocketMessage(player, fmt.Sprintf("DECK_TOO_SMALL|%d", s.Config.MinDeckSize))
+		return
+	}
+
+	// ATUALIZA ESTADO DO JOGADOR (e descarta uma oferta de revanche pendente)
+	player.mu.Lock()
+	if player.State == "Searching" {
+		player.mu.Unlock()
+		s.sendLocalized(player, msgQueueAlreadySearching)
+		return
+	}
 	player.State = "Searching"
+	player.rematchOffer = nil
 	player.mu.Unlock()
+	s.cancelPrivateMatch(player) // Na fila, o convite de partida privada deixa de valer
 
 	// Cria o ticket de matchmaking
 	ticket := MatchmakingTicket{
 		PlayerName: player.Name,
 		ServerID:   s.ServerID,
 		Timestamp:  time.Now().Unix(),
+		Priority:   s.queuePriority(player.Name),
 	}
 	ticketJson, _ := json.Marshal(ticket)
 
-	// Adiciona o jogador à fila (ZSET) com o timestamp como score (para FIFO)
-	_, err := s.RedisClient.ZAdd(ctx, matchmakingQueueKey, &redis.Z{
-		Score:  float64(ticket.Timestamp),
-		Member: string(ticketJson),
-	}).Result()
+	// Adiciona o jogador à fila (ZSET) com o timestamp ajustado pela prioridade como score
+	// (FIFO entre tickets de mesma prioridade), descartando tickets antigos do mesmo jogador
+	stale, err := atomicEnqueueTicketScript.Run(ctx, s.RedisClient, []string{queueKey}, player.Name, ticket.queueScore(), string(ticketJson)).Int()
+	if err == nil && stale > 0 {
+		slog.Warn("Tickets antigos do jogador removidos da fila", "event", "stale_ticket_removed", "player", player.Name, "queue", queueKey, "removed", stale)
+	}
 
 	if err != nil {
-		log.Printf("Erro ao adicionar %s à fila de matchmaking: %v", player.Name, err)
-		s.sendWebSocketMessage(player, "Erro interno ao entrar na fila. Tente novamente.")
+		slog.Error("Erro ao adicionar jogador à fila de matchmaking", "player", player.Name, "error", err)
+		s.sendLocalized(player, msgQueueError)
 		player.mu.Lock()
-		player.State = "Menu" // Reverte o estado
+		player.State = player.idleState() // Reverte o estado
 		player.mu.Unlock()
 		return
 	}
 
-	s.sendWebSocketMessage(player, "Entrou na fila de matchmaking. Aguardando oponente...")
+	s.sendLocalized(player, msgQueueJoined)
+	s.audit(player.Name, auditFindMatch, "", queueKey)
+	// Informa ao cliente o tempo máximo de busca configurado neste servidor
+	s.sendWebSocketMessage(player, fmt.Sprintf("SEARCH_TIMER|%d", int(s.Config.MatchmakingTimeout.Seconds())))
 
 	// Inicia um timeout para o jogador
-	go s.matchmakingTimeout(player, matchmakingTimeout)
+	go s.matchmakingTimeout(player, s.Config.MatchmakingTimeout, queueKey, string(ticketJson))
+	// Informa periodicamente a posição do jogador na fila
+	go s.queueStatusLoop(player, queueKey, string(ticketJson), time.Now())
 }
 
-// matchmakingTimeout remove o jogador da fila se o tempo esgotar.
-func (s *Server) matchmakingTimeout(player *PlayerState, timeout time.Duration) {
+// matchmakingTimeout remove o ticket do jogador da fila se o tempo esgotar.
+// Remove apenas o ticket criado por ESTA busca: um ticket mais novo do mesmo jogador
+// (ex: de uma nova conexão após reconectar) continua na fila.
+func (s *Server) matchmakingTimeout(player *PlayerState, timeout time.Duration, queueKey, ticketJSON string) {
 	time.Sleep(timeout)
 
-	ctx := context.Background()
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+
+	// Trava os comandos do jogador até o fim da transição (inclusive a partida contra o bot)
+	player.cmdMu.Lock()
+	defer player.cmdMu.Unlock()
 
 	player.mu.Lock()
 	// Se o jogador não estiver mais "Searching", ele já foi pareado.
@@ -69,115 +149,266 @@ func (s *Server) matchmakingTimeout(player *PlayerState, timeout time.Duration)
 		player.mu.Unlock()
 		return
 	}
-	// Se ainda estiver "Searching", reverte para "Menu"
-	player.State = "Menu"
+	// Se ainda estiver "Searching", reverte para "Menu" (ou "InGame", se estiver em outras partidas)
+	player.State = player.idleState()
 	player.mu.Unlock()
 
-	// Tenta remover o jogador da fila.
-	members, err := s.RedisClient.ZRange(ctx, matchmakingQueueKey, 0, -1).Result()
+	// Tenta remover o ticket da fila (ele pode ter sido devolvido com o mesmo conteúdo por requeueTickets).
+	removed, err := s.RedisClient.ZRem(ctx, queueKey, ticketJSON).Result()
 	if err != nil {
-		log.Printf("Erro ao ler fila para timeout de %s: %v", player.Name, err)
+		slog.Error("Erro ao remover ticket da fila por timeout", "player", player.Name, "error", err)
 		return
 	}
 
-	var ticketToRemove string
-	for _, member := range members {
-		if strings.Contains(member, fmt.Sprintf(`"player_name":"%s"`, player.Name)) {
-			ticketToRemove = member
-			break
+	if removed > 0 {
+		mode := gameModeClassic
+		if queueKey == ffaQueueKey {
+			mode = gameModeFFA
 		}
+		matchmakingResultsTotal.WithLabelValues(matchModeLabel(mode), "timeout").Inc()
 	}
 
-	if ticketToRemove != "" {
-		removed, _ := s.RedisClient.ZRem(ctx, matchmakingQueueKey, ticketToRemove).Result()
-		if removed > 0 {
-			// Se foi removido, significa que o timeout ocorreu e ele não foi pareado.
-			s.sendWebSocketMessage(player, "NO_MATCH_FOUND")
-			log.Printf("Jogador %s removido da fila por timeout.", player.Name)
-		}
+	if removed > 0 && s.Config.BotFallback && queueKey == matchmakingQueueKey && !s.inMaintenance() {
+		// Timeout sem oponente: joga contra um bot do servidor.
+		slog.Info("Jogador removido da fila por timeout. Iniciando partida contra bot.", "event", "matchmaking_timeout", "player", player.Name)
+		s.startBotGame(player)
+	} else if removed > 0 {
+		// Se foi removido, significa que o timeout ocorreu e ele não foi pareado.
+		s.sendWebSocketMessage(player, "NO_MATCH_FOUND")
+		slog.Info("Jogador removido da fila por timeout.", "event", "matchmaking_timeout", "player", player.Name)
 	}
 }
 
 // distributedMatchmaker é a goroutine que roda em cada servidor para tentar parear jogadores.
 func (s *Server) distributedMatchmaker() {
-	ctx := context.Background()
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		// Tenta adquirir um lock distribuído
-		lockValue := fmt.Sprintf("%s-%d", s.ServerID, time.Now().UnixNano())
-		lockTimeout := 1 * time.Second
+		if s.shuttingDown() {
+			return
+		}
+		if s.inMaintenance() {
+			continue // Os outros servidores seguem pareando (ver maintenance.go)
+		}
+		s.matchmakerRound()
+	}
+}
 
-		ok, err := s.RedisClient.SetNX(ctx, matchmakingLockKey, lockValue, lockTimeout).Result()
-		if err != nil {
-			log.Printf("Erro ao tentar adquirir lock do matchmaker: %v", err)
+// matchmakerRound faz uma rodada de pareamento da fila clássica, se conseguir o lock distribuído.
+// Sem o lock (outro matchmaker está rodando), não faz nada.
+func (s *Server) matchmakerRound() {
+	// Tenta adquirir um lock distribuído
+	lockValue := newRandomID()
+	lockTimeout := s.Config.MatchmakerLockTTL
+
+	ctx, cancel := s.redisCtx()
+	ok, err := s.RedisClient.SetNX(ctx, matchmakingLockKey, lockValue, lockTimeout).Result()
+	if err != nil {
+		cancel()
+		slog.Error("Erro ao tentar adquirir lock do matchmaker", "error", err)
+		return
+	}
+
+	if !ok {
+		// Outro matchmaker está rodando.
+		cancel()
+		return
+	}
+
+	stopRenewal := s.keepLockAlive(matchmakingLockKey, lockValue, lockTimeout)
+	s.tryPairPlayers(ctx)
+	stopRenewal()
+	cancel()
+
+	// Libera o lock (somente se ainda for nosso). Contexto próprio: o lock é liberado
+	// mesmo que a rodada tenha esgotado o seu.
+	ctx, cancel = s.redisCtx()
+	s.releaseLock(ctx, matchmakingLockKey, lockValue)
+	cancel()
+}
+
+// tryPairPlayers lê o início da fila clássica e pareia dois jogadores, se possível.
+// Deve ser chamada com o lock do matchmaker adquirido.
+func (s *Server) tryPairPlayers(ctx context.Context) {
+	// Tenta pegar os primeiros jogadores da fila
+	members, err := s.RedisClient.ZRange(ctx, matchmakingQueueKey, 0, matchmakingScanSize-1).Result()
+	if err != nil {
+		slog.Error("Erro ao ler fila de matchmaking", "error", err)
+		return
+	}
+
+	if len(members) < 2 {
+		// Não há jogadores suficientes para parear
+		return
+	}
+
+	tickets := make([]MatchmakingTicket, 0, len(members))
+	ticketJsons := make([]string, 0, len(members))
+	for _, member := range members {
+		var ticket MatchmakingTicket
+		if err := json.Unmarshal([]byte(member), &ticket); err != nil {
+			// Um ticket corrompido ficaria para sempre no início da fila: sai dela (dead letter)
+			if removed, zerr := s.RedisClient.ZRem(ctx, matchmakingQueueKey, member).Result(); zerr == nil && removed > 0 {
+				s.deadLetterTicket(ctx, matchmakingQueueKey, member, err)
+			}
 			continue
 		}
+		tickets = append(tickets, ticket)
+		ticketJsons = append(ticketJsons, member)
+	}
+	if len(tickets) < 2 {
+		return
+	}
 
-		if !ok {
-			// Outro matchmaker está rodando.
-			continue
+	// Dois tickets do mesmo jogador (ex: FIND_MATCH repetido após reconectar): descarta o
+	// mais antigo, que é o obsoleto, em vez de parear o jogador contra si mesmo.
+	if tickets[0].PlayerName == tickets[1].PlayerName {
+		slog.Warn("Ticket duplicado na fila; removendo o mais antigo", "event", "stale_ticket_removed", "player", tickets[0].PlayerName)
+		s.RedisClient.ZRem(ctx, matchmakingQueueKey, ticketJsons[0])
+		return
+	}
+
+	// Jogadores de servidores lotados (MAX_CONCURRENT_GAMES) continuam na fila até haver vaga
+	full := s.fullServers(ctx, tickets)
+	available := make([]MatchmakingTicket, 0, len(tickets))
+	availableJsons := make([]string, 0, len(tickets))
+	for k, t := range tickets {
+		if !full[t.ServerID] {
+			available = append(available, t)
+			availableJsons = append(availableJsons, ticketJsons[k])
 		}
+	}
+	if len(available) < len(tickets) {
+		slog.Debug("Jogadores de servidores lotados mantidos na fila", "event", "server_full_skipped",
+			"skipped", len(tickets)-len(available))
+	}
+	if len(available) < 2 {
+		return
+	}
+	tickets, ticketJsons = available, availableJsons
+
+	// Evita repetir o oponente da partida anterior, se houver alternativa na fila
+	i, j := s.pickMatchPair(ctx, tickets)
+	p1Ticket, p2Ticket := tickets[i], tickets[j]
+	p1TicketJson, p2TicketJson := ticketJsons[i], ticketJsons[j]
 
-		// Garante a liberação do lock
-		defer func(val string) {
-			script := redis.NewScript(`
-				if redis.call("get", KEYS[1]) == ARGV[1] then
-					return redis.call("del", KEYS[1])
-				else
-					return 0
-				end
-			`)
-			script.Run(context.Background(), s.RedisClient, []string{matchmakingLockKey}, val)
-		}(lockValue)
-
-		// Tenta pegar os dois primeiros jogadores da fila
-		members, err := s.RedisClient.ZRange(ctx, matchmakingQueueKey, 0, 1).Result()
+	// Remove os dois jogadores da fila atomicamente: ou os dois, ou nenhum
+	removed, err := atomicRemovePairScript.Run(ctx, s.RedisClient, []string{matchmakingQueueKey}, p1TicketJson, p2TicketJson).Int()
+	if err != nil || removed != 2 {
+		// Se não removeu 2, outro servidor (ou o timeout) já retirou um deles; o outro continua na fila
 		if err != nil {
-			log.Printf("Erro ao ler fila de matchmaking: %v", err)
-			continue
+			slog.Error("Erro ao remover par da fila de matchmaking", "error", err)
 		}
+		return
+	}
 
-		if len(members) < 2 {
-			// Não há jogadores suficientes para parear
-			continue
-		}
+	// Gera o ID de correlação da partida, propagado para os dois servidores
+	gameID := newRandomID()
 
-		// Jogadores encontrados
-		p1TicketJson := members[0]
-		p2TicketJson := members[1]
+	observeMatchWait(gameModeClassic, p1Ticket, p2Ticket)
+	slog.Info("Pareamento confirmado", "event", "match_paired", "game_id", gameID,
+		"player1", p1Ticket.PlayerName, "server1_id", p1Ticket.ServerID,
+		"player2", p2Ticket.PlayerName, "server2_id", p2Ticket.ServerID)
 
-		var p1Ticket, p2Ticket MatchmakingTicket
-		if err := json.Unmarshal([]byte(p1TicketJson), &p1Ticket); err != nil {
-			log.Printf("Erro ao desserializar ticket 1: %v", err)
-			continue
-		}
-		if err := json.Unmarshal([]byte(p2TicketJson), &p2Ticket); err != nil {
-			log.Printf("Erro ao desserializar ticket 2: %v", err)
-			continue
+	// Notifica os servidores envolvidos para iniciar a partida.
+	// Roda fora do lock: as retentativas com backoff podem levar alguns segundos.
+	go s.notifyMatchStart(gameID, p1Ticket, p2Ticket)
+}
+
+// pickMatchPair escolhe, entre os tickets em ordem de chegada, o primeiro par de jogadores que não
+// se enfrentou recentemente. Os mais antigos têm prioridade: cada ticket tenta os seguintes antes de
+// o próximo ser considerado. Se todos os pares forem repetidos (fila pequena, sem alternativa), os
+// dois primeiros são pareados mesmo assim, para que ninguém fique esperando indefinidamente.
+// Antes disso, um par do mesmo servidor no início da fila tem preferência (ver pickSameServerPair).
+func (s *Server) pickMatchPair(ctx context.Context, tickets []MatchmakingTicket) (int, int) {
+	pipe := s.RedisClient.Pipeline()
+	cmds := make([]*redis.StringSliceCmd, len(tickets))
+	for i, t := range tickets {
+		cmds[i] = pipe.SMembers(ctx, recentOpponentsPrefix+t.PlayerName)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		slog.Error("Erro ao ler oponentes recentes; pareando por ordem de chegada", "error", err)
+		return 0, 1
+	}
+
+	recent := make(map[string]map[string]bool, len(tickets))
+	for i, t := range tickets {
+		opponents := make(map[string]bool)
+		for _, name := range cmds[i].Val() {
+			opponents[name] = true
 		}
+		recent[t.PlayerName] = opponents
+	}
 
-		// Remove os jogadores da fila atomicamente
-		removed, err := s.RedisClient.ZRem(ctx, matchmakingQueueKey, p1TicketJson, p2TicketJson).Result()
-		if err != nil || removed != 2 {
-			// Se não removeu 2, significa que outro servidor já os removeu
-			continue
+	if i, j, ok := pickSameServerPair(tickets, recent, s.Config.MatchAffinityWait); ok {
+		slog.Debug("Par do mesmo servidor encontrado na fila", "event", "same_server_paired",
+			"player1", tickets[i].PlayerName, "player2", tickets[j].PlayerName, "server_id", tickets[i].ServerID)
+		return i, j
+	}
+
+	for i := 0; i < len(tickets); i++ {
+		for j := i + 1; j < len(tickets); j++ {
+			a, b := tickets[i].PlayerName, tickets[j].PlayerName
+			if a == b || recent[a][b] || recent[b][a] {
+				continue
+			}
+			if i != 0 || j != 1 {
+				slog.Debug("Oponente recente evitado; pareando outro par da fila", "event", "recent_opponent_skipped",
+					"player1", a, "player2", b)
+			}
+			return i, j
 		}
+	}
+	slog.Debug("Sem alternativa na fila; pareando oponentes recentes", "event", "recent_opponent_rematched",
+		"player1", tickets[0].PlayerName, "player2", tickets[1].PlayerName)
+	return 0, 1
+}
 
-		log.Printf("Pareamento confirmado: %s (Srv: %s) vs %s (Srv: %s)",
-			p1Ticket.PlayerName, p1Ticket.ServerID, p2Ticket.PlayerName, p2Ticket.ServerID)
+// pickSameServerPair procura, entre os primeiros matchAffinityLookahead tickets, o par mais antigo de
+// jogadores do mesmo servidor que não se enfrentou recentemente. Pareá-los evita a coordenação entre
+// servidores (REST + Pub/Sub). A preferência só vale enquanto o ticket mais antigo da fila esperou menos
+// que maxWait: depois disso, o pareamento volta à ordem de chegada, para que ninguém espere indefinidamente.
+func pickSameServerPair(tickets []MatchmakingTicket, recent map[string]map[string]bool, maxWait time.Duration) (int, int, bool) {
+	if time.Since(time.Unix(tickets[0].Timestamp, 0)) >= maxWait {
+		return 0, 0, false
+	}
+	limit := min(len(tickets), matchAffinityLookahead)
+	for i := 0; i < limit; i++ {
+		for j := i + 1; j < limit; j++ {
+			a, b := tickets[i].PlayerName, tickets[j].PlayerName
+			if tickets[i].ServerID != tickets[j].ServerID || a == b || recent[a][b] || recent[b][a] {
+				continue
+			}
+			return i, j, true
+		}
+	}
+	return 0, 0, false
+}
 
-		// Notifica os servidores envolvidos para iniciar a partida
-		s.notifyMatchStart(p1Ticket, p2Ticket)
+// markRecentOpponents registra que os dois jogadores acabaram de se enfrentar, para que o
+// matchmaker evite pareá-los de novo durante RECENT_OPPONENT_WINDOW.
+func (s *Server) markRecentOpponents(player1, player2 string) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	window := s.Config.RecentOpponentTTL
+	pipe := s.RedisClient.TxPipeline()
+	pipe.SAdd(ctx, recentOpponentsPrefix+player1, player2)
+	pipe.Expire(ctx, recentOpponentsPrefix+player1, window)
+	pipe.SAdd(ctx, recentOpponentsPrefix+player2, player1)
+	pipe.Expire(ctx, recentOpponentsPrefix+player2, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.Error("Erro ao registrar oponentes recentes", "player1", player1, "player2", player2, "error", err)
 	}
 }
 
 // notifyMatchStart coordena o início da partida entre os servidores.
-func (s *Server) notifyMatchStart(p1Ticket, p2Ticket MatchmakingTicket) {
-	log.Printf("Iniciando notificação de partida para %s vs %s", p1Ticket.PlayerName, p2Ticket.PlayerName)
+func (s *Server) notifyMatchStart(gameID string, p1Ticket, p2Ticket MatchmakingTicket) {
+	slog.Info("Iniciando notificação de partida", "event", "match_notify", "game_id", gameID,
+		"player1", p1Ticket.PlayerName, "player2", p2Ticket.PlayerName)
 
 	req := MatchNotificationRequest{
+		GameID:      gameID,
 		Player1Name: p1Ticket.PlayerName,
 		Player2Name: p2Ticket.PlayerName,
 		Server1ID:   p1Ticket.ServerID,
@@ -188,7 +419,12 @@ func (s *Server) notifyMatchStart(p1Ticket, p2Ticket MatchmakingTicket) {
 	if p1Ticket.ServerID != s.ServerID {
 		err := s.callRemoteMatchNotification(p1Ticket.ServerID, req)
 		if err != nil {
-			log.Printf("FALHA AO NOTIFICAR P1 (%s) no servidor %s. Partida abortada. Erro: %v", p1Ticket.PlayerName, p1Ticket.ServerID, err)
+			slog.Error("FALHA AO NOTIFICAR P1. Partida abortada.", "event", "match_aborted", "game_id", gameID,
+				"player", p1Ticket.PlayerName, "remote_server_id", p1Ticket.ServerID, "error", err)
+			// Compensação: P2 ainda não foi notificado, volta para a fila
+			if p2Ticket.ServerID != p1Ticket.ServerID {
+				s.requeueTickets(matchmakingQueueKey, p2Ticket)
+			}
 			return
 		}
 	}
@@ -197,7 +433,13 @@ func (s *Server) notifyMatchStart(p1Ticket, p2Ticket MatchmakingTicket) {
 	if p2Ticket.ServerID != s.ServerID {
 		err := s.callRemoteMatchNotification(p2Ticket.ServerID, req)
 		if err != nil {
-			log.Printf("FALHA AO NOTIFICAR P2 (%s) no servidor %s. Partida abortada. Erro: %v", p2Ticket.PlayerName, p2Ticket.ServerID, err)
+			slog.Error("FALHA AO NOTIFICAR P2. Partida abortada.", "event", "match_aborted", "game_id", gameID,
+				"player", p2Ticket.PlayerName, "remote_server_id", p2Ticket.ServerID, "error", err)
+			// Compensação: se P1 é local, ainda não começou a partida e volta para a fila.
+			// (Se P1 é remoto, o servidor dele já iniciou a partida e ela termina por timeout.)
+			if p1Ticket.ServerID == s.ServerID {
+				s.requeueTickets(matchmakingQueueKey, p1Ticket)
+			}
 			return
 		}
 	}
@@ -207,46 +449,32 @@ func (s *Server) notifyMatchStart(p1Ticket, p2Ticket MatchmakingTicket) {
 	// A própria startLocalGame vai descobrir se o jogador local é P1 ou P2.
 
 	if p1Ticket.ServerID == s.ServerID {
-		s.startLocalGame(req.Player1Name, req.Player2Name, req.Server1ID, req.Server2ID)
+		s.startLocalGame(req.GameID, req.Player1Name, req.Player2Name, req.Server1ID, req.Server2ID)
 	}
 
 	if p2Ticket.ServerID == s.ServerID {
-		s.startLocalGame(req.Player1Name, req.Player2Name, req.Server1ID, req.Server2ID)
+		s.startLocalGame(req.GameID, req.Player1Name, req.Player2Name, req.Server1ID, req.Server2ID)
 	}
 }
 
 // callRemoteMatchNotification envia a notificação de partida para um servidor remoto via REST.
 func (s *Server) callRemoteMatchNotification(remoteServerID string, req MatchNotificationRequest) error {
-	// O endereço do servidor remoto é resolvido pelo nome do serviço Docker
-	url := fmt.Sprintf("http://%s%s/api/v1/match/notify", remoteServerID, restPort)
-
-	jsonData, _ := json.Marshal(req)
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Printf("Erro ao notificar servidor %s via REST: %v", remoteServerID, err)
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Servidor %s retornou status %d ao notificar partida.", remoteServerID, resp.StatusCode)
-		return fmt.Errorf("servidor remoto retornou status %d", resp.StatusCode)
-	}
-
-	return nil
+	// O endereço do servidor remoto é resolvido pelo registro no Redis (servers:<ServerID>)
+	return s.postMatchNotification(remoteServerID, "/api/v1/match/notify", req.GameID, req)
 }
 
 // Inicia a sessão de jogo. P1, P2 e seus IDs de servidor são fornecidos pelo matchmaker.
-func (s *Server) startLocalGame(player1Name, player2Name, server1ID, server2ID string) {
+func (s *Server) startLocalGame(gameID, player1Name, player2Name, server1ID, server2ID string) {
 	// 1. Pega o jogador local do mapa, identificando se é P1 ou P2
 	s.PlayerMutex.Lock()
 	var localPlayer *PlayerState
 	var isP1 bool
 
-	// Tenta encontrar P1. Se ele estiver local E AINDA não estiver em jogo
+	// Tenta encontrar P1. Se ele estiver local E AINDA puder entrar nesta partida
+	// (ver multi_game.go: sem MAX_GAMES_PER_PLAYER, se não estiver em outra)
 	if p, ok := s.Players[player1Name]; ok {
 		p.mu.Lock()
-		if p.State != "InGame" { // Se P1 não estiver em jogo
+		if p.canJoinGame(gameID, s.Config.MaxGamesPerPlayer) {
 			localPlayer = p
 			isP1 = true
 		}
@@ -257,7 +485,7 @@ func (s *Server) startLocalGame(player1Name, player2Name, server1ID, server2ID s
 	if localPlayer == nil {
 		if p, ok := s.Players[player2Name]; ok {
 			p.mu.Lock()
-			if p.State != "InGame" { // Se P2 não estiver em jogo
+			if p.canJoinGame(gameID, s.Config.MaxGamesPerPlayer) {
 				localPlayer = p
 				isP1 = false
 			}
@@ -268,48 +496,66 @@ func (s *Server) startLocalGame(player1Name, player2Name, server1ID, server2ID s
 	if localPlayer == nil {
 		s.PlayerMutex.Unlock()
 		// Se ambos já estão InGame
-		log.Printf("startLocalGame (P1: %s, P2: %s) chamado, mas o jogador local já está 'InGame' (ou não é local).", player1Name, player2Name)
+		slog.Warn("startLocalGame chamado, mas o jogador local já está 'InGame' (ou não é local).",
+			"game_id", gameID, "player1", player1Name, "player2", player2Name)
 		return
 	}
 	s.PlayerMutex.Unlock()
 
-	// 2. Pega a mão do jogador local
-	handCards := selectRandomCards(localPlayer.Deck, 2)
-	if handCards == nil {
-		log.Printf("Erro: %s não tem cartas suficientes para jogar.", localPlayer.Name)
-		s.sendWebSocketMessage(localPlayer, "Erro: Você não tem cartas suficientes (mínimo 2).")
+	// Trava os comandos do jogador até ele estar "InGame" (ver command_guard.go). Um comando
+	// em andamento pode ter mudado o estado: a verificação acima é refeita com o lock em mãos.
+	localPlayer.cmdMu.Lock()
+	defer localPlayer.cmdMu.Unlock()
+	localPlayer.mu.Lock()
+	alreadyInGame := !localPlayer.canJoinGame(gameID, s.Config.MaxGamesPerPlayer)
+	localPlayer.mu.Unlock()
+	if alreadyInGame {
+		slog.Warn("startLocalGame chamado, mas o jogador local entrou em outra partida.",
+			"game_id", gameID, "player", localPlayer.Name)
+		return
+	}
+
+	// 2. Pega a mão do jogador local (do loadout, se houver; ver loadout.go)
+	hand := selectRandomCards(s.matchPool(localPlayer), s.Config.HandSize, s.Config.DistinctHand)
+	if hand == nil {
+		slog.Warn("Jogador não tem cartas suficientes para jogar.", "game_id", gameID, "player", localPlayer.Name)
+		s.sendWebSocketMessage(localPlayer, fmt.Sprintf("Erro: Você não tem cartas suficientes (mínimo %d).", s.Config.MinDeckSize))
 		return
 	}
-	var hand [2]Card
-	hand[0] = handCards[0]
-	hand[1] = handCards[1]
+
+	// Prazo da jogada, o mesmo nos dois servidores
+	deadline := s.claimTurnDeadline(gameID)
 
 	// 3. Trava o mapa de jogos e cria/atualiza a sessão
-	// A chave da sessão é SEMPRE player1Name.
+	// A chave da sessão é o GameID: se os dois jogadores forem locais, a segunda chamada
+	// encontra a sessão criada pela primeira.
 	s.GamesMutex.Lock()
 
-	session, exists := s.ActiveGames[player1Name]
+	session, exists := s.ActiveGames[gameID]
 	if !exists {
 		session = &GameSession{
-			mu: sync.Mutex{},
+			StartedAt: time.Now(),
+			mu:        sync.Mutex{},
 		}
-		s.ActiveGames[player1Name] = session
+		s.ActiveGames[gameID] = session
 	}
 
 	// 4. Preenche os dados da sessão (local + "fantasma" remoto)
 	session.mu.Lock()
+	session.GameID = gameID
+	session.TurnDeadline = deadline
 	session.Server1ID = server1ID
 	session.Server2ID = server2ID
 
 	if isP1 {
-		log.Printf("Iniciando partida (P1): %s vs %s.", player1Name, player2Name)
+		slog.Info("Iniciando partida (P1)", "event", "game_started", "game_id", gameID, "player", player1Name, "opponent", player2Name)
 		session.Player1 = localPlayer
 		session.Player1Hand = hand
 		// Cria um "fantasma" para o P2
 		session.Player2 = &PlayerState{Name: player2Name, ServerID: server2ID}
 	} else {
 		// O jogador local é P2
-		log.Printf("Iniciando partida (P2): %s vs %s.", localPlayer.Name, player1Name)
+		slog.Info("Iniciando partida (P2)", "event", "game_started", "game_id", gameID, "player", localPlayer.Name, "opponent", player1Name)
 		session.Player2 = localPlayer
 		session.Player2Hand = hand
 		// Cria um "fantasma" para o P1 (se P1 for remoto)
@@ -321,24 +567,32 @@ func (s *Server) startLocalGame(player1Name, player2Name, server1ID, server2ID s
 	session.mu.Unlock()
 	s.GamesMutex.Unlock()
 
-	// 5. Atualiza o estado do jogador local
+	// Cada servidor grava no replay a mão do SEU jogador local
+	s.appendReplayEvent(gameID, ReplayEvent{Type: replayEventHandDealt, Player: localPlayer.Name, Cards: hand})
+
+	// 5. Atualiza o estado do jogador local (um convite de partida privada pendente deixa de valer)
+	s.cancelPrivateMatch(localPlayer)
 	localPlayer.mu.Lock()
-	localPlayer.State = "InGame"
-	localPlayer.CurrentGame = session
+	localPlayer.addGame(gameID, session)
 	localPlayer.mu.Unlock()
 
 	// 6. Envia mensagens de início
 	s.sendWebSocketMessage(localPlayer, "MATCH_FOUND")
-	handStr := fmt.Sprintf("MATCH_START|%s (%d)|%s (%d)", hand[0].Name, hand[0].Forca, hand[1].Name, hand[1].Forca)
-	s.sendWebSocketMessage(localPlayer, handStr)
-	timerMsg := fmt.Sprintf("TIMER|%d", int(gameTurnTimeout.Seconds()))
-	s.sendWebSocketMessage(localPlayer, timerMsg)
+	// O oponente vem da notificação do matchmaker, então é conhecido mesmo quando é remoto
+	opponent := player1Name
+	if isP1 {
+		opponent = player2Name
+	}
+	s.sendGameStart(localPlayer, session, opponent, hand)
 
 	// 7. O CÉREBRO DO JOGO
 	// Apenas o servidor do P1 (o "master") escuta os eventos e o timeout.
 	if isP1 {
-		log.Printf("Servidor P1 (%s) iniciando listener para jogo %s.", s.ServerID, player1Name)
-		// s.listenForGameEvents é a função que você deve adicionar ao game.go
-		go s.listenForGameEvents(session, player1Name)
+		gamesStartedTotal.Inc()
+		slog.Info("Servidor P1 iniciando listener da partida", "game_id", gameID, "player1", player1Name)
+		go s.listenForGameEvents(session, gameID)
+	} else if server1ID != s.ServerID {
+		// O cérebro está no servidor do P1: vigia para o caso de ele cair
+		go s.watchGameBrain(localPlayer, session)
 	}
 }
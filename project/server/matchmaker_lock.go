@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SCRIPT LUA
+// Renova o TTL de um lock distribuído, somente se ele ainda pertencer a quem o adquiriu.
+// Retorna 1 se renovou, 0 se o lock expirou ou foi adquirido por outro servidor.
+//
+// KEYS[1] = a chave do lock (ex: matchmakingLockKey)
+// ARGV[1] = o valor aleatório gravado por quem adquiriu o lock
+// ARGV[2] = o novo TTL, em milissegundos
+var renewLockScript = redis.NewScript(`
+    if redis.call('GET', KEYS[1]) == ARGV[1] then
+        return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+    end
+    return 0
+`)
+
+// SCRIPT LUA
+// Libera um lock distribuído somente se ele ainda pertencer a quem o adquiriu, para que um lock
+// expirado e já adquirido por outro servidor não seja apagado por engano.
+// Retorna 1 se liberou, 0 se o lock não era mais nosso.
+//
+// KEYS[1] = a chave do lock (ex: matchmakingLockKey)
+// ARGV[1] = o valor aleatório gravado por quem adquiriu o lock
+var releaseLockScript = redis.NewScript(`
+    if redis.call("get", KEYS[1]) == ARGV[1] then
+        return redis.call("del", KEYS[1])
+    else
+        return 0
+    end
+`)
+
+// releaseLock libera o lock key se ele ainda guardar value. Falhas só são registradas:
+// no pior caso o lock expira sozinho pelo TTL.
+func (s *Server) releaseLock(ctx context.Context, key, value string) {
+	if err := releaseLockScript.Run(ctx, s.RedisClient, []string{key}, value).Err(); err != nil {
+		slog.Error("Erro ao liberar lock", "lock", key, "error", err)
+	}
+}
+
+// keepLockAlive renova o lock a cada metade do TTL enquanto a rodada do matchmaker estiver em andamento,
+// para que ele não expire no meio do pareamento (leituras da fila, oponentes recentes, ZREM) e outro
+// servidor comece a parear a mesma fila. A função retornada encerra a renovação.
+func (s *Server) keepLockAlive(key, value string, ttl time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(ttl / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ctx, cancel := s.redisCtx()
+				renewed, err := renewLockScript.Run(ctx, s.RedisClient, []string{key}, value, ttl.Milliseconds()).Int()
+				cancel()
+				if err != nil {
+					slog.Error("Erro ao renovar lock do matchmaker", "lock", key, "error", err)
+				} else if renewed == 0 {
+					slog.Warn("Lock do matchmaker perdido durante a rodada.", "event", "matchmaker_lock_lost", "lock", key)
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
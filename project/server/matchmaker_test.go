@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// Dois matchmakers (servidores diferentes) que escolheram o mesmo par disputam a remoção: só um
+// deles pode retirar os dois tickets e iniciar a partida.
+func TestAtomicRemovePairClaimedOnce(t *testing.T) {
+	s, mr := newTestServer(t)
+	const p1, p2 = `{"player_name":"Alice"}`, `{"player_name":"Bob"}`
+
+	for round := 0; round < 20; round++ {
+		mr.ZAdd(matchmakingQueueKey, 1, p1)
+		mr.ZAdd(matchmakingQueueKey, 2, p2)
+
+		var wg sync.WaitGroup
+		results := make([]int, 2)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				removed, err := atomicRemovePairScript.Run(context.Background(), s.RedisClient, []string{matchmakingQueueKey}, p1, p2).Int()
+				if err != nil {
+					t.Errorf("atomicRemovePairScript: %v", err)
+				}
+				results[i] = removed
+			}(i)
+		}
+		wg.Wait()
+
+		winners := 0
+		for _, removed := range results {
+			switch removed {
+			case 2:
+				winners++
+			case 0:
+			default:
+				t.Fatalf("rodada %d: o script removeu %d ticket(s); deveria remover os dois ou nenhum", round, removed)
+			}
+		}
+		if winners != 1 {
+			t.Fatalf("rodada %d: %d matchmakers pegaram o par, esperado exatamente 1", round, winners)
+		}
+		if mr.Exists(matchmakingQueueKey) {
+			t.Fatalf("rodada %d: a fila deveria ficar vazia", round)
+		}
+	}
+}
+
+func TestAtomicRemovePairKeepsRemainingTicket(t *testing.T) {
+	s, mr := newTestServer(t)
+	const p1, p2 = `{"player_name":"Alice"}`, `{"player_name":"Bob"}`
+	// O ticket do P1 já saiu (timeout ou outro pareamento): o do P2 continua na fila
+	mr.ZAdd(matchmakingQueueKey, 2, p2)
+
+	removed, err := atomicRemovePairScript.Run(context.Background(), s.RedisClient, []string{matchmakingQueueKey}, p1, p2).Int()
+	if err != nil {
+		t.Fatalf("atomicRemovePairScript: %v", err)
+	}
+	if removed != 0 {
+		t.Errorf("removidos = %d, esperado 0", removed)
+	}
+	if members, _ := mr.ZMembers(matchmakingQueueKey); len(members) != 1 || members[0] != p2 {
+		t.Errorf("a fila deveria manter só o ticket do P2, fila: %v", members)
+	}
+}
+
+// queuePair coloca na fila clássica dois jogadores de servidores remotos.
+func queuePair(t *testing.T, mr *miniredis.Miniredis, p1, p2 MatchmakingTicket) {
+	t.Helper()
+	for i, ticket := range []MatchmakingTicket{p1, p2} {
+		data, _ := json.Marshal(ticket)
+		mr.ZAdd(matchmakingQueueKey, float64(i+1), string(data))
+	}
+}
+
+// Uma rodada lenta passa do TTL do lock: a renovação o mantém, e o outro servidor não pareia a fila.
+func TestMatchmakerLockRenewedDuringSlowRound(t *testing.T) {
+	t.Setenv("MATCHMAKER_LOCK_TTL", "200ms")
+	mr := miniredis.RunT(t)
+	a := newTestServerOn(t, mr, "Server-A")
+	b := newTestServerOn(t, mr, "Server-B")
+	ttl := a.Config.MatchmakerLockTTL
+	queuePair(t, mr, MatchmakingTicket{PlayerName: "Alice", ServerID: "Server-R1"}, MatchmakingTicket{PlayerName: "Bob", ServerID: "Server-R2"})
+
+	if ok, err := a.RedisClient.SetNX(context.Background(), matchmakingLockKey, "lock-a", ttl).Result(); err != nil || !ok {
+		t.Fatalf("SetNX: %v, %v", ok, err)
+	}
+	stop := a.keepLockAlive(matchmakingLockKey, "lock-a", ttl)
+
+	// O miniredis só expira chaves com FastForward: o tempo avança 3/4 do TTL por volta,
+	// e a renovação (a cada TTL/2, em tempo real) precisa acontecer entre elas
+	for i := 0; i < 6; i++ {
+		time.Sleep(ttl * 3 / 4)
+		mr.FastForward(ttl * 3 / 4)
+		if got, _ := mr.Get(matchmakingLockKey); got != "lock-a" {
+			t.Fatalf("volta %d: o lock deveria continuar com o servidor A, valor %q", i, got)
+		}
+		b.matchmakerRound()
+		if members, _ := mr.ZMembers(matchmakingQueueKey); len(members) != 2 {
+			t.Fatalf("volta %d: o servidor B pareou a fila com o lock do A, fila: %v", i, members)
+		}
+	}
+
+	// Sem renovação, o lock expira no TTL
+	stop()
+	mr.FastForward(ttl)
+	if mr.Exists(matchmakingLockKey) {
+		t.Errorf("sem a renovação, o lock deveria expirar")
+	}
+}
+
+// A notificação dos servidores remotos demora mais que o TTL do lock: os tickets já saíram da fila,
+// e as rodadas seguintes (de qualquer servidor) não pareiam os mesmos jogadores de novo.
+func TestSlowMatchNotifyDoesNotDoublePair(t *testing.T) {
+	t.Setenv("MATCHMAKER_LOCK_TTL", "100ms")
+	mr := miniredis.RunT(t)
+	a := newTestServerOn(t, mr, "Server-A")
+	b := newTestServerOn(t, mr, "Server-B")
+	ttl := a.Config.MatchmakerLockTTL
+
+	var mu sync.Mutex
+	notified := make(map[string][]string) // Servidor remoto -> IDs das partidas notificadas
+	remote := func(serverID string) {
+		hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req MatchNotificationRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			time.Sleep(3 * ttl) // Notificação lenta: passa do TTL do lock
+			mu.Lock()
+			notified[serverID] = append(notified[serverID], req.GameID)
+			mu.Unlock()
+		}))
+		t.Cleanup(hs.Close)
+		mr.Set(serverRegistryPrefix+serverID, strings.TrimPrefix(hs.URL, "http://"))
+	}
+	remote("Server-R1")
+	remote("Server-R2")
+	queuePair(t, mr, MatchmakingTicket{PlayerName: "Alice", ServerID: "Server-R1"}, MatchmakingTicket{PlayerName: "Bob", ServerID: "Server-R2"})
+
+	a.matchmakerRound()
+	// Enquanto a notificação está em andamento, o lock expira e os dois servidores seguem rodando
+	for i := 0; i < 5; i++ {
+		mr.FastForward(ttl)
+		b.matchmakerRound()
+		a.matchmakerRound()
+		time.Sleep(ttl)
+	}
+
+	waitFor(t, "notificação dos dois servidores", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(notified["Server-R1"]) > 0 && len(notified["Server-R2"]) > 0
+	})
+	time.Sleep(3 * ttl) // Uma segunda notificação, se houvesse, chegaria neste intervalo
+	mu.Lock()
+	defer mu.Unlock()
+	for _, serverID := range []string{"Server-R1", "Server-R2"} {
+		if games := notified[serverID]; len(games) != 1 {
+			t.Errorf("%s notificado %d vez(es), esperado 1: %v", serverID, len(games), games)
+		}
+	}
+	if notified["Server-R1"][0] != notified["Server-R2"][0] {
+		t.Errorf("os dois servidores deveriam receber a mesma partida: %v", notified)
+	}
+	if mr.Exists(matchmakingQueueKey) {
+		t.Errorf("a fila deveria continuar vazia depois do pareamento")
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsRedisTimeout = 500 * time.Millisecond
+
+// Contadores (registrados no registry padrão do Prometheus e expostos em /metrics).
+var (
+	gamesStartedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cardgame_games_started_total",
+		Help: "Partidas iniciadas neste servidor (contadas apenas no P1-Server).",
+	})
+	gamesFinishedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cardgame_games_finished_total",
+		Help: "Partidas finalizadas neste servidor, por tipo de resultado (decided, draw, timeout, double_timeout, forfeit = desconexão, surrender = FORFEIT, brain_lost).",
+	}, []string{"outcome"})
+	packsOpenedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cardgame_packs_opened_total",
+		Help: "Tentativas de abertura de pacotes, por resultado (success, partial, empty_stock, error, duplicate).",
+	}, []string{"result"})
+	tradesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cardgame_trades_total",
+		Help: "Tentativas de troca, por resultado (queued, completed, expired, pending, self, busy, error).",
+	}, []string{"result"})
+	matchmakingResultsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cardgame_matchmaking_results_total",
+		Help: "Buscas por partida encerradas, por modo (classic, ffa) e resultado (matched, timeout, bot_fallback).",
+	}, []string{"mode", "result"})
+	stockCardsRejectedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cardgame_stock_cards_rejected_total",
+		Help: "Cartas inválidas (sem nome ou com Força fora de CARD_FORCA_MIN..CARD_FORCA_MAX) retiradas do estoque e descartadas.",
+	})
+	deadLetterTicketsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cardgame_dead_letter_tickets_total",
+		Help: "Tickets corrompidos (JSON inválido) retirados das filas de matchmaking e de trocas, por fila.",
+	}, []string{"queue"})
+	// O Timestamp do ticket tem resolução de 1s, então os buckets começam em 1s.
+	matchmakingWaitSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cardgame_matchmaking_wait_seconds",
+		Help:    "Tempo de espera na fila até o pareamento (agora - Timestamp do ticket), por modo (classic, ffa).",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 8), // 1s ... 128s
+	}, []string{"mode"})
+)
+
+// matchModeLabel retorna o rótulo de modo das métricas de matchmaking.
+func matchModeLabel(mode string) string {
+	if mode == gameModeFFA {
+		return "ffa"
+	}
+	return "classic"
+}
+
+// observeMatchWait registra, para cada ticket pareado, o tempo que o jogador esperou na fila.
+// Um ticket devolvido à fila (requeueTickets) mantém o Timestamp e é contado de novo no próximo pareamento.
+func observeMatchWait(mode string, tickets ...MatchmakingTicket) {
+	label := matchModeLabel(mode)
+	now := time.Now()
+	for _, t := range tickets {
+		matchmakingWaitSeconds.WithLabelValues(label).Observe(now.Sub(time.Unix(t.Timestamp, 0)).Seconds())
+		matchmakingResultsTotal.WithLabelValues(label, "matched").Inc()
+	}
+}
+
+// registerMetrics registra os gauges que dependem do estado do servidor ou do Redis.
+// Os valores são calculados no momento da coleta (scrape).
+func (s *Server) registerMetrics() {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cardgame_matchmaking_queue_length",
+		Help: "Jogadores na fila global de matchmaking (Redis ZSET).",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), metricsRedisTimeout)
+		defer cancel()
+		n, _ := s.RedisClient.ZCard(ctx, matchmakingQueueKey).Result()
+		return float64(n)
+	})
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cardgame_trade_queue_length",
+		Help: "Tickets na fila global de trocas (Redis LIST).",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), metricsRedisTimeout)
+		defer cancel()
+		n, _ := s.RedisClient.LLen(ctx, tradeQueueKey).Result()
+		return float64(n)
+	})
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cardgame_stock_cards_remaining",
+		Help: "Cartas restantes no estoque global (Redis LIST).",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), metricsRedisTimeout)
+		defer cancel()
+		n, _ := s.RedisClient.LLen(ctx, stockKey).Result()
+		return float64(n)
+	})
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cardgame_connected_players",
+		Help: "Jogadores conectados a este servidor.",
+	}, func() float64 {
+		s.PlayerMutex.Lock()
+		defer s.PlayerMutex.Unlock()
+		return float64(len(s.Players))
+	})
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cardgame_active_games",
+		Help: "Sessões de jogo ativas neste servidor.",
+	}, func() float64 {
+		s.GamesMutex.Lock()
+		defer s.GamesMutex.Unlock()
+		return float64(len(s.ActiveGames))
+	})
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "cardgame_max_concurrent_games",
+		Help: "Limite de partidas simultâneas neste servidor (MAX_CONCURRENT_GAMES; 0 = sem limite).",
+	}, func() float64 {
+		return float64(s.Config.MaxConcurrentGames)
+	})
+}
@@ -1,34 +1,75 @@
 package main
 
 import (
+	"context"
+	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/websocket"
 )
 
-// Card representa uma única carta do jogo, com nome e força.
+// Card representa uma única carta do jogo, com nome, força e uma habilidade especial opcional.
 type Card struct {
-	Name  string `json:"name"`
-	Forca int    `json:"forca"`
+	Name    string `json:"name"`
+	Forca   int    `json:"forca"`
+	Ability string `json:"ability,omitempty"` // Boost, Weather ou Spy (ver abilities.go)
+	Speed   int    `json:"speed,omitempty"`   // Agilidade: desempata cartas de mesma Força
+	// Metadados apenas para exibição no cliente (ver card_metadata.go). Cartas serializadas
+	// antes deles (estoque, hash da partida, trocas) continuam válidas, com os campos vazios.
+	Rarity   string `json:"rarity,omitempty"`
+	Faction  string `json:"faction,omitempty"`
+	ImageRef string `json:"image_ref,omitempty"`
 }
 
 // PlayerState (inalterado)
 type PlayerState struct {
 	Name        string
-	Deck        []Card
+	Deck        []Card     // Protegido por mu: muda por comandos e pelo Pub/Sub de trocas (ver deck.go)
+	deckSaveMu  sync.Mutex // Serializa as gravações do deck em player:deck:<nome> (ver saveDeck)
 	PacksOpened int
 	WsConn      *websocket.Conn
 	ServerID    string
 
-	mu          sync.Mutex
-	State       string
-	CurrentGame *GameSession
+	cmdMu sync.Mutex // Serializa os comandos do jogador com as mudanças de estado (ver command_guard.go)
+	mu    sync.Mutex
+	State string
+	Games map[string]*GameSession // Partidas em andamento, por GameID (protegidas por mu; ver multi_game.go)
+
+	presenceToken string          // Token da reserva de nome no cluster (player:online:<nome>)
+	done          chan struct{}   // Fechado quando o jogador desconecta
+	rematchOffer  *RematchOffer   // Oferta de revanche pendente (protegida por mu)
+	privateInvite *PrivateInvite  // Convite de partida privada criado pelo jogador (protegido por mu, ver private.go)
+	loadout       []string        // Nomes das cartas de onde a mão é sorteada; vazio = deck inteiro (protegido por mu, ver loadout.go)
+	isBot         bool            // Oponente sintético controlado pelo servidor (ver bot.go)
+	bot           *botPlayer      // Dificuldade e sorteio do bot, quando isBot (ver bot_difficulty.go)
+	botDifficulty string          // Dificuldade pedida no último "FIND_MATCH <dificuldade>" (protegida por mu)
+	limiter       *tokenBucket    // Limite de comandos por segundo (ver ratelimit.go)
+	lastGameID    string          // GameID da última partida iniciada (usado pelo "REPLAY" sem argumento)
+	locale        string          // Idioma das mensagens do catálogo, escolhido com SET_LOCALE (protegido por mu, ver i18n.go)
+	outbox        chan outboxItem // Mensagens a enviar, escritas apenas pelo writeLoop (ver ws_writer.go)
+
+	// Retomada da conexão (REJOIN, ver rejoin.go)
+	reconnectToken string      // Token que a próxima conexão apresenta para assumir este jogador
+	takenOver      atomic.Bool // Outra conexão assumiu o jogador: a limpeza desta não mexe nas partidas nem no nome
+	detached       atomic.Bool // Desconectado em partida, aguardando REJOIN: as mensagens vão para missed
+	missedMu       sync.Mutex
+	missed         []string // Mensagens enviadas enquanto detached, entregues no REJOIN (protegidas por missedMu)
 }
 
 // GameSession representa o estado de uma partida 1v1 em andamento.
 type GameSession struct {
+	GameID string // ID aleatório gerado no pareamento: chave da sessão em ActiveGames e das chaves/canais game:*:<GameID> no Redis
+	Mode   string // gameModeClassic (1v1) ou gameModeFFA (ver ffa.go)
+
+	// Campos do modo FFA (N jogadores). No modo clássico, ficam vazios.
+	Players []*PlayerState    // Todos os participantes (locais ou "fantasmas")
+	Hands   map[string][]Card // Mão de cada jogador LOCAL, por nome
+	Cards   map[string]*Card  // Cartas jogadas, preenchidas no servidor master antes de decidir
+
 	Player1 *PlayerState // Pode ser local ou "fantasma"
 	Player2 *PlayerState // Pode ser local ou "fantasma"
 
@@ -37,45 +78,163 @@ type GameSession struct {
 	Player2Card *Card
 
 	mu          sync.Mutex
-	Player1Hand [2]Card // Mão do P1 (só existe no P1-Server)
-	Player2Hand [2]Card // Mão do P2 (só existe no P2-Server)
+	Player1Hand []Card // Mão do P1 (só existe no P1-Server)
+	Player2Hand []Card // Mão do P2 (só existe no P2-Server)
 
 	Server1ID string // ID do servidor do P1
 	Server2ID string // ID do servidor do P2
+
+	ForfeitedBy   string // Nome do jogador que desconectou antes de jogar, ou desistiu (perde por W.O.)
+	ForfeitReason string // resultReasonDisconnected ou resultReasonForfeit (FORFEIT)
+
+	TurnDeadline time.Time // Prazo absoluto da jogada, compartilhado pelos servidores (ver turn_timer.go)
+	StartedAt    time.Time // Início da partida neste servidor (zero numa sessão reconstruída, ver ghostSession)
+
+	SuddenDeathRound int // Rodada de morte súbita em andamento (0 = jogada normal, ver sudden_death.go)
 }
 
 // Server (inalterado)
 type Server struct {
-	RedisClient *redis.Client
+	RedisClient redis.UniversalClient // Nó único ou Sentinel (REDIS_MODE, ver redis_client.go)
 	Router      *chi.Mux
 	Players     map[string]*PlayerState
 	PlayerMutex *sync.Mutex
 	ServerID    string
+	RestAddr    string // Endereço REST (host:porta) publicado em servers:<ServerID>
+	Config      Config
+	HTTPClient  *http.Client // Cliente REST servidor-servidor (com timeout por requisição)
+	StockSpec   StockSpec    // Distribuição de cartas usada para criar o estoque (ver stock_spec.go)
 	ActiveGames map[string]*GameSession
 	GamesMutex  sync.Mutex
+
+	// ctx é cancelado quando o servidor começa a encerrar: interrompe as operações no Redis em
+	// andamento e os laços de fundo (ver redis_ctx.go).
+	ctx  context.Context
+	stop context.CancelFunc
+
+	stockReady     atomic.Bool // Verdadeiro após initializeDistributedStock (usado pelo /readyz)
+	stockExhausted atomic.Bool // Verdadeiro enquanto o estoque global estiver esgotado (ver stock_events.go)
+	maintenance    atomic.Bool // Verdadeiro em manutenção: sem novas conexões nem partidas (ver maintenance.go)
+
+	// Jogadores desconectados em partida que ainda podem voltar com REJOIN, por nome (protegido por PlayerMutex)
+	detached map[string]*PlayerState
+}
+
+// APIError é o corpo de toda resposta de erro da API REST (ver api_errors.go).
+type APIError struct {
+	Error string `json:"error"` // Mensagem legível
+	Code  string `json:"code"`  // Código estável do erro (errCode*)
 }
 
 // Request/Response DTOs para comunicação Server-Server (REST)
 type TakePackRequest struct {
 	PlayerName string `json:"player_name"`
+	Starter    bool   `json:"starter,omitempty"` // Pacote inicial (STARTER_PACK_SIZE) em vez do extra (PACK_SIZE)
 }
 
+// Em caso de erro, traz também os campos do APIError ("error" e "code").
 type TakePackResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
-	Pack    []Card `json:"pack"`
+	Success        bool   `json:"success"`
+	Message        string `json:"message"`
+	Error          string `json:"error,omitempty"`
+	Code           string `json:"code,omitempty"`
+	Pack           []Card `json:"pack"`
+	RemainingPacks int64  `json:"remaining_packs"` // Pacotes completos que ainda restam no estoque global
+	StrayCards     int64  `json:"stray_cards"`     // Cartas que sobram além deles, insuficientes para um pacote
 }
 
 type MatchNotificationRequest struct {
+	GameID      string `json:"game_id"` // ID único da partida: correlação nos logs e chave de idempotência da notificação
 	Player1Name string `json:"player1_name"`
 	Player2Name string `json:"player2_name"`
 	Server1ID   string `json:"server1_id"`
 	Server2ID   string `json:"server2_id"`
 }
 
+// FFAMatchNotificationRequest notifica os servidores envolvidos numa partida FFA.
+// A ordem de Players é a mesma em todos os servidores; o servidor de Players[0] é o master.
+type FFAMatchNotificationRequest struct {
+	GameID  string              `json:"game_id"`
+	Players []MatchmakingTicket `json:"players"`
+}
+
 // Estruturas auxiliares para o Matchmaker Distribuído
 type MatchmakingTicket struct {
 	PlayerName string `json:"player_name"`
 	ServerID   string `json:"server_id"`
 	Timestamp  int64  `json:"timestamp"`
+	Resumes    int    `json:"resumes,omitempty"`  // Retomadas após reconexão (RESUME_SEARCH, ver search_resume.go)
+	Priority   int    `json:"priority,omitempty"` // Ajuste da posição na fila, em segundos (ver queue_priority.go)
+}
+
+// LeaderboardEntry representa a linha de um jogador no ranking global.
+type LeaderboardEntry struct {
+	Rank       int    `json:"rank"`
+	PlayerName string `json:"player_name"`
+	Wins       int    `json:"wins"`
+	Losses     int    `json:"losses"`
+	Draws      int    `json:"draws"`
+}
+
+type LeaderboardResponse struct {
+	Entries []LeaderboardEntry `json:"entries"`
+}
+
+// PlayerStatus é a resposta do comando "STATUS" (enviada como "STATUS|<json>").
+type PlayerStatus struct {
+	PlayerName  string   `json:"player_name"`
+	ServerID    string   `json:"server_id"`
+	State       string   `json:"state"`
+	PacksOpened int      `json:"packs_opened"`
+	MaxPacks    int      `json:"max_packs"`
+	DeckSize    int      `json:"deck_size"`
+	Wins        int      `json:"wins"`
+	Losses      int      `json:"losses"`
+	Draws       int      `json:"draws"`
+	Rank        int      `json:"rank,omitempty"`  // Posição no ranking (0/omitido se ainda não jogou)
+	Games       []string `json:"games,omitempty"` // GameIDs das partidas em andamento
+}
+
+// CollectionResponse é a resposta do comando "COLLECTION" (enviada como "COLLECTION|<json>").
+type CollectionResponse struct {
+	DeckSize int               `json:"deck_size"`
+	Unique   int               `json:"unique"`   // Cartas distintas do conjunto completo que o jogador possui
+	SetSize  int               `json:"set_size"` // Cartas distintas no conjunto completo (StockSpec)
+	Owned    []CollectionEntry `json:"owned"`
+	Missing  []string          `json:"missing,omitempty"` // Cartas do conjunto que o jogador ainda não tem
+}
+
+// CollectionEntry é uma carta da coleção e quantas cópias o jogador tem dela.
+type CollectionEntry struct {
+	Name     string `json:"name"`
+	Forca    int    `json:"forca"`
+	Ability  string `json:"ability,omitempty"`
+	Rarity   string `json:"rarity,omitempty"`
+	Faction  string `json:"faction,omitempty"`
+	ImageRef string `json:"image_ref,omitempty"`
+	Count    int    `json:"count"`
+}
+
+// ReplayEvent é um evento do replay de uma partida (ver replay.go).
+type ReplayEvent struct {
+	Timestamp int64  `json:"timestamp"` // Unix em milissegundos
+	Type      string `json:"type"`      // hand_dealt, card_played, forfeit, timeout, sudden_death ou result
+	Player    string `json:"player,omitempty"`
+	Cards     []Card `json:"cards,omitempty"`   // Mão sorteada ou carta jogada
+	Outcome   string `json:"outcome,omitempty"` // Rótulo do resultado (decided, draw, timeout...)
+	Detail    string `json:"detail,omitempty"`
+}
+
+// ReplayResponse é a resposta do comando "REPLAY" (enviada como "REPLAY|<json>") e do endpoint REST.
+type ReplayResponse struct {
+	GameID string        `json:"game_id"`
+	Events []ReplayEvent `json:"events"`
+}
+
+// ForceResolveResponse é a resposta do endpoint de administração que resolve uma partida travada.
+type ForceResolveResponse struct {
+	GameID  string            `json:"game_id"`
+	Mode    string            `json:"mode"`
+	Outcome string            `json:"outcome"` // Rótulo da métrica cardgame_games_finished_total
+	Results map[string]string `json:"results"` // Jogador -> mensagem "RESULT|..." publicada
 }
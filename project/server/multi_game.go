@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Com MAX_GAMES_PER_PLAYER > 1, um jogador pode estar em várias partidas ao mesmo tempo (ex:
+// partidas casuais assíncronas): durante uma partida, FIND_MATCH coloca o jogador na fila de
+// novo. As partidas ficam em PlayerState.Games, por GameID, e a jogada indica a partida:
+// "PLAY <gameID> <carta>". Só o número da carta continua valendo quando há uma única partida
+// em andamento, então o modo de uma partida só (o padrão) não muda.
+//
+// player.State continua resumindo o jogador: "Searching" enquanto procura partida (mesmo com
+// outras em andamento), "InGame" com ao menos uma partida e "Menu" sem nenhuma.
+//
+// Entre os servidores, o resultado publicado no canal player:<nome> leva o GameID
+// ("GAME_RESULT|<gameID>|RESULT|..."), para que o servidor do jogador encerre a partida certa.
+
+const (
+	// playCommandPrefix é a jogada com a partida indicada: "PLAY <gameID> <carta>".
+	playCommandPrefix = "PLAY "
+	// gameResultPrefix envolve o "RESULT|..." publicado no canal do jogador com o GameID.
+	gameResultPrefix = "GAME_RESULT|"
+)
+
+// Motivos de recusa de uma jogada que não indica uma partida válida.
+const (
+	moveRejectedGameRequired = "GAME_ID_REQUIRED" // Várias partidas em andamento: use "PLAY <gameID> <carta>"
+	moveRejectedUnknownGame  = "UNKNOWN_GAME"     // O jogador não está na partida indicada
+)
+
+// addGame registra a partida iniciada. A busca, se houver, terminou com ela.
+// Deve ser chamada com p.mu travado.
+func (p *PlayerState) addGame(gameID string, session *GameSession) {
+	if p.Games == nil {
+		p.Games = make(map[string]*GameSession)
+	}
+	p.Games[gameID] = session
+	p.State = "InGame"
+	p.rematchOffer = nil
+	p.lastGameID = gameID
+}
+
+// removeGame retira a partida encerrada. Sem outras partidas, o jogador volta ao "Menu" (ou
+// continua "Searching", se estiver na fila). Retorna a sessão retirada, ou nil se o jogador não
+// estava na partida. Deve ser chamada com p.mu travado.
+func (p *PlayerState) removeGame(gameID string) *GameSession {
+	session, ok := p.Games[gameID]
+	if !ok {
+		return nil
+	}
+	delete(p.Games, gameID)
+	if p.State == "InGame" && len(p.Games) == 0 {
+		p.State = "Menu"
+	}
+	return session
+}
+
+// idleState é o estado de quem sai da fila sem uma partida nova: "InGame" se ainda houver
+// partidas em andamento, "Menu" caso contrário. Deve ser chamada com p.mu travado.
+func (p *PlayerState) idleState() string {
+	if len(p.Games) > 0 {
+		return "InGame"
+	}
+	return "Menu"
+}
+
+// canJoinGame informa se o jogador pode entrar na partida: ainda não está nela e não atingiu o
+// limite de partidas simultâneas. Deve ser chamada com p.mu travado.
+func (p *PlayerState) canJoinGame(gameID string, maxGames int) bool {
+	_, already := p.Games[gameID]
+	return !already && len(p.Games) < maxGames
+}
+
+// gameIDs lista as partidas em andamento, em ordem. Deve ser chamada com p.mu travado.
+func (p *PlayerState) gameIDs() []string {
+	ids := make([]string, 0, len(p.Games))
+	for id := range p.Games {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// defaultGameID é a partida de um comando ou resultado sem GameID: a única em andamento ou,
+// com várias, a iniciada por último ("" se não houver nenhuma). Deve ser chamada com p.mu travado.
+func (p *PlayerState) defaultGameID() string {
+	if _, ok := p.Games[p.lastGameID]; ok {
+		return p.lastGameID
+	}
+	for _, id := range p.gameIDs() {
+		return id
+	}
+	return ""
+}
+
+// gameForMove identifica a partida de uma jogada: "PLAY <gameID> <carta>" ou só "<carta>" com uma
+// única partida em andamento. Retorna a partida e a carta, ou o motivo e a mensagem da recusa.
+// Deve ser chamada com p.mu travado.
+func (p *PlayerState) gameForMove(command string) (session *GameSession, move, reason, message string) {
+	if rest, ok := strings.CutPrefix(command, playCommandPrefix); ok {
+		gameID, move, _ := strings.Cut(strings.TrimSpace(rest), " ")
+		if session, ok := p.Games[gameID]; ok {
+			return session, move, "", ""
+		}
+		return nil, "", moveRejectedUnknownGame, fmt.Sprintf("Você não está na partida %q.", gameID)
+	}
+	if len(p.Games) == 1 {
+		for _, session := range p.Games {
+			return session, command, "", ""
+		}
+	}
+	return nil, "", moveRejectedGameRequired, fmt.Sprintf("Você está em %d partidas: jogue com \"PLAY <gameID> <carta>\" (partidas: %s).",
+		len(p.Games), strings.Join(p.gameIDs(), ", "))
+}
+
+// handleRoutedGameMove encaminha a jogada à partida indicada (ver gameForMove).
+func (s *Server) handleRoutedGameMove(player *PlayerState, command string) {
+	player.mu.Lock()
+	session, move, reason, message := player.gameForMove(command)
+	player.mu.Unlock()
+	if session == nil {
+		s.rejectMove(player, reason, message)
+		return
+	}
+	s.handleGameMove(player, session, move)
+}
+
+// handleFindAnotherMatch coloca na fila, para mais uma partida, um jogador que já está em outra.
+func (s *Server) handleFindAnotherMatch(player *PlayerState, command string) {
+	player.mu.Lock()
+	games := len(player.Games)
+	player.mu.Unlock()
+	if games >= s.Config.MaxGamesPerPlayer {
+		s.rejectCommand(player, fmt.Sprintf("Você já está em %d partida(s), o máximo simultâneo.", games))
+		return
+	}
+	if command == "FIND_MATCH FFA" {
+		s.addToMatchmakingQueue(player, ffaQueueKey)
+		return
+	}
+	s.handleFindMatch(player, command)
+}
+
+// gameResultMessage envolve o "RESULT|..." da partida com o GameID, para o canal player:<nome>.
+func gameResultMessage(gameID, result string) string {
+	return gameResultPrefix + gameID + "|" + result
+}
+
+// parseGameResult lê um resultado recebido no canal do jogador: "GAME_RESULT|<gameID>|RESULT|..."
+// ou, de um servidor sem o GameID, só "RESULT|..." (gameID vazio). ok é false para outras mensagens.
+func parseGameResult(payload string) (gameID, result string, ok bool) {
+	if strings.HasPrefix(payload, "RESULT|") {
+		return "", payload, true
+	}
+	rest, found := strings.CutPrefix(payload, gameResultPrefix)
+	if !found {
+		return "", "", false
+	}
+	gameID, result, found = strings.Cut(rest, "|")
+	if !found || !strings.HasPrefix(result, "RESULT|") {
+		return "", "", false
+	}
+	return gameID, result, true
+}
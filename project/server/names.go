@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxPlayerNameLength é o tamanho máximo do nome do jogador, em caracteres (runas).
+const maxPlayerNameLength = 24
+
+// nameSuffixSeparator separa o nome escolhido do sufixo numérico atribuído pelo servidor
+// com NAME_CONFLICT=suffix (ex: "Bob#2").
+const nameSuffixSeparator = "#"
+
+// splitNameSuffix separa um nome com sufixo atribuído ("Bob#2") em nome escolhido e número.
+// Sem sufixo válido (um inteiro de 2 em diante, sem zeros à esquerda), retorna o nome inteiro e 0.
+func splitNameSuffix(name string) (base string, n int) {
+	i := strings.LastIndex(name, nameSuffixSeparator)
+	if i < 0 {
+		return name, 0
+	}
+	digits := name[i+len(nameSuffixSeparator):]
+	if digits == "" || digits[0] == '0' {
+		return name, 0
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return name, 0
+		}
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil || n < 2 {
+		return name, 0
+	}
+	return name[:i], n
+}
+
+// validatePlayerName verifica se o nome pode ser usado no protocolo e nas chaves do Redis.
+// São aceitos apenas letras e dígitos Unicode, '-' e '_'. Isso exclui o delimitador '|' do
+// protocolo, aspas (o nome aparece em JSON), ':' e '*' (estrutura e padrões de chaves Redis)
+// e espaços. Também é aceito o sufixo atribuído pelo servidor ("Bob#2"), para que o cliente
+// possa reconectar com o nome efetivo. Retorna o motivo da recusa, ou "" se o nome for válido.
+func validatePlayerName(name string) string {
+	name, _ = splitNameSuffix(name)
+	if name == "" {
+		return "o nome não pode ser vazio"
+	}
+	if utf8.RuneCountInString(name) > maxPlayerNameLength {
+		return fmt.Sprintf("o nome deve ter no máximo %d caracteres", maxPlayerNameLength)
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return fmt.Sprintf("caractere não permitido: %q (use letras, números, '-' ou '_')", r)
+		}
+	}
+	if strings.HasPrefix(strings.ToLower(name), strings.ToLower(botNamePrefix)) {
+		return fmt.Sprintf("o prefixo %q é reservado", botNamePrefix)
+	}
+	return ""
+}
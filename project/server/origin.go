@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Verificação da origem das conexões WebSocket (proteção contra Cross-Site WebSocket Hijacking).
+// ALLOWED_ORIGINS lista, separadas por vírgula, as origens (esquema://host[:porta]) das páginas
+// que podem abrir uma conexão; "*" aceita qualquer origem (desenvolvimento local).
+// Conexões sem o cabeçalho Origin são aceitas: os navegadores sempre o enviam, então só
+// clientes que não são páginas web (como o cliente de terminal) conectam sem ele.
+
+// allowAllOrigins é o valor de ALLOWED_ORIGINS que desliga a verificação.
+const allowAllOrigins = "*"
+
+// parseAllowedOrigins lê a lista de ALLOWED_ORIGINS, já normalizada (ver normalizeOrigin).
+func parseAllowedOrigins(raw string) ([]string, error) {
+	var origins []string
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if entry == allowAllOrigins {
+			origins = append(origins, allowAllOrigins)
+			continue
+		}
+		origin, err := normalizeOrigin(entry)
+		if err != nil {
+			return nil, fmt.Errorf("ALLOWED_ORIGINS: origem inválida %q: %w", entry, err)
+		}
+		origins = append(origins, origin)
+	}
+	if len(origins) == 0 {
+		return nil, fmt.Errorf("ALLOWED_ORIGINS vazio: liste as origens permitidas ou use %q", allowAllOrigins)
+	}
+	return origins, nil
+}
+
+// normalizeOrigin reduz uma origem a "esquema://host[:porta]" em minúsculas, sem a porta
+// padrão do esquema, para que "https://Jogo.example:443" e "https://jogo.example" coincidam.
+func normalizeOrigin(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", fmt.Errorf("esquema %q não suportado (use http ou https)", u.Scheme)
+	}
+	if u.Host == "" || u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("esperado esquema://host[:porta]")
+	}
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	return scheme + "://" + host, nil
+}
+
+// checkOrigin informa se a conexão pode ser aceita pela origem dela. Retorna a origem
+// recebida para o log quando ela é recusada.
+func (s *Server) checkOrigin(r *http.Request) (origin string, ok bool) {
+	origin = r.Header.Get("Origin")
+	if origin == "" {
+		return "", true
+	}
+	normalized, err := normalizeOrigin(origin)
+	for _, allowed := range s.Config.AllowedOrigins {
+		if allowed == allowAllOrigins || (err == nil && allowed == normalized) {
+			return origin, true
+		}
+	}
+	return origin, false
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SCRIPT LUA
+// Abre vários pacotes em uma única operação atômica: calcula quantos pacotes COMPLETOS
+// existem no estoque (floor(cartas / pack_size)) e remove min(pedidos, disponíveis) * pack_size
+// cartas de uma vez. As cartas avulsas, que não completam um pacote, ficam no estoque.
+// Retorna {pacotes concedidos, {cartas removidas}} (0 e uma tabela vazia se não houver nenhum pacote completo).
+//
+// KEYS[1] = a chave da lista de estoque (stockKey)
+// ARGV[1] = o número de cartas por pacote
+// ARGV[2] = o número de pacotes pedidos
+var atomicOpenPacksScript = redis.NewScript(`
+    local stock_key = KEYS[1]
+    local pack_size = tonumber(ARGV[1])
+    local wanted = tonumber(ARGV[2])
+
+    -- 1. Quantos pacotes completos existem no estoque
+    local available = math.floor(redis.call('LLEN', stock_key) / pack_size)
+    local packs = math.min(wanted, available)
+    if packs <= 0 then
+        return {0, {}}
+    end
+
+    -- 2. Remove todas as cartas desses pacotes de uma só vez
+    return {packs, redis.call('LPOP', stock_key, packs * pack_size)}
+`)
+
+// parseOpenPacksResult lê o retorno do atomicOpenPacksScript: os pacotes concedidos e as cartas (JSON).
+func parseOpenPacksResult(result []interface{}) (packs int, cardJSONs []string, err error) {
+	if len(result) != 2 {
+		return 0, nil, fmt.Errorf("resultado com %d elemento(s), esperado 2", len(result))
+	}
+	granted, ok := result[0].(int64)
+	if !ok {
+		return 0, nil, fmt.Errorf("número de pacotes inválido (%T)", result[0])
+	}
+	items, ok := result[1].([]interface{})
+	if !ok {
+		return 0, nil, fmt.Errorf("lista de cartas inválida (%T)", result[1])
+	}
+	for _, item := range items {
+		cardJSON, ok := item.(string)
+		if !ok {
+			return 0, nil, fmt.Errorf("carta inválida (%T)", item)
+		}
+		cardJSONs = append(cardJSONs, cardJSON)
+	}
+	return int(granted), cardJSONs, nil
+}
+
+// handleOpenPacks processa o comando "OPEN_PACKS <n> [chave]": abre até n pacotes de uma vez,
+// respeitando o limite por jogador e o estoque disponível. Só os pacotes efetivamente
+// abertos contam para o limite do jogador. A chave opcional evita a abertura repetida
+// (ver pack_idempotency.go).
+func (s *Server) handleOpenPacks(player *PlayerState, command string) {
+	args := strings.Fields(strings.TrimPrefix(command, "OPEN_PACKS"))
+	if len(args) < 1 || len(args) > 2 {
+		s.sendWebSocketMessage(player, "Comando inválido. Use 'OPEN_PACKS [quantidade] [chave]'.")
+		return
+	}
+	requested, err := strconv.Atoi(args[0])
+	if err != nil || requested < 1 {
+		s.sendWebSocketMessage(player, "Comando inválido. Use 'OPEN_PACKS [quantidade] [chave]'.")
+		return
+	}
+	key := ""
+	if len(args) == 2 {
+		key = args[1]
+	}
+	s.openPacksOnce(player, key, func() (string, bool) {
+		return s.openPacks(player, requested)
+	})
+}
+
+// openPacks abre até requested pacotes e retorna a resposta ao jogador, e se algum pacote
+// foi de fato aberto.
+func (s *Server) openPacks(player *PlayerState, requested int) (string, bool) {
+	// Reserva no contador do cluster (ver pack_limit.go) apenas os pacotes dentro do limite
+	wanted, total, err := s.reservePacks(player.Name, requested)
+	if err != nil {
+		slog.Error("Erro ao reservar pacotes no contador do jogador", "player", player.Name, "error", err)
+		return "Desculpe, erro interno ao processar o estoque.", false
+	}
+	player.PacksOpened = total
+	if wanted == 0 {
+		return fmt.Sprintf("Você já abriu o máximo de %d pacotes extras.", s.Config.MaxPacksPerPlayer), false
+	}
+
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	packSize := s.Config.PackSize
+	result, err := atomicOpenPacksScript.Run(ctx, s.RedisClient, []string{stockKey}, packSize, wanted).Slice()
+	var opened int
+	var cardJSONs []string
+	if err == nil {
+		opened, cardJSONs, err = parseOpenPacksResult(result)
+	}
+	if err != nil {
+		slog.Error("Erro ao executar script LUA de pacotes em lote", "player", player.Name, "error", err)
+		packsOpenedTotal.WithLabelValues("error").Inc()
+		player.PacksOpened = s.refundPacks(player.Name, wanted)
+		return "Desculpe, erro interno ao processar o estoque.", false
+	}
+
+	var cards []Card
+	for _, cardJSON := range cardJSONs {
+		var card Card
+		if err := json.Unmarshal([]byte(cardJSON), &card); err != nil {
+			slog.Error("Erro crítico ao desserializar carta do Redis", "player", player.Name, "error", err)
+			continue
+		}
+		if !s.acceptStockCard(player.Name, card, cardJSON) {
+			continue
+		}
+		cards = append(cards, card)
+	}
+
+	s.giveCards(player, cards...)
+	packsOpenedTotal.WithLabelValues("success").Add(float64(opened))
+	if opened < wanted {
+		// Os pacotes que faltaram no estoque não contam para o limite
+		player.PacksOpened = s.refundPacks(player.Name, wanted-opened)
+		packsOpenedTotal.WithLabelValues("empty_stock").Inc()
+		s.markStockExhausted()
+	}
+	slog.Info("Pacotes abertos em lote", "event", "packs_opened", "player", player.Name,
+		"requested", requested, "opened", opened, "cards", len(cards))
+	s.audit(player.Name, auditOpenPack, "", fmt.Sprintf("%d pacote(s): %s", opened, strings.Join(cardNames(cards), ", ")))
+
+	// Constrói a resposta com o resumo de todas as cartas recebidas
+	var response string
+	if opened == 0 {
+		response = "Desculpe, não há pacotes de cartas suficientes no estoque global."
+	} else {
+		names := make([]string, 0, len(cards))
+		for _, card := range cards {
+			names = append(names, fmt.Sprintf("%s (Força: %d)%s", card.Name, card.Forca, card.abilityTag()))
+		}
+		response = fmt.Sprintf("Parabéns, %s! Você abriu %d pacote(s) e recebeu: %s.", player.Name, opened, strings.Join(names, ", "))
+	}
+	if missing := wanted - opened; missing > 0 && opened > 0 {
+		response += fmt.Sprintf(" Você recebeu %d de %d pacote(s) pedidos: os outros %d não puderam ser abertos, estoque esgotado (não contam para o seu limite).",
+			opened, wanted, missing)
+	}
+	if overLimit := requested - wanted; overLimit > 0 {
+		response += fmt.Sprintf(" %d pacote(s) ultrapassariam o limite de %d por jogador.", overLimit, s.Config.MaxPacksPerPlayer)
+	}
+
+	response += " " + s.remainingStockText(packSize) + "\n"
+	return response, opened > 0
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestOpenPacksShortStock(t *testing.T) {
+	tests := []struct {
+		stock, requested int
+		wantOpened       int
+		wantText         string
+	}{
+		{stock: 9, requested: 3, wantOpened: 3, wantText: "abriu 3 pacote(s)"},
+		{stock: 7, requested: 3, wantOpened: 2, wantText: "Você recebeu 2 de 3 pacote(s) pedidos"},
+		{stock: 3, requested: 3, wantOpened: 1, wantText: "Você recebeu 1 de 3 pacote(s) pedidos"},
+		{stock: 2, requested: 2, wantOpened: 0, wantText: "não há pacotes de cartas suficientes"},
+		{stock: 0, requested: 1, wantOpened: 0, wantText: "não há pacotes de cartas suficientes"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("estoque_%d_pedidos_%d", tt.stock, tt.requested), func(t *testing.T) {
+			t.Setenv("PACK_SIZE", "3")
+			t.Setenv("MAX_PACKS_PER_PLAYER", "5")
+			s, mr := newTestServer(t)
+			if tt.stock > 0 {
+				seedStock(t, s, Card{Name: "Ghoul", Forca: 1}, tt.stock)
+			}
+			player := newTestPlayer("Alice")
+
+			response, opened := s.openPacks(player, tt.requested)
+			if opened != (tt.wantOpened > 0) {
+				t.Errorf("openPacks informou aberto = %v, esperado %v", opened, tt.wantOpened > 0)
+			}
+			if !strings.Contains(response, tt.wantText) {
+				t.Errorf("resposta = %q, esperado conter %q", response, tt.wantText)
+			}
+			if got := len(player.deckSnapshot()); got != tt.wantOpened*s.Config.PackSize {
+				t.Errorf("deck com %d cartas, esperado %d", got, tt.wantOpened*s.Config.PackSize)
+			}
+			// Só os pacotes concedidos contam para o limite
+			if got := s.loadPacksOpened("Alice"); got != tt.wantOpened || player.PacksOpened != tt.wantOpened {
+				t.Errorf("contador = %d (PacksOpened %d), esperado %d", got, player.PacksOpened, tt.wantOpened)
+			}
+			// As cartas avulsas ficam no estoque
+			if stock, _ := mr.List(stockKey); len(stock) != tt.stock-tt.wantOpened*s.Config.PackSize {
+				t.Errorf("estoque com %d cartas, esperado %d", len(stock), tt.stock-tt.wantOpened*s.Config.PackSize)
+			}
+		})
+	}
+}
+
+func TestOpenPacksShortStockAndCap(t *testing.T) {
+	t.Setenv("PACK_SIZE", "3")
+	t.Setenv("MAX_PACKS_PER_PLAYER", "2")
+	s, _ := newTestServer(t)
+	seedStock(t, s, Card{Name: "Ghoul", Forca: 1}, 3)
+	player := newTestPlayer("Alice")
+
+	// Pede 4: o limite corta para 2, e o estoque só tem 1
+	response, _ := s.openPacks(player, 4)
+	for _, want := range []string{"Você recebeu 1 de 2 pacote(s) pedidos", "2 pacote(s) ultrapassariam o limite"} {
+		if !strings.Contains(response, want) {
+			t.Errorf("resposta = %q, esperado conter %q", response, want)
+		}
+	}
+	if got := s.loadPacksOpened("Alice"); got != 1 {
+		t.Errorf("contador = %d, esperado 1 (o pacote que faltou no estoque não conta)", got)
+	}
+
+	// Com estoque de novo, o pacote devolvido ao limite ainda pode ser aberto
+	seedStock(t, s, Card{Name: "Ghoul", Forca: 1}, 6)
+	if response, opened := s.openPacks(player, 2); !opened || !strings.Contains(response, "abriu 1 pacote(s)") {
+		t.Errorf("deveria abrir o último pacote do limite, resposta %q", response)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Um OPEN_PACK repetido (duplo clique, reenvio após uma falha de rede) abriria outro pacote e
+// gastaria mais uma vez o limite do jogador. Para evitar isso, o cliente pode enviar uma chave
+// com o comando ("OPEN_PACK <chave>" ou "OPEN_PACKS <n> <chave>"): a primeira vez que a chave
+// aparece os pacotes são abertos e a resposta fica guardada em pack:idem:<nome>:<chave> por
+// PACK_IDEMPOTENCY_WINDOW. Um comando com a mesma chave dentro da janela recebe de novo a
+// mesma resposta, sem abrir nada. Sem chave, o comando funciona como sempre.
+//
+// Se nenhum pacote for aberto (limite atingido, estoque esgotado, erro), a chave é liberada:
+// repetir o comando é uma nova tentativa.
+
+const (
+	// packIdemPrefix guarda a resposta de cada chave de abertura de pacotes, por jogador.
+	packIdemPrefix = "pack:idem:"
+	// packIdemPending marca a chave cujo comando ainda está sendo processado.
+	packIdemPending = "PENDING"
+	// maxPackIdemKeyLen é o tamanho máximo da chave enviada pelo cliente.
+	maxPackIdemKeyLen = 64
+)
+
+// validPackIdemKey informa se a chave tem só letras, números, '-' e '_' (até maxPackIdemKeyLen).
+func validPackIdemKey(key string) bool {
+	if key == "" || len(key) > maxPackIdemKeyLen {
+		return false
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// handleOpenPack processa o comando "OPEN_PACK [chave]": abre um pacote extra.
+func (s *Server) handleOpenPack(player *PlayerState, command string) {
+	key := strings.TrimSpace(strings.TrimPrefix(command, "OPEN_PACK"))
+	s.openPacksOnce(player, key, func() (string, bool) {
+		return s.openCardPackResult(player, false)
+	})
+}
+
+// openPacksOnce executa a abertura (open) e envia a resposta ao jogador. Com uma chave, a
+// resposta é guardada e um comando repetido com a mesma chave recebe a resposta guardada.
+func (s *Server) openPacksOnce(player *PlayerState, key string, open func() (string, bool)) {
+	if key == "" {
+		response, _ := open()
+		s.sendWebSocketMessage(player, response)
+		return
+	}
+	if !validPackIdemKey(key) {
+		s.sendWebSocketMessage(player, "Chave inválida: use até 64 letras, números, '-' ou '_'.")
+		return
+	}
+
+	redisKey := packIdemPrefix + player.Name + ":" + key
+	ctx, cancel := s.redisCtx()
+	claimed, err := s.RedisClient.SetNX(ctx, redisKey, packIdemPending, s.Config.PackIdemWindow).Result()
+	var previous string
+	if err == nil && !claimed {
+		previous, err = s.RedisClient.Get(ctx, redisKey).Result()
+		if err == redis.Nil {
+			// A chave expirou entre o SETNX e o GET: trata como um comando novo
+			claimed, err = s.RedisClient.SetNX(ctx, redisKey, packIdemPending, s.Config.PackIdemWindow).Result()
+		}
+	}
+	cancel()
+	if err != nil {
+		slog.Error("Erro ao verificar a chave de abertura de pacotes", "player", player.Name, "key", key, "error", err)
+		s.sendWebSocketMessage(player, "Desculpe, erro interno ao processar o estoque.")
+		return
+	}
+
+	if !claimed {
+		packsOpenedTotal.WithLabelValues("duplicate").Inc()
+		slog.Info("Abertura de pacotes repetida ignorada", "event", "pack_open_duplicate", "player", player.Name, "key", key,
+			"pending", previous == packIdemPending)
+		if previous == packIdemPending {
+			s.sendWebSocketMessage(player, "Este pedido de pacotes ainda está sendo processado.")
+			return
+		}
+		s.sendWebSocketMessage(player, previous)
+		return
+	}
+
+	response, opened := open()
+	ctx, cancel = s.redisCtx()
+	if opened {
+		err = s.RedisClient.Set(ctx, redisKey, response, s.Config.PackIdemWindow).Err()
+	} else {
+		err = s.RedisClient.Del(ctx, redisKey).Err()
+	}
+	cancel()
+	if err != nil {
+		slog.Error("Erro ao registrar o resultado da chave de abertura de pacotes", "player", player.Name, "key", key, "error", err)
+	}
+	s.sendWebSocketMessage(player, response)
+}
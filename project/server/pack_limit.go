@@ -0,0 +1,74 @@
+package main
+
+import (
+	"log/slog"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// packsCounterPrefix guarda, por jogador, quantos pacotes extras ele já abriu (player:packs:<nome>).
+// É a fonte da verdade do limite MAX_PACKS_PER_PLAYER em todo o cluster: reconectar ou
+// entrar por outro servidor com o mesmo nome não zera a contagem. O pacote inicial obrigatório
+// não entra nela: se entrasse, cada conexão gastaria um pacote do limite permanente do jogador.
+const packsCounterPrefix = "player:packs:"
+
+// SCRIPT LUA
+// Reserva pacotes no contador do jogador sem ultrapassar o limite, numa única operação atômica.
+// Retorna {pacotes reservados, total de pacotes do jogador após a reserva}.
+//
+// KEYS[1] = o contador do jogador (packsCounterPrefix + nome)
+// ARGV[1] = o número de pacotes pedidos
+// ARGV[2] = o limite de pacotes (MAX_PACKS_PER_PLAYER)
+var atomicReservePacksScript = redis.NewScript(`
+    local opened = tonumber(redis.call('GET', KEYS[1]) or '0')
+    local wanted = tonumber(ARGV[1])
+    local max = tonumber(ARGV[2])
+
+    wanted = math.max(0, math.min(wanted, max - opened))
+    if wanted > 0 then
+        opened = redis.call('INCRBY', KEYS[1], wanted)
+    end
+    return {wanted, opened}
+`)
+
+// reservePacks reserva até wanted pacotes extras para o jogador, respeitando o limite
+// MAX_PACKS_PER_PLAYER (granted pode ser menor que wanted, ou zero). Retorna também o total
+// de pacotes do jogador, que passa a valer em player.PacksOpened.
+func (s *Server) reservePacks(playerName string, wanted int) (granted, total int, err error) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	res, err := atomicReservePacksScript.Run(ctx, s.RedisClient, []string{packsCounterPrefix + playerName}, wanted,
+		s.Config.MaxPacksPerPlayer).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(res[0]), int(res[1]), nil
+}
+
+// refundPacks devolve ao contador os pacotes reservados que não puderam ser abertos
+// (estoque esgotado ou erro). Retorna o novo total do jogador.
+func (s *Server) refundPacks(playerName string, n int) int {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	total, err := s.RedisClient.DecrBy(ctx, packsCounterPrefix+playerName, int64(n)).Result()
+	if err != nil {
+		slog.Error("Erro ao devolver pacotes ao contador do jogador", "player", playerName, "packs", n, "error", err)
+	}
+	return int(total)
+}
+
+// loadPacksOpened lê do Redis quantos pacotes o jogador já abriu (em qualquer servidor ou sessão).
+func (s *Server) loadPacksOpened(playerName string) int {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	raw, err := s.RedisClient.Get(ctx, packsCounterPrefix+playerName).Result()
+	if err != nil {
+		if err != redis.Nil {
+			slog.Error("Erro ao ler contador de pacotes do jogador", "player", playerName, "error", err)
+		}
+		return 0
+	}
+	n, _ := strconv.Atoi(raw)
+	return n
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStarterPackDoesNotCountTowardPackCap(t *testing.T) {
+	s, mr := newTestServer(t)
+	seedStock(t, s, Card{Name: "Ghoul", Forca: 1}, 100)
+
+	// Cada conexão abre o pacote inicial: nenhuma delas gasta o limite permanente
+	for i := 0; i < 3; i++ {
+		if _, opened := s.openCardPackResult(newTestPlayer("Alice"), true); !opened {
+			t.Fatalf("o pacote inicial %d deveria ser aberto", i+1)
+		}
+	}
+	if mr.Exists(packsCounterPrefix + "Alice") {
+		got, _ := mr.Get(packsCounterPrefix + "Alice")
+		t.Fatalf("o pacote inicial não deveria entrar em %sAlice, contador = %s", packsCounterPrefix, got)
+	}
+
+	player := newTestPlayer("Alice")
+	for i := 0; i < s.Config.MaxPacksPerPlayer; i++ {
+		if _, opened := s.openCardPackResult(player, false); !opened {
+			t.Fatalf("o pacote extra %d deveria ser aberto", i+1)
+		}
+	}
+	if player.PacksOpened != s.Config.MaxPacksPerPlayer {
+		t.Errorf("PacksOpened = %d, esperado %d", player.PacksOpened, s.Config.MaxPacksPerPlayer)
+	}
+	response, opened := s.openCardPackResult(player, false)
+	if opened || !strings.Contains(response, "máximo") {
+		t.Errorf("o pacote além do limite deveria ser recusado, resposta %q", response)
+	}
+}
+
+func TestPackRefundedWhenStockIsEmpty(t *testing.T) {
+	s, _ := newTestServer(t)
+	player := newTestPlayer("Alice")
+
+	if _, opened := s.openCardPackResult(player, false); opened {
+		t.Fatalf("sem estoque, o pacote não deveria ser aberto")
+	}
+	if got := s.loadPacksOpened("Alice"); got != 0 {
+		t.Errorf("o pacote não aberto deveria ser devolvido ao contador, contador = %d", got)
+	}
+}
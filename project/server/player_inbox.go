@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Mensagens duráveis para jogadores desconectados. O canal player:<nome> só entrega a quem
+// está inscrito; quando ninguém está (o jogador saiu e o listener Pub/Sub encerrou), uma troca
+// concluída ou um resultado se perderiam. Essas mensagens passam por publishToPlayer, que as
+// guarda em pending:<nome> quando não há ninguém ouvindo. Ao reconectar, o listener Pub/Sub
+// da nova conexão trata as mensagens guardadas antes das ao vivo, e confirma (remove da lista)
+// cada uma só depois de escrevê-la na conexão: uma queda no meio não perde as não confirmadas.
+// Uma mensagem repetida por isso é inofensiva: o crédito de uma troca é idempotente
+// (markTradeCredited), e um resultado repetido é reexibido, mas só entra no ranking uma vez
+// (claimResultRecord).
+
+const (
+	// playerInboxPrefix é a lista de mensagens não entregues de cada jogador.
+	playerInboxPrefix = "pending:"
+	// playerInboxMax limita a lista: as mensagens mais antigas são descartadas além disso.
+	playerInboxMax = 100
+	// resultRecordedPrefix marca, por partida e jogador, o resultado já registrado no ranking.
+	resultRecordedPrefix = "result:recorded:"
+)
+
+// SCRIPT LUA
+// Publica a mensagem no canal do jogador e, se ninguém a recebeu, guarda-a na lista de pendentes.
+// Publicar e guardar na mesma operação garante que a mensagem chegue de um jeito ou de outro:
+// ou a nova conexão já estava inscrita, ou a mensagem está na lista quando ela a esvaziar.
+//
+// KEYS[1] = a lista de pendentes do jogador (pending:<nome>)
+// ARGV[1] = o canal do jogador (player:<nome>)
+// ARGV[2] = a mensagem
+// ARGV[3] = o TTL da lista, em milissegundos
+// ARGV[4] = o tamanho máximo da lista
+var publishOrBufferScript = redis.NewScript(`
+    local receivers = redis.call('PUBLISH', ARGV[1], ARGV[2])
+    if receivers == 0 then
+        redis.call('RPUSH', KEYS[1], ARGV[2])
+        redis.call('LTRIM', KEYS[1], -tonumber(ARGV[4]), -1)
+        redis.call('PEXPIRE', KEYS[1], ARGV[3])
+    end
+    return receivers
+`)
+
+// publishToPlayer envia a mensagem ao jogador (Pub/Sub) e a guarda para a próxima conexão
+// dele se ninguém estiver ouvindo.
+func (s *Server) publishToPlayer(ctx context.Context, playerName, message string) error {
+	keys := []string{playerInboxPrefix + playerName}
+	return publishOrBufferScript.Run(ctx, s.RedisClient, keys, "player:"+playerName, message,
+		s.Config.PlayerInboxTTL.Milliseconds(), playerInboxMax).Err()
+}
+
+// bufferPlayerMessage guarda a mensagem para a próxima conexão do jogador, sem publicá-la.
+// Usada pelo listener de uma conexão já encerrada, que ainda recebe mensagens enquanto
+// aguarda o resultado de uma partida (ver listenRedisPubSub).
+func (s *Server) bufferPlayerMessage(playerName, message string) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	key := playerInboxPrefix + playerName
+	pipe := s.RedisClient.TxPipeline()
+	pipe.RPush(ctx, key, message)
+	pipe.LTrim(ctx, key, -playerInboxMax, -1)
+	pipe.PExpire(ctx, key, s.Config.PlayerInboxTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.Error("Erro ao guardar mensagem para o jogador desconectado", "player", playerName, "error", err)
+	}
+}
+
+// pendingPlayerMessages lê, sem removê-las, as mensagens guardadas para o jogador, na ordem em
+// que chegaram. Cada uma é removida por ackPlayerMessage depois de tratada.
+func (s *Server) pendingPlayerMessages(playerName string) ([]string, error) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	return s.RedisClient.LRange(ctx, playerInboxPrefix+playerName, 0, -1).Result()
+}
+
+// ackPlayerMessage confirma uma mensagem guardada já tratada, removendo-a da lista. A remoção é
+// pelo conteúdo (e não pela posição), porque a lista pode ter sido cortada (playerInboxMax) ou
+// crescido enquanto as mensagens eram tratadas.
+func (s *Server) ackPlayerMessage(playerName, message string) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	if err := s.RedisClient.LRem(ctx, playerInboxPrefix+playerName, 1, message).Err(); err != nil {
+		slog.Error("Erro ao confirmar mensagem guardada do jogador", "player", playerName, "error", err)
+	}
+}
+
+// deliverPendingMessages trata as mensagens guardadas para o jogador, antes das ao vivo.
+// Cada uma só sai da lista depois que o writeLoop escreveu na conexão tudo o que ela gerou
+// (afterDelivery): se a conexão ou este servidor caírem antes, as não confirmadas são entregues
+// na próxima conexão. Retorna false quando o listener deve parar.
+func (s *Server) deliverPendingMessages(player *PlayerState) bool {
+	pending, err := s.pendingPlayerMessages(player.Name)
+	if err != nil {
+		slog.Error("Erro ao ler mensagens guardadas do jogador", "player", player.Name, "error", err)
+	}
+	if len(pending) > 0 {
+		slog.Info("Entregando mensagens guardadas durante a desconexão.", "event", "player_inbox_drained",
+			"player", player.Name, "count", len(pending))
+	}
+	for _, payload := range pending {
+		if player.isDisconnected() {
+			return false // As mensagens restantes continuam guardadas para a próxima conexão
+		}
+		keepListening := s.routePlayerMessage(player, payload)
+		message := payload
+		s.afterDelivery(player, func() { s.ackPlayerMessage(player.Name, message) })
+		if !keepListening {
+			return false
+		}
+	}
+	return true
+}
+
+// claimResultRecord decide se o resultado recebido pelo jogador deve entrar no ranking: só na
+// primeira entrega (SETNX em result:recorded:<GameID>:<nome>), já que a lista de pendentes pode
+// entregar o mesmo resultado de novo. Sem o GameID (servidor antigo), só a conexão que ainda tem
+// a partida registra.
+func (s *Server) claimResultRecord(playerName, gameID string, inGame bool) bool {
+	if gameID == "" {
+		return inGame
+	}
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	claimed, err := s.RedisClient.SetNX(ctx, resultRecordedPrefix+gameID+":"+playerName, 1, s.Config.PlayerInboxTTL).Result()
+	if err != nil {
+		slog.Error("Erro ao marcar resultado registrado", "player", playerName, "game_id", gameID, "error", err)
+		return inGame
+	}
+	return claimed
+}
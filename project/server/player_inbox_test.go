@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPendingResultRedeliveredAfterDropBeforeWrite(t *testing.T) {
+	s, mr := newTestServer(t)
+	payload := gameResultMessage("g1", resultMessage("VITÓRIA", ""))
+	mr.RPush(playerInboxPrefix+"Alice", payload)
+
+	// A primeira conexão trata o resultado, mas cai antes de escrevê-lo (nada sai do outbox)
+	first := newTestPlayer("Alice")
+	s.deliverPendingMessages(first)
+	close(first.done)
+	if pending, _ := mr.List(playerInboxPrefix + "Alice"); len(pending) != 1 {
+		t.Fatalf("sem a escrita, o resultado deveria continuar guardado, pendentes: %q", pending)
+	}
+
+	// A conexão seguinte recebe o resultado de novo e, escrito, ele sai da lista
+	second := newTestPlayer("Alice")
+	if !s.deliverPendingMessages(second) {
+		t.Fatalf("o listener da nova conexão deveria continuar")
+	}
+	messages := sentMessages(second)
+	if len(messages) != 1 || !strings.HasPrefix(messages[0], "GAME_END|") {
+		t.Errorf("a nova conexão deveria receber o resultado, mensagens: %q", messages)
+	}
+	if mr.Exists(playerInboxPrefix + "Alice") {
+		pending, _ := mr.List(playerInboxPrefix + "Alice")
+		t.Errorf("depois de escrito, o resultado deveria sair da lista, pendentes: %q", pending)
+	}
+
+	// Entregue duas vezes, o resultado conta uma só vez no ranking
+	stats, err := s.getPlayerStats("Alice")
+	if err != nil {
+		t.Fatalf("getPlayerStats: %v", err)
+	}
+	if stats.Wins != 1 || stats.Losses != 0 || stats.Draws != 0 {
+		t.Errorf("estatísticas = %+v, esperado exatamente 1 vitória", stats)
+	}
+}
+
+func TestPendingResultRecordedWhenGameNotHeldLocally(t *testing.T) {
+	s, mr := newTestServer(t)
+	mr.RPush(playerInboxPrefix+"Alice", gameResultMessage("g1", resultMessage("DERROTA", "")))
+
+	// A conexão antiga caiu sem receber o resultado: a nova não tem a partida, mas registra
+	player := newTestPlayer("Alice")
+	s.deliverPendingMessages(player)
+	sentMessages(player)
+
+	stats, err := s.getPlayerStats("Alice")
+	if err != nil {
+		t.Fatalf("getPlayerStats: %v", err)
+	}
+	if stats.Losses != 1 {
+		t.Errorf("estatísticas = %+v, esperado 1 derrota registrada pela nova conexão", stats)
+	}
+}
+
+func TestPendingResultDeliveredOverWebSocket(t *testing.T) {
+	s, mr := newTestServer(t)
+	url := startTestWebSocket(t, s)
+	mr.RPush(playerInboxPrefix+"Alice", gameResultMessage("g1", resultMessage("EMPATE", "")))
+
+	conn := dialTestPlayer(t, url, "Alice")
+	readUntilPrefix(t, conn, "GAME_END|")
+	waitFor(t, "resultado confirmado", func() bool { return !mr.Exists(playerInboxPrefix + "Alice") })
+
+	stats, err := s.getPlayerStats("Alice")
+	if err != nil {
+		t.Fatalf("getPlayerStats: %v", err)
+	}
+	if stats.Draws != 1 {
+		t.Errorf("estatísticas = %+v, esperado 1 empate", stats)
+	}
+}
+
+func TestSlowAckDoesNotBlockWriter(t *testing.T) {
+	s, _ := newTestServer(t)
+	url := startTestWebSocket(t, s)
+	conn := dialTestPlayer(t, url, "Alice")
+	readUntilPrefix(t, conn, reconnectTokenPrefix)
+	player := connectedPlayer(s, "Alice")
+
+	// Uma confirmação presa (Redis lento) não pode atrasar as mensagens seguintes
+	release := make(chan struct{})
+	defer close(release)
+	s.afterDelivery(player, func() { <-release })
+	s.sendWebSocketMessage(player, "TIMER|7")
+	readUntilPrefix(t, conn, "TIMER|7")
+}
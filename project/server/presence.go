@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	playerOnlinePrefix = "player:online:"
+	presenceTTL        = 30 * time.Second
+	presenceHeartbeat  = 10 * time.Second
+	// maxNameSuffix é o maior sufixo tentado com NAME_CONFLICT=suffix ("Bob#2" até "Bob#100").
+	maxNameSuffix = 100
+)
+
+// Valores de NAME_CONFLICT: o que fazer quando o nome escolhido já está em uso no cluster.
+const (
+	nameConflictReject = "reject" // Recusa a conexão com NAME_TAKEN
+	nameConflictSuffix = "suffix" // Atribui o primeiro "<nome>#<n>" livre e o informa com ASSIGNED_NAME
+)
+
+// Resultados do refreshPresenceScript.
+const (
+	presenceLost      = 0 // O nome foi reservado por outra conexão
+	presenceRenewed   = 1 // A reserva ainda era desta conexão e teve o TTL renovado
+	presenceReclaimed = 2 // A reserva tinha expirado (ex: Redis lento) e foi refeita por esta conexão
+)
+
+// SCRIPT LUA
+// Renova o TTL da reserva de nome se ela ainda pertencer a esta conexão, ou a refaz se ela
+// expirou e ninguém mais pegou o nome. Retorna presenceLost, presenceRenewed ou presenceReclaimed.
+//
+// KEYS[1] = a chave de presença (player:online:<nome>)
+// ARGV[1] = o token da conexão
+// ARGV[2] = o TTL em milissegundos
+var refreshPresenceScript = redis.NewScript(`
+    local owner = redis.call("get", KEYS[1])
+    if owner == ARGV[1] then
+        redis.call("pexpire", KEYS[1], ARGV[2])
+        return 1
+    end
+    if owner == false then
+        redis.call("set", KEYS[1], ARGV[1], "PX", ARGV[2])
+        return 2
+    end
+    return 0
+`)
+
+// reservePlayerName tenta reservar o nome do jogador em todo o cluster (SETNX com TTL).
+// Retorna o token da reserva, ou "" se o nome já estiver em uso.
+func (s *Server) reservePlayerName(playerName string) (string, error) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	token := newRandomID()
+
+	ok, err := s.RedisClient.SetNX(ctx, playerOnlinePrefix+playerName, token, presenceTTL).Result()
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", nil
+	}
+	return token, nil
+}
+
+// reserveAvailableName reserva o nome pedido ou, com NAME_CONFLICT=suffix e o nome em uso,
+// o primeiro "<nome>#<n>" livre a partir de n=2. O nome efetivo é único no cluster pela mesma
+// reserva (player:online:<nome efetivo>) e é usado em todas as chaves e canais do jogador.
+// Um pedido que já tem sufixo (reconexão como "Bob#2") tenta primeiro o próprio nome.
+// Retorna o nome efetivo e o token da reserva, ou "" como token se nenhum nome estiver livre.
+func (s *Server) reserveAvailableName(playerName string) (string, string, error) {
+	token, err := s.reservePlayerName(playerName)
+	// Um nome autenticado (AUTH_SECRET) é o do token: nunca recebe sufixo
+	if err != nil || token != "" || s.Config.NameConflict != nameConflictSuffix || s.Config.AuthSecret != "" {
+		return playerName, token, err
+	}
+	base, _ := splitNameSuffix(playerName)
+	for n := 2; n <= maxNameSuffix; n++ {
+		candidate := fmt.Sprintf("%s%s%d", base, nameSuffixSeparator, n)
+		if candidate == playerName {
+			continue
+		}
+		token, err := s.reservePlayerName(candidate)
+		if err != nil {
+			return playerName, "", err
+		}
+		if token != "" {
+			return candidate, token, nil
+		}
+	}
+	return playerName, "", nil
+}
+
+// releasePlayerName libera a reserva de nome feita por esta conexão.
+func (s *Server) releasePlayerName(playerName, token string) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	// A reserva de nome é liberada como um lock: somente se ainda pertencer a esta conexão
+	err := releaseLockScript.Run(ctx, s.RedisClient, []string{playerOnlinePrefix + playerName}, token).Err()
+	if err != nil {
+		slog.Error("Erro ao liberar reserva de nome", "player", playerName, "error", err)
+	}
+}
+
+// refreshPresence renova (ou refaz, se expirou) a reserva de nome desta conexão.
+func (s *Server) refreshPresence(player *PlayerState) (int, error) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	return refreshPresenceScript.Run(ctx, s.RedisClient, []string{playerOnlinePrefix + player.Name},
+		player.presenceToken, presenceTTL.Milliseconds()).Int()
+}
+
+// presenceHeartbeatLoop renova periodicamente o TTL da reserva de nome enquanto o jogador
+// estiver conectado. Se o servidor cair, a reserva expira sozinha após presenceTTL.
+// Se outra conexão (em qualquer servidor) tiver pegado o nome, esta é encerrada: dois jogadores
+// com o mesmo nome misturariam canais, trocas e resultados.
+func (s *Server) presenceHeartbeatLoop(player *PlayerState) {
+	ticker := time.NewTicker(presenceHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-player.done:
+			return
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if !s.checkPresence(player) {
+				return
+			}
+		}
+	}
+}
+
+// checkPresence faz uma renovação da reserva de nome. Retorna false se o nome passou a outra
+// conexão; nesse caso o jogador recebe NAME_TAKEN e a conexão é fechada (o listenClientCommands
+// faz a limpeza, e a reserva da outra conexão não é tocada, pois o token é outro).
+func (s *Server) checkPresence(player *PlayerState) bool {
+	res, err := s.refreshPresence(player)
+	switch {
+	case err != nil:
+		slog.Error("Erro ao renovar presença", "player", player.Name, "error", err)
+	case res == presenceReclaimed:
+		slog.Warn("Reserva de nome expirada foi refeita.", "event", "presence_reclaimed", "player", player.Name)
+	case res == presenceLost:
+		slog.Warn("Reserva de nome pertence a outra conexão; desconectando.", "event", "presence_lost", "player", player.Name)
+		s.sendWebSocketMessage(player, "NAME_TAKEN")
+		// Dá ao escritor a chance de enviar o aviso antes do fechamento
+		time.AfterFunc(time.Second, func() { player.WsConn.Close() })
+		return false
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestRefreshPresence(t *testing.T) {
+	s, mr := newTestServer(t)
+	player := newTestPlayer("Alice")
+	token, err := s.reservePlayerName(player.Name)
+	if err != nil || token == "" {
+		t.Fatalf("reservePlayerName = %q, %v", token, err)
+	}
+	player.presenceToken = token
+	key := playerOnlinePrefix + player.Name
+
+	if res, err := s.refreshPresence(player); err != nil || res != presenceRenewed {
+		t.Fatalf("reserva própria: refreshPresence = %d, %v; quer %d", res, err, presenceRenewed)
+	}
+
+	// A reserva expirou (ex: o Redis ficou lento) e ninguém pegou o nome: é refeita
+	mr.Del(key)
+	if res, err := s.refreshPresence(player); err != nil || res != presenceReclaimed {
+		t.Fatalf("reserva expirada: refreshPresence = %d, %v; quer %d", res, err, presenceReclaimed)
+	}
+	if got, _ := mr.Get(key); got != token {
+		t.Fatalf("reserva refeita com %q, quer o token da conexão %q", got, token)
+	}
+	if ttl := mr.TTL(key); ttl <= 0 {
+		t.Fatalf("reserva refeita sem TTL (%v)", ttl)
+	}
+
+	// Outra conexão pegou o nome: a reserva dela não é tocada
+	mr.Set(key, "outra-conexao")
+	if res, err := s.refreshPresence(player); err != nil || res != presenceLost {
+		t.Fatalf("nome de outra conexão: refreshPresence = %d, %v; quer %d", res, err, presenceLost)
+	}
+	if got, _ := mr.Get(key); got != "outra-conexao" {
+		t.Fatalf("reserva da outra conexão alterada para %q", got)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// privateMatchPrefix guarda o convite de uma partida privada: private:<código> -> ticket do anfitrião (JSON).
+	privateMatchPrefix = "private:"
+	// privateCodeAttempts é o número de códigos sorteados antes de desistir por colisão.
+	privateCodeAttempts = 5
+)
+
+// SCRIPT LUA
+// Resgata o convite atomicamente: lê o ticket do anfitrião e apaga o código na mesma operação,
+// para que, entre dois jogadores usando o mesmo código, só o primeiro entre na partida.
+// O anfitrião não pode resgatar o próprio convite (o código continua válido).
+// Retorna nil se o código não existir (expirado, cancelado ou já usado).
+//
+// KEYS[1] = a chave do convite (private:<código>)
+// ARGV[1] = o nome do jogador que está entrando
+var claimPrivateMatchScript = redis.NewScript(`
+    local ticket = redis.call('GET', KEYS[1])
+    if not ticket then
+        return false
+    end
+    if cjson.decode(ticket)['player_name'] == ARGV[1] then
+        return {'self', ticket}
+    end
+    redis.call('DEL', KEYS[1])
+    return {'ok', ticket}
+`)
+
+// SCRIPT LUA
+// Cancela o convite somente se ele ainda for deste anfitrião (mesmo ticket).
+//
+// KEYS[1] = a chave do convite (private:<código>)
+// ARGV[1] = o ticket do anfitrião (JSON)
+var cancelPrivateMatchScript = redis.NewScript(`
+    if redis.call('GET', KEYS[1]) == ARGV[1] then
+        return redis.call('DEL', KEYS[1])
+    end
+    return 0
+`)
+
+// PrivateInvite guarda, no servidor do anfitrião, o convite de partida privada pendente.
+type PrivateInvite struct {
+	Code      string
+	Ticket    string // Ticket do anfitrião, como gravado no Redis
+	ExpiresAt time.Time
+}
+
+// handleCreatePrivate processa o comando "CREATE_PRIVATE": gera um código de convite e o
+// devolve ao anfitrião como "PRIVATE_CODE|<código>|<segundos>". Um novo CREATE_PRIVATE
+// substitui o convite anterior.
+func (s *Server) handleCreatePrivate(player *PlayerState) {
+	if s.inMaintenance() {
+		s.sendLocalized(player, msgMaintenance)
+		return
+	}
+	player.mu.Lock()
+	searching := player.State == "Searching"
+	player.mu.Unlock()
+	if searching {
+		s.sendWebSocketMessage(player, "Saia da fila de matchmaking antes de criar uma partida privada.")
+		return
+	}
+	s.cancelPrivateMatch(player)
+
+	ticket, _ := json.Marshal(MatchmakingTicket{PlayerName: player.Name, ServerID: s.ServerID, Timestamp: time.Now().Unix()})
+	ttl := s.Config.PrivateMatchTTL
+
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	var code string
+	for attempt := 0; attempt < privateCodeAttempts && code == ""; attempt++ {
+		candidate := newInviteCode()
+		ok, err := s.RedisClient.SetNX(ctx, privateMatchPrefix+candidate, string(ticket), ttl).Result()
+		if err != nil {
+			slog.Error("Erro ao criar convite de partida privada", "player", player.Name, "error", err)
+			s.sendWebSocketMessage(player, "Erro interno ao criar a partida privada. Tente novamente.")
+			return
+		}
+		if ok {
+			code = candidate
+		}
+	}
+	if code == "" {
+		slog.Error("Não foi possível gerar um código de convite livre", "player", player.Name, "attempts", privateCodeAttempts)
+		s.sendWebSocketMessage(player, "Erro interno ao criar a partida privada. Tente novamente.")
+		return
+	}
+
+	invite := &PrivateInvite{Code: code, Ticket: string(ticket), ExpiresAt: time.Now().Add(ttl)}
+	player.mu.Lock()
+	player.privateInvite = invite
+	player.mu.Unlock()
+
+	slog.Info("Partida privada criada", "event", "private_match_created", "player", player.Name, "code", code)
+	s.sendWebSocketMessage(player, fmt.Sprintf("PRIVATE_CODE|%s|%d", code, int(ttl.Seconds())))
+	go s.expirePrivateInvite(player, invite)
+}
+
+// expirePrivateInvite avisa o anfitrião se o convite expirar sem que ninguém tenha entrado.
+// A chave no Redis expira sozinha pelo TTL.
+func (s *Server) expirePrivateInvite(player *PlayerState, invite *PrivateInvite) {
+	time.Sleep(time.Until(invite.ExpiresAt))
+
+	player.mu.Lock()
+	if player.privateInvite != invite {
+		// A partida começou, ou o convite foi cancelado ou substituído.
+		player.mu.Unlock()
+		return
+	}
+	player.privateInvite = nil
+	player.mu.Unlock()
+
+	s.sendWebSocketMessage(player, "PRIVATE_EXPIRED")
+}
+
+// cancelPrivateMatch descarta o convite pendente do jogador, se houver. Chamada quando o
+// anfitrião entra na fila, cria outro convite ou desconecta antes de alguém entrar.
+func (s *Server) cancelPrivateMatch(player *PlayerState) {
+	player.mu.Lock()
+	invite := player.privateInvite
+	player.privateInvite = nil
+	player.mu.Unlock()
+	if invite == nil {
+		return
+	}
+
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	if err := cancelPrivateMatchScript.Run(ctx, s.RedisClient, []string{privateMatchPrefix + invite.Code}, invite.Ticket).Err(); err != nil {
+		slog.Error("Erro ao cancelar convite de partida privada", "player", player.Name, "code", invite.Code, "error", err)
+		return
+	}
+	slog.Info("Partida privada cancelada", "event", "private_match_cancelled", "player", player.Name, "code", invite.Code)
+}
+
+// handleJoinPrivate processa o comando "JOIN_PRIVATE <código>": resgata o convite e inicia a
+// partida entre o anfitrião (P1) e o jogador que entrou (P2), pelo mesmo caminho do matchmaker.
+func (s *Server) handleJoinPrivate(player *PlayerState, command string) {
+	if s.inMaintenance() {
+		s.sendLocalized(player, msgMaintenance)
+		return
+	}
+	code := strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(command, "JOIN_PRIVATE")))
+	if code == "" {
+		s.sendWebSocketMessage(player, "Comando inválido. Use 'JOIN_PRIVATE [código]'.")
+		return
+	}
+
+	player.mu.Lock()
+	deckSize := len(player.Deck)
+	searching := player.State == "Searching"
+	player.mu.Unlock()
+	if searching {
+		s.sendWebSocketMessage(player, "Saia da fila de matchmaking antes de entrar em uma partida privada.")
+		return
+	}
+	if deckSize < s.Config.MinDeckSize {
+		s.sendWebSocketMessage(player, fmt.Sprintf("DECK_TOO_SMALL|%d", s.Config.MinDeckSize))
+		return
+	}
+
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	res, err := claimPrivateMatchScript.Run(ctx, s.RedisClient, []string{privateMatchPrefix + code}, player.Name).StringSlice()
+	if err == redis.Nil {
+		s.sendWebSocketMessage(player, "Código de partida privada inválido, expirado ou já utilizado.")
+		return
+	}
+	if err != nil || len(res) != 2 {
+		slog.Error("Erro ao resgatar convite de partida privada", "player", player.Name, "code", code, "error", err)
+		s.sendWebSocketMessage(player, "Erro interno ao entrar na partida privada. Tente novamente.")
+		return
+	}
+	if res[0] == "self" {
+		s.sendWebSocketMessage(player, "Você não pode entrar na sua própria partida privada. Compartilhe o código com um amigo.")
+		return
+	}
+
+	var hostTicket MatchmakingTicket
+	if err := json.Unmarshal([]byte(res[1]), &hostTicket); err != nil {
+		slog.Error("Convite de partida privada corrompido", "code", code, "error", err)
+		s.sendWebSocketMessage(player, "Erro interno ao entrar na partida privada. Tente novamente.")
+		return
+	}
+
+	gameID := newRandomID()
+	slog.Info("Partida privada confirmada", "event", "private_match_joined", "game_id", gameID, "code", code,
+		"player1", hostTicket.PlayerName, "server1_id", hostTicket.ServerID,
+		"player2", player.Name, "server2_id", s.ServerID)
+
+	joinTicket := MatchmakingTicket{PlayerName: player.Name, ServerID: s.ServerID, Timestamp: time.Now().Unix()}
+	// Em outra goroutine: startLocalGame trava o cmdMu deste jogador, travado pelo comando atual
+	go s.notifyMatchStart(gameID, hostTicket, joinTicket)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"log/slog"
+)
+
+// As filas de matchmaking (ZSET) são ordenadas pelo score do ticket, que é o timestamp de
+// entrada menos um ajuste de prioridade, em segundos (ver queueScore):
+//
+//	score = Timestamp - Priority
+//
+// Um ajuste de +N segundos equivale a ter entrado na fila N segundos antes; -N, N segundos depois.
+// Entre tickets com o mesmo ajuste, a ordem continua sendo a de chegada. Os ajustes são:
+//
+//	QUEUE_REQUEUE_BONUS    +N para o ticket devolvido à fila após uma partida abortada (requeueTickets)
+//	QUEUE_ABANDON_PENALTY  -N para quem abandonou uma partida (desconexão ou FORFEIT) há menos de
+//	                       QUEUE_ABANDON_WINDOW
+//
+// Nenhum jogador espera para sempre: o ajuste é limitado (menor que MATCHMAKING_TIMEOUT), então
+// um ticket penalizado só é ultrapassado por quem entrou até N segundos depois dele; todos os que
+// chegam depois disso ficam atrás. O Timestamp do ticket não muda, e continua sendo a base do
+// timeout da busca e da métrica de espera.
+
+// abandonedKeyPrefix marca, por QUEUE_ABANDON_WINDOW, o jogador que abandonou uma partida.
+const abandonedKeyPrefix = "player:abandoned:"
+
+// queueScore é o score do ticket na fila: o timestamp de entrada ajustado pela prioridade.
+func (t MatchmakingTicket) queueScore() float64 {
+	return float64(t.Timestamp - int64(t.Priority))
+}
+
+// queuePriority calcula o ajuste de prioridade de um novo ticket do jogador.
+func (s *Server) queuePriority(playerName string) int {
+	if s.Config.AbandonPenalty == 0 {
+		return 0
+	}
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	n, err := s.RedisClient.Exists(ctx, abandonedKeyPrefix+playerName).Result()
+	if err != nil {
+		slog.Error("Erro ao consultar abandono recente do jogador", "player", playerName, "error", err)
+		return 0
+	}
+	if n > 0 {
+		return -s.Config.AbandonPenalty
+	}
+	return 0
+}
+
+// recordAbandonment marca o jogador que perdeu a partida por desconexão ou FORFEIT,
+// para que a próxima busca dele entre na fila com a penalidade QUEUE_ABANDON_PENALTY.
+func (s *Server) recordAbandonment(playerName, resultMsg string) {
+	if s.Config.AbandonPenalty == 0 {
+		return
+	}
+	_, reason, _ := parseResultMessage(resultMsg)
+	if reason != resultReasonDisconnected && reason != resultReasonForfeit {
+		return
+	}
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	if err := s.RedisClient.Set(ctx, abandonedKeyPrefix+playerName, reason, s.Config.AbandonWindow).Err(); err != nil {
+		slog.Error("Erro ao registrar abandono de partida", "player", playerName, "error", err)
+		return
+	}
+	slog.Info("Abandono de partida registrado para a fila", "event", "queue_abandon_marked", "player", playerName,
+		"reason", reason, "window", s.Config.AbandonWindow)
+}
+
+// requeueScore é o score de um ticket devolvido à fila: o mesmo ticket (o conteúdo não muda,
+// para que o timeout da busca ainda o encontre), à frente pelo QUEUE_REQUEUE_BONUS. O bônus não
+// se acumula se o ticket for devolvido mais de uma vez.
+func (s *Server) requeueScore(t MatchmakingTicket) float64 {
+	return t.queueScore() - float64(s.Config.RequeueBonus)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestQueueOrderWithPriority(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.Config.RequeueBonus, s.Config.AbandonPenalty = 5, 10
+
+	// Entrada real (Timestamp) e ajuste de cada ticket; a ordem esperada é a do score
+	tickets := []MatchmakingTicket{
+		{PlayerName: "Penalizado", Timestamp: 100, Priority: -s.Config.AbandonPenalty}, // Conta como 110
+		{PlayerName: "Alice", Timestamp: 103},
+		{PlayerName: "Bob", Timestamp: 104},
+		{PlayerName: "Carol", Timestamp: 112},
+	}
+	scores := map[string]float64{}
+	for _, ticket := range tickets {
+		scores[ticket.PlayerName] = ticket.queueScore()
+	}
+	// Devolvido à fila depois de uma partida abortada: passa à frente pelo bônus (conta como 101)
+	requeued := MatchmakingTicket{PlayerName: "Devolvido", Timestamp: 106}
+	scores[requeued.PlayerName] = s.requeueScore(requeued)
+
+	names := make([]string, 0, len(scores))
+	for name := range scores {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return scores[names[i]] < scores[names[j]] })
+	want := []string{"Devolvido", "Alice", "Bob", "Penalizado", "Carol"}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("ordem da fila = %v, esperado %v", names, want)
+		}
+	}
+}
+
+func TestQueuePriorityDisabledWithZero(t *testing.T) {
+	t.Setenv("QUEUE_REQUEUE_BONUS", "0")
+	t.Setenv("QUEUE_ABANDON_PENALTY", "0")
+	s, mr := newTestServer(t)
+
+	s.recordAbandonment("Alice", resultMessage("DERROTA", resultReasonForfeit))
+	if mr.Exists(abandonedKeyPrefix + "Alice") {
+		t.Errorf("sem penalidade, o abandono não deveria ser registrado")
+	}
+	mr.Set(abandonedKeyPrefix+"Bob", resultReasonDisconnected)
+	if got := s.queuePriority("Bob"); got != 0 {
+		t.Errorf("queuePriority = %d, esperado 0 sem penalidade", got)
+	}
+	ticket := MatchmakingTicket{PlayerName: "Carol", Timestamp: 100}
+	if got := s.requeueScore(ticket); got != ticket.queueScore() {
+		t.Errorf("requeueScore = %v, esperado o score original (%v) sem bônus", got, ticket.queueScore())
+	}
+}
+
+func TestAbandonmentPenalizesNextSearch(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	// Só a desconexão e o FORFEIT contam como abandono
+	s.recordAbandonment("Alice", resultMessage("DERROTA", resultReasonTimeout))
+	if got := s.queuePriority("Alice"); got != 0 {
+		t.Errorf("derrota por timeout não é abandono: queuePriority = %d, esperado 0", got)
+	}
+	s.recordAbandonment("Alice", resultMessage("DERROTA", resultReasonDisconnected))
+	if got := s.queuePriority("Alice"); got != -s.Config.AbandonPenalty {
+		t.Errorf("queuePriority = %d, esperado -%d depois de abandonar", got, s.Config.AbandonPenalty)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// queueStatusInterval é o intervalo entre as consultas da posição do jogador na fila.
+	queueStatusInterval = 2 * time.Second
+	// stillSearchingInterval é o intervalo entre os avisos "STILL_SEARCHING" a um jogador sozinho na fila.
+	stillSearchingInterval = 5 * time.Second
+)
+
+// queueStatusLoop envia "QUEUE_STATUS|<posição>|<total>" ao jogador enquanto ele estiver na fila.
+// A posição vem do ZRANK do seu ticket (1 = o mais antigo) e o total do ZCARD da fila.
+// Só envia quando algo muda, e para assim que o ticket sai da fila (pareado, timeout ou desconexão).
+//
+// Sozinho na fila, o jogador não recebe nenhuma mudança: a cada stillSearchingInterval recebe
+// "STILL_SEARCHING|<segundos restantes>", para saber que a busca continua. O aviso não é enviado
+// quando o timeout da busca chega antes do próximo intervalo (o jogador vai receber o resultado dela).
+// Com BOT_FALLBACK, quem está sozinho na fila clássica há BOT_FALLBACK_ALONE_AFTER já joga contra o bot.
+func (s *Server) queueStatusLoop(player *PlayerState, queueKey, ticketJSON string, searchStarted time.Time) {
+	ticker := time.NewTicker(queueStatusInterval)
+	defer ticker.Stop()
+
+	deadline := searchStarted.Add(s.Config.MatchmakingTimeout)
+	lastNudge := searchStarted
+	lastPosition, lastTotal := int64(-1), int64(-1)
+	for {
+		player.mu.Lock()
+		searching := player.State == "Searching"
+		player.mu.Unlock()
+		if !searching {
+			return
+		}
+
+		ctx, cancel := s.redisCtx()
+		rank, err := s.RedisClient.ZRank(ctx, queueKey, ticketJSON).Result()
+		if err == redis.Nil {
+			cancel()
+			return // O ticket saiu da fila
+		}
+		if err != nil {
+			slog.Error("Erro ao consultar posição na fila", "player", player.Name, "error", err)
+		} else if total, err := s.RedisClient.ZCard(ctx, queueKey).Result(); err == nil {
+			if rank+1 != lastPosition || total != lastTotal {
+				lastPosition, lastTotal = rank+1, total
+				s.sendWebSocketMessage(player, fmt.Sprintf("QUEUE_STATUS|%d|%d", lastPosition, lastTotal))
+			}
+			if total == 1 {
+				if s.Config.BotFallback && queueKey == matchmakingQueueKey && time.Since(searchStarted) >= s.Config.BotFallbackAlone {
+					cancel()
+					s.fallBackToBotAlone(player, ticketJSON)
+					return
+				}
+				if remaining := time.Until(deadline); time.Since(lastNudge) >= stillSearchingInterval && remaining > stillSearchingInterval {
+					lastNudge = time.Now()
+					s.sendWebSocketMessage(player, fmt.Sprintf("STILL_SEARCHING|%d", int(remaining.Seconds())))
+				}
+			} else {
+				lastNudge = time.Now() // Há outros jogadores: o próximo aviso conta a partir de quando ficar sozinho
+			}
+		}
+		cancel()
+
+		select {
+		case <-player.done:
+			return
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fallBackToBotAlone tira da fila o jogador que está sozinho nela e inicia a partida contra o bot
+// sem esperar o timeout da busca. Se o matchmaker já tiver retirado o ticket (pareamento em
+// andamento), nada é feito: o jogador segue para a partida encontrada.
+func (s *Server) fallBackToBotAlone(player *PlayerState, ticketJSON string) {
+	// Mesma transição do matchmakingTimeout, com os comandos do jogador travados
+	player.cmdMu.Lock()
+	defer player.cmdMu.Unlock()
+
+	player.mu.Lock()
+	searching := player.State == "Searching"
+	player.mu.Unlock()
+	if !searching || s.inMaintenance() {
+		return // Em manutenção, a busca termina no timeout, sem partida contra o bot
+	}
+
+	ctx, cancel := s.redisCtx()
+	removed, err := s.RedisClient.ZRem(ctx, matchmakingQueueKey, ticketJSON).Result()
+	cancel()
+	if err != nil || removed == 0 {
+		if err != nil {
+			slog.Error("Erro ao remover ticket da fila para a partida contra bot", "player", player.Name, "error", err)
+		}
+		return
+	}
+
+	player.mu.Lock()
+	player.State = player.idleState()
+	player.mu.Unlock()
+
+	matchmakingResultsTotal.WithLabelValues(matchModeLabel(gameModeClassic), "bot_fallback").Inc()
+	slog.Info("Jogador sozinho na fila. Iniciando partida contra bot antes do timeout.", "event", "bot_fallback_alone", "player", player.Name)
+	s.startBotGame(player)
+}
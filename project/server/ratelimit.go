@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenBucket é um limitador de taxa simples: acumula até `capacity` fichas,
+// repostas continuamente a `rate` fichas por segundo.
+type tokenBucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	capacity float64
+	rate     float64
+	last     time.Time
+}
+
+// newTokenBucket cria um balde cheio.
+func newTokenBucket(rate float64, capacity int) *tokenBucket {
+	return &tokenBucket{
+		tokens:   float64(capacity),
+		capacity: float64(capacity),
+		rate:     rate,
+		last:     time.Now(),
+	}
+}
+
+// allow consome `cost` fichas, se houver. Retorna false (sem consumir) se não houver.
+func (b *tokenBucket) allow(cost float64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+
+	if b.tokens < cost {
+		return false
+	}
+	b.tokens -= cost
+	return true
+}
+
+// commandCost retorna quantas fichas um comando consome. Comandos que tocam o estoque,
+// a fila de trocas ou o matchmaker custam mais que as consultas simples.
+func (s *Server) commandCost(command string) float64 {
+	switch {
+	case strings.HasPrefix(command, "OPEN_PACK"), // OPEN_PACK e OPEN_PACKS
+		strings.HasPrefix(command, "TRADE_CARD"),
+		strings.HasPrefix(command, "FIND_MATCH"),
+		strings.HasPrefix(command, "JOIN_PRIVATE"): // Também dificulta adivinhar códigos de convite
+		return float64(s.Config.HeavyCommandCost)
+	default:
+		return 1
+	}
+}
+
+// allowCommand aplica o limite de taxa do jogador. As jogadas dentro de uma partida
+// ("1", "2", ..., "PLAY <gameID> <carta>") nunca são limitadas, para que o jogador não perca por timeout;
+// os demais comandos pagam as suas fichas mesmo durante a partida.
+func (s *Server) allowCommand(player *PlayerState, command string, inGame bool) bool {
+	if inGame && isGameMove(command) {
+		return true
+	}
+	return player.limiter.allow(s.commandCost(command))
+}
+
+// isGameMove informa se o comando é uma jogada: o número da carta ou "PLAY <gameID> <carta>".
+func isGameMove(command string) bool {
+	if strings.HasPrefix(command, playCommandPrefix) {
+		return true
+	}
+	if command == "" {
+		return false
+	}
+	for _, c := range command {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestRateLimitDuringGame(t *testing.T) {
+	s, _ := newTestServer(t)
+	player := newTestPlayer("Alice")
+	player.limiter = newTokenBucket(0.001, 3)
+
+	// Com o balde vazio, as jogadas continuam passando...
+	if !s.allowCommand(player, "OPEN_PACK", true) {
+		t.Fatalf("o primeiro OPEN_PACK deveria caber no balde")
+	}
+	for _, move := range []string{"1", "2", "PLAY g1 1"} {
+		if !s.allowCommand(player, move, true) {
+			t.Errorf("a jogada %q não deveria ser limitada", move)
+		}
+	}
+
+	// ...mas os demais comandos pagam as suas fichas mesmo durante a partida
+	for _, command := range []string{"OPEN_PACK", "TRADE_CARD 1", "JOIN_PRIVATE ABC", "STATUS"} {
+		if s.allowCommand(player, command, true) {
+			t.Errorf("%q deveria ser limitado durante a partida", command)
+		}
+	}
+
+	// Fora da partida, um número não é jogada e também paga
+	if s.allowCommand(player, "1", false) {
+		t.Errorf("fora da partida, \"1\" deveria ser limitado")
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Modos de conexão ao Redis (REDIS_MODE).
+const (
+	redisModeSingle   = "single"   // Um único nó (REDIS_ADDR)
+	redisModeSentinel = "sentinel" // Master monitorado pelo Sentinel, com failover automático
+	redisModeCluster  = "cluster"  // Redis Cluster: recusado (ver newRedisClient)
+)
+
+// redisOptions monta as opções do cliente Redis a partir das variáveis de ambiente:
+//
+//	REDIS_MODE              single (padrão) ou sentinel (cluster é recusado, ver newRedisClient)
+//	REDIS_ADDR              endereço host:porta do modo single (padrão: localhost:6379)
+//	REDIS_SENTINEL_ADDRS    endereços dos sentinels, separados por vírgula (modo sentinel)
+//	REDIS_MASTER_NAME       nome do master monitorado pelos sentinels (modo sentinel)
+//	REDIS_SENTINEL_PASSWORD senha dos sentinels, se diferente da do Redis (modo sentinel)
+//	REDIS_PASSWORD          senha (AUTH); vazia para Redis sem autenticação
+//	REDIS_USERNAME          usuário ACL (Redis 6+), opcional
+//	REDIS_DB                número do banco (padrão: 0)
+//	REDIS_POOL_SIZE         conexões no pool (padrão do go-redis: 10 por CPU)
+//	REDIS_TLS               habilita TLS (Redis gerenciado)
+//
+// Retorna também o modo validado, usado por newRedisClient.
+func redisOptions() (*redis.UniversalOptions, string, error) {
+	mode := strings.ToLower(os.Getenv("REDIS_MODE"))
+	if mode == "" {
+		mode = redisModeSingle
+	}
+	opts := &redis.UniversalOptions{
+		Username: os.Getenv("REDIS_USERNAME"),
+		Password: os.Getenv("REDIS_PASSWORD"),
+	}
+
+	switch mode {
+	case redisModeSingle:
+		addr := os.Getenv("REDIS_ADDR")
+		if addr == "" {
+			addr = "localhost:6379" // Default para desenvolvimento local
+		}
+		opts.Addrs = []string{addr}
+	case redisModeSentinel:
+		opts.MasterName = os.Getenv("REDIS_MASTER_NAME")
+		opts.Addrs = splitAddrs(os.Getenv("REDIS_SENTINEL_ADDRS"))
+		opts.SentinelPassword = os.Getenv("REDIS_SENTINEL_PASSWORD")
+		if opts.MasterName == "" || len(opts.Addrs) == 0 {
+			return nil, mode, fmt.Errorf("REDIS_MODE=sentinel exige REDIS_MASTER_NAME e REDIS_SENTINEL_ADDRS")
+		}
+	case redisModeCluster:
+		// Recusado por newRedisClient
+	default:
+		return nil, mode, fmt.Errorf("REDIS_MODE deve ser single ou sentinel, recebido %q", mode)
+	}
+
+	if raw := os.Getenv("REDIS_DB"); raw != "" {
+		db, err := strconv.Atoi(raw)
+		if err != nil || db < 0 {
+			return nil, mode, fmt.Errorf("REDIS_DB deve ser um inteiro não negativo, recebido %q", raw)
+		}
+		opts.DB = db
+	}
+
+	var err error
+	if os.Getenv("REDIS_POOL_SIZE") != "" {
+		if opts.PoolSize, err = envInt("REDIS_POOL_SIZE", 0); err != nil {
+			return nil, mode, err
+		}
+	}
+
+	useTLS, err := envBool("REDIS_TLS", false)
+	if err != nil {
+		return nil, mode, err
+	}
+	if useTLS {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return opts, mode, nil
+}
+
+// newRedisClient cria o cliente do modo escolhido. O resto do servidor usa apenas a interface
+// redis.UniversalClient, então funciona igual nos modos single e sentinel.
+// (redis.NewUniversalClient escolhe o modo pelo número de endereços; aqui ele é explícito.)
+//
+// O modo cluster é recusado: os scripts Lua e pipelines do servidor (fila + lock, troca pendente,
+// pendentes do jogador, capacidade...) usam várias chaves sem um hash tag comum, que no cluster
+// caem em slots diferentes e falham com CROSSSLOT.
+func newRedisClient(mode string, opts *redis.UniversalOptions) (redis.UniversalClient, error) {
+	switch mode {
+	case redisModeSentinel:
+		return redis.NewFailoverClient(opts.Failover()), nil
+	case redisModeCluster:
+		return nil, fmt.Errorf("REDIS_MODE=cluster não é suportado: as operações com várias chaves falhariam com CROSSSLOT; use single ou sentinel")
+	default:
+		return redis.NewClient(opts.Simple()), nil
+	}
+}
+
+// scanKeys lista as chaves que casam com o padrão (SCAN, sem bloquear o Redis como o KEYS).
+func (s *Server) scanKeys(ctx context.Context, match string) ([]string, error) {
+	var keys []string
+	iter := s.RedisClient.Scan(ctx, 0, match, 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// splitAddrs separa uma lista de endereços "host:porta" separados por vírgula, ignorando vazios.
+func splitAddrs(raw string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(raw, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// isRedisAuthError indica se o erro do Redis é de autenticação (senha/usuário incorretos ou ausentes).
+func isRedisAuthError(err error) bool {
+	msg := err.Error()
+	return strings.HasPrefix(msg, "WRONGPASS") || strings.HasPrefix(msg, "NOAUTH") ||
+		strings.Contains(msg, "invalid password") || strings.Contains(msg, "invalid username-password")
+}
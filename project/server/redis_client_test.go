@@ -0,0 +1,15 @@
+package main
+
+import "testing"
+
+func TestClusterModeRejected(t *testing.T) {
+	t.Setenv("REDIS_MODE", "cluster")
+	opts, mode, err := redisOptions()
+	if err != nil {
+		t.Fatalf("redisOptions: %v", err)
+	}
+	if client, err := newRedisClient(mode, opts); err == nil {
+		client.Close()
+		t.Fatalf("o modo cluster deveria ser recusado na inicialização")
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// redisCtx retorna o contexto de uma operação (ou de um grupo curto de operações) no Redis:
+// limitado por REDIS_TIMEOUT e cancelado quando o servidor começa a encerrar.
+// Um Redis travado faz a chamada falhar em vez de prender a goroutine. Sempre chame o cancel.
+func (s *Server) redisCtx() (context.Context, context.CancelFunc) {
+	return s.redisCtxTimeout(s.Config.RedisTimeout)
+}
+
+// redisCtxTimeout é como redisCtx, com um limite próprio para operações longas
+// (ex: criar ou reembaralhar o estoque inteiro).
+func (s *Server) redisCtxTimeout(timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(s.ctx, timeout)
+}
+
+// shuttingDown informa se o servidor já começou a encerrar. Usado pelos laços de fundo.
+func (s *Server) shuttingDown() bool {
+	return s.ctx.Err() != nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	serverRegistryPrefix   = "servers:"
+	serverRegistryTTL      = 30 * time.Second
+	serverRegistryInterval = 10 * time.Second
+)
+
+// registerServer grava no Redis o endereço REST deste servidor (servers:<ServerID>) com TTL.
+func (s *Server) registerServer() error {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	return s.RedisClient.Set(ctx, serverRegistryPrefix+s.ServerID, s.RestAddr, serverRegistryTTL).Err()
+}
+
+// serverRegistryLoop renova periodicamente o registro deste servidor.
+// Se o servidor cair sem encerrar graciosamente, o registro expira sozinho.
+func (s *Server) serverRegistryLoop() {
+	ticker := time.NewTicker(serverRegistryInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if s.shuttingDown() {
+			return
+		}
+		if err := s.registerServer(); err != nil {
+			slog.Error("Erro ao renovar registro do servidor", "error", err)
+		}
+		if s.inMaintenance() {
+			if err := s.publishMaintenance(true); err != nil {
+				slog.Error("Erro ao renovar a marca de manutenção", "error", err)
+			}
+		}
+	}
+}
+
+// unregisterServer remove o registro deste servidor (encerramento gracioso).
+func (s *Server) unregisterServer() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.RedisClient.Del(ctx, serverRegistryPrefix+s.ServerID).Err(); err != nil {
+		slog.Error("Erro ao remover registro do servidor", "error", err)
+		return
+	}
+	slog.Info("Registro do servidor removido.", "event", "server_unregistered")
+}
+
+// lookupServerAddr resolve o endereço REST de um servidor a partir do registro no Redis.
+func (s *Server) lookupServerAddr(serverID string) (string, error) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	addr, err := s.RedisClient.Get(ctx, serverRegistryPrefix+serverID).Result()
+	if err == redis.Nil {
+		return "", fmt.Errorf("servidor %s não está registrado", serverID)
+	}
+	if err != nil {
+		return "", err
+	}
+	return addr, nil
+}
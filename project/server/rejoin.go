@@ -0,0 +1,252 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Retomada da conexão (REJOIN). Cada conexão recebe um token em "RECONNECT_TOKEN|<token>".
+// Quando a conexão cai no meio de uma partida, o jogador não perde por W.O. na hora: ele fica
+// "desligado" (detached) por RECONNECT_GRACE, com o nome reservado, as partidas, o deck e as
+// mensagens que chegarem nesse meio-tempo. Uma nova conexão ao MESMO servidor que comece com
+// "REJOIN|<nome>|<token>" assume o jogador e recebe "REJOINED", o novo token e as mensagens
+// guardadas; depois segue como qualquer conexão. Sem a retomada a tempo, a desconexão é
+// publicada na partida como antes (ver disconnect.go).
+//
+// A conexão antiga pode ainda parecer viva (a queda da rede não fecha o socket deste lado):
+// o REJOIN com o token certo a encerra e assume o jogador no lugar dela, em vez de recusar o
+// nome. As mãos das partidas ficam só na memória deste servidor, por isso a retomada não
+// funciona em outro servidor: lá, a conexão recebe "REJOIN_FAILED" e o cliente entra de novo
+// pelo login normal.
+
+const (
+	rejoinPrefix         = "REJOIN|"
+	rejoinedMessage      = "REJOINED"
+	rejoinFailedMessage  = "REJOIN_FAILED"
+	reconnectTokenPrefix = "RECONNECT_TOKEN|"
+	// maxMissedMessages limita as mensagens guardadas para um jogador desligado.
+	maxMissedMessages = 64
+)
+
+// parseRejoin lê a primeira mensagem "REJOIN|<nome>|<token>".
+func parseRejoin(message string) (name, token string, ok bool) {
+	rest, ok := strings.CutPrefix(message, rejoinPrefix)
+	if !ok {
+		return "", "", false
+	}
+	name, token, ok = strings.Cut(rest, "|")
+	if !ok || name == "" || token == "" {
+		return "", "", false
+	}
+	return name, token, true
+}
+
+// holdForRejoin guarda uma mensagem enviada a um jogador desligado, para entregá-la no REJOIN.
+// Chamada por enqueueMessage quando a conexão do jogador já foi encerrada.
+func (p *PlayerState) holdForRejoin(message string) {
+	p.missedMu.Lock()
+	defer p.missedMu.Unlock()
+	if p.detached.Load() && len(p.missed) < maxMissedMessages {
+		p.missed = append(p.missed, message)
+	}
+}
+
+// takeMissed retira as mensagens guardadas e deixa de guardar novas.
+func (p *PlayerState) takeMissed() []string {
+	p.missedMu.Lock()
+	defer p.missedMu.Unlock()
+	p.detached.Store(false)
+	missed := p.missed
+	p.missed = nil
+	return missed
+}
+
+// detachPlayer registra o jogador, cuja conexão caiu no meio de uma partida, como desligado.
+// Retorna false se ele não pode esperar o REJOIN (fora de partida) ou se outra conexão já o
+// assumiu. Chamada na limpeza da conexão, antes de fechar player.done.
+func (s *Server) detachPlayer(player *PlayerState) bool {
+	player.mu.Lock()
+	inGame := len(player.Games) > 0
+	player.mu.Unlock()
+
+	s.PlayerMutex.Lock()
+	defer s.PlayerMutex.Unlock()
+	if s.Players[player.Name] == player {
+		delete(s.Players, player.Name)
+	}
+	if player.takenOver.Load() || !inGame {
+		return false
+	}
+	player.detached.Store(true)
+	s.detached[player.Name] = player
+	return true
+}
+
+// expireDetached encerra a espera pelo REJOIN: se o jogador não voltou, a desconexão é
+// publicada nas partidas que ainda estão em andamento e o nome é liberado.
+func (s *Server) expireDetached(player *PlayerState) {
+	s.PlayerMutex.Lock()
+	expired := s.detached[player.Name] == player
+	if expired {
+		delete(s.detached, player.Name)
+	}
+	s.PlayerMutex.Unlock()
+	if !expired {
+		return // Voltou com REJOIN
+	}
+	player.takeMissed()
+	slog.Info("Jogador não voltou a tempo; desconexão publicada.", "event", "rejoin_expired", "player", player.Name)
+	s.handleInGameDisconnect(player)
+	s.releasePlayerName(player.Name, player.presenceToken)
+}
+
+// takeOverPlayer encontra o jogador que o token de REJOIN identifica: um desligado, ou um
+// ainda conectado neste servidor (conexão antiga meio aberta), que é encerrado. Retorna nil
+// se o token não corresponder a nenhum jogador deste servidor.
+func (s *Server) takeOverPlayer(name, token string) *PlayerState {
+	validToken := func(p *PlayerState) bool {
+		return p != nil && subtle.ConstantTimeCompare([]byte(p.reconnectToken), []byte(token)) == 1
+	}
+
+	s.PlayerMutex.Lock()
+	if old := s.detached[name]; validToken(old) {
+		delete(s.detached, name)
+		s.PlayerMutex.Unlock()
+		return old
+	}
+	old := s.Players[name]
+	if !validToken(old) {
+		s.PlayerMutex.Unlock()
+		return nil
+	}
+	// A limpeza da conexão antiga (listenClientCommands) vê takenOver sob PlayerMutex
+	// e não publica a desconexão nem libera o nome
+	old.takenOver.Store(true)
+	old.detached.Store(true) // Guarda o que chegar até a transferência
+	s.PlayerMutex.Unlock()
+
+	old.WsConn.Close()
+	<-old.done
+	return old
+}
+
+// transferPlayer copia para a nova conexão o estado do jogador antigo e troca o jogador
+// antigo pelo novo nas sessões das partidas em andamento.
+func transferPlayer(old, player *PlayerState) {
+	old.mu.Lock()
+	player.Deck = old.Deck
+	player.PacksOpened = old.PacksOpened
+	player.State = old.State
+	player.Games = old.Games
+	player.loadout = old.loadout
+	player.locale = old.locale
+	player.botDifficulty = old.botDifficulty
+	player.lastGameID = old.lastGameID
+	player.presenceToken = old.presenceToken
+	old.Games = make(map[string]*GameSession)
+	old.Deck = nil
+	old.mu.Unlock()
+
+	for _, session := range player.Games {
+		session.mu.Lock()
+		if session.Player1 == old {
+			session.Player1 = player
+		}
+		if session.Player2 == old {
+			session.Player2 = player
+		}
+		for i, p := range session.Players {
+			if p == old {
+				session.Players[i] = player
+			}
+		}
+		session.mu.Unlock()
+	}
+}
+
+// lockPlayer1Commands trava o cmdMu do P1 da sessão e o retorna. O P1 só muda num REJOIN
+// (transferPlayer), mas a ordem dos locks obriga a ler Player1 antes de travar o cmdMu dele:
+// a leitura é conferida depois de travar e refeita se o jogador foi trocado no meio.
+func (session *GameSession) lockPlayer1Commands() *PlayerState {
+	for {
+		session.mu.Lock()
+		player1 := session.Player1
+		session.mu.Unlock()
+		player1.cmdMu.Lock()
+		session.mu.Lock()
+		same := session.Player1 == player1
+		session.mu.Unlock()
+		if same {
+			return player1
+		}
+		player1.cmdMu.Unlock()
+	}
+}
+
+// handleRejoin atende uma conexão que começou com "REJOIN|<nome>|<token>".
+func (s *Server) handleRejoin(conn *websocket.Conn, r *http.Request, name, token string) {
+	old := s.takeOverPlayer(name, token)
+	if old == nil {
+		slog.Info("REJOIN recusado: token desconhecido neste servidor.", "event", "rejoin_failed", "remote_addr", r.RemoteAddr, "player", name)
+		conn.WriteMessage(websocket.TextMessage, []byte(rejoinFailedMessage))
+		conn.Close()
+		return
+	}
+
+	player := &PlayerState{
+		Name:           name,
+		WsConn:         conn,
+		ServerID:       s.ServerID,
+		mu:             sync.Mutex{},
+		done:           make(chan struct{}),
+		limiter:        newTokenBucket(float64(s.Config.CommandRate), s.Config.CommandBurst),
+		outbox:         make(chan outboxItem, outboxSize),
+		reconnectToken: newRandomID(),
+	}
+	transferPlayer(old, player)
+	missed := old.takeMissed()
+
+	// A reserva do nome continua a mesma (o token de presença passa para a nova conexão)
+	if res, err := s.refreshPresence(player); err == nil && res == presenceLost {
+		slog.Warn("REJOIN recusado: o nome passou a outra conexão.", "event", "rejoin_failed", "player", name)
+		conn.WriteMessage(websocket.TextMessage, []byte(rejoinFailedMessage))
+		conn.Close()
+		return
+	}
+
+	s.PlayerMutex.Lock()
+	s.Players[name] = player
+	s.PlayerMutex.Unlock()
+
+	player.mu.Lock()
+	games := player.gameIDs()
+	player.mu.Unlock()
+	slog.Info("Jogador retomou a conexão.", "event", "player_rejoined", "player", name, "games", games, "missed", len(missed))
+	s.audit(name, auditConnect, "", "rejoin")
+	go s.writeLoop(player)
+	s.sendWebSocketMessage(player, rejoinedMessage)
+	s.sendWebSocketMessage(player, reconnectTokenPrefix+player.reconnectToken)
+	for _, message := range missed {
+		s.sendWebSocketMessage(player, message)
+	}
+	go s.presenceHeartbeatLoop(player)
+	go s.listenRedisPubSub(player)
+	s.listenClientCommands(player)
+}
+
+// startRejoinGrace mantém o jogador desligado por RECONNECT_GRACE. A reserva do nome é renovada
+// agora (o heartbeat da conexão parou) e dura mais que a espera (ver loadConfig).
+func (s *Server) startRejoinGrace(player *PlayerState) {
+	if _, err := s.refreshPresence(player); err != nil {
+		slog.Error("Erro ao renovar presença do jogador desligado", "player", player.Name, "error", err)
+	}
+	slog.Info("Jogador caiu no meio da partida; aguardando REJOIN.", "event", "player_detached", "player", player.Name,
+		"grace", s.Config.ReconnectGrace)
+	time.AfterFunc(s.Config.ReconnectGrace, func() { s.expireDetached(player) })
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestParseRejoin(t *testing.T) {
+	tests := []struct {
+		message     string
+		name, token string
+		ok          bool
+	}{
+		{"REJOIN|Alice|abc123", "Alice", "abc123", true},
+		{"REJOIN|Alice|", "", "", false},
+		{"REJOIN||abc123", "", "", false},
+		{"REJOIN|Alice", "", "", false},
+		{"Alice", "", "", false},
+		{"AUTH|Alice|abc123", "", "", false},
+	}
+	for _, tt := range tests {
+		name, token, ok := parseRejoin(tt.message)
+		if name != tt.name || token != tt.token || ok != tt.ok {
+			t.Errorf("parseRejoin(%q) = (%q, %q, %v), esperado (%q, %q, %v)", tt.message, name, token, ok, tt.name, tt.token, tt.ok)
+		}
+	}
+}
+
+// startTestWebSocket serve handleWebSocketConnection e retorna a URL ws:// do servidor.
+func startTestWebSocket(t *testing.T, s *Server) string {
+	t.Helper()
+	hs := httptest.NewServer(http.HandlerFunc(s.handleWebSocketConnection))
+	t.Cleanup(hs.Close)
+	return "ws" + strings.TrimPrefix(hs.URL, "http")
+}
+
+// dialTestPlayer conecta, envia a primeira mensagem e retorna a conexão.
+func dialTestPlayer(t *testing.T, url, first string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(first)); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	return conn
+}
+
+// readUntilPrefix lê mensagens até uma que comece com prefix e retorna o restante dela.
+func readUntilPrefix(t *testing.T, conn *websocket.Conn, prefix string) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		_, p, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("esperava %q, leitura falhou: %v", prefix, err)
+		}
+		if rest, ok := strings.CutPrefix(string(p), prefix); ok {
+			return rest
+		}
+	}
+}
+
+// waitFor espera a condição ficar verdadeira (ou falha o teste após alguns segundos).
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("tempo esgotado esperando: %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// connectedPlayer retorna o jogador conectado com o nome, quando houver.
+func connectedPlayer(s *Server, name string) *PlayerState {
+	s.PlayerMutex.Lock()
+	defer s.PlayerMutex.Unlock()
+	return s.Players[name]
+}
+
+// joinTestGame coloca o jogador conectado numa partida clássica contra um oponente remoto.
+func joinTestGame(s *Server, player *PlayerState, gameID string) *GameSession {
+	session := &GameSession{
+		GameID:    gameID,
+		Mode:      gameModeClassic,
+		Player1:   player,
+		Player2:   &PlayerState{Name: "Bob", Games: make(map[string]*GameSession)},
+		Server1ID: s.ServerID,
+		Server2ID: "Server-Other",
+	}
+	player.mu.Lock()
+	player.Games[gameID] = session
+	player.State = "InGame"
+	player.mu.Unlock()
+	s.GamesMutex.Lock()
+	s.ActiveGames[gameID] = session
+	s.GamesMutex.Unlock()
+	return session
+}
+
+func TestRejoinAfterMidGameDrop(t *testing.T) {
+	t.Setenv("RECONNECT_GRACE", "500ms")
+	s, mr := newTestServer(t)
+	url := startTestWebSocket(t, s)
+
+	conn := dialTestPlayer(t, url, "Alice")
+	token := readUntilPrefix(t, conn, reconnectTokenPrefix)
+	old := connectedPlayer(s, "Alice")
+	session := joinTestGame(s, old, "g1")
+	presence, _ := mr.Get(playerOnlinePrefix + "Alice")
+
+	// O oponente escuta o canal da partida: a queda não pode ser publicada antes da espera
+	disconnects := s.RedisClient.Subscribe(context.Background(), "game:channel:g1")
+	defer disconnects.Close()
+	if _, err := disconnects.Receive(context.Background()); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	conn.Close()
+	waitFor(t, "jogador desligado", func() bool {
+		s.PlayerMutex.Lock()
+		defer s.PlayerMutex.Unlock()
+		return s.detached["Alice"] == old
+	})
+	s.sendWebSocketMessage(old, "TIMER|7") // Chega durante a queda: entregue no REJOIN
+
+	// Um login novo com o nome ainda é recusado: a partida segue reservada para o REJOIN
+	other := dialTestPlayer(t, url, "Alice")
+	readUntilPrefix(t, other, "NAME_TAKEN")
+
+	rejoined := dialTestPlayer(t, url, rejoinPrefix+"Alice|"+token)
+	readUntilPrefix(t, rejoined, rejoinedMessage)
+	newToken := readUntilPrefix(t, rejoined, reconnectTokenPrefix)
+	if newToken == "" || newToken == token {
+		t.Errorf("REJOIN deveria renovar o token, recebido %q", newToken)
+	}
+	readUntilPrefix(t, rejoined, "TIMER|7")
+
+	player := connectedPlayer(s, "Alice")
+	if player == nil || player == old {
+		t.Fatalf("o REJOIN deveria registrar a nova conexão em Players")
+	}
+	session.mu.Lock()
+	swapped := session.Player1 == player
+	session.mu.Unlock()
+	if !swapped {
+		t.Errorf("a sessão da partida deveria apontar para a nova conexão")
+	}
+	player.mu.Lock()
+	inGame := player.Games["g1"] == session && player.State == "InGame"
+	player.mu.Unlock()
+	if !inGame {
+		t.Errorf("a partida e o estado deveriam passar para a nova conexão")
+	}
+	if got, _ := mr.Get(playerOnlinePrefix + "Alice"); got != presence {
+		t.Errorf("a reserva do nome deveria continuar com o mesmo token, antes %q, agora %q", presence, got)
+	}
+
+	// Depois da espera, nada foi publicado: a queda não virou derrota
+	time.Sleep(s.Config.ReconnectGrace + 100*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if msg, err := disconnects.ReceiveMessage(ctx); err == nil {
+		t.Errorf("nenhuma desconexão deveria ser publicada após o REJOIN, recebido %q", msg.Payload)
+	}
+}
+
+func TestRejoinTakesOverHalfOpenConnection(t *testing.T) {
+	s, mr := newTestServer(t)
+	url := startTestWebSocket(t, s)
+
+	conn := dialTestPlayer(t, url, "Alice")
+	token := readUntilPrefix(t, conn, reconnectTokenPrefix)
+	old := connectedPlayer(s, "Alice")
+	presence, _ := mr.Get(playerOnlinePrefix + "Alice")
+
+	// A conexão antiga continua aberta do lado do servidor (queda da rede do cliente)
+	rejoined := dialTestPlayer(t, url, rejoinPrefix+"Alice|"+token)
+	readUntilPrefix(t, rejoined, rejoinedMessage)
+
+	if !old.isDisconnected() {
+		t.Errorf("o REJOIN deveria encerrar a conexão antiga")
+	}
+	if player := connectedPlayer(s, "Alice"); player == nil || player == old {
+		t.Errorf("o REJOIN deveria assumir o lugar da conexão antiga")
+	}
+	// A limpeza da conexão antiga não libera o nome da nova
+	time.Sleep(100 * time.Millisecond)
+	if got, _ := mr.Get(playerOnlinePrefix + "Alice"); got != presence {
+		t.Errorf("a reserva do nome deveria continuar com a nova conexão, antes %q, agora %q", presence, got)
+	}
+}
+
+func TestRejoinRejectsUnknownToken(t *testing.T) {
+	s, _ := newTestServer(t)
+	url := startTestWebSocket(t, s)
+
+	conn := dialTestPlayer(t, url, "Alice")
+	readUntilPrefix(t, conn, reconnectTokenPrefix)
+	old := connectedPlayer(s, "Alice")
+
+	intruder := dialTestPlayer(t, url, rejoinPrefix+"Alice|token-errado")
+	readUntilPrefix(t, intruder, rejoinFailedMessage)
+	if connectedPlayer(s, "Alice") != old || old.isDisconnected() {
+		t.Errorf("um token errado não pode derrubar a conexão do jogador")
+	}
+}
+
+func TestRejoinGraceExpires(t *testing.T) {
+	t.Setenv("RECONNECT_GRACE", "200ms")
+	s, mr := newTestServer(t)
+	url := startTestWebSocket(t, s)
+
+	conn := dialTestPlayer(t, url, "Alice")
+	token := readUntilPrefix(t, conn, reconnectTokenPrefix)
+	joinTestGame(s, connectedPlayer(s, "Alice"), "g1")
+
+	disconnects := s.RedisClient.Subscribe(context.Background(), "game:channel:g1")
+	defer disconnects.Close()
+	if _, err := disconnects.Receive(context.Background()); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg, err := disconnects.ReceiveMessage(ctx)
+	if err != nil {
+		t.Fatalf("a desconexão deveria ser publicada ao fim da espera: %v", err)
+	}
+	if msg.Payload != disconnectEventPrefix+"Alice" {
+		t.Errorf("mensagem publicada = %q, esperado %q", msg.Payload, disconnectEventPrefix+"Alice")
+	}
+	waitFor(t, "nome liberado", func() bool { return !mr.Exists(playerOnlinePrefix + "Alice") })
+
+	late := dialTestPlayer(t, url, rejoinPrefix+"Alice|"+token)
+	readUntilPrefix(t, late, rejoinFailedMessage)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const rematchKeyPrefix = "rematch:"
+
+// RematchOffer guarda, no servidor de cada jogador, os dados da partida que acabou
+// para que uma revanche possa ser iniciada direto entre os dois (sem a fila de matchmaking).
+type RematchOffer struct {
+	GameID      string
+	Player1Name string
+	Player2Name string
+	Server1ID   string
+	Server2ID   string
+	ExpiresAt   time.Time
+}
+
+// SCRIPT LUA
+// Registra a aceitação de revanche de um jogador e retorna quantos jogadores já aceitaram.
+// O servidor que observar 2 aceitações é o único que orquestra a nova partida.
+//
+// KEYS[1] = a chave da revanche (rematch:<gameID>)
+// ARGV[1] = o nome do jogador que aceitou
+// ARGV[2] = o TTL restante da janela, em milissegundos
+var acceptRematchScript = redis.NewScript(`
+    redis.call('HSET', KEYS[1], ARGV[1], '1')
+    redis.call('PEXPIRE', KEYS[1], ARGV[2])
+    return redis.call('HLEN', KEYS[1])
+`)
+
+// offerRematch envia ao jogador local a oferta de revanche e agenda sua expiração.
+// Deve ser chamada com session.mu travado.
+func (s *Server) offerRematch(player *PlayerState, session *GameSession) {
+	offer := &RematchOffer{
+		GameID:      session.GameID,
+		Player1Name: session.Player1.Name,
+		Player2Name: session.Player2.Name,
+		Server1ID:   session.Server1ID,
+		Server2ID:   session.Server2ID,
+		ExpiresAt:   time.Now().Add(s.Config.RematchWindow),
+	}
+
+	player.mu.Lock()
+	player.rematchOffer = offer
+	player.mu.Unlock()
+
+	s.sendWebSocketMessage(player, fmt.Sprintf("REMATCH_OFFER|%d", int(s.Config.RematchWindow.Seconds())))
+	go s.expireRematchOffer(player, offer)
+}
+
+// expireRematchOffer descarta a oferta se a janela terminar sem que a revanche comece.
+func (s *Server) expireRematchOffer(player *PlayerState, offer *RematchOffer) {
+	time.Sleep(time.Until(offer.ExpiresAt))
+
+	player.mu.Lock()
+	if player.rematchOffer != offer {
+		// A revanche começou, ou o jogador seguiu para outra ação.
+		player.mu.Unlock()
+		return
+	}
+	player.rematchOffer = nil
+	player.mu.Unlock()
+
+	s.sendWebSocketMessage(player, "REMATCH_EXPIRED")
+}
+
+// handleRematch processa o comando "REMATCH" do jogador.
+func (s *Server) handleRematch(player *PlayerState) {
+	if s.inMaintenance() {
+		s.sendLocalized(player, msgMaintenance)
+		return
+	}
+	player.mu.Lock()
+	offer := player.rematchOffer
+	player.mu.Unlock()
+
+	if offer == nil || time.Now().After(offer.ExpiresAt) {
+		s.sendWebSocketMessage(player, "Nenhuma revanche disponível no momento.")
+		return
+	}
+	remaining := time.Until(offer.ExpiresAt)
+
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	rematchKey := rematchKeyPrefix + offer.GameID
+	accepted, err := acceptRematchScript.Run(ctx, s.RedisClient, []string{rematchKey}, player.Name, remaining.Milliseconds()).Int()
+	if err != nil {
+		slog.Error("Erro ao registrar aceitação de revanche", "game_id", offer.GameID, "player", player.Name, "error", err)
+		s.sendWebSocketMessage(player, "Erro interno ao aceitar a revanche. Tente novamente.")
+		return
+	}
+
+	if accepted < 2 {
+		slog.Info("Revanche aceita, aguardando oponente", "event", "rematch_accepted", "game_id", offer.GameID, "player", player.Name)
+		s.sendWebSocketMessage(player, "Revanche aceita. Aguardando o oponente...")
+		return
+	}
+
+	// Ambos aceitaram: este servidor orquestra a nova partida, na mesma ordem (P1, P2).
+	s.RedisClient.Del(ctx, rematchKey)
+	newGameID := newRandomID()
+	slog.Info("Revanche confirmada", "event", "rematch_started", "game_id", newGameID, "previous_game_id", offer.GameID,
+		"player1", offer.Player1Name, "player2", offer.Player2Name)
+
+	p1Ticket := MatchmakingTicket{PlayerName: offer.Player1Name, ServerID: offer.Server1ID, Timestamp: time.Now().Unix()}
+	p2Ticket := MatchmakingTicket{PlayerName: offer.Player2Name, ServerID: offer.Server2ID, Timestamp: time.Now().Unix()}
+	// Em outra goroutine: startLocalGame trava o cmdMu deste jogador, travado pelo comando atual
+	go s.notifyMatchStart(newGameID, p1Ticket, p2Ticket)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// replayKeyPrefix guarda, por GameID, a lista (Redis LIST) de eventos da partida em ordem.
+	replayKeyPrefix = "game:replay:"
+	// replayTTL é por quanto tempo o replay fica disponível após o último evento.
+	replayTTL = 24 * time.Hour
+	// replayMaxEvents limita o tamanho da lista (os eventos mais antigos são descartados).
+	replayMaxEvents = 200
+)
+
+// Tipos de evento do replay.
+const (
+	replayEventHandDealt   = "hand_dealt"   // Mão sorteada para um jogador (gravada pelo servidor dele)
+	replayEventCardPlayed  = "card_played"  // Carta jogada, observada pelo cérebro no hash game:state
+	replayEventForfeit     = "forfeit"      // Jogador desconectou sem jogar
+	replayEventTimeout     = "timeout"      // O prazo da jogada terminou
+	replayEventSuddenDeath = "sudden_death" // Empate: início de uma rodada de morte súbita
+	replayEventExtension   = "extension"    // Prazo da jogada estendido a pedido de um jogador
+	replayEventResult      = "result"       // Resultado final da partida
+)
+
+// appendReplayEvent acrescenta um evento ao replay da partida, renovando o TTL e aplicando o limite.
+// Falhas são apenas registradas: o replay nunca interrompe a partida.
+func (s *Server) appendReplayEvent(gameID string, event ReplayEvent) {
+	if gameID == "" {
+		return
+	}
+	event.Timestamp = time.Now().UnixMilli()
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("Erro ao serializar evento do replay", "game_id", gameID, "error", err)
+		return
+	}
+
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	key := replayKeyPrefix + gameID
+	pipe := s.RedisClient.TxPipeline()
+	pipe.RPush(ctx, key, eventJSON)
+	pipe.LTrim(ctx, key, -replayMaxEvents, -1)
+	pipe.Expire(ctx, key, replayTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.Error("Erro ao gravar evento do replay", "game_id", gameID, "type", event.Type, "error", err)
+	}
+}
+
+// recordNewMoves grava no replay as jogadas do hash game:state que ainda não foram registradas.
+// players traduz o campo do hash para o nome do jogador ("p1_card" -> P1 no modo clássico;
+// no modo FFA o campo já é o nome). recorded guarda os campos já gravados pelo cérebro.
+// Como o hash recebe tanto as jogadas locais quanto as do servidor remoto, as duas entram no replay.
+func (s *Server) recordNewMoves(gameID string, moves map[string]string, players map[string]string, recorded map[string]bool) {
+	for field, raw := range moves {
+		if recorded[field] {
+			continue
+		}
+		var card Card
+		if err := json.Unmarshal([]byte(raw), &card); err != nil {
+			continue
+		}
+		recorded[field] = true
+		name, ok := players[field]
+		if !ok {
+			name = field
+		}
+		s.appendReplayEvent(gameID, ReplayEvent{Type: replayEventCardPlayed, Player: name, Cards: []Card{card}})
+	}
+}
+
+// getReplay lê os eventos da partida em ordem. Retorna uma lista vazia se o replay não existir.
+func (s *Server) getReplay(gameID string) ([]ReplayEvent, error) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	raw, err := s.RedisClient.LRange(ctx, replayKeyPrefix+gameID, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	events := make([]ReplayEvent, 0, len(raw))
+	for _, r := range raw {
+		var event ReplayEvent
+		if err := json.Unmarshal([]byte(r), &event); err != nil {
+			slog.Error("Evento de replay corrompido", "game_id", gameID, "error", err)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// handleReplayCommand responde ao comando "REPLAY [gameID]" com os eventos da partida
+// ("REPLAY|<json>"). Sem ID, usa a última partida do jogador.
+func (s *Server) handleReplayCommand(player *PlayerState, command string) {
+	gameID := strings.TrimSpace(strings.TrimPrefix(command, "REPLAY"))
+	if gameID == "" {
+		player.mu.Lock()
+		gameID = player.lastGameID
+		player.mu.Unlock()
+	}
+	if gameID == "" {
+		s.sendWebSocketMessage(player, "Você ainda não jogou nenhuma partida. Use 'REPLAY <id da partida>'.")
+		return
+	}
+
+	events, err := s.getReplay(gameID)
+	if err != nil {
+		slog.Error("Erro ao ler replay", "game_id", gameID, "player", player.Name, "error", err)
+		s.sendWebSocketMessage(player, "Erro interno ao consultar o replay. Tente novamente.")
+		return
+	}
+	if len(events) == 0 {
+		s.sendWebSocketMessage(player, fmt.Sprintf("Nenhum replay encontrado para a partida %s.", gameID))
+		return
+	}
+
+	replayJSON, _ := json.Marshal(ReplayResponse{GameID: gameID, Events: events})
+	s.sendWebSocketMessage(player, "REPLAY|"+string(replayJSON))
+}
+
+// handleGetReplay implementa o endpoint REST GET /api/v1/games/{gameID}/replay.
+func (s *Server) handleGetReplay(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "gameID")
+	events, err := s.getReplay(gameID)
+	if err != nil {
+		slog.Error("Erro ao ler replay via REST", "game_id", gameID, "error", err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Erro interno ao consultar o replay")
+		return
+	}
+	if len(events) == 0 {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "Replay não encontrado")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ReplayResponse{GameID: gameID, Events: events})
+}
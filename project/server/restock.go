@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SCRIPT LUA
+// Repõe o estoque de forma justa: adiciona as novas cartas e embaralha a lista INTEIRA
+// (Fisher-Yates), numa única operação atômica. Sem isso, cartas repostas com RPUSH ficariam
+// no fim da fila e só sairiam (LPOP) depois que todo o estoque original acabasse.
+//
+// KEYS[1] = a chave da lista de estoque (stockKey)
+// ARGV[1] = a semente do embaralhamento
+// ARGV[2..n] = as novas cartas (JSON)
+var atomicRestockScript = redis.NewScript(`
+    local stock_key = KEYS[1]
+    math.randomseed(tonumber(ARGV[1]))
+
+    -- 1. Junta o estoque restante com as novas cartas
+    local cards = redis.call('LRANGE', stock_key, 0, -1)
+    for i = 2, #ARGV do
+        cards[#cards + 1] = ARGV[i]
+    end
+
+    -- 2. Embaralha tudo
+    for i = #cards, 2, -1 do
+        local j = math.random(i)
+        cards[i], cards[j] = cards[j], cards[i]
+    end
+
+    -- 3. Regrava a lista (em lotes, para não estourar o limite de argumentos do Lua)
+    redis.call('DEL', stock_key)
+    for i = 1, #cards, 1000 do
+        redis.call('RPUSH', stock_key, unpack(cards, i, math.min(i + 999, #cards)))
+    end
+    return #cards
+`)
+
+const (
+	// restockLockKey garante que apenas um servidor faça a reposição automática por vez.
+	restockLockKey = "lock:restock"
+	// restockLockTTL cobre com folga o script de reposição (que reembaralha o estoque inteiro).
+	restockLockTTL = 30 * time.Second
+	// stockWatermarkInterval é o intervalo entre as verificações do tamanho do estoque.
+	stockWatermarkInterval = 5 * time.Second
+)
+
+// RestockRequest é o corpo de POST /api/v1/stock/restock (mesmo formato de STOCK_SPEC_FILE).
+type RestockRequest struct {
+	Cards []StockSpecCard `json:"cards"`
+}
+
+// restockCards adiciona as cartas ao estoque global, reembaralhando o que restou.
+// Retorna o novo tamanho do estoque. Uma carta inválida recusa a reposição inteira.
+func (s *Server) restockCards(cards []Card) (int64, error) {
+	args := []interface{}{time.Now().UnixNano() % (1 << 31)}
+	for _, card := range cards {
+		if err := s.Config.validateStockCard(card.Name, card.Forca); err != nil {
+			return 0, fmt.Errorf("reposição recusada: %w", err)
+		}
+		cardJSON, _ := json.Marshal(card)
+		args = append(args, string(cardJSON))
+	}
+	// Reembaralha a lista inteira: usa o mesmo limite da criação do estoque
+	ctx, cancel := s.redisCtxTimeout(stockInitTimeout)
+	defer cancel()
+	return atomicRestockScript.Run(ctx, s.RedisClient, []string{stockKey}, args...).Int64()
+}
+
+// handleRestock implementa o endpoint REST de reposição do estoque global.
+func (s *Server) handleRestock(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	var req RestockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "Requisição inválida: "+err.Error())
+		return
+	}
+	spec := StockSpec{Cards: req.Cards}
+	if err := spec.validate(s.Config); err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("Reposição inválida: %v", err))
+		return
+	}
+
+	added := spec.expand()
+	total, err := s.restockCards(added)
+	if err != nil {
+		slog.Error("Erro ao repor o estoque", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Erro interno ao repor o estoque")
+		return
+	}
+
+	slog.Info("Estoque reposto.", "event", "stock_restocked", "added", len(added), "cards", total)
+	s.markStockReplenished()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"added": int64(len(added)), "cards": total})
+}
+
+// stockWatermarkLoop verifica periodicamente o estoque global e o repõe automaticamente quando
+// ele cai abaixo de STOCK_LOW_WATERMARK. Roda em todos os servidores; o lock lock:restock garante
+// que só um deles reponha por vez.
+func (s *Server) stockWatermarkLoop() {
+	ticker := time.NewTicker(stockWatermarkInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if s.shuttingDown() {
+			return
+		}
+		s.checkStockWatermark()
+	}
+}
+
+// checkStockWatermark repõe um lote de RESTOCK_BATCH_SIZE cartas se o estoque estiver abaixo do watermark.
+func (s *Server) checkStockWatermark() {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	count, err := s.RedisClient.LLen(ctx, stockKey).Result()
+	if err != nil {
+		slog.Error("Erro ao verificar tamanho do estoque", "error", err)
+		return
+	}
+	if count >= int64(s.Config.StockLowWatermark) {
+		return
+	}
+
+	lockValue := newRandomID()
+	ok, err := s.RedisClient.SetNX(ctx, restockLockKey, lockValue, restockLockTTL).Result()
+	if err != nil {
+		slog.Error("Erro ao tentar adquirir lock de reposição", "error", err)
+		return
+	}
+	if !ok {
+		return // Outro servidor já está repondo
+	}
+	defer func() {
+		// Contexto próprio: o lock é liberado mesmo que a reposição tenha esgotado o seu
+		ctx, cancel := s.redisCtx()
+		defer cancel()
+		s.releaseLock(ctx, restockLockKey, lockValue)
+	}()
+
+	// Verifica de novo com o lock em mãos: outro servidor pode ter acabado de repor,
+	// e um segundo lote duplicaria a reposição.
+	count, err = s.RedisClient.LLen(ctx, stockKey).Result()
+	if err != nil {
+		slog.Error("Erro ao verificar tamanho do estoque", "error", err)
+		return
+	}
+	if count >= int64(s.Config.StockLowWatermark) {
+		return
+	}
+
+	batch := s.StockSpec.sample(s.Config.RestockBatchSize)
+	total, err := s.restockCards(batch)
+	if err != nil {
+		slog.Error("Erro na reposição automática do estoque", "error", err)
+		return
+	}
+	slog.Info("Estoque reposto automaticamente.", "event", "stock_auto_restocked", "triggered_by", s.ServerID,
+		"previous_cards", count, "added", len(batch), "cards", total, "watermark", s.Config.StockLowWatermark)
+	s.markStockReplenished()
+}
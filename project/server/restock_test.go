@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// seedStock grava as cartas no estoque global, na ordem (sem embaralhar).
+func seedStock(t *testing.T, s *Server, card Card, copies int) {
+	t.Helper()
+	cards := make([]Card, copies)
+	for i := range cards {
+		cards[i] = card
+	}
+	if err := s.pushStockInBatches(context.Background(), cards); err != nil {
+		t.Fatalf("pushStockInBatches: %v", err)
+	}
+}
+
+// testAdminToken é o ADMIN_TOKEN dos testes de endpoints de administração.
+const testAdminToken = "segredo-de-teste"
+
+// newRestockRequest monta um POST /api/v1/stock/restock autorizado com testAdminToken.
+func newRestockRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/stock/restock", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	return req
+}
+
+// drainStock abre pacotes até o estoque acabar e retorna as cartas na ordem em que saíram.
+func drainStock(t *testing.T, s *Server) []Card {
+	t.Helper()
+	var drawn []Card
+	for {
+		pack, err := s.openCardPackDistributed("Alice", s.Config.PackSize)
+		if errors.Is(err, errStockEmpty) {
+			return drawn
+		}
+		if err != nil {
+			t.Fatalf("openCardPackDistributed: %v", err)
+		}
+		drawn = append(drawn, pack...)
+	}
+}
+
+func TestRestockedCardsAreDrawnBeforeOldStockRunsOut(t *testing.T) {
+	s, _ := newTestServer(t)
+	ghoul := Card{Name: "Ghoul", Forca: 1}
+	geralt := Card{Name: "Geralt de Rívia", Forca: 15}
+	const oldCards, newCards = 90, 9
+	seedStock(t, s, ghoul, oldCards)
+
+	restocked := make([]Card, newCards)
+	for i := range restocked {
+		restocked[i] = geralt
+	}
+	total, err := s.restockCards(restocked)
+	if err != nil {
+		t.Fatalf("restockCards: %v", err)
+	}
+	if total != oldCards+newCards {
+		t.Fatalf("estoque após reposição = %d, esperado %d", total, oldCards+newCards)
+	}
+
+	drawn := drainStock(t, s)
+	if len(drawn) != oldCards+newCards {
+		t.Fatalf("cartas retiradas = %d, esperado %d", len(drawn), oldCards+newCards)
+	}
+	first, count := -1, 0
+	for i, card := range drawn {
+		if card.Name == geralt.Name {
+			count++
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	if count != newCards {
+		t.Errorf("cartas repostas retiradas = %d, esperado %d", count, newCards)
+	}
+	// Sem o reembaralhamento, as cartas repostas só sairiam depois das 90 originais
+	if first >= oldCards {
+		t.Errorf("a primeira carta reposta saiu na posição %d, depois de todo o estoque original", first)
+	}
+}
+
+func TestRestockEndpointRequiresAdminToken(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", testAdminToken)
+	s, mr := newTestServer(t)
+	body := `{"cards": [{"name": "Geralt de Rívia", "forca": 15, "copies": 6}]}`
+
+	for _, auth := range []string{"", "Bearer token-errado"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/stock/restock", strings.NewReader(body))
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		s.handleRestock(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status = %d, esperado %d", auth, rec.Code, http.StatusUnauthorized)
+		}
+	}
+	if mr.Exists(stockKey) {
+		t.Errorf("a reposição não autorizada não deveria mexer no estoque")
+	}
+}
+
+func TestRestockEndpointRefillsEmptyStock(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", testAdminToken)
+	s, _ := newTestServer(t)
+	if _, err := s.openCardPackDistributed("Alice", s.Config.PackSize); !errors.Is(err, errStockEmpty) {
+		t.Fatalf("estoque vazio deveria retornar errStockEmpty, recebido %v", err)
+	}
+
+	body := `{"cards": [{"name": "Geralt de Rívia", "forca": 15, "copies": 6}]}`
+	rec := httptest.NewRecorder()
+	s.handleRestock(rec, newRestockRequest(body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, esperado 200 (corpo: %s)", rec.Code, rec.Body.String())
+	}
+	var resp map[string]int64
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("resposta inválida: %v", err)
+	}
+	if resp["added"] != 6 || resp["cards"] != 6 {
+		t.Errorf("resposta = %v, esperado added=6 e cards=6", resp)
+	}
+
+	pack, err := s.openCardPackDistributed("Alice", s.Config.PackSize)
+	if err != nil {
+		t.Fatalf("o pacote deveria sair das cartas repostas: %v", err)
+	}
+	for _, card := range pack {
+		if card.Name != "Geralt de Rívia" {
+			t.Errorf("carta retirada = %q, esperado a carta reposta", card.Name)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Uma queda de conexão durante a busca não tira o ticket da fila: ele só sai no pareamento ou no
+// timeout. Ao reconectar, o cliente que estava procurando envia "RESUME_SEARCH" e o servidor
+// retoma a busca com o mesmo ticket, sem perder a posição na fila:
+//
+//	SEARCH_RESUMED|<segundos restantes>  o ticket continua na fila (o prazo conta desde o FIND_MATCH)
+//	SEARCH_EXPIRED                       a busca expirou (ou o ticket foi pareado) durante a queda
+//
+// O ticket retomado passa a apontar para este servidor (a reconexão pode ter caído em outro) e
+// ganha um novo conteúdo (Resumes), para que o timeout da conexão anterior não o retire da fila.
+// O score (a posição, com o ajuste de prioridade, ver queue_priority.go) é mantido.
+
+// SCRIPT LUA
+// Retoma o ticket do jogador na fila, mantendo o score (a posição). Um ticket vencido é retirado.
+// Retorna {"resumed", <ticket novo>}, {"expired", ""} ou {"none", ""}.
+//
+// KEYS[1] = a fila de matchmaking (matchmakingQueueKey ou ffaQueueKey)
+// ARGV[1] = o nome do jogador
+// ARGV[2] = o ServerID deste servidor
+// ARGV[3] = o menor timestamp ainda dentro do prazo da busca (agora - MATCHMAKING_TIMEOUT)
+var atomicResumeTicketScript = redis.NewScript(`
+    for _, member in ipairs(redis.call('ZRANGE', KEYS[1], 0, -1)) do
+        local ok, ticket = pcall(cjson.decode, member)
+        if ok and type(ticket) == 'table' and ticket.player_name == ARGV[1] then
+            local score = redis.call('ZSCORE', KEYS[1], member)
+            redis.call('ZREM', KEYS[1], member)
+            if tonumber(ticket.timestamp) < tonumber(ARGV[3]) then
+                return {'expired', ''}
+            end
+            ticket.server_id = ARGV[2]
+            ticket.resumes = (tonumber(ticket.resumes) or 0) + 1
+            local resumed = cjson.encode(ticket)
+            redis.call('ZADD', KEYS[1], score, resumed)
+            return {'resumed', resumed}
+        end
+    end
+    return {'none', ''}
+`)
+
+// handleResumeSearch processa o comando "RESUME_SEARCH", enviado pelo cliente ao reconectar no
+// meio de uma busca. Procura o ticket do jogador na fila clássica e na FFA.
+func (s *Server) handleResumeSearch(player *PlayerState) {
+	minTimestamp := time.Now().Add(-s.Config.MatchmakingTimeout).Unix()
+	expired := false
+	for _, queueKey := range []string{matchmakingQueueKey, ffaQueueKey} {
+		ctx, cancel := s.redisCtx()
+		result, err := atomicResumeTicketScript.Run(ctx, s.RedisClient, []string{queueKey}, player.Name, s.ServerID, minTimestamp).StringSlice()
+		cancel()
+		if err != nil || len(result) != 2 {
+			slog.Error("Erro ao retomar a busca do jogador", "player", player.Name, "queue", queueKey, "error", err)
+			s.sendWebSocketMessage(player, "Erro interno ao retomar a busca. Tente novamente.")
+			return
+		}
+		switch result[0] {
+		case "expired":
+			expired = true
+		case "resumed":
+			s.resumeSearch(player, queueKey, result[1])
+			return
+		}
+	}
+
+	slog.Info("Busca não retomada: ticket ausente ou vencido.", "event", "search_resume_expired", "player", player.Name, "expired", expired)
+	s.sendWebSocketMessage(player, "SEARCH_EXPIRED")
+}
+
+// resumeSearch volta o jogador ao estado de busca com o ticket retomado, com o tempo que restava.
+func (s *Server) resumeSearch(player *PlayerState, queueKey, ticketJSON string) {
+	var ticket MatchmakingTicket
+	if err := json.Unmarshal([]byte(ticketJSON), &ticket); err != nil {
+		slog.Error("Ticket retomado inválido", "player", player.Name, "queue", queueKey, "error", err)
+		s.sendWebSocketMessage(player, "SEARCH_EXPIRED")
+		return
+	}
+	searchStarted := time.Unix(ticket.Timestamp, 0)
+	remaining := time.Until(searchStarted.Add(s.Config.MatchmakingTimeout))
+
+	player.mu.Lock()
+	player.State = "Searching"
+	player.rematchOffer = nil
+	player.mu.Unlock()
+
+	slog.Info("Busca retomada após reconexão.", "event", "search_resumed", "player", player.Name, "queue", queueKey,
+		"remaining", remaining, "resumes", ticket.Resumes)
+	s.sendWebSocketMessage(player, fmt.Sprintf("SEARCH_RESUMED|%d", int(remaining.Seconds())))
+
+	go s.matchmakingTimeout(player, remaining, queueKey, ticketJSON)
+	go s.queueStatusLoop(player, queueKey, ticketJSON, searchStarted)
+}
@@ -3,27 +3,27 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Constantes globais
 const (
-	webPort            = ":8080"
-	restPort           = ":8081" // Porta para comunicação Server-Server (REST)
-	matchmakingTimeout = 15 * time.Second
-	gameTurnTimeout    = 10 * time.Second
+	webPort  = ":8080"
+	restPort = ":8081" // Porta para comunicação Server-Server (REST)
 )
 
 // FUNÇÕES DE INICIALIZAÇÃO E ORQUESTRAÇÃO
@@ -34,26 +34,57 @@ func main() {
 	if serverID == "" {
 		serverID = fmt.Sprintf("Server-Local-%d", rand.Intn(10000))
 	}
-	log.Printf("Iniciando servidor com ID: %s", serverID)
+	// Configura o logger estruturado (LOG_LEVEL / LOG_FORMAT)
+	if err := setupLogger(serverID); err != nil {
+		fmt.Fprintf(os.Stderr, "Configuração de log inválida: %v\n", err)
+		os.Exit(1)
+	}
+	slog.Info("Iniciando servidor", "event", "server_starting")
+
+	// Carrega a configuração ajustável (timeouts, pacotes, locks)
+	cfg, err := loadConfig()
+	if err != nil {
+		fatal("Configuração inválida", "error", err)
+	}
+	cfg.logConfig()
+
+	// Distribuição de cartas do estoque (arquivo opcional, validado antes de tocar no Redis)
+	stockSpec, err := loadStockSpec(cfg.StockSpecFile, cfg)
+	if err != nil {
+		fatal("Distribuição do estoque inválida", "error", err)
+	}
 
 	// 2. Inicializa o cliente Redis
-	redisAddr := os.Getenv("REDIS_ADDR")
-	if redisAddr == "" {
-		redisAddr = "localhost:6379" // Default para desenvolvimento local
+	// (modo, endereços, senha, banco, pool e TLS vêm das variáveis REDIS_*, ver redis_client.go)
+	redisOpts, redisMode, err := redisOptions()
+	if err != nil {
+		fatal("Configuração do Redis inválida", "redis_mode", redisMode, "error", err)
+	}
+	redisAddr := strings.Join(redisOpts.Addrs, ",")
+	rdb, err := newRedisClient(redisMode, redisOpts)
+	if err != nil {
+		fatal("Configuração do Redis inválida", "redis_mode", redisMode, "error", err)
 	}
-	rdb := redis.NewClient(&redis.Options{
-		Addr: redisAddr,
-		DB:   0,
-	})
 
 	// Verifica a conexão com o Redis
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	_, err := rdb.Ping(ctx).Result()
+	_, err = rdb.Ping(ctx).Result()
+	if err != nil && isRedisAuthError(err) {
+		fatal("Falha de autenticação no Redis: verifique REDIS_PASSWORD/REDIS_USERNAME", "redis_mode", redisMode, "redis_addr", redisAddr, "error", err)
+	}
 	if err != nil {
-		log.Fatalf("Erro ao conectar ao Redis: %v", err)
+		fatal("Erro ao conectar ao Redis", "redis_mode", redisMode, "redis_addr", redisAddr, "master_name", redisOpts.MasterName,
+			"tls", redisOpts.TLSConfig != nil, "error", err)
+	}
+	slog.Info("Conexão com Redis estabelecida com sucesso.", "redis_mode", redisMode, "redis_addr", redisAddr,
+		"master_name", redisOpts.MasterName, "db", redisOpts.DB, "pool_size", redisOpts.PoolSize, "tls", redisOpts.TLSConfig != nil)
+
+	// Endereço REST pelo qual os outros servidores alcançam este (registrado no Redis)
+	restAddr := os.Getenv("SERVER_ADDR")
+	if restAddr == "" {
+		restAddr = serverID + restPort // Default: o ID é o nome do serviço Docker
 	}
-	log.Println("Conexão com Redis estabelecida com sucesso.")
 
 	// 3. Inicializa o servidor principal
 	s := &Server{
@@ -61,37 +92,71 @@ func main() {
 		Players:     make(map[string]*PlayerState),
 		PlayerMutex: &sync.Mutex{},
 		ServerID:    serverID,
+		RestAddr:    restAddr,
+		Config:      cfg,
+		HTTPClient:  newServerHTTPClient(cfg.NotifyTimeout),
+		StockSpec:   stockSpec,
 		// INICIALIZA NOVOS CAMPOS
 		ActiveGames: make(map[string]*GameSession),
 		GamesMutex:  sync.Mutex{},
+		detached:    make(map[string]*PlayerState),
 	}
+	s.ctx, s.stop = context.WithCancel(context.Background())
 
-	// 4. Inicializa o estoque de cartas (apenas se não existir)
-	s.initializeDistributedStock()
-
-	// 5. Inicia o servidor REST (Server-Server Communication)
+	// 4. Inicia o servidor REST (Server-Server Communication)
+	// Sobe antes do estoque para que as sondas de liveness/readiness respondam
+	// durante a inicialização (o /readyz retorna 503 até o estoque estar pronto).
 	s.Router = chi.NewRouter()
 	s.Router.Use(middleware.Logger)
 	s.Router.Use(middleware.Recoverer)
+	s.registerMetrics()
 	s.setupRestRoutes()
 	go func() {
-		log.Printf("Servidor REST (Server-Server) iniciado na porta %s", restPort)
+		slog.Info("Servidor REST (Server-Server) iniciado", "port", restPort)
 		if err := http.ListenAndServe(restPort, s.Router); err != nil {
-			log.Fatalf("Erro ao iniciar servidor REST: %v", err)
+			fatal("Erro ao iniciar servidor REST", "error", err)
 		}
 	}()
 
+	// 5. Inicializa o estoque de cartas (apenas se não existir)
+	s.initializeDistributedStock()
+	s.stockReady.Store(true)
+
+	// Conclui ou desfaz trocas que este servidor deixou pela metade antes de reiniciar
+	s.recoverPendingTrades()
+
+	// Resolve as partidas que este servidor coordenava quando caiu (ver game_reconcile.go)
+	s.reconcileStaleGames()
+
+	// Avisa os jogadores locais quando o estoque global acaba ou é reposto (canal stock:events)
+	go s.listenStockEvents()
+
+	// Repõe o estoque automaticamente quando ele fica abaixo do watermark (um servidor por vez)
+	if s.Config.AutoRestock {
+		go s.stockWatermarkLoop()
+	}
+
 	// 6. Inicia o servidor WebSocket (Client-Server Communication)
 	http.HandleFunc("/", s.handleWebSocketConnection)
 	go func() {
-		log.Printf("Servidor WebSocket (Client-Server) iniciado na porta %s", webPort)
+		slog.Info("Servidor WebSocket (Client-Server) iniciado", "port", webPort)
 		if err := http.ListenAndServe(webPort, nil); err != nil {
-			log.Fatalf("Erro ao iniciar servidor WebSocket: %v", err)
+			fatal("Erro ao iniciar servidor WebSocket", "error", err)
 		}
 	}()
 
-	// 7. Inicia o Matchmaker Distribuído
+	// 7. Registra o endereço REST deste servidor para descoberta pelos demais
+	if err := s.registerServer(); err != nil {
+		fatal("Erro ao registrar servidor no Redis", "error", err)
+	}
+	slog.Info("Servidor registrado", "event", "server_registered", "rest_addr", s.RestAddr)
+	go s.serverRegistryLoop()
+	go s.capacityLoop()
+	go s.tradeWantExpiryLoop()
+
+	// 8. Inicia o Matchmaker Distribuído
 	go s.distributedMatchmaker()
+	go s.distributedFFAMatchmaker()
 
 	fmt.Println("Servidor iniciado. Pressione Ctrl+C para encerrar.")
 
@@ -100,15 +165,50 @@ func main() {
 	signal.Notify(quitChannel, syscall.SIGINT, syscall.SIGTERM)
 	<-quitChannel
 	fmt.Println("\nEncerrando servidor...")
+	// Interrompe as operações no Redis em andamento e os laços de fundo
+	s.stop()
+	s.unregisterServer()
 }
 
 // setupRestRoutes configura as rotas para a comunicação Server-Server.
 func (s *Server) setupRestRoutes() {
+	// Sondas de orquestração (Docker/Kubernetes), fora de /api/v1 e sem autenticação
+	s.Router.Get("/healthz", s.handleHealthz)
+	s.Router.Get("/readyz", s.handleReadyz)
+	// Métricas operacionais no formato do Prometheus
+	s.Router.Handle("/metrics", promhttp.Handler())
+
 	s.Router.Route("/api/v1", func(r chi.Router) {
 		// Endpoint para um servidor solicitar um pacote de cartas do estoque global
 		r.Post("/stock/take", s.handleTakeCardPack)
+		// Endpoint de operação para repor o estoque global (as cartas novas são misturadas às restantes)
+		r.Post("/stock/restock", s.handleRestock)
 		// Endpoint para um servidor notificar outro sobre um jogador pareado
 		r.Post("/match/notify", s.handleMatchNotification)
+		// Endpoint para notificar servidores sobre uma partida "todos contra todos" (FFA)
+		r.Post("/match/ffa/notify", s.handleFFAMatchNotification)
+		// Endpoints com os servidores registrados e a carga de cada um (ver cluster.go)
+		r.Get("/cluster", s.handleGetCluster)
+		r.Get("/server/load", s.handleGetServerLoad)
+		// Endpoint para ferramentas externas consultarem o ranking global
+		r.Get("/leaderboard", s.handleGetLeaderboard)
+		// Endpoint para consultar as ofertas da fila de trocas (sem os donos)
+		r.Get("/trades/queue", s.handleGetTradeQueue)
+		// Endpoint de administração (ADMIN_TOKEN) com as partidas de que este servidor é o cérebro
+		r.Get("/games", s.handleListActiveGames)
+		// Endpoint para consultar o replay (eventos em ordem) de uma partida
+		r.Get("/games/{gameID}/replay", s.handleGetReplay)
+		// Endpoints de administração (ADMIN_TOKEN) com os registros das partidas terminadas
+		r.Get("/games/records", s.handleGetGameRecords)
+		r.Get("/games/records/{gameID}", s.handleGetGameRecord)
+		// Endpoint de administração (ADMIN_TOKEN) para resolver uma partida travada
+		r.Post("/game/{gameID}/resolve", s.handleForceResolve)
+		// Endpoint de administração (ADMIN_TOKEN) com o histórico de ações de um jogador
+		r.Get("/players/{name}/audit", s.handleGetAudit)
+		// Endpoint de administração (ADMIN_TOKEN) que emite o token de conexão de um jogador (AUTH_SECRET)
+		r.Post("/auth/token", s.handleIssueAuthToken)
+		// Endpoint de administração (ADMIN_TOKEN) que liga e desliga o modo de manutenção deste servidor
+		r.Post("/maintenance", s.handleMaintenance)
 	})
 }
 
@@ -116,26 +216,45 @@ func (s *Server) setupRestRoutes() {
 func (s *Server) handleTakeCardPack(w http.ResponseWriter, r *http.Request) {
 	var req TakePackRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Requisição inválida", http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "Requisição inválida: "+err.Error())
 		return
 	}
 
 	// Tenta abrir o pacote de forma distribuída
-	pack, err := s.openCardPackDistributed(req.PlayerName)
+	packSize := s.Config.packSize(req.Starter)
+	pack, err := s.openCardPackDistributed(req.PlayerName, packSize)
+	// O estoque restante real, inclusive as cartas avulsas que não formam um pacote
+	remaining, stray, _ := s.stockRemaining(packSize)
 	if err != nil {
-		w.WriteHeader(http.StatusConflict)
+		// Mesmo formato do APIError, com o estoque restante
+		status, code := http.StatusConflict, errCodeStockEmpty
+		if !errors.Is(err, errStockEmpty) {
+			status, code = http.StatusInternalServerError, errCodeInternal
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
 		json.NewEncoder(w).Encode(TakePackResponse{
-			Success: false,
-			Message: err.Error(),
+			Success:        false,
+			Message:        err.Error(),
+			Error:          err.Error(),
+			Code:           code,
+			RemainingPacks: remaining,
+			StrayCards:     stray,
 		})
 		return
 	}
 
+	message := "Pacote de cartas retirado com sucesso."
+	if len(pack) < packSize {
+		message = fmt.Sprintf("Último pacote do estoque retirado incompleto (%d de %d cartas).", len(pack), packSize)
+	}
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(TakePackResponse{
-		Success: true,
-		Message: "Pacote de cartas retirado com sucesso.",
-		Pack:    pack,
+		Success:        true,
+		Message:        message,
+		Pack:           pack,
+		RemainingPacks: remaining,
+		StrayCards:     stray,
 	})
 }
 
@@ -144,8 +263,8 @@ func (s *Server) handleTakeCardPack(w http.ResponseWriter, r *http.Request) {
 // handleMatchNotification implementa o endpoint REST...
 func (s *Server) handleMatchNotification(w http.ResponseWriter, r *http.Request) {
 	var req MatchNotificationRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Requisição inválida", http.StatusBadRequest)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.GameID == "" {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "Requisição inválida: game_id ausente ou JSON malformado")
 		return
 	}
 
@@ -154,12 +273,27 @@ func (s *Server) handleMatchNotification(w http.ResponseWriter, r *http.Request)
 	isPlayerLocal := req.Server1ID == s.ServerID || req.Server2ID == s.ServerID
 
 	if isPlayerLocal {
+		// Notificação repetida (retentativa): responde 200 sem criar uma segunda sessão.
+		first, err := s.claimMatchNotification(req.GameID)
+		if err != nil {
+			slog.Error("Erro ao registrar notificação de partida", "game_id", req.GameID, "error", err)
+			writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Erro interno ao registrar a notificação")
+			return
+		}
+		if !first {
+			slog.Info("Notificação de partida duplicada ignorada", "event", "match_notify_duplicate", "game_id", req.GameID)
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(map[string]bool{"success": true, "duplicate": true})
+			return
+		}
+
 		// Passa P1, P2 e os IDs de ambos os servidores.
 		// startLocalGame vai descobrir qual deles é o local.
-		s.startLocalGame(req.Player1Name, req.Player2Name, req.Server1ID, req.Server2ID)
+		s.startLocalGame(req.GameID, req.Player1Name, req.Player2Name, req.Server1ID, req.Server2ID)
 	} else {
-		log.Printf("Notificação de partida recebida, mas nenhum jogador é local: %v", req)
-		http.Error(w, "Nenhum jogador local envolvido.", http.StatusConflict)
+		slog.Warn("Notificação de partida recebida, mas nenhum jogador é local",
+			"event", "match_notify_rejected", "game_id", req.GameID, "player1", req.Player1Name, "player2", req.Player2Name)
+		writeAPIError(w, http.StatusConflict, errCodeNoLocalPlayer, "Nenhum jogador local envolvido.")
 		return
 	}
 
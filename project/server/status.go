@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// handleStatus responde ao comando "STATUS" com um resumo do jogador em JSON ("STATUS|{...}").
+func (s *Server) handleStatus(player *PlayerState) {
+	// Os campos do jogador são lidos sob player.mu, para não disputar com as
+	// transições de matchmaking e de partida.
+	player.mu.Lock()
+	status := PlayerStatus{
+		PlayerName:  player.Name,
+		ServerID:    player.ServerID,
+		State:       player.State,
+		PacksOpened: player.PacksOpened,
+		MaxPacks:    s.Config.MaxPacksPerPlayer,
+		DeckSize:    len(player.Deck),
+		Games:       player.gameIDs(),
+	}
+	player.mu.Unlock()
+
+	// Estatísticas do ranking (ver leaderboard.go)
+	stats, err := s.getPlayerStats(player.Name)
+	if err != nil {
+		slog.Error("Erro ao ler estatísticas do jogador", "player", player.Name, "error", err)
+	} else {
+		status.Wins, status.Losses, status.Draws = stats.Wins, stats.Losses, stats.Draws
+	}
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	rank, err := s.RedisClient.ZRevRank(ctx, leaderboardKey, player.Name).Result()
+	if err == nil {
+		status.Rank = int(rank) + 1
+	} else if err != redis.Nil {
+		slog.Error("Erro ao ler posição no ranking", "player", player.Name, "error", err)
+	}
+
+	statusJSON, _ := json.Marshal(status)
+	s.sendWebSocketMessage(player, "STATUS|"+string(statusJSON))
+}
@@ -3,9 +3,11 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -13,25 +15,44 @@ import (
 
 const (
 	stockKey = "global_card_stock"
+	// stockInitTimeout limita a criação do estoque inteiro na subida (bem mais lenta que REDIS_TIMEOUT).
+	stockInitTimeout = 1 * time.Minute
+	// stockInitBatchesPerExec é o número de lotes (STOCK_INIT_BATCH_SIZE) enviados em cada pipeline
+	// da criação do estoque; o progresso é registrado a cada pipeline.
+	stockInitBatchesPerExec = 5
 )
 
+// starterPackPrefix marca, por jogador, que o pacote inicial já foi entregue (player:starter:<nome>).
+const starterPackPrefix = "player:starter:"
+
+// errStockEmpty indica que o estoque global não tem um pacote completo (ver openCardPackDistributed).
+var errStockEmpty = errors.New("não há pacotes de cartas suficientes no estoque global")
+
 // SCRIPT LUA
 // Este script é executado atomicamente pelo Redis para cada chamada.
-// Ele verifica se há cartas suficientes (3) e, se houver, as remove da lista (LPOP)
+// Ele verifica se há cartas suficientes (3) e, se houver, as remove do início da fila (LPOP)
 // e as retorna. Tudo em uma única operação indivisível.
+// Se sobrarem menos cartas que um pacote, retorna o número de cartas que sobraram (inteiro),
+// ou, com allow_partial, entrega essas últimas cartas como um pacote incompleto.
 //
 // KEYS[1] = a chave da lista de estoque (stockKey)
-// ARGV[1] = o número de cartas por pacote (pack_size = 3)
+// ARGV[1] = o número de cartas do pacote (pack_size: STARTER_PACK_SIZE ou PACK_SIZE, ver Config.packSize)
+// ARGV[2] = "1" para entregar o último pacote incompleto (ALLOW_PARTIAL_PACK), "0" caso contrário
 var atomicOpenPackScript = redis.NewScript(`
     local stock_key = KEYS[1]
     local pack_size = tonumber(ARGV[1])
+    local allow_partial = ARGV[2] == '1'
     
     -- 1. Verifica o tamanho atual da lista
     local current_stock = redis.call('LLEN', stock_key)
     
-    -- 2. Se for menor que o tamanho do pacote (3), retorna uma tabela vazia
+    -- 2. Se for menor que o tamanho do pacote (3), retorna quantas cartas sobraram
+    --    (ou as entrega como o último pacote, incompleto)
     if current_stock < pack_size then
-        return {}
+        if allow_partial and current_stock > 0 then
+            return redis.call('LPOP', stock_key, current_stock)
+        end
+        return current_stock
     end
     
     -- 3. Se houver estoque, remove 'pack_size' (3) cartas do início da lista
@@ -41,56 +62,62 @@ var atomicOpenPackScript = redis.NewScript(`
     return cards
 `)
 
+// baseCards são as cartas base do jogo (algumas com habilidades especiais, ver abilities.go).
+// A Agilidade (Speed) é distinta entre cartas de mesma Força, para que só a mesma carta empate.
+// A raridade e a imagem são preenchidas por withCardMetadata (ver card_metadata.go).
+var baseCards = withCardMetadata([]Card{
+	{Name: "Camponês Armado", Forca: 1, Speed: 2, Faction: FactionNorth},
+	{Name: "Batedor Anão", Forca: 1, Ability: AbilitySpy, Speed: 6, Faction: FactionScoiatael},
+	{Name: "Arqueiro Elfo", Forca: 1, Speed: 8, Faction: FactionScoiatael},
+	{Name: "Ghoul", Forca: 1, Speed: 5, Faction: FactionMonsters},
+	{Name: "Nekker", Forca: 1, Speed: 7, Faction: FactionMonsters},
+	{Name: "Infantaria Leve", Forca: 2, Speed: 4, Faction: FactionNorth},
+	{Name: "Guerrilheiro Scoia'tael", Forca: 2, Ability: AbilityBoost, Speed: 9, Faction: FactionScoiatael},
+	{Name: "Balista", Forca: 2, Speed: 1, Faction: FactionNorth},
+	{Name: "Lanceiro de Kaedwen", Forca: 3, Speed: 5, Faction: FactionNorth},
+	{Name: "Caçador de Recompensa", Forca: 3, Ability: AbilitySpy, Speed: 7, Faction: FactionNeutral},
+	{Name: "Grifo", Forca: 3, Speed: 9, Faction: FactionMonsters},
+	{Name: "Cavaleiro de Aedirn", Forca: 4, Speed: 6, Faction: FactionNorth},
+	{Name: "Elemental da Terra", Forca: 4, Ability: AbilityWeather, Speed: 2, Faction: FactionMonsters},
+	{Name: "Guerreiro Anão", Forca: 5, Speed: 3, Faction: FactionScoiatael},
+	{Name: "Wyvern", Forca: 5, Speed: 8, Faction: FactionMonsters},
+	{Name: "Gigante de Gelo", Forca: 6, Ability: AbilityWeather, Speed: 2, Faction: FactionMonsters},
+	{Name: "Leshen", Forca: 6, Speed: 6, Faction: FactionMonsters},
+	{Name: "Grão-Mestre Bruxo", Forca: 7, Ability: AbilityBoost, Speed: 9, Faction: FactionNeutral},
+	{Name: "Draug", Forca: 7, Speed: 4, Faction: FactionMonsters},
+	{Name: "Ifrit", Forca: 8, Speed: 7, Faction: FactionMonsters},
+	{Name: "Cavaleiro da Morte", Forca: 8, Speed: 5, Faction: FactionNilfgaard},
+	{Name: "Behemoth", Forca: 9, Speed: 3, Faction: FactionMonsters},
+	{Name: "Dragão Menor", Forca: 10, Speed: 8, Faction: FactionMonsters},
+	{Name: "Comandante Veterano", Forca: 10, Ability: AbilityBoost, Speed: 6, Faction: FactionNilfgaard},
+	{Name: "Eredin Bréacc Glas", Forca: 11, Speed: 7, Faction: FactionMonsters},
+	{Name: "Imlerith", Forca: 11, Speed: 5, Faction: FactionMonsters},
+	{Name: "Vernon Roche", Forca: 12, Ability: AbilitySpy, Speed: 8, Faction: FactionNorth},
+	{Name: "Iorveth", Forca: 12, Speed: 9, Faction: FactionScoiatael},
+	{Name: "Philippa Eilhart", Forca: 13, Speed: 6, Faction: FactionNorth},
+	{Name: "Triss Merigold", Forca: 13, Speed: 7, Faction: FactionNorth},
+	{Name: "Yennefer de Vengerberg", Forca: 14, Speed: 8, Faction: FactionNeutral},
+	{Name: "Rei Foltest", Forca: 14, Speed: 5, Faction: FactionNorth},
+	{Name: "Geralt de Rívia", Forca: 15, Speed: 10, Faction: FactionNeutral},
+})
+
 // initializeDistributedStock cria o estoque de cartas no Redis.
 func (s *Server) initializeDistributedStock() {
-	ctx := context.Background()
+	ctx, cancel := s.redisCtxTimeout(stockInitTimeout)
+	defer cancel()
 	// Verifica se o estoque já existe no Redis.
 	count, err := s.RedisClient.LLen(ctx, stockKey).Result()
 	if err != nil {
-		log.Fatalf("Erro ao verificar estoque no Redis: %v", err)
+		fatal("Erro ao verificar estoque no Redis", "error", err)
 	}
 
 	if count > 0 {
-		log.Printf("Estoque de cartas já existe no Redis. Total de pacotes: %d", count/3)
+		slog.Info("Estoque de cartas já existe no Redis.", "packs", count/int64(s.Config.PackSize))
 		return
 	}
 
-	// 1. Definição das cartas base
-	baseCards := []Card{
-		{Name: "Camponês Armado", Forca: 1}, {Name: "Batedor Anão", Forca: 1}, {Name: "Arqueiro Elfo", Forca: 1},
-		{Name: "Ghoul", Forca: 1}, {Name: "Nekker", Forca: 1}, {Name: "Infantaria Leve", Forca: 2},
-		{Name: "Guerrilheiro Scoia'tael", Forca: 2}, {Name: "Balista", Forca: 2}, {Name: "Lanceiro de Kaedwen", Forca: 3},
-		{Name: "Caçador de Recompensa", Forca: 3}, {Name: "Grifo", Forca: 3}, {Name: "Cavaleiro de Aedirn", Forca: 4},
-		{Name: "Elemental da Terra", Forca: 4}, {Name: "Guerreiro Anão", Forca: 5}, {Name: "Wyvern", Forca: 5},
-		{Name: "Gigante de Gelo", Forca: 6}, {Name: "Leshen", Forca: 6}, {Name: "Grão-Mestre Bruxo", Forca: 7},
-		{Name: "Draug", Forca: 7}, {Name: "Ifrit", Forca: 8}, {Name: "Cavaleiro da Morte", Forca: 8},
-		{Name: "Behemoth", Forca: 9}, {Name: "Dragão Menor", Forca: 10}, {Name: "Comandante Veterano", Forca: 10},
-		{Name: "Eredin Bréacc Glas", Forca: 11}, {Name: "Imlerith", Forca: 11}, {Name: "Vernon Roche", Forca: 12},
-		{Name: "Iorveth", Forca: 12}, {Name: "Philippa Eilhart", Forca: 13}, {Name: "Triss Merigold", Forca: 13},
-		{Name: "Yennefer de Vengerberg", Forca: 14}, {Name: "Rei Foltest", Forca: 14}, {Name: "Geralt de Rívia", Forca: 15},
-	}
-
-	// 2. Cria um grande estoque de cartas (90000 cartas)
-	fullCardStock := []Card{}
-	for _, card := range baseCards {
-		copies := 10 // Padrão para as cartas mais raras (Força > 10)
-		if card.Forca >= 1 && card.Forca <= 3 {
-			copies = 4000
-		} else if card.Forca >= 4 && card.Forca <= 6 {
-			copies = 3000
-		} else if card.Forca >= 7 && card.Forca <= 10 {
-			copies = 2000
-		}
-		for i := 0; i < copies; i++ {
-			fullCardStock = append(fullCardStock, card)
-		}
-	}
-
-	// Garante que o estoque tenha exatamente 90000 cartas
-	for len(fullCardStock) < 90000 {
-		fullCardStock = append(fullCardStock, baseCards[0])
-	}
-	fullCardStock = fullCardStock[:90000]
+	// 1 e 2. Cria o estoque a partir da distribuição configurada (ver stock_spec.go)
+	fullCardStock := s.StockSpec.expand()
 
 	// 3. Embaralha o estoque
 	rand.Seed(time.Now().UnixNano())
@@ -98,46 +125,96 @@ func (s *Server) initializeDistributedStock() {
 		fullCardStock[i], fullCardStock[j] = fullCardStock[j], fullCardStock[i]
 	})
 
-	// 4. Converte as cartas para JSON e as adiciona ao Redis como uma lista (LIFO - Rpush)
-	var cardJsons []interface{}
-	for _, card := range fullCardStock {
-		cardJson, _ := json.Marshal(card)
-		cardJsons = append(cardJsons, string(cardJson))
+	// 4. Converte as cartas para JSON e as adiciona ao Redis como uma fila (FIFO):
+	// entram no fim (RPUSH) e os pacotes saem do início (LPOP). Reposições posteriores
+	// usam restockCards, que reembaralha a lista inteira (ver restock.go).
+	if err := s.pushStockInBatches(ctx, fullCardStock); err != nil {
+		// Um estoque pela metade seria aceito como pronto na próxima subida: é descartado
+		s.RedisClient.Del(ctx, stockKey)
+		fatal("Erro ao inicializar o estoque no Redis", "error", err)
 	}
 
-	// Adiciona todas as cartas ao Redis.
-	s.RedisClient.RPush(ctx, stockKey, cardJsons...)
+	slog.Info("Estoque de cartas inicializado no Redis.", "event", "stock_initialized", "cards", len(fullCardStock))
+	s.markStockReplenished()
+}
+
+// pushStockInBatches adiciona as cartas ao fim do estoque em lotes de STOCK_INIT_BATCH_SIZE
+// (um RPUSH por lote), para que nenhum comando ultrapasse os limites de tamanho do Redis.
+// Os lotes seguem em pipelines de stockInitBatchesPerExec lotes, com o progresso no log.
+func (s *Server) pushStockInBatches(ctx context.Context, cards []Card) error {
+	batchSize := s.Config.StockInitBatchSize
+	pipe := s.RedisClient.Pipeline()
+	batches := 0
+	for start := 0; start < len(cards); start += batchSize {
+		end := min(start+batchSize, len(cards))
+		batch := make([]interface{}, 0, end-start)
+		for _, card := range cards[start:end] {
+			cardJSON, _ := json.Marshal(card)
+			batch = append(batch, string(cardJSON))
+		}
+		pipe.RPush(ctx, stockKey, batch...)
+		batches++
 
-	log.Printf("Estoque de cartas inicializado no Redis. Total de cartas: %d", len(fullCardStock))
+		if batches%stockInitBatchesPerExec == 0 || end == len(cards) {
+			if _, err := pipe.Exec(ctx); err != nil {
+				return fmt.Errorf("lote até a carta %d: %w", end, err)
+			}
+			slog.Info("Inicializando o estoque de cartas...", "event", "stock_init_progress", "cards", end, "total", len(cards))
+		}
+	}
+	return nil
 }
 
-// openCardPack distribuído: remove um pacote do estoque global (Redis) de forma ATÔMICA.
-func (s *Server) openCardPackDistributed(playerName string) ([]Card, error) {
-	ctx := context.Background()
-	const packSize = 3 // Um pacote tem 3 cartas
+// openCardPack distribuído: remove um pacote de packSize cartas do estoque global (Redis) de forma ATÔMICA.
+func (s *Server) openCardPackDistributed(playerName string, packSize int) ([]Card, error) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+
+	allowPartial := "0"
+	if s.Config.AllowPartialPack {
+		allowPartial = "1"
+	}
 
 	// Executa o script LUA atomicamente
 	// KEYS[1] = stockKey
-	// ARGV[1] = packSize (3)
-	result, err := atomicOpenPackScript.Run(ctx, s.RedisClient, []string{stockKey}, packSize).Result()
+	// ARGV[1] = packSize
+	// ARGV[2] = allowPartial
+	result, err := atomicOpenPackScript.Run(ctx, s.RedisClient, []string{stockKey}, packSize, allowPartial).Result()
 	if err != nil {
 		// Erro na execução do script
-		log.Printf("Servidor %s: Erro ao executar script LUA: %v", s.ServerID, err)
+		slog.Error("Erro ao executar script LUA", "player", playerName, "error", err)
+		packsOpenedTotal.WithLabelValues("error").Inc()
 		return nil, fmt.Errorf("erro interno ao processar o estoque: %w", err)
 	}
 
 	// 2. Processa o resultado do script
-	// O LUA retorna um []interface{} de strings (JSON)
+	// O LUA retorna um []interface{} de strings (JSON), ou o número de cartas que sobraram
+	// no estoque (int64) se elas não formam um pacote completo.
+	if stray, isCount := result.(int64); isCount {
+		packsOpenedTotal.WithLabelValues("empty_stock").Inc()
+		s.markStockExhausted()
+		if stray == 0 {
+			slog.Warn("Tentativa de abrir pacote, mas estoque insuficiente.", "event", "stock_empty", "player", playerName)
+			return nil, errStockEmpty
+		}
+		// As cartas que sobraram só saem com ALLOW_PARTIAL_PACK ou depois de uma reposição
+		slog.Warn("Estoque com cartas avulsas, insuficientes para um pacote.", "event", "stock_stranded",
+			"player", playerName, "stray_cards", stray, "pack_size", packSize)
+		return nil, fmt.Errorf("%w (restam %d carta(s) avulsa(s), menos que um pacote de %d)", errStockEmpty, stray, packSize)
+	}
 	cardInterfaces, ok := result.([]interface{})
 	if !ok {
-		log.Printf("Servidor %s: Resultado inesperado do script LUA: %T", s.ServerID, result)
+		slog.Error("Resultado inesperado do script LUA", "player", playerName, "type", fmt.Sprintf("%T", result))
+		packsOpenedTotal.WithLabelValues("error").Inc()
 		return nil, fmt.Errorf("erro interno (resultado script)")
 	}
 
 	// 3. Verifica se o pacote foi retornado
 	// Se o script retornou uma tabela vazia ({}), o estoque acabou.
 	if len(cardInterfaces) == 0 {
-		log.Printf("Servidor %s: Tentativa de abrir pacote para %s, mas estoque insuficiente.", s.ServerID, playerName)
+		slog.Warn("Tentativa de abrir pacote, mas estoque insuficiente.", "event", "stock_empty", "player", playerName)
+		packsOpenedTotal.WithLabelValues("empty_stock").Inc()
+		s.markStockExhausted()
 		return nil, fmt.Errorf("não há pacotes de cartas suficientes no estoque global")
 	}
 
@@ -146,67 +223,178 @@ func (s *Server) openCardPackDistributed(playerName string) ([]Card, error) {
 	for _, cardJSON := range cardInterfaces {
 		cardString, isString := cardJSON.(string)
 		if !isString {
-			log.Printf("Erro crítico ao desserializar carta do Redis: item não é string")
+			slog.Error("Erro crítico ao desserializar carta do Redis: item não é string", "player", playerName)
+			packsOpenedTotal.WithLabelValues("error").Inc()
 			return nil, fmt.Errorf("erro interno ao processar pacote (item não string)")
 		}
 
 		var card Card
 		if err := json.Unmarshal([]byte(cardString), &card); err != nil {
-			log.Printf("Erro crítico ao desserializar carta do Redis: %v", err)
+			slog.Error("Erro crítico ao desserializar carta do Redis", "player", playerName, "error", err)
+			packsOpenedTotal.WithLabelValues("error").Inc()
 			return nil, fmt.Errorf("erro interno ao processar pacote (json invalido)")
 		}
+		if !s.acceptStockCard(playerName, card, cardString) {
+			continue
+		}
 		pack = append(pack, card)
 	}
+	if len(pack) == 0 {
+		packsOpenedTotal.WithLabelValues("error").Inc()
+		return nil, fmt.Errorf("erro interno ao processar pacote (cartas inválidas no estoque)")
+	}
+
+	// Um pacote com cartas descartadas por acceptStockCard não é o último do estoque
+	if len(cardInterfaces) < packSize {
+		slog.Warn("Último pacote do estoque entregue incompleto.", "event", "partial_pack_opened",
+			"player", playerName, "cards", len(pack), "pack_size", packSize)
+		packsOpenedTotal.WithLabelValues("partial").Inc()
+		s.markStockExhausted()
+		return pack, nil
+	}
 
+	packsOpenedTotal.WithLabelValues("success").Inc()
 	return pack, nil
 }
 
+// acceptStockCard verifica uma carta retirada do estoque antes que ela entre no deck do jogador.
+// Uma entrada corrompida (ex: sem nome, ou que virou a carta vazia ao desserializar) é descartada
+// e registrada, em vez de chegar silenciosamente ao deck.
+func (s *Server) acceptStockCard(playerName string, card Card, cardJSON string) bool {
+	if err := s.Config.validateStockCard(card.Name, card.Forca); err != nil {
+		slog.Error("Carta inválida retirada do estoque; descartada.", "event", "stock_card_rejected",
+			"player", playerName, "card_json", cardJSON, "error", err)
+		stockCardsRejectedTotal.Inc()
+		return false
+	}
+	return true
+}
+
+// stockRemaining informa quantos pacotes completos ainda existem no estoque global e quantas
+// cartas avulsas sobram além deles (menos que um pacote de packSize cartas). Com cartas avulsas
+// e nenhum pacote, o estoque está esgotado na prática, embora o LLEN não seja zero.
+func (s *Server) stockRemaining(packSize int) (packs, stray int64, err error) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	cards, err := s.RedisClient.LLen(ctx, stockKey).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	return cards / int64(packSize), cards % int64(packSize), nil
+}
+
+// remainingStockText descreve o estoque restante para o jogador, incluindo as cartas avulsas.
+func (s *Server) remainingStockText(packSize int) string {
+	packs, stray, err := s.stockRemaining(packSize)
+	if err != nil {
+		slog.Error("Erro ao consultar o estoque restante", "error", err)
+		return "Pacotes restantes no servidor: indisponível"
+	}
+	text := fmt.Sprintf("Pacotes restantes no servidor: %d", packs)
+	if stray > 0 {
+		text += fmt.Sprintf(" (e %d carta(s) avulsa(s), insuficientes para um pacote)", stray)
+	}
+	return text
+}
+
+// grantStarterPack entrega o pacote inicial obrigatório na conexão, apenas a jogadores novos.
+// A marca player:starter:<nome> registra, de forma permanente e para o cluster inteiro, quem já
+// recebeu o pacote: quem volta (com o deck restaurado de player:deck:<nome>, ver deck.go) não o
+// recebe de novo, para não gastar o estoque global a cada reconexão. Se o pacote não puder ser
+// aberto (estoque esgotado), a marca é desfeita e o jogador o recebe na próxima conexão.
+func (s *Server) grantStarterPack(player *PlayerState) {
+	ctx, cancel := s.redisCtx()
+	isNew, err := s.RedisClient.SetNX(ctx, starterPackPrefix+player.Name, s.ServerID, 0).Result()
+	cancel()
+	if err != nil {
+		slog.Error("Erro ao verificar o pacote inicial do jogador", "player", player.Name, "error", err)
+		return
+	}
+	if !isNew {
+		slog.Info("Jogador já recebeu o pacote inicial; não entregue de novo.", "event", "starter_pack_skipped",
+			"player", player.Name, "deck_size", len(player.deckSnapshot()))
+		return
+	}
+
+	response, opened := s.openCardPackResult(player, true)
+	if !opened {
+		ctx, cancel := s.redisCtx()
+		defer cancel()
+		if err := s.RedisClient.Del(ctx, starterPackPrefix+player.Name).Err(); err != nil {
+			slog.Error("Erro ao desfazer a marca do pacote inicial", "player", player.Name, "error", err)
+		}
+	}
+	s.sendWebSocketMessage(player, response)
+}
+
 // openCardPack é a função que o servidor local chamará.
 func (s *Server) openCardPack(player *PlayerState, isMandatory bool) {
-	if !isMandatory && player.PacksOpened >= 3 {
-		s.sendWebSocketMessage(player, "Você já abriu o máximo de 3 pacotes.")
-		return
+	response, _ := s.openCardPackResult(player, isMandatory)
+	s.sendWebSocketMessage(player, response)
+}
+
+// openCardPackResult abre um pacote e retorna a resposta ao jogador; opened informa se o pacote
+// foi de fato aberto. O limite de pacotes extras vale para o cluster inteiro (contador
+// player:packs:<nome>, ver pack_limit.go); o pacote inicial obrigatório não entra na contagem.
+func (s *Server) openCardPackResult(player *PlayerState, isMandatory bool) (response string, opened bool) {
+	granted := 0
+	if !isMandatory {
+		var total int
+		var err error
+		granted, total, err = s.reservePacks(player.Name, 1)
+		if err != nil {
+			slog.Error("Erro ao reservar pacote no contador do jogador", "player", player.Name, "error", err)
+			return "Desculpe, erro interno ao processar o estoque.", false
+		}
+		player.PacksOpened = total
+		if granted == 0 {
+			return fmt.Sprintf("Você já abriu o máximo de %d pacotes extras.", s.Config.MaxPacksPerPlayer), false
+		}
 	}
 
-	pack, err := s.openCardPackDistributed(player.Name)
+	pack, err := s.openCardPackDistributed(player.Name, s.Config.packSize(isMandatory))
 	if err != nil {
-		s.sendWebSocketMessage(player, fmt.Sprintf("Desculpe, %s", err.Error()))
-		return
+		// O pacote não foi aberto: não conta para o limite
+		if granted > 0 {
+			player.PacksOpened = s.refundPacks(player.Name, granted)
+		}
+		return fmt.Sprintf("Desculpe, %s", err.Error()), false
 	}
 
-	player.Deck = append(player.Deck, pack...)
-	player.PacksOpened++
+	s.giveCards(player, pack...)
+	s.audit(player.Name, auditOpenPack, "", strings.Join(cardNames(pack), ", "))
 
-	// Constrói e envia a resposta ao jogador
-	var response string
+	// Constrói a resposta ao jogador
 	if isMandatory {
 		response = fmt.Sprintf("Bem-vindo(a), %s! Você recebeu seu pacote inicial: ", player.Name)
 	} else {
 		response = fmt.Sprintf("Parabéns, %s! Você abriu um pacote extra e recebeu: ", player.Name)
 	}
 	for i, card := range pack {
-		response += fmt.Sprintf("%s (Força: %d)", card.Name, card.Forca)
+		response += fmt.Sprintf("%s (Força: %d)%s", card.Name, card.Forca, card.abilityTag())
 		if i < len(pack)-1 {
 			response += ", "
 		}
 	}
+	if packSize := s.Config.packSize(isMandatory); len(pack) < packSize {
+		response += fmt.Sprintf(" (último pacote do estoque, incompleto: %d de %d cartas)", len(pack), packSize)
+	}
 	// Consulta o estoque restante
-	remainingPacks, _ := s.RedisClient.LLen(context.Background(), stockKey).Result()
-	response += fmt.Sprintf(". Pacotes restantes no servidor: %d\n", remainingPacks/3)
-
-	s.sendWebSocketMessage(player, response)
+	response += ". " + s.remainingStockText(s.Config.PackSize) + "\n"
+	return response, true
 }
 
 // viewDeck envia ao jogador uma lista de todas as cartas em seu deck.
 func (s *Server) viewDeck(player *PlayerState) {
-	if len(player.Deck) == 0 {
+	deck := player.deckSnapshot()
+	if len(deck) == 0 {
 		s.sendWebSocketMessage(player, "Seu deck está vazio.")
 		return
 	}
 	response := "Seu deck: "
-	for i, card := range player.Deck {
-		response += fmt.Sprintf("%s (Força: %d)", card.Name, card.Forca)
-		if i < len(player.Deck)-1 {
+	for i, card := range deck {
+		response += fmt.Sprintf("%s (Força: %d)%s", card.Name, card.Forca, card.abilityTag())
+		if i < len(deck)-1 {
 			response += " | "
 		}
 	}
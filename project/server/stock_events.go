@@ -0,0 +1,94 @@
+package main
+
+import (
+	"log/slog"
+)
+
+const (
+	// stockEventsChannel é o canal Pub/Sub, assinado por todos os servidores, dos avisos do estoque global.
+	stockEventsChannel = "stock:events"
+	// stockExhaustedKey marca no Redis que o estoque está esgotado, para que um servidor que suba
+	// (ou um jogador que conecte) durante a falta também seja avisado.
+	stockExhaustedKey = "stock:exhausted"
+
+	stockExhaustedEvent   = "STOCK_EXHAUSTED"   // O estoque global acabou: OPEN_PACK não entrega cartas
+	stockReplenishedEvent = "STOCK_REPLENISHED" // O estoque foi reposto
+)
+
+// markStockExhausted avisa todos os servidores que o estoque acabou. Só o primeiro servidor a
+// marcar a falta publica o aviso; avisos repetidos são ignorados pelos ouvintes de qualquer forma.
+func (s *Server) markStockExhausted() {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	marked, err := s.RedisClient.SetNX(ctx, stockExhaustedKey, s.ServerID, 0).Result()
+	if err != nil {
+		slog.Error("Erro ao marcar o estoque como esgotado", "error", err)
+		return
+	}
+	if !marked {
+		return
+	}
+	slog.Warn("Estoque global esgotado. Avisando os servidores.", "event", "stock_exhausted")
+	if err := s.RedisClient.Publish(ctx, stockEventsChannel, stockExhaustedEvent).Err(); err != nil {
+		slog.Error("Erro ao publicar aviso de estoque esgotado", "error", err)
+	}
+}
+
+// markStockReplenished avisa todos os servidores que o estoque voltou, após uma reposição.
+func (s *Server) markStockReplenished() {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	cleared, err := s.RedisClient.Del(ctx, stockExhaustedKey).Result()
+	if err != nil {
+		slog.Error("Erro ao desmarcar o estoque esgotado", "error", err)
+		return
+	}
+	if cleared == 0 {
+		return // O estoque não estava esgotado: ninguém precisa ser avisado
+	}
+	slog.Info("Estoque global reposto. Avisando os servidores.", "event", "stock_replenished")
+	if err := s.RedisClient.Publish(ctx, stockEventsChannel, stockReplenishedEvent).Err(); err != nil {
+		slog.Error("Erro ao publicar aviso de estoque reposto", "error", err)
+	}
+}
+
+// listenStockEvents assina o canal de avisos do estoque e repassa cada MUDANÇA de estado aos
+// jogadores conectados a este servidor. Roda durante toda a vida do servidor.
+func (s *Server) listenStockEvents() {
+	// Estado inicial: o estoque pode ter acabado antes de este servidor subir
+	ctx, cancel := s.redisCtx()
+	if exists, err := s.RedisClient.Exists(ctx, stockExhaustedKey).Result(); err == nil {
+		s.stockExhausted.Store(exists > 0)
+	}
+	cancel()
+
+	// A assinatura dura a vida do servidor: só o encerramento a interrompe
+	pubsub := s.RedisClient.Subscribe(s.ctx, stockEventsChannel)
+	defer pubsub.Close()
+	for msg := range pubsub.Channel() {
+		if msg.Payload != stockExhaustedEvent && msg.Payload != stockReplenishedEvent {
+			continue
+		}
+		// Avisos duplicados (mesmo estado) não são repassados
+		exhausted := msg.Payload == stockExhaustedEvent
+		if s.stockExhausted.Swap(exhausted) == exhausted {
+			continue
+		}
+		s.broadcastToLocalPlayers(msg.Payload)
+	}
+}
+
+// broadcastToLocalPlayers envia a mensagem a todos os jogadores conectados a este servidor.
+func (s *Server) broadcastToLocalPlayers(message string) {
+	s.PlayerMutex.Lock()
+	players := make([]*PlayerState, 0, len(s.Players))
+	for _, p := range s.Players {
+		players = append(players, p)
+	}
+	s.PlayerMutex.Unlock()
+
+	for _, p := range players {
+		s.sendWebSocketMessage(p, message)
+	}
+	slog.Info("Aviso enviado aos jogadores locais", "event", "local_broadcast", "message", message, "players", len(players))
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+)
+
+// defaultStockTotal é o tamanho do estoque padrão (90000 cartas).
+const defaultStockTotal = 90000
+
+// StockSpec define a distribuição de cartas do estoque global (a "raridade" de cada carta).
+// Pode ser carregada de um arquivo JSON (STOCK_SPEC_FILE) para rebalancear a economia
+// sem recompilar. Ex:
+//
+//	{"total": 1000, "cards": [{"name": "Ghoul", "forca": 1, "copies": 990},
+//	                          {"name": "Geralt de Rívia", "forca": 15, "copies": 10}]}
+type StockSpec struct {
+	Total int             `json:"total"` // Opcional: se informado, a soma das cópias deve ser igual a ele
+	Cards []StockSpecCard `json:"cards"`
+}
+
+// StockSpecCard é uma carta do estoque e quantas cópias dela existem.
+type StockSpecCard struct {
+	Name    string `json:"name"`
+	Forca   int    `json:"forca"`
+	Ability string `json:"ability,omitempty"`
+	Speed   int    `json:"speed,omitempty"`
+	// Metadados de exibição (opcionais). Sem eles, a raridade e a imagem são derivadas da carta.
+	Rarity   string `json:"rarity,omitempty"`
+	Faction  string `json:"faction,omitempty"`
+	ImageRef string `json:"image_ref,omitempty"`
+	Copies   int    `json:"copies"`
+}
+
+// card retorna a carta descrita pela especificação, com os metadados de exibição preenchidos.
+func (c StockSpecCard) card() Card {
+	card := Card{Name: c.Name, Forca: c.Forca, Ability: c.Ability, Speed: c.Speed,
+		Rarity: c.Rarity, Faction: c.Faction, ImageRef: c.ImageRef}
+	return card.withMetadata()
+}
+
+// loadStockSpec lê a especificação do estoque do arquivo informado.
+// Sem arquivo, usa a distribuição padrão (defaultStockSpec), que também precisa caber na faixa
+// de Força configurada.
+func loadStockSpec(path string, cfg Config) (StockSpec, error) {
+	if path == "" {
+		spec := defaultStockSpec()
+		if err := spec.validate(cfg); err != nil {
+			return StockSpec{}, fmt.Errorf("distribuição padrão fora da configuração: %w", err)
+		}
+		return spec, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return StockSpec{}, fmt.Errorf("erro ao ler STOCK_SPEC_FILE: %w", err)
+	}
+	var spec StockSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return StockSpec{}, fmt.Errorf("STOCK_SPEC_FILE inválido: %w", err)
+	}
+	if err := spec.validate(cfg); err != nil {
+		return StockSpec{}, fmt.Errorf("STOCK_SPEC_FILE inválido: %w", err)
+	}
+	return spec, nil
+}
+
+// validateStockCard verifica uma carta que entra no estoque global (ou sai dele): o nome não
+// pode ser vazio e a Força deve estar entre CARD_FORCA_MIN e CARD_FORCA_MAX.
+func (cfg Config) validateStockCard(name string, forca int) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("carta sem nome")
+	}
+	if forca < cfg.CardForcaMin || forca > cfg.CardForcaMax {
+		return fmt.Errorf("carta %q com Força inválida (%d): deve estar entre %d e %d", name, forca, cfg.CardForcaMin, cfg.CardForcaMax)
+	}
+	return nil
+}
+
+// validate verifica as cartas e o total do estoque.
+func (spec StockSpec) validate(cfg Config) error {
+	if len(spec.Cards) == 0 {
+		return fmt.Errorf("nenhuma carta definida")
+	}
+	seen := make(map[string]bool)
+	sum := 0
+	for _, c := range spec.Cards {
+		if err := cfg.validateStockCard(c.Name, c.Forca); err != nil {
+			return err
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("carta %q definida mais de uma vez", c.Name)
+		}
+		seen[c.Name] = true
+		if _, ok := abilityNames[c.Ability]; c.Ability != "" && !ok {
+			return fmt.Errorf("carta %q com habilidade desconhecida %q", c.Name, c.Ability)
+		}
+		if c.Speed < 0 {
+			return fmt.Errorf("carta %q com Agilidade negativa (%d)", c.Name, c.Speed)
+		}
+		if c.Copies < 0 {
+			return fmt.Errorf("carta %q com número de cópias negativo (%d)", c.Name, c.Copies)
+		}
+		sum += c.Copies
+	}
+	if sum == 0 {
+		return fmt.Errorf("o estoque não tem nenhuma cópia")
+	}
+	if spec.Total != 0 && spec.Total != sum {
+		return fmt.Errorf("a soma das cópias (%d) é diferente do total informado (%d)", sum, spec.Total)
+	}
+	return nil
+}
+
+// expand gera a lista de cartas do estoque (ainda não embaralhada).
+func (spec StockSpec) expand() []Card {
+	var stock []Card
+	for _, c := range spec.Cards {
+		card := c.card()
+		for i := 0; i < c.Copies; i++ {
+			stock = append(stock, card)
+		}
+	}
+	return stock
+}
+
+// sample sorteia n cartas respeitando a distribuição (o número de cópias é o peso de cada carta).
+// Usado pela reposição automática, que adiciona lotes menores que o estoque completo.
+func (spec StockSpec) sample(n int) []Card {
+	total := 0
+	for _, c := range spec.Cards {
+		total += c.Copies
+	}
+	if total == 0 {
+		return nil
+	}
+
+	cards := make([]Card, 0, n)
+	for i := 0; i < n; i++ {
+		pick := rand.Intn(total)
+		for _, c := range spec.Cards {
+			if pick < c.Copies {
+				cards = append(cards, c.card())
+				break
+			}
+			pick -= c.Copies
+		}
+	}
+	return cards
+}
+
+// defaultStockSpec é a distribuição padrão do jogo, por faixa de Força:
+// 1-3: 4000 cópias, 4-6: 3000, 7-10: 2000, acima de 10: 10.
+// O primeiro card base completa o estoque até defaultStockTotal.
+func defaultStockSpec() StockSpec {
+	spec := StockSpec{Total: defaultStockTotal}
+	sum := 0
+	for _, card := range baseCards {
+		copies := 10 // Padrão para as cartas mais raras (Força > 10)
+		if card.Forca >= 1 && card.Forca <= 3 {
+			copies = 4000
+		} else if card.Forca >= 4 && card.Forca <= 6 {
+			copies = 3000
+		} else if card.Forca >= 7 && card.Forca <= 10 {
+			copies = 2000
+		}
+		spec.Cards = append(spec.Cards, StockSpecCard{Name: card.Name, Forca: card.Forca, Ability: card.Ability, Speed: card.Speed,
+			Rarity: card.Rarity, Faction: card.Faction, ImageRef: card.ImageRef, Copies: copies})
+		sum += copies
+	}
+	// Garante que o estoque tenha exatamente defaultStockTotal cartas
+	spec.Cards[0].Copies += defaultStockTotal - sum
+	return spec
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateStockCard(t *testing.T) {
+	cfg := Config{CardForcaMin: 1, CardForcaMax: 20}
+	tests := []struct {
+		name  string
+		forca int
+		valid bool
+	}{
+		{"Ghoul", 1, true},
+		{"Geralt de Rívia", 20, true},
+		{"", 5, false},
+		{"   ", 5, false},
+		{"Ghoul", 0, false},
+		{"Ghoul", -3, false},
+		{"Ghoul", 21, false},
+		{"Ghoul", 9999, false},
+	}
+	for _, tt := range tests {
+		err := cfg.validateStockCard(tt.name, tt.forca)
+		if (err == nil) != tt.valid {
+			t.Errorf("validateStockCard(%q, %d) = %v, válida esperado: %v", tt.name, tt.forca, err, tt.valid)
+		}
+	}
+}
+
+func TestRestockRejectsInvalidCards(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", testAdminToken)
+	s, mr := newTestServer(t)
+	seedStock(t, s, Card{Name: "Ghoul", Forca: 1}, 3)
+
+	// Uma carta inválida recusa a reposição inteira
+	if _, err := s.restockCards([]Card{{Name: "Ghoul", Forca: 1}, {Name: "Trapaça", Forca: 999}}); err == nil {
+		t.Errorf("restockCards deveria recusar uma carta com Força fora da faixa")
+	}
+	if stock, _ := mr.List(stockKey); len(stock) != 3 {
+		t.Errorf("a reposição recusada não deveria mudar o estoque: %d cartas, esperado 3", len(stock))
+	}
+
+	for _, body := range []string{
+		`{"cards": [{"name": "", "forca": 5, "copies": 1}]}`,
+		`{"cards": [{"name": "Trapaça", "forca": 999, "copies": 1}]}`,
+		`{"cards": [{"name": "Trapaça", "forca": -1, "copies": 1}]}`,
+	} {
+		rec := httptest.NewRecorder()
+		s.handleRestock(rec, newRestockRequest(body))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("reposição %s: status = %d, esperado 400", body, rec.Code)
+		}
+	}
+	if stock, _ := mr.List(stockKey); len(stock) != 3 {
+		t.Errorf("as reposições recusadas não deveriam mudar o estoque: %d cartas, esperado 3", len(stock))
+	}
+}
+
+func TestLoadStockSpecRejectsCardsOutsideRange(t *testing.T) {
+	// A distribuição padrão tem cartas de Força 15: uma faixa menor a recusa no startup
+	if _, err := loadStockSpec("", Config{CardForcaMin: 1, CardForcaMax: 10}); err == nil {
+		t.Errorf("a distribuição padrão deveria ser recusada com CARD_FORCA_MAX=10")
+	}
+	if _, err := loadStockSpec("", Config{CardForcaMin: defaultCardForcaMin, CardForcaMax: defaultCardForcaMax}); err != nil {
+		t.Errorf("a distribuição padrão deveria caber na faixa padrão: %v", err)
+	}
+}
+
+func TestOpenPackDiscardsCorruptedStockCards(t *testing.T) {
+	t.Setenv("PACK_SIZE", "3")
+	s, mr := newTestServer(t)
+	mr.RPush(stockKey, `{"name": "", "forca": 0}`, `{}`, `{"name": "Ghoul", "forca": 1}`)
+
+	pack, err := s.openCardPackDistributed("Alice", s.Config.PackSize)
+	if err != nil {
+		t.Fatalf("openCardPackDistributed: %v", err)
+	}
+	if len(pack) != 1 || pack[0].Name != "Ghoul" {
+		t.Errorf("só a carta válida deveria chegar ao jogador, pacote: %v", pack)
+	}
+
+	// Um pacote só de cartas corrompidas é um erro, e não um pacote vazio
+	mr.RPush(stockKey, `{}`, `{"name": "Trapaça", "forca": 999}`, `{"name": " ", "forca": 3}`)
+	if pack, err := s.openCardPackDistributed("Alice", s.Config.PackSize); err == nil {
+		t.Errorf("um pacote só de cartas inválidas deveria falhar, pacote: %v", pack)
+	}
+}
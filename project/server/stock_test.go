@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenPackWithStrayCards(t *testing.T) {
+	tests := []struct {
+		stray        int
+		allowPartial bool
+	}{
+		{1, false},
+		{2, false},
+		{1, true},
+		{2, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("restam_%d_parcial_%v", tt.stray, tt.allowPartial), func(t *testing.T) {
+			t.Setenv("PACK_SIZE", "3")
+			t.Setenv("ALLOW_PARTIAL_PACK", fmt.Sprint(tt.allowPartial))
+			s, mr := newTestServer(t)
+			seedStock(t, s, Card{Name: "Ghoul", Forca: 1}, tt.stray)
+
+			pack, err := s.openCardPackDistributed("Alice", s.Config.PackSize)
+			if tt.allowPartial {
+				if err != nil || len(pack) != tt.stray {
+					t.Fatalf("o último pacote deveria sair incompleto com %d carta(s): pacote %v, erro %v", tt.stray, pack, err)
+				}
+				if mr.Exists(stockKey) {
+					t.Errorf("o estoque deveria ficar vazio depois do pacote incompleto")
+				}
+				return
+			}
+
+			if !errors.Is(err, errStockEmpty) {
+				t.Fatalf("com %d carta(s), o pacote deveria ser recusado com errStockEmpty, recebido %v", tt.stray, err)
+			}
+			if want := fmt.Sprintf("restam %d carta(s) avulsa(s)", tt.stray); !strings.Contains(err.Error(), want) {
+				t.Errorf("o erro deveria informar as cartas avulsas (%q), recebido %q", want, err.Error())
+			}
+			if packs, stray, _ := s.stockRemaining(s.Config.PackSize); packs != 0 || stray != int64(tt.stray) {
+				t.Errorf("stockRemaining = (%d, %d), esperado (0, %d)", packs, stray, tt.stray)
+			}
+			if text := s.remainingStockText(s.Config.PackSize); !strings.Contains(text, fmt.Sprintf("%d carta(s) avulsa(s)", tt.stray)) {
+				t.Errorf("o texto do estoque deveria citar as cartas avulsas, recebido %q", text)
+			}
+
+			// O endpoint REST informa o estoque real, embora o LLEN não seja zero
+			rec := httptest.NewRecorder()
+			s.handleTakeCardPack(rec, httptest.NewRequest(http.MethodPost, "/api/v1/stock/take", strings.NewReader(`{"player_name": "Alice"}`)))
+			if rec.Code != http.StatusConflict {
+				t.Fatalf("status = %d, esperado %d", rec.Code, http.StatusConflict)
+			}
+			var resp TakePackResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("resposta inválida: %v", err)
+			}
+			if resp.Code != errCodeStockEmpty || resp.RemainingPacks != 0 || resp.StrayCards != int64(tt.stray) {
+				t.Errorf("resposta = %+v, esperado code %q, 0 pacotes e %d carta(s) avulsa(s)", resp, errCodeStockEmpty, tt.stray)
+			}
+		})
+	}
+}
+
+func TestStarterPackNewVsReturningPlayer(t *testing.T) {
+	s, mr := newTestServer(t)
+	seedStock(t, s, Card{Name: "Ghoul", Forca: 1}, 30)
+
+	// Jogador novo: recebe o pacote inicial, e o deck fica salvo
+	first := newTestPlayer("Alice")
+	first.Deck = s.loadDeck("Alice")
+	s.grantStarterPack(first)
+	if got := len(first.deckSnapshot()); got != s.Config.StarterPackSize {
+		t.Fatalf("jogador novo com %d cartas, esperado o pacote inicial (%d)", got, s.Config.StarterPackSize)
+	}
+	if messages := sentMessages(first); len(messages) != 1 || !strings.Contains(messages[0], "pacote inicial") {
+		t.Errorf("o jogador novo deveria receber a mensagem do pacote inicial, mensagens: %q", messages)
+	}
+	stockAfterFirst, _ := mr.List(stockKey)
+
+	// Jogador que volta: o deck é restaurado e o estoque não é gasto de novo
+	returning := newTestPlayer("Alice")
+	returning.Deck = s.loadDeck("Alice")
+	s.grantStarterPack(returning)
+	if got, want := returning.deckSnapshot(), first.deckSnapshot(); len(got) != len(want) {
+		t.Errorf("jogador que volta com %d cartas, esperado o deck salvo (%d)", len(got), len(want))
+	}
+	if messages := sentMessages(returning); len(messages) != 0 {
+		t.Errorf("o jogador que volta não deveria receber o pacote inicial, mensagens: %q", messages)
+	}
+	if stock, _ := mr.List(stockKey); len(stock) != len(stockAfterFirst) {
+		t.Errorf("o estoque não deveria mudar na volta: %d cartas, antes %d", len(stock), len(stockAfterFirst))
+	}
+}
+
+func TestStarterPackRetriedAfterEmptyStock(t *testing.T) {
+	s, mr := newTestServer(t)
+
+	player := newTestPlayer("Alice")
+	s.grantStarterPack(player)
+	if len(player.deckSnapshot()) != 0 {
+		t.Fatalf("sem estoque, o jogador não deveria receber cartas")
+	}
+	if mr.Exists(starterPackPrefix + "Alice") {
+		t.Fatalf("sem o pacote entregue, a marca %sAlice deveria ser desfeita", starterPackPrefix)
+	}
+
+	seedStock(t, s, Card{Name: "Ghoul", Forca: 1}, 30)
+	retry := newTestPlayer("Alice")
+	s.grantStarterPack(retry)
+	if got := len(retry.deckSnapshot()); got != s.Config.StarterPackSize {
+		t.Errorf("na conexão seguinte, o jogador deveria receber o pacote inicial: %d cartas", got)
+	}
+}
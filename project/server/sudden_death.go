@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Com SUDDEN_DEATH_ROUNDS > 0, um empate nas cartas de uma partida clássica não encerra a partida:
+// o cérebro inicia uma rodada de morte súbita, em que cada jogador recebe uma carta nova e tem um
+// novo prazo para jogá-la. As rodadas se repetem até alguém vencer ou até o limite, quando vale o
+// empate. A carta é sorteada conforme a distribuição do estoque (StockSpec.sample), sem retirá-la
+// do estoque nem do deck: ela só existe na rodada.
+//
+// Cada rodada tem os próprios campos no hash game:state:<GameID> ("p1_card:sd1", "p2_card:sd1",
+// ver cardField), então uma jogada atrasada da rodada anterior não conta na seguinte. A rodada em
+// andamento também fica no hash (suddenDeathRoundField), para quem resolve a partida fora do cérebro
+// (administração, reconciliação, watchdog) ler as jogadas da rodada certa. O P1 recebe a
+// rodada direto do cérebro; o P2 a recebe pelo canal player:<nome> (guardada em pending:<nome> se ele
+// estiver entre conexões), e o servidor dele atualiza a sua sessão (mão e prazo) antes de encaminhá-la:
+//
+//	REVEAL|<json>         carta do oponente que empatou
+//	SUDDEN_DEATH|<json>   nova mão (uma carta), rodada e prazo (GameSuddenDeath)
+
+const (
+	// suddenDeathPrefix é a mensagem de uma rodada de morte súbita, ao jogador e entre os servidores.
+	suddenDeathPrefix = "SUDDEN_DEATH|"
+	// suddenDeathRoundField é o campo do hash game:state com a rodada em andamento (ausente = jogada normal).
+	suddenDeathRoundField = "sudden_death_round"
+)
+
+// GameSuddenDeath é o payload de "SUDDEN_DEATH|<json>": os campos do GameStart (com a mão de
+// uma carta e o novo prazo), a rodada e o prazo absoluto, usado pelo servidor do P2.
+type GameSuddenDeath struct {
+	GameStart
+	Round      int   `json:"round"`
+	MaxRounds  int   `json:"max_rounds"`
+	DeadlineMs int64 `json:"deadline_ms"` // Prazo da jogada (Unix em ms), o mesmo do cérebro
+}
+
+// cardField é o campo da jogada no hash game:state: "p1_card"/"p2_card" na jogada normal e
+// "p1_card:sd<n>"/"p2_card:sd<n>" na rodada n de morte súbita.
+func cardField(base string, round int) string {
+	if round == 0 {
+		return base
+	}
+	return fmt.Sprintf("%s:sd%d", base, round)
+}
+
+// suddenDeathRoundOf lê, dos campos do hash game:state, a rodada de morte súbita em andamento.
+func suddenDeathRoundOf(moves map[string]string) int {
+	round, _ := strconv.Atoi(moves[suddenDeathRoundField])
+	return round
+}
+
+// fillSessionFromMoves preenche uma sessão reconstruída fora do cérebro com a rodada em andamento e
+// as jogadas dela: numa rodada de morte súbita, as cartas de "p1_card"/"p2_card" são as que empataram.
+func (s *Server) fillSessionFromMoves(session *GameSession, moves map[string]string) {
+	round := suddenDeathRoundOf(moves)
+	session.mu.Lock()
+	session.SuddenDeathRound = round
+	session.mu.Unlock()
+	s.fillSessionFromRedis(session, moves[cardField("p1_card", round)], moves[cardField("p2_card", round)])
+}
+
+// tiedByCards informa se as duas cartas jogadas empataram (sem desistência nem timeout).
+// Deve ser chamada com session.mu travado.
+func (g *GameSession) tiedByCards() bool {
+	if g.ForfeitedBy != "" || g.Player1Card == nil || g.Player2Card == nil {
+		return false
+	}
+	return resolveCardDuel(*g.Player1Card, *g.Player2Card).Winner == 0
+}
+
+// suddenDeathText é o prefixo do texto do resultado decidido numa rodada de morte súbita.
+// Deve ser chamada com session.mu travado.
+func (g *GameSession) suddenDeathText() []LocalizedText {
+	if g.SuddenDeathRound == 0 {
+		return nil
+	}
+	return []LocalizedText{localized(msgResultSuddenDeath, g.SuddenDeathRound)}
+}
+
+// startSuddenDeath inicia a próxima rodada de morte súbita após um empate. Roda no cérebro
+// (P1-Server): mostra a cada jogador a carta do oponente, sorteia as novas cartas e envia a
+// rodada. Retorna a rodada iniciada e o novo prazo.
+func (s *Server) startSuddenDeath(session *GameSession) (int, time.Time) {
+	dealt := s.StockSpec.sample(2)
+	deadline := time.Now().Add(s.Config.GameTurnTimeout)
+
+	session.mu.Lock()
+	gameID := session.GameID
+	p1, p2 := session.Player1, session.Player2
+	p1Tied, p2Tied := session.Player1Card, session.Player2Card
+	session.SuddenDeathRound++
+	round := session.SuddenDeathRound
+	session.Player1Card, session.Player2Card = nil, nil
+	session.Player1Hand = []Card{dealt[0]}
+	if p2.isBot {
+		session.Player2Hand = []Card{dealt[1]} // O bot joga neste servidor (ver bot.go)
+	}
+	session.TurnDeadline = deadline
+	session.mu.Unlock()
+
+	logger := slog.With("game_id", gameID)
+	logger.Info("Empate. Iniciando rodada de morte súbita.", "event", "sudden_death", "round", round,
+		"max_rounds", s.Config.SuddenDeathRounds, "player1_card", dealt[0].Name, "player2_card", dealt[1].Name)
+	s.appendReplayEvent(gameID, ReplayEvent{Type: replayEventSuddenDeath, Detail: fmt.Sprintf("rodada %d", round)})
+	s.appendReplayEvent(gameID, ReplayEvent{Type: replayEventHandDealt, Player: p1.Name, Cards: dealt[:1]})
+	s.appendReplayEvent(gameID, ReplayEvent{Type: replayEventHandDealt, Player: p2.Name, Cards: dealt[1:]})
+
+	// O prazo compartilhado passa a ser o da rodada (ver turn_timer.go), e a rodada fica registrada
+	// junto às jogadas
+	ctx, cancel := s.redisCtx()
+	pipe := s.RedisClient.TxPipeline()
+	pipe.Set(ctx, gameDeadlinePrefix+gameID, deadline.UnixMilli(), s.Config.GameTurnTimeout+gameDeadlineGrace)
+	pipe.HSet(ctx, gameStatePrefix+gameID, suddenDeathRoundField, round)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Error("Erro ao registrar a rodada de morte súbita", "round", round, "error", err)
+	}
+	cancel()
+
+	suddenDeath := func(opponent string, card Card) string {
+		payload, _ := json.Marshal(GameSuddenDeath{
+			GameStart: GameStart{GameID: gameID, Mode: gameModeClassic, Opponent: opponent, Hand: []Card{card},
+				TurnSeconds: remainingSeconds(deadline)},
+			Round:      round,
+			MaxRounds:  s.Config.SuddenDeathRounds,
+			DeadlineMs: deadline.UnixMilli(),
+		})
+		return suddenDeathPrefix + string(payload)
+	}
+
+	s.sendWebSocketMessage(p1, revealMessage(gameID, p2.Name, p2Tied))
+	s.sendWebSocketMessage(p1, suddenDeath(p2.Name, dealt[0]))
+	if p2.isBot {
+		go s.playBotMove(session, gameID)
+		return round, deadline
+	}
+	// Como no resultado, cada PUBLISH só começa depois do anterior: o REVEAL chega antes da rodada.
+	// Se o P2 estiver entre conexões, as duas mensagens esperam a próxima (ver player_inbox.go).
+	for _, message := range []string{revealMessage(gameID, p1.Name, p1Tied), suddenDeath(p1.Name, dealt[1])} {
+		ctx, cancel := s.redisCtx()
+		err := s.publishToPlayer(ctx, p2.Name, message)
+		cancel()
+		if err != nil {
+			logger.Error("Erro ao publicar rodada de morte súbita via Redis", "player", p2.Name, "error", err)
+		}
+	}
+	return round, deadline
+}
+
+// applySuddenDeath recebe, no servidor do P2, uma rodada de morte súbita publicada pelo cérebro:
+// atualiza a mão e o prazo da sessão local e encaminha a rodada ao jogador.
+func (s *Server) applySuddenDeath(player *PlayerState, message string) {
+	var round GameSuddenDeath
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(message, suddenDeathPrefix)), &round); err != nil {
+		slog.Error("Rodada de morte súbita inválida", "player", player.Name, "error", err)
+		return
+	}
+	player.mu.Lock()
+	session := player.Games[round.GameID]
+	player.mu.Unlock()
+	if session == nil {
+		slog.Warn("Rodada de morte súbita de uma partida que não está em andamento", "player", player.Name, "game_id", round.GameID)
+		return
+	}
+
+	session.mu.Lock()
+	session.SuddenDeathRound = round.Round
+	session.TurnDeadline = time.UnixMilli(round.DeadlineMs)
+	if session.Player2 != nil && session.Player2.Name == player.Name {
+		session.Player2Hand = round.Hand
+	}
+	session.mu.Unlock()
+	s.sendWebSocketMessage(player, message)
+}
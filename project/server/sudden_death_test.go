@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestCardField(t *testing.T) {
+	tests := []struct {
+		base  string
+		round int
+		want  string
+	}{
+		{"p1_card", 0, "p1_card"},
+		{"p2_card", 0, "p2_card"},
+		{"p1_card", 1, "p1_card:sd1"},
+		{"p2_card", 3, "p2_card:sd3"},
+	}
+	for _, tt := range tests {
+		if got := cardField(tt.base, tt.round); got != tt.want {
+			t.Errorf("cardField(%q, %d) = %q, esperado %q", tt.base, tt.round, got, tt.want)
+		}
+	}
+}
+
+// playRound grava as jogadas da rodada no hash da partida e avisa o cérebro.
+func playRound(t *testing.T, mr *miniredis.Miniredis, round int, p1Card, p2Card Card) {
+	t.Helper()
+	for base, card := range map[string]Card{"p1_card": p1Card, "p2_card": p2Card} {
+		cardJSON, _ := json.Marshal(card)
+		mr.HSet("game:state:g1", cardField(base, round), string(cardJSON))
+	}
+	mr.Publish("game:channel:g1", "MOVE")
+}
+
+// waitForMessage espera a mensagem do jogador que começa com prefix e retorna o restante dela.
+func waitForMessage(t *testing.T, player *PlayerState, prefix string) string {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case item := <-player.outbox:
+			if item.delivered != nil {
+				item.delivered()
+				continue
+			}
+			if rest, ok := strings.CutPrefix(item.message, prefix); ok {
+				return rest
+			}
+		case <-timeout:
+			t.Fatalf("o jogador %s não recebeu %q", player.Name, prefix)
+			return ""
+		}
+	}
+}
+
+func TestSuddenDeath(t *testing.T) {
+	dragon, goblin := Card{Name: "Dragão", Forca: 10}, Card{Name: "Goblin", Forca: 2}
+	tests := []struct {
+		name        string
+		rounds      string // SUDDEN_DEATH_ROUNDS
+		suddenDeath []Card // Jogadas da rodada de morte súbita (P1, P2); nil = não há rodada
+		want        string // Resultado do P1
+	}{
+		{"sem morte súbita o empate vale", "0", nil, "EMPATE"},
+		{"a morte súbita desempata", "1", []Card{dragon, goblin}, "VITÓRIA"},
+		{"rodadas esgotadas", "1", []Card{goblin, goblin}, "EMPATE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SUDDEN_DEATH_ROUNDS", tt.rounds)
+			s, mr := newTestServer(t)
+			s.StockSpec = defaultStockSpec()
+			p1, p2 := newTestPlayer("Alice"), newTestPlayer("Bob")
+			session := newTestClassicGame(s, "g1", p1, p2, []Card{dragon}, []Card{dragon})
+			session.Server2ID = "Server-Other"
+			session.TurnDeadline = time.Now().Add(5 * time.Second)
+
+			finished := make(chan struct{})
+			go func() {
+				s.listenForGameEvents(session, "g1")
+				close(finished)
+			}()
+			waitFor(t, "cérebro da partida inscrito", func() bool { return mr.PubSubNumSub("game:channel:g1")["game:channel:g1"] > 0 })
+			playRound(t, mr, 0, dragon, dragon)
+
+			if tt.suddenDeath != nil {
+				var round GameSuddenDeath
+				if err := json.Unmarshal([]byte(waitForMessage(t, p1, suddenDeathPrefix)), &round); err != nil {
+					t.Fatalf("SUDDEN_DEATH inválido: %v", err)
+				}
+				if round.Round != 1 || len(round.Hand) != 1 || round.TurnSeconds <= 0 {
+					t.Errorf("rodada = %+v, esperado a rodada 1 com uma carta e novo prazo", round)
+				}
+				if got := mr.HGet("game:state:g1", suddenDeathRoundField); got != "1" {
+					t.Errorf("%s no hash da partida = %q, esperado \"1\"", suddenDeathRoundField, got)
+				}
+				// Ninguém ouve o canal do P2: a rodada fica guardada para a próxima conexão dele
+				waitFor(t, "rodada do P2 guardada", func() bool {
+					pending, _ := mr.List(playerInboxPrefix + "Bob")
+					return len(pending) >= 2
+				})
+				pending, _ := mr.List(playerInboxPrefix + "Bob")
+				if len(pending) != 2 || !strings.HasPrefix(pending[0], "REVEAL|") || !strings.HasPrefix(pending[1], suddenDeathPrefix) {
+					t.Errorf("o REVEAL e a SUDDEN_DEATH do P2 deveriam ficar guardados, pendentes: %q", pending)
+				}
+				playRound(t, mr, 1, tt.suddenDeath[0], tt.suddenDeath[1])
+			}
+
+			var end GameEnd
+			json.Unmarshal([]byte(waitForMessage(t, p1, "GAME_END|")), &end)
+			if end.Result != tt.want {
+				t.Errorf("resultado do P1 = %q, esperado %q", end.Result, tt.want)
+			}
+			select {
+			case <-finished:
+			case <-time.After(5 * time.Second):
+				t.Fatal("o cérebro da partida não terminou")
+			}
+		})
+	}
+}
+
+// seedSuddenDeathState grava uma partida na rodada 1 de morte súbita: as cartas da jogada normal
+// empataram, e na rodada o P1 jogou o Goblin. Retorna os campos do hash.
+func seedSuddenDeathState(t *testing.T, s *Server, mr *miniredis.Miniredis, p2Played bool) map[string]string {
+	t.Helper()
+	dragon, _ := json.Marshal(Card{Name: "Dragão", Forca: 10})
+	goblin, _ := json.Marshal(Card{Name: "Goblin", Forca: 2})
+	mr.HSet("game:state:g1", "p1_card", string(dragon), "p2_card", string(dragon),
+		suddenDeathRoundField, "1", "p1_card:sd1", string(goblin))
+	if p2Played {
+		mr.HSet("game:state:g1", "p2_card:sd1", string(dragon))
+	}
+	moves, err := s.RedisClient.HGetAll(s.ctx, "game:state:g1").Result()
+	if err != nil {
+		t.Fatalf("HGetAll: %v", err)
+	}
+	return moves
+}
+
+// pendingResult retorna o resultado guardado para o jogador ("RESULT|...") ou falha o teste.
+func pendingResult(t *testing.T, mr *miniredis.Miniredis, name string) string {
+	t.Helper()
+	pending, _ := mr.List(playerInboxPrefix + name)
+	for _, payload := range pending {
+		if _, result, ok := parseGameResult(payload); ok {
+			return result
+		}
+	}
+	t.Fatalf("nenhum resultado guardado para %s, pendentes: %q", name, pending)
+	return ""
+}
+
+func TestResolveOutsideBrainUsesSuddenDeathRound(t *testing.T) {
+	meta := GameMeta{GameID: "g1", Mode: gameModeClassic, BrainServerID: "Server-Test", Players: []string{"Alice", "Bob"}}
+
+	t.Run("administração", func(t *testing.T) {
+		s, mr := newTestServer(t)
+		session, _ := ghostSession(meta)
+		_, results, ok := s.forceResolveClassic(session, seedSuddenDeathState(t, s, mr, true))
+		if !ok {
+			t.Fatalf("forceResolveClassic deveria resolver a partida")
+		}
+		if !strings.HasPrefix(results["Bob"], "RESULT|VITÓRIA") {
+			t.Errorf("resultado do P2 = %q, esperado a vitória da rodada de morte súbita", results["Bob"])
+		}
+	})
+
+	t.Run("reconciliação", func(t *testing.T) {
+		s, mr := newTestServer(t)
+		s.resolveStaleGame(meta, seedSuddenDeathState(t, s, mr, true))
+		if result := pendingResult(t, mr, "Bob"); !strings.HasPrefix(result, "RESULT|VITÓRIA") {
+			t.Errorf("resultado do P2 = %q, esperado a vitória da rodada de morte súbita", result)
+		}
+	})
+
+	t.Run("watchdog", func(t *testing.T) {
+		s, mr := newTestServer(t)
+		seedSuddenDeathState(t, s, mr, false)
+		// A SUDDEN_DEATH não chegou ao servidor do P2: a sessão local ainda está na jogada normal
+		player := newTestPlayer("Bob")
+		session := &GameSession{GameID: "g1", Mode: gameModeClassic, Player2: player}
+		s.resolveOrphanedGame(player, session)
+		if result := pendingResult(t, mr, "Bob"); !strings.HasPrefix(result, "RESULT|EMPATE") {
+			t.Errorf("resultado do P2 = %q, esperado empate (ele não jogou na rodada de morte súbita)", result)
+		}
+	})
+}
+
+func TestBufferedSuddenDeathReachesNextConnection(t *testing.T) {
+	s, mr := newTestServer(t)
+	player := newTestPlayer("Bob")
+	session := &GameSession{GameID: "g1", Mode: gameModeClassic, Player1: &PlayerState{Name: "Alice"}, Player2: player}
+	player.Games["g1"] = session
+	goblin := Card{Name: "Goblin", Forca: 2}
+	payload, _ := json.Marshal(GameSuddenDeath{
+		GameStart:  GameStart{GameID: "g1", Mode: gameModeClassic, Opponent: "Alice", Hand: []Card{goblin}},
+		Round:      1,
+		MaxRounds:  1,
+		DeadlineMs: time.Now().Add(time.Minute).UnixMilli(),
+	})
+	mr.RPush(playerInboxPrefix+"Bob", suddenDeathPrefix+string(payload))
+
+	s.deliverPendingMessages(player)
+	messages := sentMessages(player)
+	if len(messages) != 1 || !strings.HasPrefix(messages[0], suddenDeathPrefix) {
+		t.Errorf("a nova conexão deveria receber a rodada guardada, mensagens: %q", messages)
+	}
+	if session.SuddenDeathRound != 1 || len(session.Player2Hand) != 1 || session.Player2Hand[0] != goblin {
+		t.Errorf("a sessão local deveria passar para a rodada 1 com a nova carta: rodada %d, mão %v", session.SuddenDeathRound, session.Player2Hand)
+	}
+	if mr.Exists(playerInboxPrefix + "Bob") {
+		t.Errorf("depois de entregue, a rodada deveria sair da lista de pendentes")
+	}
+}
@@ -1,169 +1,316 @@
-package main
-
-import (
-	"context"
-	"encoding/json"
-	"fmt"
-	"log"
-	"strconv"
-	"strings"
-	"time"
-
-	"github.com/go-redis/redis/v8"
-)
-
-const (
-	tradeQueueKey = "trade_queue"
-	tradeLockKey  = "lock:trade"
-)
-
-type TradeTicket struct {
-	PlayerName string `json:"player_name"`
-	ServerID   string `json:"server_id"`
-	Card       Card   `json:"card"`
-}
-
-// handleTradeCard é chamado pelo websocket.go
-func (s *Server) handleTradeCard(player *PlayerState, command string) {
-	// 1. Validar o estado do jogador
-	player.mu.Lock()
-	if player.State == "InGame" || player.State == "Searching" {
-		player.mu.Unlock()
-		s.sendWebSocketMessage(player, "Você não pode trocar cartas enquanto estiver em jogo ou procurando partida.")
-		return
-	}
-	player.mu.Unlock()
-
-	// 2. Parsear o índice
-	indexStr := strings.TrimSpace(strings.TrimPrefix(command, "TRADE_CARD"))
-	if indexStr == "" {
-		s.sendWebSocketMessage(player, "Comando inválido. Use 'TRADE_CARD [numero]'.")
-		return
-	}
-
-	index, err := strconv.Atoi(indexStr)
-	if err != nil {
-		s.sendWebSocketMessage(player, "Número da carta inválido.")
-		return
-	}
-
-	if index < 1 || index > len(player.Deck) {
-		s.sendWebSocketMessage(player, "Número da carta fora do alcance do seu deck.")
-		return
-	}
-
-	cardIndex := index - 1
-
-	// 3. Remover a carta do deck do jogador (localmente)
-	cardToTrade := player.Deck[cardIndex]
-	player.Deck = append(player.Deck[:cardIndex], player.Deck[cardIndex+1:]...)
-
-	log.Printf("Jogador %s está tentando trocar a carta: %s", player.Name, cardToTrade.Name)
-
-	// 4. Executar a troca distribuída
-	s.performDistributedTrade(player, cardToTrade)
-}
-
-// performDistributedTrade usa TradeTicket e Pub/Sub para notificar o remetente.
-func (s *Server) performDistributedTrade(player *PlayerState, cardToTrade Card) {
-	ctx := context.Background()
-
-	// 1. Tenta adquirir um lock distribuído
-	lockValue := fmt.Sprintf("%s-%d", s.ServerID, time.Now().UnixNano())
-	lockTimeout := 3 * time.Second
-
-	ok, err := s.RedisClient.SetNX(ctx, tradeLockKey, lockValue, lockTimeout).Result()
-	if err != nil {
-		log.Printf("Erro ao tentar adquirir lock de troca: %v", err)
-		s.sendWebSocketMessage(player, "Erro interno no sistema de trocas. Tente novamente.")
-		player.Deck = append(player.Deck, cardToTrade) // Devolve a carta
-		return
-	}
-
-	if !ok {
-		s.sendWebSocketMessage(player, "O sistema de trocas está ocupado. Tente novamente em alguns segundos.")
-		player.Deck = append(player.Deck, cardToTrade) // Devolve a carta
-		return
-	}
-
-	// Garante a liberação do lock
-	defer func(val string) {
-		script := redis.NewScript(`
-			if redis.call("get", KEYS[1]) == ARGV[1] then
-				return redis.call("del", KEYS[1])
-			else
-				return 0
-			end
-		`)
-		script.Run(context.Background(), s.RedisClient, []string{tradeLockKey}, val)
-	}(lockValue)
-
-	// 2. Tenta pegar um ticket da fila (LPOP)
-	ticketJSONReceived, err := s.RedisClient.LPop(ctx, tradeQueueKey).Result()
-
-	// Cria o ticket do jogador ATUAL (ex: Jogador B)
-	ticketToSend := TradeTicket{
-		PlayerName: player.Name,
-		ServerID:   s.ServerID,
-		Card:       cardToTrade,
-	}
-
-	if err == redis.Nil {
-		// CASO 1: FILA VAZIA (JOGADOR A)
-		// Serializa e adiciona o ticket do jogador A à fila (RPUSH)
-		ticketJSONToSend, _ := json.Marshal(ticketToSend)
-		s.RedisClient.RPush(ctx, tradeQueueKey, ticketJSONToSend)
-
-		log.Printf("Fila de trocas vazia. %s adicionou %s.", player.Name, cardToTrade.Name)
-		s.sendWebSocketMessage(player, fmt.Sprintf("Sua carta '%s' foi adicionada à fila de trocas. Aguardando outro jogador...", cardToTrade.Name))
-		return
-	}
-
-	if err != nil {
-		// Erro real do Redis
-		log.Printf("Erro ao dar LPOP na fila de trocas: %v", err)
-		s.sendWebSocketMessage(player, "Erro interno ao acessar a fila de trocas. Tente novamente.")
-		player.Deck = append(player.Deck, cardToTrade) // Devolve a carta
-		return
-	}
-
-	// CASO 2: SUCESSO! (JOGADOR B)
-	// Um ticket (do Jogador A) foi recebido.
-
-	// Desserializa o ticket recebido (do Jogador A)
-	var receivedTicket TradeTicket
-	if err := json.Unmarshal([]byte(ticketJSONReceived), &receivedTicket); err != nil {
-		log.Printf("Erro crítico ao desserializar ticket da fila de trocas: %v", err)
-		s.sendWebSocketMessage(player, "Erro! O ticket na fila estava corrompido. Sua carta foi devolvida.")
-		player.Deck = append(player.Deck, cardToTrade) // Devolve a carta B
-
-		// Devolve o ticket corrompido à fila para não perdê-lo
-		s.RedisClient.LPush(ctx, tradeQueueKey, ticketJSONReceived)
-		return
-	}
-
-	receivedCard := receivedTicket.Card             // Carta do Jogador A
-	receivedPlayerName := receivedTicket.PlayerName // Nome do Jogador A
-
-	// 4. Adiciona a carta recebida (de A) ao deck do Jogador B (local)
-	player.Deck = append(player.Deck, receivedCard)
-
-	log.Printf("Troca local bem-sucedida para %s. Enviou %s, Recebeu %s.", player.Name, cardToTrade.Name, receivedCard.Name)
-	s.sendWebSocketMessage(player, fmt.Sprintf("Troca realizada! Você enviou '%s (Força: %d)' e recebeu '%s (Força: %d)'.", cardToTrade.Name, cardToTrade.Forca, receivedCard.Name, receivedCard.Forca))
-
-	// --- 5. Notificar Jogador A via Pub/Sub ---
-
-	// Prepara a mensagem para o Jogador A
-	// Envia a carta do Jogador B, 'cardToTrade', para o Jogador A
-	cardB_JSON, _ := json.Marshal(cardToTrade)
-	messageForA := fmt.Sprintf("TRADE_COMPLETE|%s", string(cardB_JSON))
-	channelForA := fmt.Sprintf("player:%s", receivedPlayerName)
-
-	// Publica a mensagem
-	if err := s.RedisClient.Publish(ctx, channelForA, messageForA).Err(); err != nil {
-		log.Printf("FALHA CRÍTICA AO PUBLICAR TROCA para %s: %v", receivedPlayerName, err)
-		// Lógica de compensação (ex: devolver a carta de A para a fila)
-	} else {
-		log.Printf("Notificação de troca enviada para %s (%s) via Pub/Sub.", receivedPlayerName, receivedCard.Name)
-	}
-}
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	tradeQueueKey = "trade_queue"
+	tradeLockKey  = "lock:trade"
+)
+
+// maxTradeBundleSize é o maior número de cartas que podem ser oferecidas numa única troca (TRADE_CARDS).
+const maxTradeBundleSize = 10
+
+// TradeTicket é a oferta de um jogador na fila de trocas: um pacote de uma ou mais cartas.
+// Pacotes só são pareados com pacotes do mesmo tamanho (ver tradeQueueKeyFor) e, se houver
+// pedido (Want), apenas com ofertas que o atendam (ver trade_want.go).
+type TradeTicket struct {
+	PlayerName string     `json:"player_name"`
+	ServerID   string     `json:"server_id"`
+	Cards      []Card     `json:"cards"`
+	Want       *TradeWant `json:"want,omitempty"`
+	QueuedAt   int64      `json:"queued_at,omitempty"` // Unix; usado para expirar os pedidos (TRADE_WANT_TTL)
+}
+
+// tradeQueueKeyFor retorna a fila de trocas para pacotes de n cartas.
+// A troca de uma carta (TRADE_CARD) continua usando a fila original.
+func tradeQueueKeyFor(n int) string {
+	if n <= 1 {
+		return tradeQueueKey
+	}
+	return fmt.Sprintf("%s:%d", tradeQueueKey, n)
+}
+
+// handleTradeCard é chamado pelo websocket.go para "TRADE_CARD <n>" e "TRADE_CARDS <n1,n2,...>",
+// com o pedido opcional "WANT <nome>" ou "WANT <Força mínima>" no fim.
+func (s *Server) handleTradeCard(player *PlayerState, command string) {
+	command, rawWant, hasWant := splitTradeWant(command)
+	var want *TradeWant
+	if hasWant {
+		var errText LocalizedText
+		if want, errText = s.parseTradeWant(rawWant); errText.ID != "" {
+			s.sendWebSocketMessage(player, errText.render(player.currentLocale()))
+			return
+		}
+	}
+
+	// Só uma troca em aberto por jogador (ver trade_pending.go)
+	if open, err := s.hasOpenTrade(player.Name); err != nil {
+		slog.Error("Erro ao consultar troca em aberto", "player", player.Name, "error", err)
+		s.sendLocalized(player, msgTradeError)
+		return
+	} else if open {
+		slog.Info("Troca recusada: jogador já tem uma troca em aberto", "event", "trade_rejected_pending", "player", player.Name)
+		tradesTotal.WithLabelValues("pending").Inc()
+		s.sendWebSocketMessage(player, tradePendingReplyPrefix+localized(msgTradePending).render(player.currentLocale()))
+		return
+	}
+
+	// 1. Validar o estado do jogador. O deck fica travado da validação dos índices até a
+	// remoção das cartas, para que uma troca recebida no meio não mude as posições (ver deck.go).
+	player.mu.Lock()
+	if player.State == "InGame" || player.State == "Searching" {
+		player.mu.Unlock()
+		s.sendLocalized(player, msgTradeNotAllowed)
+		return
+	}
+
+	// 2. Parsear e validar TODOS os índices antes de mexer no deck
+	indices, errText := parseTradeIndices(command, len(player.Deck))
+	if errText.ID != "" {
+		player.mu.Unlock()
+		s.sendWebSocketMessage(player, errText.render(player.currentLocale()))
+		return
+	}
+
+	// Não deixa o deck ficar abaixo do mínimo necessário para jogar
+	if len(player.Deck)-len(indices) < s.Config.MinDeckSize {
+		player.mu.Unlock()
+		s.sendLocalized(player, msgTradeMinDeck, s.Config.MinDeckSize)
+		return
+	}
+
+	// 3. Remover as cartas do deck do jogador (localmente)
+	selected := make(map[int]bool, len(indices))
+	for _, index := range indices {
+		selected[index-1] = true
+	}
+	var cardsToTrade []Card
+	for _, index := range indices {
+		cardsToTrade = append(cardsToTrade, player.Deck[index-1])
+	}
+	remaining := make([]Card, 0, len(player.Deck)-len(indices))
+	for i, card := range player.Deck {
+		if !selected[i] {
+			remaining = append(remaining, card)
+		}
+	}
+	player.Deck = remaining
+	player.mu.Unlock()
+	s.saveDeck(player)
+
+	slog.Info("Jogador está tentando trocar cartas", "event", "trade_requested", "player", player.Name,
+		"cards", cardNames(cardsToTrade), "bundle_size", len(cardsToTrade), "want", want)
+	s.audit(player.Name, auditTrade, "", fmt.Sprintf("ofereceu %s; pedido: %s", strings.Join(cardNames(cardsToTrade), ", "), want))
+
+	// 4. Executar a troca distribuída
+	s.performDistributedTrade(player, cardsToTrade, want)
+}
+
+// parseTradeIndices lê os números das cartas de "TRADE_CARD <n>" ou "TRADE_CARDS <n1,n2,...>"
+// e os valida contra o tamanho do deck. Retorna os índices (a partir de 1) ou o motivo da recusa
+// (errText.ID != "").
+func parseTradeIndices(command string, deckSize int) (indices []int, errText LocalizedText) {
+	var rawIndices []string
+	if strings.HasPrefix(command, "TRADE_CARDS") {
+		list := strings.TrimSpace(strings.TrimPrefix(command, "TRADE_CARDS"))
+		if list == "" {
+			return nil, localized(msgTradeBundleUsage)
+		}
+		rawIndices = strings.Split(list, ",")
+		if len(rawIndices) > maxTradeBundleSize {
+			return nil, localized(msgTradeBundleTooLarge, maxTradeBundleSize)
+		}
+	} else {
+		indexStr := strings.TrimSpace(strings.TrimPrefix(command, "TRADE_CARD"))
+		if indexStr == "" {
+			return nil, localized(msgTradeCardUsage)
+		}
+		rawIndices = []string{indexStr}
+	}
+
+	seen := make(map[int]bool)
+	indices = make([]int, 0, len(rawIndices))
+	for _, raw := range rawIndices {
+		index, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, localized(msgTradeInvalidIndex)
+		}
+		if index < 1 || index > deckSize {
+			return nil, localized(msgTradeIndexOutOfRange)
+		}
+		if seen[index] {
+			return nil, localized(msgTradeDuplicateIndex, index)
+		}
+		seen[index] = true
+		indices = append(indices, index)
+	}
+	return indices, LocalizedText{}
+}
+
+// describeCards formata as cartas para as mensagens de troca (ex: "'Grifo (Força: 3)', 'Ghoul (Força: 1)'").
+func describeCards(cards []Card) string {
+	parts := make([]string, 0, len(cards))
+	for _, c := range cards {
+		parts = append(parts, fmt.Sprintf("'%s (Força: %d)'", c.Name, c.Forca))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// cardNames retorna os nomes das cartas (para os logs).
+func cardNames(cards []Card) []string {
+	names := make([]string, 0, len(cards))
+	for _, c := range cards {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// performDistributedTrade usa TradeTicket e Pub/Sub para notificar o remetente.
+// O pacote só é pareado com outro do mesmo tamanho, e as cartas são trocadas em bloco.
+// Com pedido (want), só troca com um ticket que o atenda e cujo pedido as cartas atendam.
+func (s *Server) performDistributedTrade(player *PlayerState, cardsToTrade []Card, want *TradeWant) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	queueKey := tradeQueueKeyFor(len(cardsToTrade))
+
+	// 1. Tenta adquirir um lock distribuído
+	lockValue := newRandomID()
+	lockTimeout := s.Config.TradeLockTTL
+
+	ok, err := s.RedisClient.SetNX(ctx, tradeLockKey, lockValue, lockTimeout).Result()
+	if err != nil {
+		slog.Error("Erro ao tentar adquirir lock de troca", "player", player.Name, "error", err)
+		tradesTotal.WithLabelValues("error").Inc()
+		s.sendLocalized(player, msgTradeError)
+		s.giveCards(player, cardsToTrade...) // Devolve as cartas
+		return
+	}
+
+	if !ok {
+		tradesTotal.WithLabelValues("busy").Inc()
+		s.sendLocalized(player, msgTradeBusy)
+		s.giveCards(player, cardsToTrade...) // Devolve as cartas
+		return
+	}
+
+	// Garante a liberação do lock
+	defer func(val string) {
+		// Contexto próprio: o lock é liberado mesmo que a troca tenha esgotado o seu
+		ctx, cancel := s.redisCtx()
+		defer cancel()
+		s.releaseLock(ctx, tradeLockKey, val)
+	}(lockValue)
+
+	// Cria o ticket do jogador ATUAL (ex: Jogador B)
+	ticketToSend := TradeTicket{
+		PlayerName: player.Name,
+		ServerID:   s.ServerID,
+		Cards:      cardsToTrade,
+		Want:       want,
+		QueuedAt:   time.Now().Unix(),
+	}
+
+	// 2. Tenta pegar um ticket compatível da fila, registrando a troca pendente na mesma operação
+	tradeID := newRandomID()
+	ticketJSONReceived, err := s.claimTradeTicket(queueKey, tradeID, ticketToSend)
+
+	if err == redis.Nil {
+		// CASO 1: FILA VAZIA OU SEM TICKET COMPATÍVEL (JOGADOR A)
+		// Serializa e adiciona o ticket do jogador A à fila (RPUSH)
+		ticketJSONToSend, _ := json.Marshal(ticketToSend)
+		s.RedisClient.RPush(ctx, queueKey, ticketJSONToSend)
+		s.markOpenTrade(player.Name, queueKey)
+
+		slog.Info("Nenhum ticket compatível na fila de trocas. Ticket adicionado.", "event", "trade_queued", "player", player.Name,
+			"cards", cardNames(cardsToTrade), "queue", queueKey, "want", want)
+		tradesTotal.WithLabelValues("queued").Inc()
+		if len(cardsToTrade) == 1 {
+			s.sendLocalized(player, msgTradeQueuedCard, cardsToTrade[0].Name)
+		} else {
+			s.sendLocalized(player, msgTradeQueuedBundle, len(cardsToTrade), describeCards(cardsToTrade))
+		}
+		if want != nil {
+			s.sendLocalized(player, msgTradeWantNotice, want.text().render(player.currentLocale()), s.Config.TradeWantTTL)
+		}
+		return
+	}
+
+	if err != nil {
+		// Erro real do Redis
+		slog.Error("Erro ao dar LPOP na fila de trocas", "player", player.Name, "trade_id", tradeID, "error", err)
+		tradesTotal.WithLabelValues("error").Inc()
+		s.sendLocalized(player, msgTradeQueueError)
+		s.giveCards(player, cardsToTrade...) // Devolve as cartas
+		return
+	}
+
+	// CASO 2: SUCESSO! (JOGADOR B)
+	// Um ticket (do Jogador A) foi recebido.
+
+	// Desserializa o ticket recebido (do Jogador A)
+	var receivedTicket TradeTicket
+	if err := json.Unmarshal([]byte(ticketJSONReceived), &receivedTicket); err != nil {
+		slog.Error("Erro crítico ao desserializar ticket da fila de trocas", "player", player.Name, "error", err)
+		tradesTotal.WithLabelValues("error").Inc()
+		s.sendLocalized(player, msgTradeCorruptedTicket)
+		s.giveCards(player, cardsToTrade...) // Devolve as cartas de B
+
+		// O ticket corrompido NÃO volta à fila (seria retirado de novo a cada troca): vai para o
+		// dead letter, e a troca pendente é descartada
+		s.deadLetterTicket(ctx, queueKey, ticketJSONReceived, err)
+		if err := s.discardPendingTrade(tradeID); err != nil {
+			slog.Error("Erro ao descartar troca pendente", "trade_id", tradeID, "error", err)
+		}
+		return
+	}
+
+	// Autotroca (o próprio ticket, ex: de uma sessão anterior com o mesmo nome): a busca já os
+	// ignora (findCompatibleTradeTicket), mas se acontecer, o ticket volta ao início da fila e as
+	// cartas voltam ao deck, sem troca
+	if receivedTicket.PlayerName == player.Name {
+		slog.Warn("Autotroca detectada; troca desfeita", "event", "trade_self_match", "player", player.Name, "trade_id", tradeID)
+		tradesTotal.WithLabelValues("self").Inc()
+		if err := s.rollbackPendingTrade(queueKey, tradeID); err != nil {
+			slog.Error("Erro ao desfazer autotroca", "trade_id", tradeID, "error", err)
+		}
+		s.giveCards(player, cardsToTrade...) // Devolve as cartas
+		s.sendLocalized(player, msgTradeSelfMatch)
+		return
+	}
+
+	receivedCards := receivedTicket.Cards           // Cartas do Jogador A
+	receivedPlayerName := receivedTicket.PlayerName // Nome do Jogador A
+
+	// 4. Adiciona as cartas recebidas (de A) ao deck do Jogador B (local)
+	s.giveCards(player, receivedCards...)
+	if _, err := s.markTradeCredited(tradeID, tradeFieldCreditB); err != nil {
+		slog.Error("Erro ao marcar troca como creditada (B)", "trade_id", tradeID, "player", player.Name, "error", err)
+	}
+
+	tradesTotal.WithLabelValues("completed").Inc()
+	s.audit(player.Name, auditTrade, "", "recebeu "+strings.Join(cardNames(receivedCards), ", "))
+	slog.Info("Troca local bem-sucedida", "event", "trade_completed", "player", player.Name,
+		"cards_sent", cardNames(cardsToTrade), "cards_received", cardNames(receivedCards), "counterpart", receivedPlayerName)
+	s.sendLocalized(player, msgTradeCompleted, describeCards(cardsToTrade), describeCards(receivedCards))
+
+	// --- 5. Notificar Jogador A via Pub/Sub ---
+
+	// Envia as cartas do Jogador B, 'cardsToTrade', para o Jogador A.
+	// O registro pendente só é apagado quando o servidor de A confirmar o crédito;
+	// se este servidor cair antes, recoverPendingTrades reenvia a notificação no startup.
+	if err := s.publishTradeComplete(tradeID, receivedPlayerName, cardsToTrade); err != nil {
+		slog.Error("FALHA CRÍTICA AO PUBLICAR TROCA", "player", receivedPlayerName, "error", err)
+		// Lógica de compensação (ex: devolver a carta de A para a fila)
+	} else {
+		slog.Info("Notificação de troca enviada via Pub/Sub.", "player", receivedPlayerName, "cards", cardNames(cardsToTrade))
+	}
+}
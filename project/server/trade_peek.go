@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// A consulta da fila de trocas (TRADE_QUEUE_PEEK e GET /api/v1/trades/queue) mostra as ofertas
+// que estão esperando, sem os donos, para o jogador decidir se vale a pena trocar agora.
+// A leitura é só um LRANGE por fila (um por tamanho de pacote), num único pipeline: não usa o
+// lock:trade nem altera as filas, então não atrasa as trocas. Cada fila é lida de uma vez; entre
+// filas diferentes, a visão pode estar defasada por uma troca concorrente.
+
+// tradePeekLimit é o número máximo de ofertas mostradas de cada fila.
+const tradePeekLimit = 20
+
+// TradeQueueOffer é uma oferta da fila de trocas, sem o jogador nem o servidor de origem.
+type TradeQueueOffer struct {
+	Cards          []Card     `json:"cards"`
+	Want           *TradeWant `json:"want,omitempty"`            // O que o dono aceita em troca (nil = qualquer carta)
+	WaitingSeconds int64      `json:"waiting_seconds,omitempty"` // Há quanto tempo a oferta está na fila
+}
+
+// TradeQueueView é a visão de uma fila de trocas (pacotes de BundleSize cartas).
+type TradeQueueView struct {
+	BundleSize int               `json:"bundle_size"`
+	Total      int64             `json:"total"` // Ofertas na fila; só as primeiras tradePeekLimit vêm em Offers
+	Offers     []TradeQueueOffer `json:"offers"`
+}
+
+// TradeQueueResponse é a resposta de GET /api/v1/trades/queue.
+type TradeQueueResponse struct {
+	Queues []TradeQueueView `json:"queues"`
+}
+
+// peekTradeQueues lê, sem travar, as ofertas das filas de trocas que não estão vazias,
+// na ordem em que serão pareadas.
+func (s *Server) peekTradeQueues() ([]TradeQueueView, error) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+
+	lengths := make([]*redis.IntCmd, maxTradeBundleSize)
+	ranges := make([]*redis.StringSliceCmd, maxTradeBundleSize)
+	_, err := s.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for n := 1; n <= maxTradeBundleSize; n++ {
+			key := tradeQueueKeyFor(n)
+			lengths[n-1] = pipe.LLen(ctx, key)
+			ranges[n-1] = pipe.LRange(ctx, key, 0, tradePeekLimit-1)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	views := []TradeQueueView{}
+	for i := range ranges {
+		if lengths[i].Val() == 0 {
+			continue
+		}
+		view := TradeQueueView{BundleSize: i + 1, Total: lengths[i].Val(), Offers: []TradeQueueOffer{}}
+		for _, raw := range ranges[i].Val() {
+			var ticket TradeTicket
+			if json.Unmarshal([]byte(raw), &ticket) != nil {
+				continue // Ticket corrompido: sai da fila (dead letter) na próxima troca
+			}
+			offer := TradeQueueOffer{Cards: ticket.Cards, Want: ticket.Want}
+			if ticket.QueuedAt > 0 {
+				offer.WaitingSeconds = now - ticket.QueuedAt
+			}
+			view.Offers = append(view.Offers, offer)
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// handleTradeQueuePeek processa o comando "TRADE_QUEUE_PEEK": lista as ofertas da fila de trocas.
+func (s *Server) handleTradeQueuePeek(player *PlayerState) {
+	views, err := s.peekTradeQueues()
+	if err != nil {
+		slog.Error("Erro ao ler a fila de trocas", "player", player.Name, "error", err)
+		s.sendWebSocketMessage(player, "Erro interno ao consultar a fila de trocas. Tente novamente.")
+		return
+	}
+
+	response := "Fila de trocas:"
+	if len(views) == 0 {
+		response += " nenhuma oferta esperando."
+	}
+	for _, view := range views {
+		response += fmt.Sprintf("\nPacotes de %d carta(s) - %d oferta(s):", view.BundleSize, view.Total)
+		for _, offer := range view.Offers {
+			response += fmt.Sprintf("\n- %s (pede %s)", describeCards(offer.Cards), offer.Want)
+		}
+		if hidden := view.Total - int64(len(view.Offers)); hidden > 0 {
+			response += fmt.Sprintf("\n  ... e mais %d oferta(s).", hidden)
+		}
+	}
+	s.sendWebSocketMessage(player, response)
+}
+
+// handleGetTradeQueue implementa o endpoint REST GET /api/v1/trades/queue.
+func (s *Server) handleGetTradeQueue(w http.ResponseWriter, r *http.Request) {
+	views, err := s.peekTradeQueues()
+	if err != nil {
+		slog.Error("Erro ao ler a fila de trocas via REST", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "Erro interno ao consultar a fila de trocas")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(TradeQueueResponse{Queues: views})
+}
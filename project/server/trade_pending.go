@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log/slog"
+)
+
+// Cada jogador tem no máximo uma troca em aberto na fila: enquanto o ticket dele espera por
+// outro jogador, um novo TRADE_CARD/TRADE_CARDS é recusado com "TRADE_PENDING|<texto>", em vez
+// de empilhar mais cartas na fila sem que ele possa acompanhá-las.
+//
+// A troca em aberto é marcada em trade:open:<nome> quando o ticket entra na fila e desmarcada
+// quando o jogador recebe as cartas da troca (TRADE_COMPLETE) ou quando o pedido expira
+// (TRADE_WANT_TTL). A marca tem TTL (TRADE_PENDING_TTL), para que uma troca perdida (servidor
+// caído, notificação não entregue) não bloqueie o jogador para sempre. O prefixo trade:pending:
+// já é o dos registros das trocas em andamento (ver trade_swap.go).
+
+// tradeOpenPrefix marca o jogador que tem um ticket esperando na fila de trocas.
+const tradeOpenPrefix = "trade:open:"
+
+// tradePendingReplyPrefix é a resposta ao TRADE_CARD de quem já tem uma troca em aberto.
+const tradePendingReplyPrefix = "TRADE_PENDING|"
+
+// hasOpenTrade informa se o jogador já tem um ticket esperando na fila de trocas.
+func (s *Server) hasOpenTrade(playerName string) (bool, error) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	n, err := s.RedisClient.Exists(ctx, tradeOpenPrefix+playerName).Result()
+	return n > 0, err
+}
+
+// markOpenTrade registra que o ticket do jogador entrou na fila de trocas.
+func (s *Server) markOpenTrade(playerName, queueKey string) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	if err := s.RedisClient.Set(ctx, tradeOpenPrefix+playerName, queueKey, s.Config.TradePendingTTL).Err(); err != nil {
+		slog.Error("Erro ao registrar troca em aberto", "player", playerName, "error", err)
+	}
+}
+
+// clearOpenTrade desmarca a troca em aberto do jogador (concluída ou expirada).
+func (s *Server) clearOpenTrade(playerName string) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	if err := s.RedisClient.Del(ctx, tradeOpenPrefix+playerName).Err(); err != nil {
+		slog.Error("Erro ao desmarcar troca em aberto", "player", playerName, "error", err)
+	}
+}
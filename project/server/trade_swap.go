@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// tradePendingPrefix guarda cada troca em andamento (hash) até os dois lados serem creditados.
+	tradePendingPrefix = "trade:pending:"
+	// tradePendingSetKey indexa os IDs das trocas pendentes, para a recuperação no startup.
+	tradePendingSetKey = "trades:pending"
+)
+
+// Campos do hash trade:pending:<tradeID>.
+const (
+	tradeFieldTicketA = "ticket_a"   // Ticket de quem estava na fila (Jogador A)
+	tradeFieldTicketB = "ticket_b"   // Ticket de quem retirou da fila (Jogador B)
+	tradeFieldServerB = "server_b"   // Servidor do Jogador B (responsável por concluir a troca)
+	tradeFieldQueue   = "queue"      // Fila de onde o ticket de A saiu (depende do tamanho do pacote)
+	tradeFieldCreditA = "a_credited" // Jogador A já recebeu a carta de B
+	tradeFieldCreditB = "b_credited" // Jogador B já recebeu a carta de A
+)
+
+// SCRIPT LUA
+// Retira da fila o ticket escolhido (findCompatibleTradeTicket) e, na MESMA operação, grava o
+// registro da troca pendente. Assim a carta de A nunca fica "no ar" entre a retirada e a notificação.
+//
+// KEYS[1] = a fila de trocas do tamanho do pacote (tradeQueueKeyFor)
+// KEYS[2] = o registro da troca (trade:pending:<tradeID>)
+// KEYS[3] = o índice de trocas pendentes (tradePendingSetKey)
+// ARGV[1] = o ID da troca
+// ARGV[2] = o ticket do Jogador B (JSON)
+// ARGV[3] = o ID do servidor do Jogador B
+// ARGV[4] = o ticket do Jogador A a retirar (JSON, como está na fila)
+var atomicClaimTradeScript = redis.NewScript(`
+    local ticket = ARGV[4]
+    if redis.call('LREM', KEYS[1], 1, ticket) == 0 then
+        return false
+    end
+    redis.call('HSET', KEYS[2], 'ticket_a', ticket, 'ticket_b', ARGV[2], 'server_b', ARGV[3], 'queue', KEYS[1])
+    redis.call('SADD', KEYS[3], ARGV[1])
+    return ticket
+`)
+
+// SCRIPT LUA
+// Marca um lado da troca como creditado. Quando os dois lados estiverem creditados,
+// o registro é apagado. Retorna 1 se este lado foi marcado agora, ou 0 se já estava
+// (ou se a troca já foi concluída), para que a carta nunca seja creditada duas vezes.
+//
+// KEYS[1] = o registro da troca (trade:pending:<tradeID>)
+// KEYS[2] = o índice de trocas pendentes (tradePendingSetKey)
+// ARGV[1] = o ID da troca
+// ARGV[2] = o campo a marcar ("a_credited" ou "b_credited")
+var markTradeCreditedScript = redis.NewScript(`
+    if redis.call('EXISTS', KEYS[1]) == 0 then
+        return 0
+    end
+    local newly = redis.call('HSETNX', KEYS[1], ARGV[2], '1')
+    if redis.call('HEXISTS', KEYS[1], 'a_credited') == 1 and redis.call('HEXISTS', KEYS[1], 'b_credited') == 1 then
+        redis.call('DEL', KEYS[1])
+        redis.call('SREM', KEYS[2], ARGV[1])
+    end
+    return newly
+`)
+
+// SCRIPT LUA
+// Desfaz uma troca em que o Jogador B ainda não foi creditado: devolve o ticket de A
+// ao INÍCIO da fila (ele continua sendo o próximo) e apaga o registro.
+// Retorna 0 (sem alterações) se B já tiver sido creditado.
+//
+// KEYS[1] = a fila de trocas de onde o ticket de A saiu
+// KEYS[2] = o registro da troca (trade:pending:<tradeID>)
+// KEYS[3] = o índice de trocas pendentes (tradePendingSetKey)
+// ARGV[1] = o ID da troca
+var rollbackTradeScript = redis.NewScript(`
+    if redis.call('HEXISTS', KEYS[2], 'b_credited') == 1 then
+        return 0
+    end
+    local ticket = redis.call('HGET', KEYS[2], 'ticket_a')
+    if ticket then
+        redis.call('LPUSH', KEYS[1], ticket)
+    end
+    redis.call('DEL', KEYS[2])
+    redis.call('SREM', KEYS[3], ARGV[1])
+    return 1
+`)
+
+// claimTradeTicket retira da fila o primeiro ticket de A compatível com o de B (ver trade_want.go)
+// e registra a troca pendente atomicamente. Retorna redis.Nil se nenhum ticket servir.
+func (s *Server) claimTradeTicket(queueKey, tradeID string, ticketB TradeTicket) (string, error) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	ticketA, err := s.findCompatibleTradeTicket(ctx, queueKey, ticketB)
+	if err != nil {
+		return "", err
+	}
+	ticketBJSON, _ := json.Marshal(ticketB)
+	keys := []string{queueKey, tradePendingPrefix + tradeID, tradePendingSetKey}
+	return atomicClaimTradeScript.Run(ctx, s.RedisClient, keys, tradeID, string(ticketBJSON), s.ServerID, ticketA).Text()
+}
+
+// markTradeCredited marca um lado da troca como creditado. Retorna true se deve creditar agora.
+func (s *Server) markTradeCredited(tradeID, field string) (bool, error) {
+	keys := []string{tradePendingPrefix + tradeID, tradePendingSetKey}
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	newly, err := markTradeCreditedScript.Run(ctx, s.RedisClient, keys, tradeID, field).Int()
+	return newly == 1, err
+}
+
+// rollbackPendingTrade devolve o ticket de A à fila e descarta a troca (se B não foi creditado).
+func (s *Server) rollbackPendingTrade(queueKey, tradeID string) error {
+	keys := []string{queueKey, tradePendingPrefix + tradeID, tradePendingSetKey}
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	return rollbackTradeScript.Run(ctx, s.RedisClient, keys, tradeID).Err()
+}
+
+// discardPendingTrade apaga o registro de uma troca cujo ticket de A estava corrompido,
+// sem devolver o ticket à fila (ver deadLetterTicket).
+func (s *Server) discardPendingTrade(tradeID string) error {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	pipe := s.RedisClient.TxPipeline()
+	pipe.Del(ctx, tradePendingPrefix+tradeID)
+	pipe.SRem(ctx, tradePendingSetKey, tradeID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// publishTradeComplete envia ao Jogador A (via Pub/Sub) as cartas recebidas de B.
+// Se A estiver desconectado, a notificação espera a próxima conexão dele (ver player_inbox.go).
+func (s *Server) publishTradeComplete(tradeID, playerAName string, cardsB []Card) error {
+	cardsJSON, _ := json.Marshal(cardsB)
+	message := fmt.Sprintf("TRADE_COMPLETE|%s|%s", tradeID, string(cardsJSON))
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	return s.publishToPlayer(ctx, playerAName, message)
+}
+
+// recoverPendingTrades conclui ou desfaz, no startup, as trocas que ESTE servidor (o do Jogador B)
+// deixou pela metade ao cair:
+//   - B ainda não creditado: a troca é desfeita e o ticket de A volta ao início da fila;
+//   - B creditado, A não: a notificação para A é reenviada (o crédito de A é idempotente).
+//
+// Os decks ficam apenas em memória, então o deck de B já se perdeu com a queda; o que se
+// garante aqui é que a carta de A não desaparece da fila sem chegar a ninguém.
+func (s *Server) recoverPendingTrades() {
+	ctx, cancel := s.redisCtxTimeout(reconcileTimeout)
+	defer cancel()
+	ids, err := s.RedisClient.SMembers(ctx, tradePendingSetKey).Result()
+	if err != nil {
+		slog.Error("Erro ao listar trocas pendentes", "error", err)
+		return
+	}
+
+	for _, tradeID := range ids {
+		record, err := s.RedisClient.HGetAll(ctx, tradePendingPrefix+tradeID).Result()
+		if err != nil {
+			slog.Error("Erro ao ler troca pendente", "trade_id", tradeID, "error", err)
+			continue
+		}
+		if len(record) == 0 {
+			s.RedisClient.SRem(ctx, tradePendingSetKey, tradeID)
+			continue
+		}
+		if record[tradeFieldServerB] != s.ServerID {
+			continue
+		}
+
+		if record[tradeFieldCreditB] == "" {
+			queueKey := record[tradeFieldQueue]
+			if queueKey == "" {
+				queueKey = tradeQueueKey // Registros anteriores às trocas em pacote
+			}
+			if err := s.rollbackPendingTrade(queueKey, tradeID); err != nil {
+				slog.Error("Erro ao desfazer troca pendente", "trade_id", tradeID, "error", err)
+				continue
+			}
+			slog.Warn("Troca interrompida desfeita; ticket devolvido à fila.", "event", "trade_rolled_back", "trade_id", tradeID)
+			continue
+		}
+
+		var ticketA, ticketB TradeTicket
+		if json.Unmarshal([]byte(record[tradeFieldTicketA]), &ticketA) != nil || json.Unmarshal([]byte(record[tradeFieldTicketB]), &ticketB) != nil {
+			slog.Error("Registro de troca pendente corrompido", "trade_id", tradeID)
+			continue
+		}
+		if err := s.publishTradeComplete(tradeID, ticketA.PlayerName, ticketB.Cards); err != nil {
+			slog.Error("Erro ao reenviar notificação de troca", "trade_id", tradeID, "player", ticketA.PlayerName, "error", err)
+			continue
+		}
+		slog.Warn("Troca interrompida concluída; notificação reenviada.", "event", "trade_replayed", "trade_id", tradeID, "player", ticketA.PlayerName)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// newTradingPlayer cria um jogador no menu com cartas de sobra para trocar sem passar do MIN_DECK_SIZE.
+func newTradingPlayer(s *Server, name string) *PlayerState {
+	player := newTestPlayer(name)
+	for i := 0; i < s.Config.MinDeckSize+3; i++ {
+		player.Deck = append(player.Deck, Card{Name: fmt.Sprintf("%s-%d", name, i), Forca: 1})
+	}
+	return player
+}
+
+// queuedTradePlayers retorna os donos dos tickets na fila de trocas de uma carta, na ordem.
+func queuedTradePlayers(t *testing.T, s *Server) []string {
+	t.Helper()
+	raw, err := s.RedisClient.LRange(s.ctx, tradeQueueKey, 0, -1).Result()
+	if err != nil {
+		t.Fatalf("LRange: %v", err)
+	}
+	var names []string
+	for _, r := range raw {
+		var ticket TradeTicket
+		json.Unmarshal([]byte(r), &ticket)
+		names = append(names, ticket.PlayerName)
+	}
+	return names
+}
+
+func TestRepeatedTradeRejectedWhilePending(t *testing.T) {
+	s, mr := newTestServer(t)
+	alice := newTradingPlayer(s, "Alice")
+
+	s.handleTradeCard(alice, "TRADE_CARD 1")
+	sentMessages(alice)
+	if !mr.Exists(tradeOpenPrefix + "Alice") {
+		t.Fatalf("a troca em aberto deveria ficar marcada em %sAlice", tradeOpenPrefix)
+	}
+	deckSize := len(alice.deckSnapshot())
+
+	// Segunda tentativa com o ticket ainda na fila: recusada, sem mexer no deck nem na fila
+	s.handleTradeCard(alice, "TRADE_CARD 1")
+	messages := sentMessages(alice)
+	if len(messages) != 1 || !strings.HasPrefix(messages[0], tradePendingReplyPrefix) {
+		t.Errorf("a segunda troca deveria ser recusada com %q, mensagens: %q", tradePendingReplyPrefix, messages)
+	}
+	if got := len(alice.deckSnapshot()); got != deckSize {
+		t.Errorf("o deck mudou com a troca recusada: %d cartas, esperado %d", got, deckSize)
+	}
+	if queued := queuedTradePlayers(t, s); len(queued) != 1 {
+		t.Errorf("a fila deveria ter só o primeiro ticket, fila: %v", queued)
+	}
+
+	// Bob retira o ticket da fila: a notificação (guardada para Alice, que não está ouvindo)
+	// desmarca a troca, e Alice pode trocar de novo
+	s.handleTradeCard(newTradingPlayer(s, "Bob"), "TRADE_CARD 1")
+	pending, _ := mr.List(playerInboxPrefix + "Alice")
+	if len(pending) != 1 || !strings.HasPrefix(pending[0], "TRADE_COMPLETE|") {
+		t.Fatalf("Alice deveria receber o TRADE_COMPLETE, pendentes: %q", pending)
+	}
+	s.routePlayerMessage(alice, pending[0])
+	if mr.Exists(tradeOpenPrefix + "Alice") {
+		t.Errorf("a troca concluída deveria desmarcar %sAlice", tradeOpenPrefix)
+	}
+	sentMessages(alice)
+	s.handleTradeCard(alice, "TRADE_CARD 1")
+	if queued := queuedTradePlayers(t, s); len(queued) != 1 || queued[0] != "Alice" {
+		t.Errorf("depois da troca concluída, Alice deveria poder trocar de novo, fila: %v", queued)
+	}
+}
+
+func TestOpenTradeMarkExpires(t *testing.T) {
+	s, mr := newTestServer(t)
+	alice := newTradingPlayer(s, "Alice")
+	s.handleTradeCard(alice, "TRADE_CARD 1")
+
+	// Uma troca perdida (ex: notificação não entregue) não bloqueia o jogador depois do TRADE_PENDING_TTL
+	mr.FastForward(s.Config.TradePendingTTL)
+	sentMessages(alice)
+	s.handleTradeCard(alice, "TRADE_CARD 1")
+	for _, message := range sentMessages(alice) {
+		if strings.HasPrefix(message, tradePendingReplyPrefix) {
+			t.Errorf("depois do TRADE_PENDING_TTL a troca não deveria ser recusada: %q", message)
+		}
+	}
+}
+
+// queueTradeTicket coloca na fila de trocas de uma carta o ticket de outra sessão do jogador.
+func queueTradeTicket(t *testing.T, s *Server, name string, card Card) string {
+	t.Helper()
+	ticketJSON, _ := json.Marshal(TradeTicket{PlayerName: name, ServerID: "Server-Other", Cards: []Card{card}})
+	if err := s.RedisClient.RPush(s.ctx, tradeQueueKey, string(ticketJSON)).Err(); err != nil {
+		t.Fatalf("RPush: %v", err)
+	}
+	return string(ticketJSON)
+}
+
+func TestSelfTradeSkipsOwnTicket(t *testing.T) {
+	s, mr := newTestServer(t)
+	// Alice tem um ticket na fila, de uma sessão anterior com o mesmo nome (em outro servidor)
+	queueTradeTicket(t, s, "Alice", Card{Name: "Ghoul", Forca: 1})
+	alice := newTradingPlayer(s, "Alice")
+	offered := Card{Name: "Grifo", Forca: 3}
+
+	s.performDistributedTrade(alice, []Card{offered}, nil)
+
+	// Sem outro jogador na fila, a troca não acontece: o ticket novo espera atrás do antigo
+	if queued := queuedTradePlayers(t, s); len(queued) != 2 || queued[0] != "Alice" || queued[1] != "Alice" {
+		t.Errorf("os dois tickets de Alice deveriam continuar na fila, fila: %v", queued)
+	}
+	for _, card := range alice.deckSnapshot() {
+		if card.Name == "Ghoul" {
+			t.Errorf("Alice não pode receber a própria carta de volta por uma troca")
+		}
+	}
+	if pending, _ := mr.List(playerInboxPrefix + "Alice"); len(pending) != 0 {
+		t.Errorf("nenhuma troca deveria ser notificada, pendentes: %q", pending)
+	}
+	if mr.Exists(tradePendingSetKey) {
+		t.Errorf("nenhuma troca pendente deveria ser registrada")
+	}
+}
+
+func TestSelfTradeMatchesNextPlayer(t *testing.T) {
+	s, _ := newTestServer(t)
+	queueTradeTicket(t, s, "Alice", Card{Name: "Ghoul", Forca: 1})
+	queueTradeTicket(t, s, "Bob", Card{Name: "Dragão", Forca: 10})
+	alice := newTradingPlayer(s, "Alice")
+
+	s.performDistributedTrade(alice, []Card{{Name: "Grifo", Forca: 3}}, nil)
+
+	// O próprio ticket é pulado: a troca é com o de Bob, e o de Alice continua na fila
+	if queued := queuedTradePlayers(t, s); len(queued) != 1 || queued[0] != "Alice" {
+		t.Errorf("só o ticket antigo de Alice deveria continuar na fila, fila: %v", queued)
+	}
+	received := false
+	for _, card := range alice.deckSnapshot() {
+		received = received || card.Name == "Dragão"
+	}
+	if !received {
+		t.Errorf("Alice deveria receber a carta de Bob")
+	}
+}
+
+// Se o ticket retirado for do próprio jogador (corrida com outra sessão), a troca é desfeita:
+// o ticket volta ao início da fila e o registro pendente é apagado.
+func TestSelfTradeRollbackRestoresTicket(t *testing.T) {
+	s, mr := newTestServer(t)
+	own := queueTradeTicket(t, s, "Alice", Card{Name: "Ghoul", Forca: 1})
+	queueTradeTicket(t, s, "Bob", Card{Name: "Dragão", Forca: 10})
+
+	ticketB, _ := json.Marshal(TradeTicket{PlayerName: "Alice", ServerID: s.ServerID, Cards: []Card{{Name: "Grifo", Forca: 3}}})
+	keys := []string{tradeQueueKey, tradePendingPrefix + "t1", tradePendingSetKey}
+	if err := atomicClaimTradeScript.Run(s.ctx, s.RedisClient, keys, "t1", string(ticketB), s.ServerID, own).Err(); err != nil {
+		t.Fatalf("atomicClaimTradeScript: %v", err)
+	}
+	if err := s.rollbackPendingTrade(tradeQueueKey, "t1"); err != nil {
+		t.Fatalf("rollbackPendingTrade: %v", err)
+	}
+
+	if queued := queuedTradePlayers(t, s); len(queued) != 2 || queued[0] != "Alice" || queued[1] != "Bob" {
+		t.Errorf("o ticket de Alice deveria voltar ao início da fila, fila: %v", queued)
+	}
+	if mr.Exists(tradePendingPrefix+"t1") || mr.Exists(tradePendingSetKey) {
+		t.Errorf("o registro da troca desfeita deveria ser apagado")
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// tradeWantSweepEvery é o intervalo da varredura que devolve os tickets com "WANT" expirados.
+const tradeWantSweepEvery = 10 * time.Second
+
+// TradeWant é o que um jogador aceita receber em troca ("TRADE_CARD 2 WANT Grifo" ou
+// "TRADE_CARD 2 WANT 5"): uma carta pelo nome ou qualquer carta com pelo menos essa Força.
+// Num pacote (TRADE_CARDS), basta que uma das cartas recebidas atenda ao pedido.
+type TradeWant struct {
+	Name     string `json:"name,omitempty"`
+	MinForca int    `json:"min_forca,omitempty"`
+}
+
+// satisfiedBy indica se as cartas oferecidas atendem ao pedido. Um pedido nil aceita qualquer carta.
+func (w *TradeWant) satisfiedBy(cards []Card) bool {
+	if w == nil {
+		return true
+	}
+	for _, card := range cards {
+		if w.Name != "" && strings.EqualFold(card.Name, w.Name) {
+			return true
+		}
+		if w.MinForca > 0 && card.Forca >= w.MinForca {
+			return true
+		}
+	}
+	return false
+}
+
+// text descreve o pedido para as mensagens ao jogador, no idioma dele.
+func (w *TradeWant) text() LocalizedText {
+	if w == nil {
+		return localized(msgTradeWantAny)
+	}
+	if w.Name != "" {
+		return localized(msgTradeWantCard, w.Name)
+	}
+	return localized(msgTradeWantMinForca, w.MinForca)
+}
+
+// String descreve o pedido para os logs e a auditoria.
+func (w *TradeWant) String() string {
+	if w == nil {
+		return "qualquer carta"
+	}
+	if w.Name != "" {
+		return fmt.Sprintf("'%s'", w.Name)
+	}
+	return fmt.Sprintf("uma carta com Força %d ou mais", w.MinForca)
+}
+
+// ticketsCompatible indica se dois tickets podem ser trocados: cada um atende ao pedido do outro.
+func ticketsCompatible(a, b TradeTicket) bool {
+	return a.Want.satisfiedBy(b.Cards) && b.Want.satisfiedBy(a.Cards)
+}
+
+// splitTradeWant separa o pedido do resto do comando ("TRADE_CARD 2 WANT Grifo" → "TRADE_CARD 2", "Grifo").
+func splitTradeWant(command string) (string, string, bool) {
+	before, after, found := strings.Cut(command, " WANT")
+	return strings.TrimSpace(before), strings.TrimSpace(after), found
+}
+
+// parseTradeWant valida o pedido contra o conjunto de cartas do estoque, para que um pedido que
+// nunca pode ser atendido (carta inexistente ou Força acima da maior carta) seja recusado de início.
+// Retorna o pedido ou o motivo da recusa (errText.ID != "").
+func (s *Server) parseTradeWant(raw string) (want *TradeWant, errText LocalizedText) {
+	if raw == "" {
+		return nil, localized(msgTradeWantMissing)
+	}
+	if minForca, err := strconv.Atoi(raw); err == nil {
+		maxForca := 0
+		for _, c := range s.StockSpec.Cards {
+			maxForca = max(maxForca, c.Forca)
+		}
+		if minForca < 1 || minForca > maxForca {
+			return nil, localized(msgTradeWantBadForca, maxForca)
+		}
+		return &TradeWant{MinForca: minForca}, LocalizedText{}
+	}
+	for _, c := range s.StockSpec.Cards {
+		if strings.EqualFold(c.Name, raw) {
+			return &TradeWant{Name: c.Name}, LocalizedText{}
+		}
+	}
+	return nil, localized(msgTradeWantUnknownCard, raw)
+}
+
+// findCompatibleTradeTicket procura, na ordem da fila, o primeiro ticket compatível com o do jogador
+// (ticketsCompatible). Retorna o JSON do ticket como está na fila, ou redis.Nil se nenhum servir.
+// Tickets corrompidos encontrados no caminho saem da fila (dead letter). Os tickets do próprio
+// jogador são ignorados: trocar consigo mesmo só embaralharia o deck.
+// Deve ser chamada com o lock de trocas: entre a leitura e a retirada (claimTradeTicket), a fila só
+// muda pela varredura de expiração, e nesse caso a retirada falha e o jogador entra na fila.
+func (s *Server) findCompatibleTradeTicket(ctx context.Context, queueKey string, ticket TradeTicket) (string, error) {
+	queued, err := s.RedisClient.LRange(ctx, queueKey, 0, -1).Result()
+	if err != nil {
+		return "", err
+	}
+	for _, raw := range queued {
+		var candidate TradeTicket
+		if err := json.Unmarshal([]byte(raw), &candidate); err != nil {
+			if removed, lerr := s.RedisClient.LRem(ctx, queueKey, 1, raw).Result(); lerr == nil && removed > 0 {
+				s.deadLetterTicket(ctx, queueKey, raw, err)
+			}
+			continue
+		}
+		if candidate.PlayerName == ticket.PlayerName {
+			continue
+		}
+		if ticketsCompatible(candidate, ticket) {
+			return raw, nil
+		}
+	}
+	return "", redis.Nil
+}
+
+// tradeWantExpiryLoop devolve aos donos, depois de TRADE_WANT_TTL na fila, as cartas dos tickets
+// com pedido ("WANT") que ninguém atendeu. Tickets sem pedido esperam indefinidamente, como antes.
+// Todos os servidores varrem as filas; o LREM decide qual deles devolve cada ticket.
+func (s *Server) tradeWantExpiryLoop() {
+	ticker := time.NewTicker(tradeWantSweepEvery)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if s.shuttingDown() {
+			return
+		}
+		for n := 1; n <= maxTradeBundleSize; n++ {
+			s.expireTradeWants(tradeQueueKeyFor(n))
+		}
+	}
+}
+
+// expireTradeWants retira da fila os tickets com pedido expirados e devolve as cartas via Pub/Sub.
+func (s *Server) expireTradeWants(queueKey string) {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	queued, err := s.RedisClient.LRange(ctx, queueKey, 0, -1).Result()
+	if err != nil {
+		slog.Error("Erro ao ler a fila de trocas", "queue", queueKey, "error", err)
+		return
+	}
+
+	deadline := time.Now().Add(-s.Config.TradeWantTTL).Unix()
+	for _, raw := range queued {
+		var ticket TradeTicket
+		if json.Unmarshal([]byte(raw), &ticket) != nil || ticket.Want == nil || ticket.QueuedAt > deadline {
+			continue
+		}
+		removed, err := s.RedisClient.LRem(ctx, queueKey, 1, raw).Result()
+		if err != nil || removed == 0 {
+			continue // Outro servidor (ou uma troca) chegou antes
+		}
+
+		// O pedido vai em JSON: o servidor do dono o descreve no idioma do jogador
+		wantJSON, _ := json.Marshal(ticket.Want)
+		cardsJSON, _ := json.Marshal(ticket.Cards)
+		message := fmt.Sprintf("TRADE_EXPIRED|%s|%s", wantJSON, cardsJSON)
+		if err := s.publishToPlayer(ctx, ticket.PlayerName, message); err != nil {
+			slog.Error("Erro ao devolver cartas de troca expirada; ticket devolvido à fila", "player", ticket.PlayerName, "error", err)
+			s.RedisClient.RPush(ctx, queueKey, raw)
+			continue
+		}
+		s.clearOpenTrade(ticket.PlayerName)
+		tradesTotal.WithLabelValues("expired").Inc()
+		slog.Info("Pedido de troca expirado; cartas devolvidas.", "event", "trade_want_expired", "player", ticket.PlayerName,
+			"cards", cardNames(ticket.Cards), "want", ticket.Want.String(), "queue", queueKey)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Um jogador com a conexão lenta pode pedir mais tempo para a jogada com "REQUEST_EXTENSION
+// [gameID]": o prazo da rodada aumenta em TURN_EXTENSION, para os DOIS jogadores. Cada jogador
+// pode pedir uma única vez por partida (valendo também para as rodadas de morte súbita), então a
+// partida dura no máximo 2 × TURN_EXTENSION além do normal. Só vale no modo clássico.
+//
+// O pedido é registrado pelo servidor de quem pede (HSETNX em game:extension:<GameID>, o que
+// garante o uso único mesmo com jogadores em servidores diferentes) e enviado ao cérebro pelo
+// canal da partida. O cérebro é quem estende o prazo: só ele sabe se a rodada ainda está aberta.
+// O novo prazo vai aos jogadores como no início de uma rodada de morte súbita (o P2 pelo canal
+// player:<nome>, e o servidor dele atualiza a sessão antes de encaminhar):
+//
+//	TURN_EXTENSION|<nome>   pedido, no canal game:channel:<GameID>
+//	TURN_EXTENDED|<json>    novo prazo, aos jogadores (TurnExtended)
+
+const (
+	// gameExtensionPrefix guarda, por partida, quem já usou a extensão de tempo.
+	gameExtensionPrefix = "game:extension:"
+	// turnExtensionEventPrefix é o pedido de extensão enviado ao cérebro.
+	turnExtensionEventPrefix = "TURN_EXTENSION|"
+	// turnExtendedPrefix é o aviso do novo prazo, ao jogador e entre os servidores.
+	turnExtendedPrefix = "TURN_EXTENDED|"
+)
+
+// TurnExtended é o payload de "TURN_EXTENDED|<json>".
+type TurnExtended struct {
+	GameID      string `json:"game_id"`
+	Player      string `json:"player"`       // Quem pediu a extensão
+	TurnSeconds int    `json:"turn_seconds"` // Tempo restante com a extensão
+	DeadlineMs  int64  `json:"deadline_ms"`  // Novo prazo (Unix em ms), o mesmo do cérebro
+}
+
+// handleRequestExtension processa o comando "REQUEST_EXTENSION [gameID]".
+func (s *Server) handleRequestExtension(player *PlayerState, command string) {
+	gameID := strings.TrimSpace(strings.TrimPrefix(command, "REQUEST_EXTENSION"))
+	player.mu.Lock()
+	if gameID == "" {
+		gameID = player.defaultGameID()
+	}
+	game := player.Games[gameID]
+	player.mu.Unlock()
+	if game == nil {
+		s.rejectCommand(player, fmt.Sprintf("Você não está na partida %q.", gameID))
+		return
+	}
+
+	game.mu.Lock()
+	mode := game.Mode
+	deadline := game.TurnDeadline
+	field := cardField("p2_card", game.SuddenDeathRound)
+	if player.Name == game.Player1.Name {
+		field = cardField("p1_card", game.SuddenDeathRound)
+	}
+	game.mu.Unlock()
+	if mode == gameModeFFA {
+		s.rejectCommand(player, "Não é possível pedir mais tempo numa partida FFA.")
+		return
+	}
+	if time.Now().After(deadline) {
+		s.rejectCommand(player, "O tempo de jogada já terminou.")
+		return
+	}
+
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	played, err := s.RedisClient.HExists(ctx, gameStatePrefix+gameID, field).Result()
+	if err == nil && played {
+		s.rejectCommand(player, "Você já fez sua jogada nesta rodada.")
+		return
+	}
+	var first bool
+	if err == nil {
+		first, err = s.RedisClient.HSetNX(ctx, gameExtensionPrefix+gameID, player.Name, deadline.UnixMilli()).Result()
+	}
+	if err != nil {
+		slog.Error("Erro ao registrar pedido de extensão de tempo", "game_id", gameID, "player", player.Name, "error", err)
+		s.sendWebSocketMessage(player, "Erro interno ao pedir mais tempo. Tente novamente.")
+		return
+	}
+	if !first {
+		s.rejectCommand(player, "Você já usou a extensão de tempo nesta partida.")
+		return
+	}
+	s.RedisClient.Expire(ctx, gameExtensionPrefix+gameID, s.gameStateTTL())
+
+	if err := s.RedisClient.Publish(ctx, fmt.Sprintf("game:channel:%s", gameID), turnExtensionEventPrefix+player.Name).Err(); err != nil {
+		slog.Error("Erro ao publicar pedido de extensão de tempo", "game_id", gameID, "player", player.Name, "error", err)
+		s.sendWebSocketMessage(player, "Erro interno ao pedir mais tempo. Tente novamente.")
+		return
+	}
+	slog.Info("Extensão de tempo pedida", "event", "turn_extension_requested", "game_id", gameID, "player", player.Name)
+}
+
+// extendTurn aplica, no cérebro (P1-Server), a extensão pedida pelo jogador: adia o prazo da
+// rodada e avisa os dois jogadores. Retorna o novo prazo, ou false se a rodada já terminou.
+func (s *Server) extendTurn(session *GameSession, requester string) (time.Time, bool) {
+	session.mu.Lock()
+	if time.Now().After(session.TurnDeadline) {
+		session.mu.Unlock()
+		return time.Time{}, false
+	}
+	session.TurnDeadline = session.TurnDeadline.Add(s.Config.TurnExtension)
+	deadline := session.TurnDeadline
+	gameID := session.GameID
+	p1, p2 := session.Player1, session.Player2
+	session.mu.Unlock()
+
+	logger := slog.With("game_id", gameID)
+	logger.Info("Prazo da jogada estendido", "event", "turn_extended", "player", requester,
+		"extension", s.Config.TurnExtension, "remaining_seconds", remainingSeconds(deadline))
+	s.appendReplayEvent(gameID, ReplayEvent{Type: replayEventExtension, Player: requester})
+
+	// O prazo compartilhado passa a ser o novo (ver turn_timer.go)
+	ctx, cancel := s.redisCtx()
+	s.RedisClient.Set(ctx, gameDeadlinePrefix+gameID, deadline.UnixMilli(), time.Until(deadline)+gameDeadlineGrace)
+	cancel()
+
+	payload, _ := json.Marshal(TurnExtended{GameID: gameID, Player: requester,
+		TurnSeconds: remainingSeconds(deadline), DeadlineMs: deadline.UnixMilli()})
+	message := turnExtendedPrefix + string(payload)
+	s.sendWebSocketMessage(p1, message)
+	if !p2.isBot {
+		ctx, cancel := s.redisCtx()
+		err := s.RedisClient.Publish(ctx, fmt.Sprintf("player:%s", p2.Name), message).Err()
+		cancel()
+		if err != nil {
+			logger.Error("Erro ao publicar extensão de tempo via Redis", "player", p2.Name, "error", err)
+		}
+	}
+	return deadline, true
+}
+
+// turnExtensionRequester extrai o nome do jogador de um evento "TURN_EXTENSION|<nome>".
+func turnExtensionRequester(payload string) (string, bool) {
+	return strings.CutPrefix(payload, turnExtensionEventPrefix)
+}
+
+// applyTurnExtension recebe, no servidor do P2, o novo prazo publicado pelo cérebro: atualiza
+// a sessão local (para as jogadas e o GET_TIMER) e encaminha o aviso ao jogador.
+func (s *Server) applyTurnExtension(player *PlayerState, message string) {
+	var extended TurnExtended
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(message, turnExtendedPrefix)), &extended); err != nil {
+		slog.Error("Extensão de tempo inválida", "player", player.Name, "error", err)
+		return
+	}
+	player.mu.Lock()
+	session := player.Games[extended.GameID]
+	player.mu.Unlock()
+	if session == nil {
+		return // A partida já terminou
+	}
+	session.mu.Lock()
+	session.TurnDeadline = time.UnixMilli(extended.DeadlineMs)
+	session.mu.Unlock()
+	s.sendWebSocketMessage(player, message)
+}
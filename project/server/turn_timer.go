@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// gameDeadlinePrefix guarda o prazo final da jogada (Unix em ms) de cada partida, por GameID.
+	gameDeadlinePrefix = "game:deadline:"
+	// gameDeadlineGrace mantém a chave viva um pouco além do prazo, para consultas tardias.
+	gameDeadlineGrace = 1 * time.Minute
+)
+
+// claimTurnDeadline define o prazo da jogada da partida, compartilhado por todos os servidores.
+// O primeiro servidor a iniciar a partida grava o prazo (SETNX); os demais leem o mesmo valor.
+// Assim o timeout do "cérebro" e os contadores dos clientes partem do mesmo instante absoluto.
+func (s *Server) claimTurnDeadline(gameID string) time.Time {
+	ctx, cancel := s.redisCtx()
+	defer cancel()
+	key := gameDeadlinePrefix + gameID
+	deadline := time.Now().Add(s.Config.GameTurnTimeout)
+
+	ok, err := s.RedisClient.SetNX(ctx, key, deadline.UnixMilli(), s.Config.GameTurnTimeout+gameDeadlineGrace).Result()
+	if err != nil {
+		slog.Error("Erro ao gravar prazo da jogada; usando prazo local", "game_id", gameID, "error", err)
+		return deadline
+	}
+	if ok {
+		return deadline
+	}
+
+	raw, err := s.RedisClient.Get(ctx, key).Result()
+	if err != nil {
+		slog.Error("Erro ao ler prazo da jogada; usando prazo local", "game_id", gameID, "error", err)
+		return deadline
+	}
+	ms, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		slog.Error("Prazo da jogada inválido no Redis; usando prazo local", "game_id", gameID, "value", raw)
+		return deadline
+	}
+	return time.UnixMilli(ms)
+}
+
+// remainingSeconds retorna os segundos restantes até o prazo (arredondado para cima, nunca negativo).
+func remainingSeconds(deadline time.Time) int {
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return 0
+	}
+	return int((remaining + time.Second - 1) / time.Second)
+}
+
+// timerMessage formata o "TIMER|n" com o tempo restante da jogada da partida.
+func (g *GameSession) timerMessage() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return fmt.Sprintf("TIMER|%d", remainingSeconds(g.TurnDeadline))
+}
+
+// handleGetTimer responde ao comando "GET_TIMER [gameID]" com o tempo restante da jogada.
+// Sem o GameID, vale a partida em andamento (a iniciada por último, se houver várias).
+func (s *Server) handleGetTimer(player *PlayerState, command string) {
+	gameID := strings.TrimSpace(strings.TrimPrefix(command, "GET_TIMER"))
+	player.mu.Lock()
+	if gameID == "" {
+		gameID = player.defaultGameID()
+	}
+	game := player.Games[gameID]
+	player.mu.Unlock()
+
+	if game == nil {
+		// Usado também pelo cliente após reconectar, para saber se a partida ainda existe.
+		s.sendWebSocketMessage(player, "NO_ACTIVE_GAME")
+		return
+	}
+	s.sendWebSocketMessage(player, game.timerMessage())
+}
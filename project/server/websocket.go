@@ -1,65 +1,175 @@
 package main
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-// upgrader 
+// maxClientMessageSize limita o tamanho de uma mensagem recebida do cliente. Os comandos são
+// curtos (o maior é o login com token); uma mensagem maior derruba a conexão, em vez de ocupar
+// memória do servidor.
+const maxClientMessageSize = 8 * 1024
+
+// errClientMessageTooLarge indica uma mensagem do cliente maior que maxClientMessageSize.
+var errClientMessageTooLarge = errors.New("mensagem do cliente maior que o limite")
+
+const (
+	// pongWait é por quanto tempo a conexão pode ficar sem receber nada do cliente (nem o pong
+	// de um ping) antes de ser encerrada. Sem isso, uma conexão meio aberta (a rede do cliente
+	// caiu sem fechar o socket) seguiria viva, segurando a reserva do nome e as partidas.
+	pongWait = 30 * time.Second
+	// pingPeriod é o intervalo dos pings do writeLoop; menor que pongWait, para o pong chegar a tempo.
+	pingPeriod = pongWait * 9 / 10
+)
+
+// setReadDeadline aplica o prazo de leitura pongWait à conexão; cada pong o renova.
+func setReadDeadline(conn *websocket.Conn) {
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+}
+
+// readClientMessage lê a próxima mensagem do cliente, com no máximo maxClientMessageSize bytes.
+// O SetReadLimit da conexão vale para o quadro como chega (comprimido); aqui o limite vale também
+// para a mensagem descomprimida, então uma mensagem pequena que se expande muito não passa.
+func readClientMessage(conn *websocket.Conn) ([]byte, error) {
+	_, r, err := conn.NextReader()
+	if err != nil {
+		return nil, err
+	}
+	message, err := io.ReadAll(io.LimitReader(r, maxClientMessageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(message) > maxClientMessageSize {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseMessageTooBig, ""),
+			time.Now().Add(time.Second))
+		return nil, errClientMessageTooLarge
+	}
+	return message, nil
+}
+
+// upgrader aceita a compressão por mensagem (permessage-deflate), quando o cliente a oferece:
+// as respostas grandes (replays, coleção, deck) ficam bem menores. Os buffers só definem o
+// tamanho de cada leitura/escrita no socket, não o tamanho máximo das mensagens.
 var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
+	ReadBufferSize:    4096,
+	WriteBufferSize:   4096,
+	EnableCompression: true,
 	CheckOrigin: func(r *http.Request) bool {
-		return true
+		return true // A origem já foi verificada em handleWebSocketConnection (ALLOWED_ORIGINS)
 	},
 }
 
 // handleWebSocketConnection
 func (s *Server) handleWebSocketConnection(w http.ResponseWriter, r *http.Request) {
+	if origin, ok := s.checkOrigin(r); !ok {
+		slog.Warn("Conexão recusada: origem não permitida.", "event", "origin_rejected", "remote_addr", r.RemoteAddr, "origin", origin)
+		http.Error(w, "Origem não permitida", http.StatusForbidden)
+		return
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("Erro ao fazer upgrade para WebSocket: %v", err)
+		slog.Error("Erro ao fazer upgrade para WebSocket", "remote_addr", r.RemoteAddr, "error", err)
 		return
 	}
+	conn.SetReadLimit(maxClientMessageSize)
+	setReadDeadline(conn)
 
-	_, p, err := conn.ReadMessage()
+	p, err := readClientMessage(conn)
 	if err != nil {
-		log.Printf("Erro ao ler nome do jogador: %v", err)
+		slog.Error("Erro ao ler nome do jogador", "remote_addr", r.RemoteAddr, "error", err)
+		conn.Close()
+		return
+	}
+	// A retomada vem antes da manutenção: ela não cria uma sessão nova, continua uma em andamento
+	if name, token, ok := parseRejoin(strings.TrimSpace(string(p))); ok {
+		s.handleRejoin(conn, r, name, token)
+		return
+	}
+	if s.inMaintenance() {
+		slog.Info("Conexão recusada: servidor em manutenção.", "event", "maintenance_refused", "remote_addr", r.RemoteAddr)
+		conn.WriteMessage(websocket.TextMessage, []byte(maintenanceMessage))
+		conn.Close()
+		return
+	}
+	playerName, reason := s.authenticateConnection(strings.TrimSpace(string(p)))
+	if reason != "" {
+		slog.Warn("Conexão recusada: autenticação falhou.", "event", "auth_failed", "remote_addr", r.RemoteAddr,
+			"player", playerName, "reason", reason)
+		conn.WriteMessage(websocket.TextMessage, []byte(authFailedPrefix+reason))
 		conn.Close()
 		return
 	}
-	playerName := strings.TrimSpace(string(p))
 
-	if playerName == "" {
-		conn.WriteMessage(websocket.TextMessage, []byte("Nome de jogador inválido. Desconectando."))
+	if reason := validatePlayerName(playerName); reason != "" {
+		slog.Warn("Conexão recusada: nome inválido.", "event", "invalid_name", "remote_addr", r.RemoteAddr, "reason", reason)
+		conn.WriteMessage(websocket.TextMessage, []byte("INVALID_NAME|"+reason))
 		conn.Close()
 		return
 	}
 
+	// Reserva o nome em todo o cluster para evitar colisões entre servidores
+	requestedName := playerName
+	playerName, presenceToken, err := s.reserveAvailableName(requestedName)
+	if err != nil {
+		slog.Error("Erro ao reservar nome no Redis", "player", playerName, "error", err)
+		conn.WriteMessage(websocket.TextMessage, []byte("Erro interno ao conectar. Tente novamente."))
+		conn.Close()
+		return
+	}
+	if presenceToken == "" {
+		slog.Warn("Conexão recusada: nome já está em uso no cluster.", "event", "name_taken", "player", playerName)
+		conn.WriteMessage(websocket.TextMessage, []byte("NAME_TAKEN"))
+		conn.Close()
+		return
+	}
+	if playerName != requestedName {
+		// Antes de qualquer outra mensagem: o cliente passa a se identificar pelo nome efetivo
+		slog.Info("Nome em uso no cluster; sufixo atribuído.", "event", "name_assigned", "requested", requestedName, "player", playerName)
+		conn.WriteMessage(websocket.TextMessage, []byte("ASSIGNED_NAME|"+playerName))
+	}
+
 	player := &PlayerState{
-		Name:        playerName,
-		Deck:        []Card{},
-		PacksOpened: 0,
-		WsConn:      conn,
-		ServerID:    s.ServerID,
-		mu:          sync.Mutex{},
-		State:       "Menu",
-		CurrentGame: nil,
+		Name:           playerName,
+		Deck:           s.loadDeck(playerName),        // Salvo na conexão anterior, se houver (ver deck.go)
+		PacksOpened:    s.loadPacksOpened(playerName), // Contagem do cluster, não apenas desta sessão
+		WsConn:         conn,
+		ServerID:       s.ServerID,
+		mu:             sync.Mutex{},
+		State:          "Menu",
+		Games:          make(map[string]*GameSession),
+		presenceToken:  presenceToken,
+		loadout:        s.loadLoadout(playerName), // Escolhido em uma conexão anterior, se houver
+		done:           make(chan struct{}),
+		limiter:        newTokenBucket(float64(s.Config.CommandRate), s.Config.CommandBurst),
+		outbox:         make(chan outboxItem, outboxSize),
+		reconnectToken: newRandomID(),
 	}
 
 	s.PlayerMutex.Lock()
 	s.Players[playerName] = player
 	s.PlayerMutex.Unlock()
 
-	log.Printf("Jogador %s conectado via WebSocket.", playerName)
-	s.openCardPack(player, true)
+	slog.Info("Jogador conectado via WebSocket.", "event", "player_connected", "player", playerName)
+	s.audit(playerName, auditConnect, "", "")
+	go s.writeLoop(player)
+	s.sendWebSocketMessage(player, reconnectTokenPrefix+player.reconnectToken)
+	go s.presenceHeartbeatLoop(player)
+	s.grantStarterPack(player)
+	if s.stockExhausted.Load() {
+		s.sendWebSocketMessage(player, stockExhaustedEvent)
+	}
 	go s.listenRedisPubSub(player)
 	s.listenClientCommands(player)
 }
@@ -67,119 +177,313 @@ func (s *Server) handleWebSocketConnection(w http.ResponseWriter, r *http.Reques
 // listenClientCommands
 func (s *Server) listenClientCommands(player *PlayerState) {
 	defer func() {
-		s.PlayerMutex.Lock()
-		delete(s.Players, player.Name)
-		s.PlayerMutex.Unlock()
+		detached := s.detachPlayer(player)
+		close(player.done)
+		s.cancelPrivateMatch(player)
 		player.WsConn.Close()
-		log.Printf("Jogador %s desconectado.", player.Name)
+		switch {
+		case player.takenOver.Load():
+			// Outra conexão assumiu o jogador (REJOIN): as partidas e a reserva do nome seguem com ela
+			slog.Info("Conexão substituída por um REJOIN.", "event", "player_taken_over", "player", player.Name)
+			return
+		case detached:
+			s.startRejoinGrace(player)
+		default:
+			s.handleInGameDisconnect(player)
+			s.releasePlayerName(player.Name, player.presenceToken)
+		}
+		slog.Info("Jogador desconectado.", "event", "player_disconnected", "player", player.Name)
+		s.audit(player.Name, auditDisconnect, "", "")
 	}()
 
 	for {
-		_, message, err := player.WsConn.ReadMessage()
+		message, err := readClientMessage(player.WsConn)
 		if err != nil {
+			if errors.Is(err, errClientMessageTooLarge) {
+				slog.Warn("Mensagem grande demais; conexão encerrada.", "event", "message_too_large", "player", player.Name,
+					"limit", maxClientMessageSize)
+			}
 			break
 		}
 
 		command := strings.TrimSpace(string(message))
-		log.Printf("Comando recebido de %s: %s", player.Name, command)
+		slog.Debug("Comando recebido", "event", "command", "player", player.Name, "command", command)
 
-		player.mu.Lock()
-		state := player.State
-		game := player.CurrentGame
-		player.mu.Unlock()
+		// O estado lido e a ação tomada não podem ser separados por uma mudança de estado
+		// vinda de outra goroutine (ver command_guard.go)
+		player.cmdMu.Lock()
+		s.handleCommand(player, command)
+		player.cmdMu.Unlock()
+	}
+}
 
-		if state == "InGame" && game != nil {
-			s.handleGameMove(player, game, command)
-		} else {
-			switch {
-			case command == "FIND_MATCH":
-				s.addToMatchmakingQueue(player)
-			case command == "OPEN_PACK":
-				s.openCardPack(player, false)
-			case command == "VIEW_DECK":
-				s.viewDeck(player)
-			case strings.HasPrefix(command, "TRADE_CARD"):
-				s.handleTradeCard(player, command)
-			default:
-				s.sendWebSocketMessage(player, "Comando inválido.")
-			}
+// handleCommand valida o comando contra o estado atual do jogador e o executa.
+// Deve ser chamada com player.cmdMu travado.
+func (s *Server) handleCommand(player *PlayerState, command string) {
+	player.mu.Lock()
+	state := player.State
+	inGame := len(player.Games) > 0
+	player.mu.Unlock()
+
+	if !s.allowCommand(player, command, inGame) {
+		slog.Warn("Comando recusado por limite de taxa", "event", "rate_limited", "player", player.Name, "command", command)
+		s.sendWebSocketMessage(player, "RATE_LIMITED")
+		return
+	}
+	if reason := commandRejection(state, inGame, command); reason != "" {
+		slog.Info("Comando recusado pelo estado do jogador", "event", "command_rejected", "player", player.Name, "state", state, "command", command)
+		s.rejectCommand(player, reason)
+		return
+	}
+
+	if inGame {
+		switch {
+		case strings.HasPrefix(command, "GET_TIMER"):
+			s.handleGetTimer(player, command)
+		case command == "STATUS":
+			s.handleStatus(player)
+		case strings.HasPrefix(command, "FORFEIT"):
+			s.handleForfeit(player, command)
+		case strings.HasPrefix(command, "REQUEST_EXTENSION"):
+			s.handleRequestExtension(player, command)
+		case strings.HasPrefix(command, "SET_LOCALE"):
+			s.handleSetLocale(player, command)
+		case strings.HasPrefix(command, "FIND_MATCH") && s.Config.MaxGamesPerPlayer > 1:
+			s.handleFindAnotherMatch(player, command)
+		default:
+			s.handleRoutedGameMove(player, command)
+		}
+	} else {
+		switch {
+		case command == "FIND_MATCH FFA":
+			s.addToMatchmakingQueue(player, ffaQueueKey)
+		case strings.HasPrefix(command, "FIND_MATCH"):
+			s.handleFindMatch(player, command)
+		case command == "RESUME_SEARCH":
+			s.handleResumeSearch(player)
+		case command == "OPEN_PACK" || strings.HasPrefix(command, "OPEN_PACK "):
+			s.handleOpenPack(player, command)
+		case strings.HasPrefix(command, "OPEN_PACKS"):
+			s.handleOpenPacks(player, command)
+		case command == "VIEW_DECK":
+			s.viewDeck(player)
+		case command == "COLLECTION":
+			s.handleCollection(player)
+		case strings.HasPrefix(command, "TRADE_CARD"):
+			s.handleTradeCard(player, command)
+		case command == "TRADE_QUEUE_PEEK":
+			s.handleTradeQueuePeek(player)
+		case strings.HasPrefix(command, "LEADERBOARD"):
+			s.handleLeaderboardCommand(player, command)
+		case strings.HasPrefix(command, "H2H"):
+			s.handleH2HCommand(player, command)
+		case command == "REMATCH":
+			s.handleRematch(player)
+		case strings.HasPrefix(command, "GET_TIMER"):
+			s.handleGetTimer(player, command)
+		case command == "STATUS":
+			s.handleStatus(player)
+		case strings.HasPrefix(command, "REPLAY"):
+			s.handleReplayCommand(player, command)
+		case strings.HasPrefix(command, "SET_LOADOUT"):
+			s.handleSetLoadout(player, command)
+		case strings.HasPrefix(command, "SET_LOCALE"):
+			s.handleSetLocale(player, command)
+		case command == "CREATE_PRIVATE":
+			s.handleCreatePrivate(player)
+		case strings.HasPrefix(command, "JOIN_PRIVATE"):
+			s.handleJoinPrivate(player, command)
+		default:
+			s.sendLocalized(player, msgInvalidCommand)
 		}
 	}
 }
 
-// sendWebSocketMessage
+// sendWebSocketMessage enfileira a mensagem para o escritor da conexão do jogador (ver ws_writer.go).
+// Pode ser chamada de qualquer goroutine.
 func (s *Server) sendWebSocketMessage(player *PlayerState, message string) {
-	err := player.WsConn.WriteMessage(websocket.TextMessage, []byte(message))
-	if err != nil {
-		log.Printf("Erro ao enviar mensagem para %s: %v", player.Name, err)
-		player.WsConn.Close()
-	}
+	s.enqueueMessage(player, message)
 }
 
 // listenRedisPubSub
 func (s *Server) listenRedisPubSub(player *PlayerState) {
-	ctx := context.Background()
-	pubsub := s.RedisClient.Subscribe(ctx, fmt.Sprintf("player:%s", player.Name))
+	// A assinatura dura a conexão inteira: só a desconexão ou o encerramento do servidor a interrompem
+	pubsub := s.RedisClient.Subscribe(s.ctx, fmt.Sprintf("player:%s", player.Name))
 	defer pubsub.Close()
 
+	// Encerra a inscrição quando o jogador desconecta. Se ele estiver em partida,
+	// aguarda o "RESULT|" pendente para que o resultado ainda seja registrado.
+	go func() {
+		<-player.done
+		player.mu.Lock()
+		inGame := len(player.Games) > 0
+		player.mu.Unlock()
+		// Assumido por um REJOIN, o jogador tem outro listener: este não espera o resultado
+		if inGame && !player.takenOver.Load() {
+			time.Sleep(s.Config.GameTurnTimeout + pendingResultGrace)
+		}
+		pubsub.Close()
+	}()
+
+	// Só depois de inscrito esvazia as mensagens guardadas enquanto o jogador estava desconectado:
+	// o que chegar a partir daqui já vem pelo canal (ver player_inbox.go).
+	if _, err := pubsub.Receive(s.ctx); err != nil {
+		if !player.isDisconnected() && !s.shuttingDown() {
+			slog.Error("Erro ao se inscrever no canal do jogador", "player", player.Name, "error", err)
+		}
+		return
+	}
+	if !s.deliverPendingMessages(player) {
+		return
+	}
+
 	for {
-		msg, err := pubsub.ReceiveMessage(ctx)
+		msg, err := pubsub.ReceiveMessage(s.ctx)
 		if err != nil {
-			log.Printf("Erro ao receber mensagem Pub/Sub para %s: %v", player.Name, err)
+			if player.isDisconnected() || s.shuttingDown() {
+				return
+			}
+			slog.Error("Erro ao receber mensagem Pub/Sub", "player", player.Name, "error", err)
 			return
 		}
 
-		log.Printf("Mensagem Pub/Sub recebida para %s: %s", player.Name, msg.Payload)
-
-		// LÓGICA DE ROTEAMENTO DE MENSAGEM 
+		slog.Debug("Mensagem Pub/Sub recebida", "player", player.Name, "payload", msg.Payload)
+		if !s.routePlayerMessage(player, msg.Payload) {
+			return
+		}
+	}
+}
 
-		if strings.HasPrefix(msg.Payload, "RESULT|") {
-			// LIMPEZA DE ESTADO PÓS-JOGO 
-			log.Printf("Limpando estado de jogo para %s após resultado (via Pub/Sub).", player.Name)
+// routePlayerMessage trata uma mensagem do canal player:<nome>, ao vivo ou guardada enquanto o
+// jogador estava desconectado. Retorna false quando o listener deve parar.
+func (s *Server) routePlayerMessage(player *PlayerState, payload string) bool {
+	// LÓGICA DE ROTEAMENTO DE MENSAGEM 
 
-			player.mu.Lock()
-			player.State = "Menu"
+	if gameID, result, ok := parseGameResult(payload); ok {
+		// LIMPEZA DE ESTADO PÓS-JOGO 
+		slog.Info("Limpando estado de jogo após resultado (via Pub/Sub).", "event", "game_result_received", "player", player.Name, "game_id", gameID)
 
-			if player.CurrentGame != nil {
-				gameID := player.CurrentGame.Player1.Name
-				s.GamesMutex.Lock()
-				if _, ok := s.ActiveGames[gameID]; ok {
-					log.Printf("Removendo sessão %s do ActiveGames (P2-Server).", gameID)
-					delete(s.ActiveGames, gameID)
-				}
-				s.GamesMutex.Unlock()
-				player.CurrentGame = nil
+		// Trava os comandos do jogador durante a transição (ver command_guard.go)
+		player.cmdMu.Lock()
+		player.mu.Lock()
+		if gameID == "" {
+			gameID = player.defaultGameID() // Resultado sem GameID: vale para a partida em andamento
+		}
+		finishedGame := player.removeGame(gameID)
+		if finishedGame != nil {
+			s.GamesMutex.Lock()
+			if _, ok := s.ActiveGames[gameID]; ok {
+				slog.Debug("Removendo sessão do ActiveGames (P2-Server).", "game_id", gameID)
+				delete(s.ActiveGames, gameID)
 			}
-			player.mu.Unlock()
+			s.GamesMutex.Unlock()
+		}
+		player.mu.Unlock()
+		player.cmdMu.Unlock()
+
+		// O P2-Server registra no ranking apenas o resultado do seu jogador (P2).
+		// O resultado do P1 é registrado pelo P1-Server em determineWinner.
+		// O resultado pode chegar mais de uma vez (à conexão antiga, que ainda tem a partida, e
+		// de novo pela lista de pendentes à nova): só a primeira entrega registra (claimResultRecord).
+		if outcome, ok := outcomeFromResult(result); ok && s.claimResultRecord(player.Name, gameID, finishedGame != nil) {
+			s.recordGameResult(player.Name, outcome)
+			s.audit(player.Name, auditResult, gameID, resultAuditDetail(outcome, result))
+			s.recordAbandonment(player.Name, result)
+		}
+
+		// Envia o resultado ao jogador como "GAME_END|<json>" (ver game.go). Desconectado, ele só
+		// o recebe se voltar com REJOIN (ver rejoin.go); o resultado já foi registrado.
+		s.sendWebSocketMessage(player, gameEndMessage(gameID, result, player.currentLocale()))
+		if player.isDisconnected() {
+			return false
+		}
 
-			// Envia a mensagem de resultado
-			s.sendWebSocketMessage(player, msg.Payload)
+		// Oferece revanche ao P2 (a oferta ao P1 é feita pelo P1-Server)
+		if finishedGame != nil && finishedGame.Mode == gameModeClassic {
+			finishedGame.mu.Lock()
+			s.offerRematch(player, finishedGame)
+			finishedGame.mu.Unlock()
+		}
 
-		} else if strings.HasPrefix(msg.Payload, "TRADE_COMPLETE|") {
-			// PROCESSAMENTO DE TROCA CONCLUÍDA 
-			log.Printf("Recebida notificação de troca completa para %s.", player.Name)
+	} else if strings.HasPrefix(payload, "TRADE_COMPLETE|") {
+		// PROCESSAMENTO DE TROCA CONCLUÍDA 
+		if player.isDisconnected() {
+			// Conexão encerrada (o listener ainda aguarda um resultado): as cartas iriam para um
+			// deck descartado. A notificação fica para a próxima conexão do jogador.
+			s.bufferPlayerMessage(player.Name, payload)
+			return true
+		}
+		slog.Info("Recebida notificação de troca completa.", "event", "trade_completed", "player", player.Name)
 
-			cardJSON := strings.TrimPrefix(msg.Payload, "TRADE_COMPLETE|")
-			var receivedCard Card
-			var notificationMsg string
+		// Formato: TRADE_COMPLETE|<tradeID>|<cartas JSON>
+		parts := strings.SplitN(strings.TrimPrefix(payload, "TRADE_COMPLETE|"), "|", 2)
+		var receivedCards []Card
+		var notification LocalizedText
 
-			if err := json.Unmarshal([]byte(cardJSON), &receivedCard); err == nil {
-				// Adiciona a carta recebida ao deck local do jogador
-				player.Deck = append(player.Deck, receivedCard)
-				notificationMsg = fmt.Sprintf("Troca concluída! Sua carta anterior foi trocada por '%s (Força: %d)'.", receivedCard.Name, receivedCard.Forca)
-				log.Printf("Carta %s adicionada ao deck de %s via Pub/Sub.", receivedCard.Name, player.Name)
+		if len(parts) != 2 {
+			slog.Error("Notificação de troca malformada", "player", player.Name, "payload", payload)
+			notification = localized(msgTradeReceivedError)
+		} else if err := json.Unmarshal([]byte(parts[1]), &receivedCards); err != nil {
+			slog.Error("Erro ao desserializar carta de troca via Pub/Sub", "player", player.Name, "error", err)
+			notification = localized(msgTradeReceivedError)
+		} else if credit, err := s.markTradeCredited(parts[0], tradeFieldCreditA); err == nil && !credit {
+			// Notificação repetida (reenviada na recuperação): a carta já foi creditada.
+			slog.Info("Notificação de troca duplicada ignorada.", "player", player.Name, "trade_id", parts[0])
+			return true
+		} else {
+			if err != nil {
+				slog.Error("Erro ao marcar troca como creditada (A)", "trade_id", parts[0], "player", player.Name, "error", err)
+			}
+			// Adiciona as cartas recebidas ao deck local do jogador
+			s.giveCards(player, receivedCards...)
+			s.clearOpenTrade(player.Name) // O ticket dele saiu da fila: pode trocar de novo
+			s.audit(player.Name, auditTrade, "", "recebeu "+strings.Join(cardNames(receivedCards), ", "))
+			if len(receivedCards) == 1 {
+				notification = localized(msgTradeReceivedCard, describeCards(receivedCards))
 			} else {
-				log.Printf("Erro ao desserializar carta de troca via Pub/Sub para %s: %v", player.Name, err)
-				notificationMsg = "Erro ao processar uma troca recebida."
+				notification = localized(msgTradeReceivedBundle, len(receivedCards), describeCards(receivedCards))
 			}
+			slog.Info("Cartas adicionadas ao deck via Pub/Sub.", "player", player.Name, "cards", cardNames(receivedCards), "trade_id", parts[0])
+		}
 
-			// Envia a notificação formatada para o cliente
-			s.sendWebSocketMessage(player, notificationMsg)
+		// Envia a notificação formatada para o cliente, no idioma dele
+		s.sendWebSocketMessage(player, notification.render(player.currentLocale()))
 
-		} else {
-			//  MENSAGEM PADRÃO
-			// Encaminha qualquer outra mensagem
-			s.sendWebSocketMessage(player, msg.Payload)
+	} else if strings.HasPrefix(payload, suddenDeathPrefix) {
+		// Empate com SUDDEN_DEATH_ROUNDS: nova rodada da partida (ver sudden_death.go)
+		s.applySuddenDeath(player, payload)
+
+	} else if strings.HasPrefix(payload, turnExtendedPrefix) {
+		// Prazo da jogada estendido pelo cérebro (ver turn_extension.go)
+		s.applyTurnExtension(player, payload)
+
+	} else if strings.HasPrefix(payload, "TRADE_EXPIRED|") {
+		if player.isDisconnected() {
+			s.bufferPlayerMessage(player.Name, payload) // Mesmo caso do TRADE_COMPLETE
+			return true
+		}
+		// Pedido de troca (WANT) não atendido a tempo: as cartas voltam ao deck (ver trade_want.go)
+		// Formato: TRADE_EXPIRED|<pedido JSON>|<cartas JSON>
+		parts := strings.SplitN(strings.TrimPrefix(payload, "TRADE_EXPIRED|"), "|", 2)
+		var returnedCards []Card
+		if len(parts) != 2 || json.Unmarshal([]byte(parts[1]), &returnedCards) != nil {
+			slog.Error("Devolução de troca malformada", "player", player.Name, "payload", payload)
+			return true
 		}
+		s.giveCards(player, returnedCards...)
+		slog.Info("Cartas de troca expirada devolvidas ao deck.", "event", "trade_want_returned", "player", player.Name, "cards", cardNames(returnedCards))
+		locale := player.currentLocale()
+		want := parts[0] // Servidores anteriores mandam o pedido já descrito, em português
+		var parsed *TradeWant
+		if json.Unmarshal([]byte(parts[0]), &parsed) == nil {
+			want = parsed.text().render(locale)
+		}
+		s.sendWebSocketMessage(player, localized(msgTradeExpired, want, describeCards(returnedCards)).render(locale))
+
+	} else {
+		//  MENSAGEM PADRÃO
+		// Encaminha qualquer outra mensagem
+		s.sendWebSocketMessage(player, payload)
 	}
+	return true
 }
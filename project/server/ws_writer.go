@@ -0,0 +1,121 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// outboxSize é quantas mensagens podem ficar pendentes para um jogador antes de a conexão
+	// ser considerada travada e encerrada.
+	outboxSize = 64
+	// writeWait é o prazo de cada escrita no WebSocket; um cliente que não lê não trava o escritor.
+	writeWait = 5 * time.Second
+	// ackQueueSize é quantos avisos de entrega (afterDelivery) podem esperar o confirmador do jogador.
+	ackQueueSize = 64
+)
+
+// outboxItem é uma entrada da fila de saída do jogador: uma mensagem a escrever ou, com
+// delivered, só um aviso de que tudo o que foi enfileirado antes dele já foi escrito (ver afterDelivery).
+type outboxItem struct {
+	message   string
+	delivered func()
+}
+
+// writeLoop é o ÚNICO escritor da conexão WebSocket do jogador. O gorilla/websocket não aceita
+// escritas concorrentes, então todo envio (comandos, Pub/Sub, resultado da partida, timers)
+// passa pelo canal player.outbox e é serializado aqui, na ordem em que foi enfileirado.
+// Encerra quando o jogador desconecta (player.done) ou quando uma escrita falha.
+//
+// Os avisos de entrega (afterDelivery) não rodam aqui: eles confirmam mensagens no Redis, e um
+// Redis lento travaria todo o envio ao jogador. Vão, na ordem, para o confirmador (ackLoop).
+func (s *Server) writeLoop(player *PlayerState) {
+	acks := make(chan func(), ackQueueSize)
+	go ackLoop(acks)
+	defer close(acks) // O confirmador termina os avisos já recebidos (mensagens já escritas)
+
+	// Os pings mantêm o prazo de leitura (pongWait) renovado enquanto o cliente responde
+	ping := time.NewTicker(pingPeriod)
+	defer ping.Stop()
+	for {
+		select {
+		case <-player.done:
+			return
+		case <-ping.C:
+			if err := player.WsConn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				player.WsConn.Close()
+				return
+			}
+		case item := <-player.outbox:
+			if item.delivered != nil {
+				select {
+				case acks <- item.delivered:
+				default:
+					// Sem confirmação, a mensagem é entregue de novo na próxima conexão (ver player_inbox.go)
+					slog.Warn("Fila de confirmações cheia; confirmação descartada", "event", "ack_queue_full", "player", player.Name)
+				}
+				continue
+			}
+			player.WsConn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := player.WsConn.WriteMessage(websocket.TextMessage, []byte(item.message)); err != nil {
+				slog.Error("Erro ao enviar mensagem", "player", player.Name, "error", err)
+				// Fechar a conexão encerra o listenClientCommands, que faz a limpeza do jogador.
+				player.WsConn.Close()
+				return
+			}
+		}
+	}
+}
+
+// ackLoop chama, em ordem, os avisos de entrega de um jogador, até o writeLoop fechar o canal.
+func ackLoop(acks <-chan func()) {
+	for delivered := range acks {
+		delivered()
+	}
+}
+
+// enqueueMessage coloca a mensagem na fila de saída do jogador sem bloquear.
+// O canal nunca é fechado (os remetentes estão em várias goroutines); a desconexão é
+// sinalizada por player.done, e mensagens enviadas depois dela são descartadas (ou guardadas
+// para o REJOIN, se o jogador caiu no meio de uma partida; ver rejoin.go).
+// Se a fila estiver cheia, o cliente não está lendo: a conexão é encerrada.
+func (s *Server) enqueueMessage(player *PlayerState, message string) {
+	if player.outbox == nil {
+		return // Jogador "fantasma" (remoto) ou bot: não há conexão local
+	}
+	select {
+	case <-player.done:
+		player.holdForRejoin(message)
+		return
+	default:
+	}
+	s.pushOutbox(player, outboxItem{message: message})
+}
+
+// afterDelivery chama delivered, no confirmador do jogador (ackLoop), depois que todas as mensagens
+// enfileiradas até agora para ele tiverem sido escritas na conexão. Se a conexão cair antes,
+// delivered nunca é chamada.
+func (s *Server) afterDelivery(player *PlayerState, delivered func()) {
+	if player.outbox == nil {
+		return
+	}
+	select {
+	case <-player.done:
+		return
+	default:
+	}
+	s.pushOutbox(player, outboxItem{delivered: delivered})
+}
+
+// pushOutbox coloca o item na fila de saída. Se ela estiver cheia, o cliente não está lendo:
+// a conexão é encerrada.
+func (s *Server) pushOutbox(player *PlayerState, item outboxItem) {
+	select {
+	case player.outbox <- item:
+	default:
+		slog.Error("Fila de saída cheia; encerrando conexão", "event", "outbox_full", "player", player.Name)
+		player.WsConn.Close()
+	}
+}
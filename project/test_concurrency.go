@@ -1,9 +1,14 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -13,9 +18,12 @@ import (
 
 // Constantes de teste
 const (
-	numBots           = 10
-	packsToOpenPerBot = 3
-	serverWsUrl       = "ws://server-1:8080" // Conecta ao primeiro servidor
+	numBots            = 10
+	packsToOpenPerBot  = 3
+	defaultServerWsUrl = "ws://server-1:8080" // Conecta ao primeiro servidor (TEST_SERVER_URL)
+	defaultRedisAddr   = "redis:6379"         // Redis do docker-compose (REDIS_ADDR)
+	defaultPackSize    = 3                    // Deve ser o mesmo PACK_SIZE dos servidores (PACK_SIZE)
+	stockKey           = "global_card_stock"  // Mesma chave usada pelo servidor (server/stock.go)
 
 	// Estratégia de retry do bot quando o servidor retornar "falha ao adquirir lock"
 	botMaxRetries = 3
@@ -24,15 +32,34 @@ const (
 
 // Estrutura para rastrear o estado do teste
 type TestState struct {
-	PacksOpened int
-	Mutex       sync.Mutex
+	PacksOpened  int
+	StarterPacks int // Dos PacksOpened, quantos foram o pacote inicial (STARTER_PACK_SIZE cartas)
+	Mutex        sync.Mutex
 }
 
 var globalTestState = TestState{}
 
+// Configuração do teste, lida das variáveis de ambiente para rodar tanto no Docker quanto localmente
+// (ex: REDIS_ADDR=localhost:6379 TEST_SERVER_URL=ws://localhost:8080 go run test_concurrency.go).
+var (
+	serverWsUrl = envOr("TEST_SERVER_URL", defaultServerWsUrl)
+	redisAddr   = envOr("REDIS_ADDR", defaultRedisAddr)
+	packSize    = defaultPackSize
+	starterSize = 0 // STARTER_PACK_SIZE; 0 = igual a PACK_SIZE, como no servidor
+)
+
 func main() {
 	log.Println("--- INICIANDO TESTE DE CONCORRÊNCIA DE ABERTURA DE PACOTES ---")
 	log.Printf("Simulando %d bots, cada um tentando abrir %d pacotes.", numBots, packsToOpenPerBot)
+	packSize = envSize("PACK_SIZE", defaultPackSize)
+	starterSize = envSize("STARTER_PACK_SIZE", packSize)
+
+	// Foto do estoque ANTES dos bots, lida direto do Redis
+	stockBefore, err := readStock()
+	if err != nil {
+		log.Fatalf("ERRO: não foi possível ler o estoque no Redis (%s): %v", redisAddr, err)
+	}
+	log.Printf("Estoque no Redis antes do teste: %d cartas.", len(stockBefore))
 
 	var wg sync.WaitGroup
 	wg.Add(numBots)
@@ -58,19 +85,128 @@ func main() {
 	log.Printf("Total de pacotes ABERTOS com sucesso (rastreado localmente): %d", globalTestState.PacksOpened)
 	log.Println("--------------------------")
 
-	// O teste é considerado bem-sucedido se o número de pacotes abertos for o esperado,
-	// e se o log do servidor não apresentar erros de concorrência no estoque.
-	// A verificação final do estoque no Redis deve ser feita manualmente ou via script
-	// para garantir que não houve duplicação ou perda.
-
 	// Se o número de pacotes abertos for menor que o esperado, pode ser devido ao limite de 3
 	// pacotes por jogador (que é uma regra de negócio).
 	expectedPacks := numBots * packsToOpenPerBot
 	if globalTestState.PacksOpened > expectedPacks {
 		log.Fatalf("ERRO: Pacotes abertos (%d) excedem o esperado (%d). Possível duplicação/falha de concorrência.", globalTestState.PacksOpened, expectedPacks)
-	} else {
-		log.Printf("Teste de concorrência concluído. O número de pacotes abertos está dentro do limite esperado.")
 	}
+
+	// Foto do estoque DEPOIS: todos os bots já receberam suas respostas, então não há retirada em andamento.
+	stockAfter, err := readStock()
+	if err != nil {
+		log.Fatalf("ERRO: não foi possível ler o estoque no Redis (%s): %v", redisAddr, err)
+	}
+	extraPacks := globalTestState.PacksOpened - globalTestState.StarterPacks
+	removed := globalTestState.StarterPacks*starterSize + extraPacks*packSize
+	if err := verifyStock(stockBefore, stockAfter, removed); err != nil {
+		log.Fatalf("ERRO: %v", err)
+	}
+	log.Printf("Estoque no Redis depois do teste: %d cartas (%d retiradas = %d pacotes iniciais x %d + %d pacotes extras x %d).",
+		len(stockAfter), removed, globalTestState.StarterPacks, starterSize, extraPacks, packSize)
+	log.Printf("Teste de concorrência concluído. O estoque no Redis confere com os pacotes abertos.")
+}
+
+// verifyStock confere que exatamente `removed` cartas saíram do início do estoque e que o
+// restante ficou intacto: como os pacotes saem por LPOP, o estoque depois deve ser igual ao
+// de antes sem as `removed` primeiras cartas. Qualquer carta duplicada, perdida ou retirada
+// fora de ordem quebra essa igualdade.
+// Uma reposição durante o teste (AUTO_RESTOCK, endpoint de restock) também a quebra: rode o
+// teste com estoque acima de STOCK_LOW_WATERMARK, ou com AUTO_RESTOCK=false.
+func verifyStock(before, after []string, removed int) error {
+	if got := len(before) - len(after); got != removed {
+		return fmt.Errorf("o estoque perdeu %d cartas, mas os bots abriram pacotes com %d (duplicação, perda ou reposição durante o teste)", got, removed)
+	}
+	for i, card := range after {
+		if before[removed+i] != card {
+			return fmt.Errorf("o estoque restante difere do original na posição %d: esperado %s, encontrado %s", i, before[removed+i], card)
+		}
+	}
+	return nil
+}
+
+// readStock lê a lista inteira do estoque (LRANGE stockKey 0 -1) direto do Redis.
+// Fala o protocolo RESP pela conexão TCP, para que o teste não dependa do cliente Redis do servidor.
+func readStock() ([]string, error) {
+	conn, err := net.DialTimeout("tcp", redisAddr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	args := []string{"LRANGE", stockKey, "0", "-1"}
+	cmd := fmt.Sprintf("*%d\r\n", len(args))
+	for _, a := range args {
+		cmd += fmt.Sprintf("$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := conn.Write([]byte(cmd)); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	header, err := readRESPLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(header, "-") {
+		return nil, fmt.Errorf("erro do Redis: %s", header[1:])
+	}
+	if !strings.HasPrefix(header, "*") {
+		return nil, fmt.Errorf("resposta inesperada do Redis: %q", header)
+	}
+	count, err := strconv.Atoi(header[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	cards := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		line, err := readRESPLine(r)
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimPrefix(line, "$"))
+		if err != nil || !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("item inesperado na resposta do Redis: %q", line)
+		}
+		buf := make([]byte, size+2) // conteúdo + "\r\n"
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		cards = append(cards, string(buf[:size]))
+	}
+	return cards, nil
+}
+
+// readRESPLine lê uma linha do protocolo RESP, sem o "\r\n" final.
+func readRESPLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+// envSize lê um tamanho de pacote da variável de ambiente (padrão se não estiver definida).
+func envSize(name string, fallback int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		log.Fatalf("%s inválido: %q", name, raw)
+	}
+	return n
+}
+
+// envOr retorna a variável de ambiente, ou o padrão se ela não estiver definida.
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
 }
 
 func runTestBot(playerName string) {
@@ -86,7 +222,7 @@ func runTestBot(playerName string) {
 	conn.WriteMessage(websocket.TextMessage, []byte(playerName))
 
 	// 2. Espera a resposta inicial do servidor (pacote inicial obrigatório)
-	_, p, err := conn.ReadMessage()
+	p, err := readReply(conn)
 	if err != nil {
 		log.Printf("[Bot %s]: Erro ao receber pacote inicial: %v", playerName, err)
 		return
@@ -95,9 +231,10 @@ func runTestBot(playerName string) {
 	// Log da resposta inicial e contagem
 	resp := string(p)
 	log.Printf("[Bot %s] Resposta inicial: %s", playerName, resp)
-	if strings.Contains(resp, "Bem-vindo(a)") || strings.Contains(resp, "Parabéns") {
+	if strings.Contains(resp, "Bem-vindo(a)") {
 		globalTestState.Mutex.Lock()
 		globalTestState.PacksOpened++
+		globalTestState.StarterPacks++
 		globalTestState.Mutex.Unlock()
 	} else {
 		log.Printf("[Bot %s] Pacote inicial não confirmado como sucesso pelo bot (resposta recebida).", playerName)
@@ -106,7 +243,7 @@ func runTestBot(playerName string) {
 	// 3. Ação automatizada: O bot abre os pacotes extras.
 	for i := 0; i < packsToOpenPerBot-1; i++ { // -1 porque o primeiro já foi aberto
 		conn.WriteMessage(websocket.TextMessage, []byte("OPEN_PACK"))
-		_, p, err := conn.ReadMessage()
+		p, err := readReply(conn)
 		if err != nil {
 			log.Printf("[Bot %s]: Erro ao abrir pacote extra: %v", playerName, err)
 			break
@@ -124,3 +261,18 @@ func runTestBot(playerName string) {
 		}
 	}
 }
+
+// readReply lê a próxima resposta do servidor, ignorando os avisos do estoque global
+// (STOCK_EXHAUSTED/STOCK_REPLENISHED), que podem chegar entre um comando e a sua resposta.
+func readReply(conn *websocket.Conn) ([]byte, error) {
+	for {
+		_, p, err := conn.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+		if msg := strings.TrimSpace(string(p)); msg == "STOCK_EXHAUSTED" || msg == "STOCK_REPLENISHED" {
+			continue
+		}
+		return p, nil
+	}
+}